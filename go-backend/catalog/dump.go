@@ -0,0 +1,162 @@
+// Package catalog reads maker and model dumps, such as an offline extract of
+// the NHTSA vPIC database, for the bulk import of the car catalog.
+package catalog
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+)
+
+// the most entries a single dump may contain
+const MaxEntries = 100000
+
+// Column names accepted for the maker and the model, compared case insensitively.
+// The vPIC names come first.
+var (
+	makerColumns = []string{"make_name", "makename", "make", "maker", "brand"}
+	modelColumns = []string{"model_name", "modelname", "model"}
+)
+
+// A row of a JSON dump, both the vPIC and the plain names are accepted
+type jsonEntry struct {
+	MakeName  string `json:"Make_Name"`
+	ModelName string `json:"Model_Name"`
+	Maker     string `json:"maker"`
+	Model     string `json:"model"`
+}
+
+// Reads a CSV dump with a header row. The model column is optional, without it
+// only makers are imported.
+func ParseCSV(r io.Reader) ([]models.CatalogEntry, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("the file is empty")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	makerIndex := findColumn(header, makerColumns)
+	if makerIndex == -1 {
+		return nil, errors.New("the file has no maker column")
+	}
+	modelIndex := findColumn(header, modelColumns)
+
+	var entries []models.CatalogEntry
+	seen := make(map[models.CatalogEntry]bool)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		entry := models.CatalogEntry{Maker: field(record, makerIndex), Model: field(record, modelIndex)}
+		entries, err = appendEntry(entries, seen, entry)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+// Reads a JSON dump, either a list of rows or a vPIC response with the rows
+// in Results
+func ParseJSON(r io.Reader) ([]models.CatalogEntry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []jsonEntry
+	err = json.Unmarshal(data, &rows)
+	if err != nil {
+		var response struct {
+			Results []jsonEntry `json:"Results"`
+		}
+		if json.Unmarshal(data, &response) != nil {
+			return nil, errors.New("the file is not a list of makers and models")
+		}
+		rows = response.Results
+	}
+
+	var entries []models.CatalogEntry
+	seen := make(map[models.CatalogEntry]bool)
+	for _, row := range rows {
+		entry := models.CatalogEntry{Maker: row.MakeName, Model: row.ModelName}
+		if entry.Maker == "" {
+			entry.Maker = row.Maker
+		}
+		if entry.Model == "" {
+			entry.Model = row.Model
+		}
+
+		entries, err = appendEntry(entries, seen, entry)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+// Adds the cleaned up entry unless it is blank or a repeated one
+func appendEntry(entries []models.CatalogEntry, seen map[models.CatalogEntry]bool, entry models.CatalogEntry) ([]models.CatalogEntry, error) {
+	entry.Maker = normalizeName(entry.Maker)
+	entry.Model = normalizeName(entry.Model)
+	if entry.Maker == "" {
+		return entries, nil
+	}
+
+	key := models.CatalogEntry{Maker: strings.ToLower(entry.Maker), Model: strings.ToLower(entry.Model)}
+	if seen[key] {
+		return entries, nil
+	}
+	seen[key] = true
+
+	if len(entries) == MaxEntries {
+		return nil, fmt.Errorf("the file has more than %d entries", MaxEntries)
+	}
+
+	return append(entries, entry), nil
+}
+
+// Trims the name and collapses the inner whitespace
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+func findColumn(header []string, names []string) int {
+	for _, name := range names {
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), name) {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+func field(record []string, index int) string {
+	if index < 0 || index >= len(record) {
+		return ""
+	}
+
+	return record[index]
+}
@@ -0,0 +1,59 @@
+package catalog
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCSV_VPIC(t *testing.T) {
+	csv := "\ufeffMake_ID,Make_Name,Model_ID,Model_Name\n" +
+		"482,VOLKSWAGEN,3133,Golf\n" +
+		"482,Volkswagen, 3133 ,golf\n" +
+		"441,TESLA,1685,Model  S\n" +
+		"441,TESLA,,\n" +
+		",,,\n"
+
+	entries, err := ParseCSV(strings.NewReader(csv))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.CatalogEntry{
+		{Maker: "VOLKSWAGEN", Model: "Golf"},
+		{Maker: "TESLA", Model: "Model S"},
+		{Maker: "TESLA"},
+	}, entries)
+}
+
+func TestParseCSV_MakersOnly(t *testing.T) {
+	entries, err := ParseCSV(strings.NewReader("Maker\nŠkoda\nCitroën\n"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.CatalogEntry{{Maker: "Škoda"}, {Maker: "Citroën"}}, entries)
+}
+
+func TestParseCSV_Errors(t *testing.T) {
+	_, err := ParseCSV(strings.NewReader(""))
+	assert.EqualError(t, err, "the file is empty")
+
+	_, err = ParseCSV(strings.NewReader("Name,Year\nGolf,2020\n"))
+	assert.EqualError(t, err, "the file has no maker column")
+}
+
+func TestParseJSON(t *testing.T) {
+	vpic := `{"Count":2,"Message":"Response returned successfully","Results":[
+		{"Make_ID":482,"Make_Name":"VOLKSWAGEN","Model_ID":3133,"Model_Name":"Golf"},
+		{"Make_ID":482,"Make_Name":"VOLKSWAGEN","Model_ID":3134,"Model_Name":"Jetta"}]}`
+
+	entries, err := ParseJSON(strings.NewReader(vpic))
+	assert.NoError(t, err)
+	assert.Equal(t, []models.CatalogEntry{{Maker: "VOLKSWAGEN", Model: "Golf"}, {Maker: "VOLKSWAGEN", Model: "Jetta"}}, entries)
+
+	entries, err = ParseJSON(strings.NewReader(`[{"maker":"Dacia","model":"Duster"},{"maker":"dacia","model":"duster"}]`))
+	assert.NoError(t, err)
+	assert.Equal(t, []models.CatalogEntry{{Maker: "Dacia", Model: "Duster"}}, entries)
+
+	_, err = ParseJSON(strings.NewReader(`"Golf"`))
+	assert.EqualError(t, err, "the file is not a list of makers and models")
+}
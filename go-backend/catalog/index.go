@@ -0,0 +1,313 @@
+package catalog
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+)
+
+// Kinds of search results
+const (
+	KindMaker = "maker"
+	KindModel = "model"
+)
+
+// How well a name matches the query, the lower the better
+const (
+	rankExact = iota
+	rankPrefix
+	rankWordPrefix
+	rankFullPrefix
+	rankContains
+	rankFuzzy
+)
+
+// the shortest query that is looked for inside the names
+const minContainsLength = 3
+
+// A maker or model matching a search, MakerID and MakerName are the result
+// itself for a maker
+type Match struct {
+	Kind      string `json:"kind"`
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	MakerID   int    `json:"maker_id"`
+	MakerName string `json:"maker_name"`
+}
+
+type indexEntry struct {
+	match Match
+	// folded name without separators, e.g. "id4" for "ID.4"
+	key string
+	// folded words of the name
+	words []string
+	// folded maker and model name, so "skoda oct" finds the Octavia
+	full string
+}
+
+type rankedMatch struct {
+	entry *indexEntry
+	rank  int
+	typos int
+}
+
+// An in-memory index of the makers and models for the typeahead search. It is
+// safe for concurrent use and rebuilt whenever the catalog changes.
+type Index struct {
+	mu      sync.RWMutex
+	entries []indexEntry
+}
+
+func NewIndex() *Index {
+	return &Index{}
+}
+
+// Replaces the indexed makers and models. Models of a maker that is not given
+// are left out.
+func (i *Index) Rebuild(makers []models.CarMaker, carModels []models.CarModel) {
+	entries := make([]indexEntry, 0, len(makers)+len(carModels))
+	names := make(map[int]string, len(makers))
+
+	for _, maker := range makers {
+		names[maker.ID] = maker.Name
+		entries = append(entries, newEntry(Match{Kind: KindMaker, ID: maker.ID, Name: maker.Name, MakerID: maker.ID, MakerName: maker.Name}))
+	}
+
+	for _, model := range carModels {
+		makerName, ok := names[model.CarMakerID]
+		if !ok {
+			continue
+		}
+		entries = append(entries, newEntry(Match{Kind: KindModel, ID: model.ID, Name: model.Name, MakerID: model.CarMakerID, MakerName: makerName}))
+	}
+
+	i.mu.Lock()
+	i.entries = entries
+	i.mu.Unlock()
+}
+
+func newEntry(match Match) indexEntry {
+	words := strings.Fields(Fold(match.Name))
+	entry := indexEntry{match: match, key: strings.Join(words, ""), words: words}
+	if match.Kind == KindModel {
+		entry.full = strings.Join(strings.Fields(Fold(match.MakerName)), "") + entry.key
+	}
+
+	return entry
+}
+
+// Returns up to limit makers and models matching the query, the best first.
+// Exact and prefix matches rank before substring matches and those before
+// matches with typos. Makers come before models on the same rank.
+func (i *Index) Search(query string, limit int) []Match {
+	q := strings.Join(strings.Fields(Fold(query)), "")
+	if q == "" || limit <= 0 {
+		return []Match{}
+	}
+
+	maxTypos := allowedTypos(q)
+
+	i.mu.RLock()
+	var ranked []rankedMatch
+	for n := range i.entries {
+		entry := &i.entries[n]
+		if rank, typos, ok := entry.rank(q, maxTypos); ok {
+			ranked = append(ranked, rankedMatch{entry: entry, rank: rank, typos: typos})
+		}
+	}
+	i.mu.RUnlock()
+
+	sort.Slice(ranked, func(a, b int) bool {
+		x, y := ranked[a], ranked[b]
+		if x.rank != y.rank {
+			return x.rank < y.rank
+		}
+		if x.typos != y.typos {
+			return x.typos < y.typos
+		}
+		if x.entry.match.Kind != y.entry.match.Kind {
+			return x.entry.match.Kind == KindMaker
+		}
+		if len(x.entry.key) != len(y.entry.key) {
+			return len(x.entry.key) < len(y.entry.key)
+		}
+		return x.entry.match.Name < y.entry.match.Name
+	})
+
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	matches := make([]Match, 0, len(ranked))
+	for _, r := range ranked {
+		matches = append(matches, r.entry.match)
+	}
+
+	return matches
+}
+
+func (e *indexEntry) rank(q string, maxTypos int) (int, int, bool) {
+	switch {
+	case e.key == q:
+		return rankExact, 0, true
+	case strings.HasPrefix(e.key, q):
+		return rankPrefix, 0, true
+	}
+
+	for _, word := range e.words {
+		if strings.HasPrefix(word, q) {
+			return rankWordPrefix, 0, true
+		}
+	}
+
+	if e.full != "" && strings.HasPrefix(e.full, q) {
+		return rankFullPrefix, 0, true
+	}
+
+	// a letter or two is found in almost every name
+	if len(q) >= minContainsLength && strings.Contains(e.key, q) {
+		return rankContains, 0, true
+	}
+
+	if maxTypos == 0 {
+		return 0, 0, false
+	}
+
+	typos := prefixDistance(q, e.key)
+	if e.full != "" {
+		if d := prefixDistance(q, e.full); d < typos {
+			typos = d
+		}
+	}
+
+	if typos > maxTypos {
+		return 0, 0, false
+	}
+
+	return rankFuzzy, typos, true
+}
+
+// Short queries have to match exactly, longer ones may contain typos
+func allowedTypos(q string) int {
+	switch n := len([]rune(q)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// The fewest edits that turn the query into a prefix of the name, a swap of
+// two neighbouring letters counts as one edit
+func prefixDistance(query, name string) int {
+	q, s := []rune(query), []rune(name)
+
+	prev2 := make([]int, len(s)+1)
+	prev := make([]int, len(s)+1)
+	cur := make([]int, len(s)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(q); i++ {
+		cur[0] = i
+		for j := 1; j <= len(s); j++ {
+			cost := 1
+			if q[i-1] == s[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && q[i-1] == s[j-2] && q[i-2] == s[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+
+	best := prev[0]
+	for _, d := range prev {
+		if d < best {
+			best = d
+		}
+	}
+
+	return best
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+
+	return m
+}
+
+// Lower cases the name and strips the diacritics, everything but letters and
+// digits becomes a space, e.g. "Citroën DS-3" becomes "citroen ds 3"
+func Fold(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r <= unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+		case r > unicode.MaxASCII && unicode.IsLetter(r):
+			b.WriteString(unaccent(r))
+		default:
+			b.WriteRune(' ')
+		}
+	}
+
+	return b.String()
+}
+
+// Latin base letters of the accented letters used in maker and model names
+func unaccent(r rune) string {
+	switch r {
+	case 'à', 'á', 'â', 'ä', 'ã', 'å', 'ā', 'ą', 'ă':
+		return "a"
+	case 'æ':
+		return "ae"
+	case 'ç', 'č', 'ć':
+		return "c"
+	case 'ď', 'đ':
+		return "d"
+	case 'è', 'é', 'ê', 'ë', 'ě', 'ē', 'ę':
+		return "e"
+	case 'ğ':
+		return "g"
+	case 'ì', 'í', 'î', 'ï', 'ī', 'ı':
+		return "i"
+	case 'ľ', 'ĺ', 'ł':
+		return "l"
+	case 'ñ', 'ň', 'ń':
+		return "n"
+	case 'ò', 'ó', 'ô', 'ö', 'õ', 'ø', 'ō', 'ő':
+		return "o"
+	case 'œ':
+		return "oe"
+	case 'ř', 'ŕ':
+		return "r"
+	case 'š', 'ś', 'ş', 'ș':
+		return "s"
+	case 'ß':
+		return "ss"
+	case 'ť', 'ţ', 'ț':
+		return "t"
+	case 'ù', 'ú', 'û', 'ü', 'ů', 'ū', 'ű':
+		return "u"
+	case 'ý', 'ÿ':
+		return "y"
+	case 'ž', 'ź', 'ż':
+		return "z"
+	}
+
+	return string(r)
+}
@@ -0,0 +1,96 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func testIndex() *Index {
+	index := NewIndex()
+	index.Rebuild(
+		[]models.CarMaker{
+			{ID: 6, Name: "Citroën"},
+			{ID: 17, Name: "Mercedes-Benz"},
+			{ID: 23, Name: "Škoda"},
+			{ID: 28, Name: "Volkswagen"},
+		},
+		[]models.CarModel{
+			{ID: 40, CarMakerID: 6, Name: "C4 Picasso"},
+			{ID: 41, CarMakerID: 6, Name: "DS3"},
+			{ID: 150, CarMakerID: 17, Name: "C-Class"},
+			{ID: 190, CarMakerID: 23, Name: "Octavia"},
+			{ID: 226, CarMakerID: 28, Name: "Golf"},
+			{ID: 231, CarMakerID: 28, Name: "ID.4"},
+			{ID: 300, CarMakerID: 99, Name: "Orphan"},
+		},
+	)
+
+	return index
+}
+
+func names(matches []Match) []string {
+	result := []string{}
+	for _, m := range matches {
+		result = append(result, m.Name)
+	}
+
+	return result
+}
+
+func TestFold(t *testing.T) {
+	assert.Equal(t, "citroen ds 3", Fold("Citroën DS-3"))
+	assert.Equal(t, "skoda", Fold("ŠKODA"))
+	assert.Equal(t, "strasse", Fold("Straße"))
+}
+
+func TestSearch_Accents(t *testing.T) {
+	index := testIndex()
+
+	// the maker comes first, then its models
+	assert.Equal(t, []string{"Citroën", "DS3", "C4 Picasso"}, names(index.Search("citroen", 10)))
+	assert.Equal(t, []string{"Citroën", "DS3", "C4 Picasso"}, names(index.Search("CITROËN", 10)))
+	assert.Equal(t, []string{"Škoda", "Octavia"}, names(index.Search("skod", 10)))
+}
+
+func TestSearch_Ranking(t *testing.T) {
+	index := testIndex()
+
+	// the names starting with the query come before the models of a maker starting with it
+	matches := index.Search("c", 10)
+	assert.Equal(t, []string{"Citroën", "C-Class", "C4 Picasso", "DS3"}, names(matches))
+	assert.Equal(t, Match{Kind: KindModel, ID: 150, Name: "C-Class", MakerID: 17, MakerName: "Mercedes-Benz"}, matches[1])
+
+	assert.Equal(t, []string{"Mercedes-Benz"}, names(index.Search("benz", 10)))
+	assert.Equal(t, []string{"ID.4"}, names(index.Search("id4", 10)))
+	assert.Equal(t, []string{"Octavia"}, names(index.Search("skoda oct", 10)))
+	assert.Equal(t, []string{"C4 Picasso"}, names(index.Search("picasso", 10)))
+	assert.Equal(t, []string{"Citroën", "C-Class"}, names(index.Search("c", 2)))
+}
+
+func TestSearch_Typos(t *testing.T) {
+	index := testIndex()
+
+	assert.Equal(t, []string{"Volkswagen", "ID.4", "Golf"}, names(index.Search("volksawgen", 10)))
+	assert.Equal(t, []string{"Octavia"}, names(index.Search("octvia", 10)))
+	assert.Equal(t, []string{"Mercedes-Benz", "C-Class"}, names(index.Search("mercedez", 10)))
+	// short queries have to match exactly
+	assert.Empty(t, index.Search("gof", 10))
+}
+
+func TestSearch_Empty(t *testing.T) {
+	index := testIndex()
+
+	assert.Equal(t, []Match{}, index.Search("  ", 10))
+	assert.Empty(t, index.Search("orphan", 10))
+	assert.Empty(t, NewIndex().Search("golf", 10))
+}
+
+func TestPrefixDistance(t *testing.T) {
+	assert.Equal(t, 0, prefixDistance("golf", "golf"))
+	assert.Equal(t, 0, prefixDistance("gol", "golf"))
+	assert.Equal(t, 1, prefixDistance("glof", "golf"))
+	assert.Equal(t, 1, prefixDistance("gplf", "golf"))
+	assert.Equal(t, 2, prefixDistance("gxxf", "golf"))
+}
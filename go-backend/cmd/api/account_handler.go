@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/acornak/car-maintenance-tracker/export"
+	"github.com/acornak/car-maintenance-tracker/models"
+)
+
+// largest archive accepted by the account import
+const maxAccountImportSize = 50 << 20
+
+// largest decompressed JSON document accepted by the account import, the
+// decoded document is held in memory until the import is done
+const maxAccountDocumentSize = 50 << 20
+
+// Streams a ZIP archive with all data of the authenticated user
+func (app *application) exportAccountHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		app.methodNotAllowed(w)
+		return
+	}
+
+	userId, ok := app.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := app.models.DB.GetUserByID(userId)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	cars, err := app.models.DB.GetAllCarsByUserID(userId)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	histories := make([]models.CarHistory, 0, len(cars))
+	for _, car := range cars {
+		// cars shared with the user belong to the export of their owner and
+		// fleet cars to their organization
+		if car.UserId != userId || car.OrganizationID != nil {
+			continue
+		}
+
+		history, err := app.models.DB.GetCarHistory(car)
+		if err != nil {
+			app.logger.Error(err)
+			app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+			return
+		}
+		histories = append(histories, history)
+	}
+
+	now := time.Now()
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="car-maintenance-export-%s.zip"`, now.Format("2006-01-02")))
+	w.WriteHeader(http.StatusOK)
+
+	// the status is already sent, a failure can only be logged
+	err = export.Write(r.Context(), w, export.NewDocument(*user, histories, now), app.storage)
+	if err != nil {
+		app.logger.Error(err)
+	}
+}
+
+// Adds the cars of an exported archive, sent as the request body, to the
+// account of the authenticated user
+func (app *application) importAccountHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		app.methodNotAllowed(w)
+		return
+	}
+
+	userId, ok := app.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	archive, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxAccountImportSize))
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, errors.New("the archive must not be larger than 50 MB"), http.StatusBadRequest)
+		return
+	}
+
+	doc, err := export.Read(archive, maxAccountDocumentSize)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// uploaded images belong to the exported cars and are not in the archive,
+	// only pasted URLs still work for the imported cars
+	for i := range doc.Cars {
+		if validateCarImageURL(doc.Cars[i].Car.Image) != nil {
+			doc.Cars[i].Car.Image = ""
+		}
+	}
+
+	err = app.checkImportedAttachments(&doc)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var stored []string
+	imported, err := app.models.DB.ImportCarHistories(userId, doc.Cars, app.config.storage.quota, func(a models.Attachment) (string, error) {
+		key, err := attachmentKey(userId, a.CarID)
+		if err != nil {
+			return "", err
+		}
+
+		content, _, err := doc.OpenAttachment(a)
+		if err != nil {
+			return "", err
+		}
+		defer content.Close()
+
+		err = app.storage.Put(r.Context(), key, content, a.SizeBytes, a.ContentType)
+		if err != nil {
+			return "", err
+		}
+		stored = append(stored, key)
+
+		return key, nil
+	})
+	if err != nil {
+		// nothing is imported, the files stored so far have to go again
+		for _, key := range stored {
+			if deleteErr := app.storage.Delete(r.Context(), key); deleteErr != nil {
+				app.logger.Error(deleteErr)
+			}
+		}
+
+		app.logger.Error(err)
+		switch {
+		case errors.Is(err, models.ErrInvalidImport):
+			app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		case errors.Is(err, models.ErrQuotaExceeded):
+			app.writer.ErrorJson(w, err, http.StatusRequestEntityTooLarge)
+		default:
+			app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusCreated, map[string]int{"cars": imported}, "imported")
+}
+
+// Checks the files of the imported attachments the same way as uploads. The
+// type is detected from the content and the size taken from the archive,
+// attachments whose file was missing from the export are left out.
+func (app *application) checkImportedAttachments(doc *export.Document) error {
+	maxSize := app.config.storage.maxUploadSize
+
+	for i := range doc.Cars {
+		attachments := doc.Cars[i].Attachments[:0]
+
+		for _, a := range doc.Cars[i].Attachments {
+			content, size, err := doc.OpenAttachment(a)
+			if errors.Is(err, export.ErrNoFile) {
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			head := make([]byte, 512)
+			n, err := io.ReadFull(content, head)
+			content.Close()
+			if errors.Is(err, io.EOF) {
+				return fmt.Errorf("file of attachment %d is empty", a.ID)
+			} else if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+				return err
+			}
+
+			if size > maxSize {
+				return fmt.Errorf("file of attachment %d must not be larger than %d MB", a.ID, maxSize>>20)
+			}
+
+			contentType, ok := sniffAttachment(head[:n])
+			if !ok {
+				return fmt.Errorf("file of attachment %d: only PDF, JPEG, PNG and WebP files can be attached", a.ID)
+			}
+
+			a.FileName = sanitizeFileName(a.FileName, contentType)
+			a.ContentType = contentType
+			a.SizeBytes = size
+			attachments = append(attachments, a)
+		}
+
+		doc.Cars[i].Attachments = attachments
+	}
+
+	return nil
+}
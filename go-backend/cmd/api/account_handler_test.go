@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acornak/car-maintenance-tracker/export"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/acornak/car-maintenance-tracker/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func exportedDocument(t *testing.T, files map[string]string, attachments ...models.Attachment) export.Document {
+	store, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create the store: %v", err)
+	}
+
+	for key, content := range files {
+		err = store.Put(context.Background(), key, strings.NewReader(content), int64(len(content)), "")
+		if err != nil {
+			t.Fatalf("failed to store %s: %v", key, err)
+		}
+	}
+
+	cars := []models.CarHistory{{Car: models.Car{ID: 3}, Attachments: attachments}}
+	var buf bytes.Buffer
+	err = export.Write(context.Background(), &buf, export.NewDocument(models.User{ID: 5}, cars, time.Now()), store)
+	if err != nil {
+		t.Fatalf("failed to write the archive: %v", err)
+	}
+
+	doc, err := export.Read(buf.Bytes(), 1<<20)
+	if err != nil {
+		t.Fatalf("failed to read the archive: %v", err)
+	}
+
+	return doc
+}
+
+func TestCheckImportedAttachments(t *testing.T) {
+	app := &application{config: config{storage: storageConfig{maxUploadSize: 1 << 20}}}
+
+	doc := exportedDocument(t, map[string]string{"attachments/5/3/abc": "%PDF-1.7\n invoice"},
+		models.Attachment{ID: 14, FileName: "evil\r\n.pdf", ContentType: "text/html", SizeBytes: 1 << 30, StorageKey: "attachments/5/3/abc"},
+		models.Attachment{ID: 15, FileName: "lost.pdf", StorageKey: "attachments/5/3/def"},
+	)
+
+	assert.NoError(t, app.checkImportedAttachments(&doc))
+	assert.Equal(t, []models.Attachment{
+		{ID: 14, FileName: "evil.pdf", ContentType: "application/pdf", SizeBytes: 17},
+	}, doc.Cars[0].Attachments)
+}
+
+func TestCheckImportedAttachments_Rejected(t *testing.T) {
+	app := &application{config: config{storage: storageConfig{maxUploadSize: 16}}}
+
+	doc := exportedDocument(t, map[string]string{"attachments/5/3/abc": "<html><script>alert(1)</script>"},
+		models.Attachment{ID: 14, FileName: "page.pdf", StorageKey: "attachments/5/3/abc"})
+	assert.EqualError(t, app.checkImportedAttachments(&doc), "file of attachment 14 must not be larger than 0 MB")
+
+	app.config.storage.maxUploadSize = 1 << 20
+	assert.EqualError(t, app.checkImportedAttachments(&doc), "file of attachment 14: only PDF, JPEG, PNG and WebP files can be attached")
+}
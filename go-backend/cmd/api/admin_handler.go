@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/acornak/car-maintenance-tracker/catalog"
+	"github.com/acornak/car-maintenance-tracker/models"
+)
+
+const maxCatalogImportSize = 20 << 20
+
+// the length of the name columns of the catalog tables
+const maxCatalogNameLength = 100
+
+type catalogNameRequest struct {
+	Name string `json:"name"`
+}
+
+func (app *application) createMakerHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		app.methodNotAllowed(w)
+		return
+	}
+
+	if _, ok := app.authenticateAdmin(w, r); !ok {
+		return
+	}
+
+	name, ok := app.readCatalogName(w, r)
+	if !ok {
+		return
+	}
+
+	maker, err := app.models.DB.InsertMaker(name)
+	if err != nil {
+		app.catalogError(w, err)
+		return
+	}
+	app.refreshCatalogIndex()
+
+	app.writer.WriteJson(w, http.StatusCreated, maker, "maker")
+}
+
+func (app *application) createModelHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		app.methodNotAllowed(w)
+		return
+	}
+
+	if _, ok := app.authenticateAdmin(w, r); !ok {
+		return
+	}
+
+	var req struct {
+		CarMakerID int    `json:"car_maker_id"`
+		Name       string `json:"name"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	name, err := validateCatalogName(req.Name)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	model, err := app.models.DB.InsertModel(req.CarMakerID, name)
+	if err != nil {
+		app.catalogError(w, err)
+		return
+	}
+	app.refreshCatalogIndex()
+
+	app.writer.WriteJson(w, http.StatusCreated, model, "model")
+}
+
+func (app *application) renameMakerHandler(w http.ResponseWriter, r *http.Request) {
+	app.renameCatalogEntry(w, r, app.models.DB.RenameMaker)
+}
+
+func (app *application) renameModelHandler(w http.ResponseWriter, r *http.Request) {
+	app.renameCatalogEntry(w, r, app.models.DB.RenameModel)
+}
+
+func (app *application) mergeMakerHandler(w http.ResponseWriter, r *http.Request) {
+	app.mergeCatalogEntry(w, r, app.models.DB.MergeMaker)
+}
+
+func (app *application) mergeModelHandler(w http.ResponseWriter, r *http.Request) {
+	app.mergeCatalogEntry(w, r, app.models.DB.MergeModel)
+}
+
+func (app *application) retireMakerHandler(w http.ResponseWriter, r *http.Request) {
+	app.retireCatalogEntry(w, r, app.models.DB.RetireMaker)
+}
+
+func (app *application) retireModelHandler(w http.ResponseWriter, r *http.Request) {
+	app.retireCatalogEntry(w, r, app.models.DB.RetireModel)
+}
+
+// Renames the maker or model given by the id query parameter
+func (app *application) renameCatalogEntry(w http.ResponseWriter, r *http.Request, rename func(int, string) error) {
+	if r.Method != http.MethodPost {
+		app.methodNotAllowed(w)
+		return
+	}
+
+	if _, ok := app.authenticateAdmin(w, r); !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	name, ok := app.readCatalogName(w, r)
+	if !ok {
+		return
+	}
+
+	err = rename(id, name)
+	if err != nil {
+		app.catalogError(w, err)
+		return
+	}
+	app.refreshCatalogIndex()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Merges the maker or model given by the id query parameter into the one
+// given by into, the merged entry is retired
+func (app *application) mergeCatalogEntry(w http.ResponseWriter, r *http.Request, merge func(int, int) error) {
+	if r.Method != http.MethodPost {
+		app.methodNotAllowed(w)
+		return
+	}
+
+	if _, ok := app.authenticateAdmin(w, r); !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	into, err := strconv.Atoi(r.URL.Query().Get("into"))
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if id == into {
+		err = errors.New("an entry can not be merged into itself")
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = merge(id, into)
+	if err != nil {
+		app.catalogError(w, err)
+		return
+	}
+	app.refreshCatalogIndex()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Retires the maker or model given by the id query parameter
+func (app *application) retireCatalogEntry(w http.ResponseWriter, r *http.Request, retire func(int) error) {
+	if r.Method != http.MethodPost {
+		app.methodNotAllowed(w)
+		return
+	}
+
+	if _, ok := app.authenticateAdmin(w, r); !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = retire(id)
+	if err != nil {
+		app.catalogError(w, err)
+		return
+	}
+	app.refreshCatalogIndex()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Adds the makers and models of a CSV or JSON dump that are not in the catalog
+// yet. The format is taken from the content type, CSV is the default.
+func (app *application) importCatalogHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		app.methodNotAllowed(w)
+		return
+	}
+
+	if _, ok := app.authenticateAdmin(w, r); !ok {
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxCatalogImportSize)
+
+	var entries []models.CatalogEntry
+	var err error
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		entries, err = catalog.ParseJSON(body)
+	} else {
+		entries, err = catalog.ParseCSV(body)
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, errors.New("the file must not be larger than 20 MB"), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	for _, entry := range entries {
+		if len(entry.Maker) > maxCatalogNameLength || len(entry.Model) > maxCatalogNameLength {
+			err = errors.New("names must not be longer than 100 characters")
+			app.logger.Error(err)
+			app.writer.ErrorJson(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	result, err := app.models.DB.ImportCatalog(entries)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+	if result.MakersCreated > 0 || result.ModelsCreated > 0 {
+		app.refreshCatalogIndex()
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, result, "imported")
+}
+
+// Reads and checks the name of a catalog entry from the request body
+func (app *application) readCatalogName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req catalogNameRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return "", false
+	}
+
+	name, err := validateCatalogName(req.Name)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return "", false
+	}
+
+	return name, true
+}
+
+func validateCatalogName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return "", errors.New("name is required")
+	}
+
+	if len(name) > maxCatalogNameLength {
+		return "", errors.New("name must not be longer than 100 characters")
+	}
+
+	return name, nil
+}
+
+// Writes a catalog error, a name that is already taken is a conflict
+func (app *application) catalogError(w http.ResponseWriter, err error) {
+	if errors.Is(err, models.ErrDuplicateName) {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusConflict)
+		return
+	}
+
+	app.modelError(w, err)
+}
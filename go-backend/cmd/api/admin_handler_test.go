@@ -0,0 +1,20 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCatalogName(t *testing.T) {
+	name, err := validateCatalogName("  Alfa   Romeo ")
+	assert.NoError(t, err)
+	assert.Equal(t, "Alfa Romeo", name)
+
+	_, err = validateCatalogName("   ")
+	assert.EqualError(t, err, "name is required")
+
+	_, err = validateCatalogName(strings.Repeat("a", 101))
+	assert.EqualError(t, err, "name must not be longer than 100 characters")
+}
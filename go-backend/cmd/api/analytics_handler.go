@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+)
+
+// Cost analytics of the user's cars. Query parameters: period (week, month or
+// year, defaults to month), car_id to limit the report to one car, from and to.
+func (app *application) getAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		app.methodNotAllowed(w)
+		return
+	}
+
+	userId, ok := app.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	filter := models.AnalyticsFilter{UserID: userId, Period: models.PeriodMonth}
+	query := r.URL.Query()
+
+	if period := query.Get("period"); period != "" {
+		if !models.IsAnalyticsPeriod(period) {
+			app.writer.ErrorJson(w, errors.New("invalid period, expected week, month or year"), http.StatusBadRequest)
+			return
+		}
+		filter.Period = period
+	}
+
+	var err error
+	filter.Dates, err = parseDateRange(r)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if carIdStr := query.Get("car_id"); carIdStr != "" {
+		carId, err := strconv.Atoi(carIdStr)
+		if err != nil {
+			app.logger.Error(err)
+			app.writer.ErrorJson(w, errors.New("invalid car id"), http.StatusBadRequest)
+			return
+		}
+
+		car, ok := app.authorizeCar(w, userId, carId, models.RoleViewer)
+		if !ok {
+			return
+		}
+		// a car shared with the user is reported with the costs of its owner
+		filter.UserID = car.UserId
+		filter.CarID = &carId
+	}
+
+	analytics, err := app.models.DB.GetAnalytics(filter)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, analytics, "analytics")
+}
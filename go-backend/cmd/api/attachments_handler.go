@@ -0,0 +1,283 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/acornak/car-maintenance-tracker/storage"
+)
+
+// how much of a multipart upload is held in memory, the rest goes to a temporary file
+const maxAttachmentMemory = 1 << 20
+
+// file types that can be attached, detected from the content rather than the
+// name or the type the client claims
+var attachmentContentTypes = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+}
+
+// Detects the type of a file from its first bytes, ok is false for types that
+// cannot be attached
+func sniffAttachment(head []byte) (string, bool) {
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	_, ok := attachmentContentTypes[contentType]
+
+	return contentType, ok
+}
+
+// Keeps the base name of an uploaded file without control characters, and
+// gives the file a name when the client sent none
+func sanitizeFileName(name, contentType string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+
+	if name == "" || name == "." || name == "/" {
+		name = "attachment" + attachmentContentTypes[contentType]
+	}
+
+	if len(name) > 255 {
+		ext := path.Ext(name)
+		if len(ext) > 16 {
+			ext = ""
+		}
+		name = strings.ToValidUTF8(name[:255-len(ext)], "") + ext
+	}
+
+	return name
+}
+
+// The files are grouped by the owner of the car and the car, the random name
+// keeps the keys of different uploads apart
+func attachmentKey(userId, carId int) (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("attachments/%d/%d/%s", userId, carId, hex.EncodeToString(b)), nil
+}
+
+// Attachments of the car, ?record_type= and ?record_id= narrow them down to one record
+func (app *application) getAttachmentsHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	query := r.URL.Query()
+
+	recordType := strings.ToLower(query.Get("record_type"))
+	if recordType != "" && !models.IsAttachmentRecordType(recordType) {
+		app.writer.ErrorJson(w, errors.New("invalid record type"), http.StatusBadRequest)
+		return
+	}
+
+	var recordId int
+	if id := query.Get("record_id"); id != "" {
+		var err error
+		recordId, err = strconv.Atoi(id)
+		if err != nil || recordId <= 0 {
+			app.writer.ErrorJson(w, errors.New("invalid record id"), http.StatusBadRequest)
+			return
+		}
+	}
+
+	attachments, err := app.models.DB.GetAttachmentsByCarID(car.ID, recordType, recordId)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, attachments, "attachments")
+}
+
+// Uploads a file as a multipart form with the file and the record_type and
+// record_id of the maintenance record, expense or document it belongs to. The
+// size counts against the storage quota of the owner of the car, or of the
+// organization for a fleet car.
+func (app *application) addAttachmentHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	maxSize := app.config.storage.maxUploadSize
+
+	// leave room for the other fields and the multipart boundaries
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+maxAttachmentMemory)
+	err := r.ParseMultipartForm(maxAttachmentMemory)
+	if err != nil {
+		app.logger.Error(err)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			app.writer.ErrorJson(w, fmt.Errorf("file must not be larger than %d MB", maxSize>>20), http.StatusRequestEntityTooLarge)
+			return
+		}
+		app.writer.ErrorJson(w, errors.New("expected a multipart form with a file"), http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	attachment := models.Attachment{
+		UserID:     car.UserId,
+		CarID:      car.ID,
+		RecordType: r.FormValue("record_type"),
+	}
+	attachment.RecordID, _ = strconv.Atoi(r.FormValue("record_id"))
+
+	err = attachment.Validate()
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	exists, err := app.models.DB.AttachmentRecordExists(car.ID, attachment.RecordType, attachment.RecordID)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		app.writer.ErrorJson(w, fmt.Errorf("%s not found", attachment.RecordType), http.StatusNotFound)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, errors.New("missing file"), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxSize {
+		app.writer.ErrorJson(w, fmt.Errorf("file must not be larger than %d MB", maxSize>>20), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if errors.Is(err, io.EOF) {
+		app.writer.ErrorJson(w, errors.New("file is empty"), http.StatusBadRequest)
+		return
+	} else if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	contentType, ok := sniffAttachment(head[:n])
+	if !ok {
+		app.writer.ErrorJson(w, errors.New("only PDF, JPEG, PNG and WebP files can be attached"), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	attachment.FileName = sanitizeFileName(header.Filename, contentType)
+	attachment.ContentType = contentType
+	attachment.SizeBytes = header.Size
+	attachment.StorageKey, err = attachmentKey(car.UserId, car.ID)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	err = app.storage.Put(r.Context(), attachment.StorageKey, file, attachment.SizeBytes, contentType)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, errors.New("failed to store the file"), http.StatusInternalServerError)
+		return
+	}
+
+	attachment.ID, err = app.models.DB.InsertAttachment(attachment, app.config.storage.quota)
+	if err != nil {
+		// the file is stored before the quota is checked, so it has to go again
+		if deleteErr := app.storage.Delete(r.Context(), attachment.StorageKey); deleteErr != nil {
+			app.logger.Error(deleteErr)
+		}
+
+		app.logger.Error(err)
+		if errors.Is(err, models.ErrQuotaExceeded) {
+			app.writer.ErrorJson(w, err, http.StatusRequestEntityTooLarge)
+			return
+		}
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusCreated, attachment, "attachment")
+}
+
+// Downloads the file. It is always served as a download with the sniffed type,
+// so an uploaded file cannot run as a page of the API's origin.
+func (app *application) getAttachmentHandler(w http.ResponseWriter, r *http.Request, car models.Car, attachmentId int) {
+	attachment, err := app.models.DB.GetAttachmentByID(car.ID, attachmentId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	content, err := app.storage.Get(r.Context(), attachment.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, errors.New("file of the attachment is missing"), http.StatusNotFound)
+		return
+	} else if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.SizeBytes, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = io.Copy(w, content)
+	if err != nil {
+		app.logger.Error(err)
+	}
+}
+
+func (app *application) removeAttachmentHandler(w http.ResponseWriter, r *http.Request, car models.Car, attachmentId int) {
+	attachment, err := app.models.DB.GetAttachmentByID(car.ID, attachmentId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	err = app.models.DB.RemoveAttachment(car.ID, attachmentId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	// a file left behind only takes up space, the attachment is gone either way
+	err = app.storage.Delete(r.Context(), attachment.StorageKey)
+	if err != nil {
+		app.logger.Error(err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSniffAttachment(t *testing.T) {
+	allowed := map[string]string{
+		"%PDF-1.7\n":                   "application/pdf",
+		"\xff\xd8\xff\xe0\x00\x10JFIF": "image/jpeg",
+		"\x89PNG\r\n\x1a\n":            "image/png",
+		"RIFF\x00\x00\x00\x00WEBPVP8 ": "image/webp",
+	}
+	for head, expected := range allowed {
+		contentType, ok := sniffAttachment([]byte(head))
+		assert.True(t, ok, expected)
+		assert.Equal(t, expected, contentType)
+	}
+
+	rejected := []string{"<html><script>alert(1)</script>", "GIF89a", "plain text", "PK\x03\x04"}
+	for _, head := range rejected {
+		_, ok := sniffAttachment([]byte(head))
+		assert.False(t, ok, head)
+	}
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	assert.Equal(t, "invoice.pdf", sanitizeFileName("invoice.pdf", "application/pdf"))
+	assert.Equal(t, "passwd", sanitizeFileName("../../etc/passwd", "application/pdf"))
+	assert.Equal(t, "scan.png", sanitizeFileName(`C:\Users\me\scan.png`, "image/png"))
+	assert.Equal(t, "evilname.pdf", sanitizeFileName("evil\"\r\nname.pdf", "application/pdf"))
+	assert.Equal(t, "attachment.jpg", sanitizeFileName("", "image/jpeg"))
+
+	long := sanitizeFileName(strings.Repeat("ř", 200)+".pdf", "application/pdf")
+	assert.LessOrEqual(t, len(long), 255)
+	assert.True(t, strings.HasSuffix(long, ".pdf"))
+}
+
+func TestAttachmentKey(t *testing.T) {
+	key, err := attachmentKey(5, 2)
+	assert.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^attachments/5/2/[0-9a-f]{32}$`), key)
+
+	other, err := attachmentKey(5, 2)
+	assert.NoError(t, err)
+	assert.NotEqual(t, key, other)
+}
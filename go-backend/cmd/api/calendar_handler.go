@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/acornak/car-maintenance-tracker/ical"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/acornak/car-maintenance-tracker/reminder"
+)
+
+// the events of the feed are announced this long before they are due
+var calendarAlarms = []time.Duration{7 * 24 * time.Hour, 24 * time.Hour}
+
+// POST creates a new feed URL for the authenticated user and revokes the
+// previous one, DELETE revokes the feed
+func (app *application) calendarTokenHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := app.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		feedToken, err := app.models.DB.CreateCalendarToken(userId)
+		if err != nil {
+			app.logger.Error(err)
+			app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		path := "/api/" + app.apiVersion + "/calendar/feed/" + feedToken + ".ics"
+		app.writer.WriteJson(w, http.StatusCreated, map[string]string{"url": requestOrigin(r) + path}, "calendar")
+	case http.MethodDelete:
+		err := app.models.DB.RevokeCalendarToken(userId)
+		if err != nil {
+			app.modelError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		app.methodNotAllowed(w)
+	}
+}
+
+// Serves the iCalendar feed at /calendar/feed/{token}.ics. Calendar clients
+// cannot send the access token cookie, the secret token in the URL identifies
+// the user instead.
+func (app *application) calendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		app.methodNotAllowed(w)
+		return
+	}
+
+	feedToken := strings.TrimPrefix(r.URL.Path, "/api/"+app.apiVersion+"/calendar/feed/")
+	feedToken = strings.TrimSuffix(feedToken, ".ics")
+	if feedToken == "" || strings.Contains(feedToken, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	userId, err := app.models.DB.GetUserIDByCalendarToken(feedToken)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	cars, err := app.models.DB.GetCarsByUserID(userId)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	cal := ical.Calendar{Name: "Car maintenance", Now: now}
+	for _, car := range cars {
+		services, err := app.models.DB.GetDueServicesByCarID(car.ID, now)
+		if err != nil {
+			app.logger.Error(err)
+			app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		documents, err := app.models.DB.GetDocumentExpiriesByCarID(car.ID, now, reminder.DocumentNoticeDays)
+		if err != nil {
+			app.logger.Error(err)
+			app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		label := app.carLabel(car)
+		for _, service := range services {
+			if event, ok := serviceEvent(label, service); ok {
+				cal.Events = append(cal.Events, event)
+			}
+		}
+		for _, document := range documents {
+			cal.Events = append(cal.Events, documentEvent(label, document))
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=900")
+	err = ical.Write(w, cal)
+	if err != nil {
+		app.logger.Error(err)
+	}
+}
+
+// Builds the calendar event of a service. Services only due by distance have no
+// date and are left out of the calendar.
+func serviceEvent(carLabel string, service models.DueService) (ical.Event, bool) {
+	if service.DueDate == nil {
+		return ical.Event{}, false
+	}
+
+	description := fmt.Sprintf("Last performed on %s at %d km.", service.LastServiceDate.Format("2006-01-02"), service.LastOdometer)
+	if service.DueOdometer != nil {
+		description += fmt.Sprintf("\nDue at %d km at the latest.", *service.DueOdometer)
+	}
+
+	return ical.Event{
+		// the reminder key only changes when the service is performed
+		UID:         reminder.ServiceReminder(service).DedupeKey + "@car-maintenance-tracker",
+		Date:        *service.DueDate,
+		Summary:     fmt.Sprintf("%s: %s", carLabel, service.ServiceType),
+		Description: description,
+		Alarms:      calendarAlarms,
+	}, true
+}
+
+// Builds the calendar event on the last day a document is valid
+func documentEvent(carLabel string, document models.DocumentExpiry) ical.Event {
+	var details []string
+	if document.Issuer != "" {
+		details = append(details, "Issued by "+document.Issuer+".")
+	}
+	if document.PolicyNumber != "" {
+		details = append(details, "Number "+document.PolicyNumber+".")
+	}
+	if document.MileageLimit != nil {
+		details = append(details, fmt.Sprintf("Valid up to %d km.", *document.MileageLimit))
+	}
+
+	return ical.Event{
+		// the reminder key only changes when the document is renewed
+		UID:         reminder.DocumentReminder(document).DedupeKey + "@car-maintenance-tracker",
+		Date:        document.ValidUntil,
+		Summary:     fmt.Sprintf("%s: %s expires", carLabel, models.DocumentTypeName(document.DocumentType)),
+		Description: strings.Join(details, "\n"),
+		Alarms:      calendarAlarms,
+	}
+}
+
+// Names the car by its maker, model and license plate
+func (app *application) carLabel(car models.Car) string {
+	var parts []string
+
+	maker, err := app.models.DB.GetMakerByID(car.BrandID)
+	if err == nil {
+		parts = append(parts, maker.Name)
+	}
+
+	model, err := app.models.DB.GetModelByID(car.ModelID)
+	if err == nil {
+		parts = append(parts, model.Name)
+	}
+
+	if car.LicensePlate != "" {
+		parts = append(parts, "("+car.LicensePlate+")")
+	}
+
+	if len(parts) == 0 {
+		return fmt.Sprintf("Car %d", car.ID)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Returns the scheme and host the client used to reach the API
+func requestOrigin(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	return scheme + "://" + r.Host
+}
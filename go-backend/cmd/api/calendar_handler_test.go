@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceEvent(t *testing.T) {
+	dueDate := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	dueOdometer := 45000
+	service := models.DueService{
+		ScheduleID:      1,
+		ServiceType:     "Oil change",
+		LastServiceDate: time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC),
+		LastOdometer:    30000,
+		DueDate:         &dueDate,
+		DueOdometer:     &dueOdometer,
+	}
+
+	event, ok := serviceEvent("Škoda Octavia", service)
+
+	assert.True(t, ok)
+	assert.Equal(t, "service:1:2022-07-01:30000@car-maintenance-tracker", event.UID)
+	assert.Equal(t, "Škoda Octavia: Oil change", event.Summary)
+	assert.Equal(t, dueDate, event.Date)
+	assert.Contains(t, event.Description, "Due at 45000 km")
+	assert.Len(t, event.Alarms, 2)
+
+	service.DueDate = nil
+	_, ok = serviceEvent("Škoda Octavia", service)
+	assert.False(t, ok)
+}
+
+func TestDocumentEvent(t *testing.T) {
+	validUntil := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	document := models.DocumentExpiry{
+		VehicleDocument: models.VehicleDocument{
+			ID:           4,
+			DocumentType: models.DocumentInsurance,
+			Issuer:       "Allianz",
+			PolicyNumber: "P-123",
+			ValidUntil:   validUntil,
+		},
+	}
+
+	event := documentEvent("Škoda Octavia", document)
+
+	assert.Equal(t, "document:4:2023-07-01@car-maintenance-tracker", event.UID)
+	assert.Equal(t, "Škoda Octavia: Insurance expires", event.Summary)
+	assert.Equal(t, validUntil, event.Date)
+	assert.Equal(t, "Issued by Allianz.\nNumber P-123.", event.Description)
+}
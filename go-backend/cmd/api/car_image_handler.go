@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/acornak/car-maintenance-tracker/imaging"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/acornak/car-maintenance-tracker/storage"
+)
+
+type carImageResponse struct {
+	models.CarImage
+	// URL of each rendition by its size name
+	URLs map[string]string `json:"urls"`
+}
+
+// The URL of a rendition of the uploaded image. It stays the same as long as
+// the image does, a new upload gets a new token and so new URLs.
+func (app *application) carImageURL(carId int, size, token string) string {
+	return fmt.Sprintf("/api/%s/cars/%d/image/%s?v=%s", app.apiVersion, carId, size, token)
+}
+
+func (app *application) newCarImageResponse(img models.CarImage) carImageResponse {
+	response := carImageResponse{CarImage: img, URLs: make(map[string]string)}
+	for _, size := range imaging.Sizes {
+		response.URLs[size.Name] = app.carImageURL(img.CarID, size.Name, img.Token)
+	}
+
+	return response
+}
+
+// Reports whether the image of a car is the URL of one of its uploaded images
+func (app *application) isUploadedCarImage(carId int, image string) bool {
+	prefix := fmt.Sprintf("/api/%s/cars/%d/image/", app.apiVersion, carId)
+
+	return strings.HasPrefix(image, prefix)
+}
+
+// Checks an image URL a client sets on a car. Until all cars have an uploaded
+// image, a pasted absolute http or https URL is still accepted.
+func validateCarImageURL(image string) error {
+	if image == "" {
+		return nil
+	}
+
+	if len(image) > 400 {
+		return errors.New("image URL must not be longer than 400 characters")
+	}
+
+	u, err := url.Parse(image)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("image must be an http or https URL, or be uploaded")
+	}
+
+	return nil
+}
+
+func (app *application) getCarImageHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	img, err := app.models.DB.GetCarImage(car.ID)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, app.newCarImageResponse(img), "image")
+}
+
+// Uploads a JPEG, PNG or WebP photo of the car as the file of a multipart
+// form. The renditions of every size replace the image of the car.
+func (app *application) uploadCarImageHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	maxSize := app.config.storage.maxUploadSize
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+maxAttachmentMemory)
+	err := r.ParseMultipartForm(maxAttachmentMemory)
+	if err != nil {
+		app.logger.Error(err)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			app.writer.ErrorJson(w, fmt.Errorf("image must not be larger than %d MB", maxSize>>20), http.StatusRequestEntityTooLarge)
+			return
+		}
+		app.writer.ErrorJson(w, errors.New("expected a multipart form with an image file"), http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, errors.New("missing image file"), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxSize {
+		app.writer.ErrorJson(w, fmt.Errorf("image must not be larger than %d MB", maxSize>>20), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	result, err := imaging.Process(file)
+	if errors.Is(err, imaging.ErrUnsupported) {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, imaging.ErrUnsupported, http.StatusUnsupportedMediaType)
+		return
+	} else if errors.Is(err, imaging.ErrTooLarge) {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusRequestEntityTooLarge)
+		return
+	} else if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	b := make([]byte, 16)
+	_, err = rand.Read(b)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+	token := hex.EncodeToString(b)
+
+	img := models.CarImage{
+		CarID:         car.ID,
+		UserID:        car.UserId,
+		Token:         token,
+		StoragePrefix: fmt.Sprintf("cars/%d/%d/%s", car.UserId, car.ID, token),
+		Width:         result.Width,
+		Height:        result.Height,
+	}
+
+	for _, size := range imaging.Sizes {
+		data := result.Renditions[size.Name]
+		err = app.storage.Put(r.Context(), img.StorageKey(size.Name), bytes.NewReader(data), int64(len(data)), "image/jpeg")
+		if err != nil {
+			app.logger.Error(err)
+			app.deleteCarImageFiles(r.Context(), img)
+			app.writer.ErrorJson(w, errors.New("failed to store the image"), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	previous, err := app.models.DB.ReplaceCarImage(img, app.carImageURL(car.ID, "large", token))
+	if err != nil {
+		app.deleteCarImageFiles(r.Context(), img)
+		app.modelError(w, err)
+		return
+	}
+
+	if previous != nil {
+		app.deleteCarImageFiles(r.Context(), *previous)
+	}
+
+	img, err = app.models.DB.GetCarImage(car.ID)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusCreated, app.newCarImageResponse(img), "image")
+}
+
+// Removes the image of the car, whether it was uploaded or a pasted URL
+func (app *application) removeCarImageHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	car.Image = ""
+	err := app.models.DB.UpdateCar(car)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	app.removeUploadedCarImage(r.Context(), car.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Serves a rendition of the uploaded image. Requested with the token of the
+// current image the response can be cached for good, otherwise it has to be
+// revalidated.
+func (app *application) serveCarImageHandler(w http.ResponseWriter, r *http.Request, car models.Car, size string) {
+	if !imaging.IsSize(size) {
+		app.writer.ErrorJson(w, errors.New("invalid image size"), http.StatusNotFound)
+		return
+	}
+
+	img, err := app.models.DB.GetCarImage(car.ID)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	etag := strconv.Quote(img.Token + "-" + size)
+	w.Header().Set("ETag", etag)
+	if r.URL.Query().Get("v") == img.Token {
+		w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "private, no-cache")
+	}
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	content, err := app.storage.Get(r.Context(), img.StorageKey(size))
+	if errors.Is(err, storage.ErrNotFound) {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, errors.New("image file is missing"), http.StatusNotFound)
+		return
+	} else if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = io.Copy(w, content)
+	if err != nil {
+		app.logger.Error(err)
+	}
+}
+
+// Deletes the uploaded image of a car, if it has one, with its renditions
+func (app *application) removeUploadedCarImage(ctx context.Context, carId int) {
+	img, err := app.models.DB.RemoveCarImage(carId)
+	if errors.Is(err, models.ErrRecordNotFound) {
+		return
+	} else if err != nil {
+		app.logger.Error(err)
+		return
+	}
+
+	app.deleteCarImageFiles(ctx, img)
+}
+
+// Deletes the renditions of an image, a file left behind only takes up space
+func (app *application) deleteCarImageFiles(ctx context.Context, img models.CarImage) {
+	for _, size := range imaging.Sizes {
+		err := app.storage.Delete(ctx, img.StorageKey(size.Name))
+		if err != nil {
+			app.logger.Error(err)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCarImageURL(t *testing.T) {
+	valid := []string{"", "https://example.com/octavia.jpg", "http://cdn.example.com/cars/1.png?w=400"}
+	for _, image := range valid {
+		assert.NoError(t, validateCarImageURL(image), image)
+	}
+
+	invalid := []string{"image.jpg", "javascript:alert(1)", "/api/v1/cars/2/image/large?v=abc", "ftp://example.com/car.jpg", "https://" + string(make([]byte, 400))}
+	for _, image := range invalid {
+		assert.Error(t, validateCarImageURL(image), image)
+	}
+}
+
+func TestCarImageURLs(t *testing.T) {
+	app := &application{apiVersion: "v1"}
+
+	response := app.newCarImageResponse(models.CarImage{CarID: 12, Token: "abc", Width: 1600, Height: 1200})
+
+	assert.Equal(t, "/api/v1/cars/12/image/large?v=abc", response.URLs["large"])
+	assert.Equal(t, "/api/v1/cars/12/image/thumb?v=abc", response.URLs["thumb"])
+	assert.Len(t, response.URLs, 4)
+
+	assert.True(t, app.isUploadedCarImage(12, response.URLs["large"]))
+	assert.False(t, app.isUploadedCarImage(1, response.URLs["large"]))
+	assert.False(t, app.isUploadedCarImage(12, "https://example.com/octavia.jpg"))
+}
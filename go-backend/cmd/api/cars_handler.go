@@ -1,13 +1,18 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/acornak/car-maintenance-tracker/models"
 	"github.com/acornak/car-maintenance-tracker/token"
+	"github.com/acornak/car-maintenance-tracker/vin"
 )
 
 func (app *application) addCarHandler(w http.ResponseWriter, r *http.Request) {
@@ -47,13 +52,32 @@ func (app *application) addCarHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	car, ok := app.readNewCar(w, r, userId)
+	if !ok {
+		return
+	}
+
+	// Insert the car into the database
+	err = app.models.DB.InsertCar(car)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	// Return a 201 status code
+	w.WriteHeader(http.StatusCreated)
+}
+
+// Decodes and checks a car the user adds, for the user or for an organization
+func (app *application) readNewCar(w http.ResponseWriter, r *http.Request, userId int) (models.Car, bool) {
 	// Parse the request body into a addCarRequest struct
 	var req models.Car
-	err = json.NewDecoder(r.Body).Decode(&req)
+	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		app.logger.Error(err)
 		app.writer.ErrorJson(w, err, http.StatusBadRequest)
-		return
+		return req, false
 	}
 
 	// Create a new car
@@ -67,19 +91,22 @@ func (app *application) addCarHandler(w http.ResponseWriter, r *http.Request) {
 		Image:        req.Image,
 		LicensePlate: req.LicensePlate,
 		VIN:          req.VIN,
+		VariantID:    req.VariantID,
 		Description:  req.Description,
 	}
 
-	// Insert the car into the database
-	err = app.models.DB.InsertCar(car)
+	err = validateCarImageURL(car.Image)
 	if err != nil {
 		app.logger.Error(err)
-		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
-		return
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return car, false
 	}
 
-	// Return a 201 status code
-	w.WriteHeader(http.StatusCreated)
+	if !app.checkCarVIN(w, &car) || !app.checkCarVariant(w, car) {
+		return car, false
+	}
+
+	return car, true
 }
 
 func (app *application) getCarsByUserHandler(w http.ResponseWriter, r *http.Request) {
@@ -119,7 +146,15 @@ func (app *application) getCarsByUserHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	cars, err := app.models.DB.GetCarsByUserID(userId)
+	// archived cars are only listed on request
+	includeArchived, _ := strconv.ParseBool(r.URL.Query().Get("include_archived"))
+
+	var cars []models.Car
+	if includeArchived {
+		cars, err = app.models.DB.GetAllCarsByUserID(userId)
+	} else {
+		cars, err = app.models.DB.GetCarsByUserID(userId)
+	}
 	if err != nil {
 		app.logger.Error(err)
 		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
@@ -257,18 +292,231 @@ func (app *application) getCarByIDHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	car, err := app.models.DB.GetCarByID(carId)
+	car, ok := app.authorizeCar(w, userId, carId, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, car, "car")
+}
+
+// Replaces all editable fields of a car
+func (app *application) updateCarHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	var req models.Car
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	app.saveCar(w, r, car, req)
+}
+
+// Updates only the fields present in the request body
+func (app *application) patchCarHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	// decoding over the stored car keeps the fields the client left out
+	req := car
+	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		app.logger.Error(err)
-		app.writer.ErrorJson(w, errors.New("user is not authorized to get this car"), http.StatusUnauthorized)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
 		return
 	}
 
-	if car.UserId != userId {
-		app.logger.Error("user is not authorized to get this car")
-		app.writer.ErrorJson(w, errors.New("user is not authorized to get this car"), http.StatusUnauthorized)
+	app.saveCar(w, r, car, req)
+}
+
+func (app *application) saveCar(w http.ResponseWriter, r *http.Request, car, req models.Car) {
+	// an image that is not changed is kept even if it is a pasted URL that
+	// would no longer be accepted
+	previousImage := car.Image
+	if req.Image != previousImage {
+		err := validateCarImageURL(req.Image)
+		if err != nil {
+			app.logger.Error(err)
+			app.writer.ErrorJson(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// the id, the owner and the archived state can not be changed here
+	car.BrandID = req.BrandID
+	car.ModelID = req.ModelID
+	car.Year = req.Year
+	car.Color = req.Color
+	car.Price = req.Price
+	car.Image = req.Image
+	car.Description = req.Description
+	car.LicensePlate = req.LicensePlate
+	car.VIN = req.VIN
+	car.VariantID = req.VariantID
+
+	err := car.Validate()
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if !app.checkCarVIN(w, &car) || !app.checkCarVariant(w, car) {
+		return
+	}
+
+	err = app.models.DB.UpdateCar(car)
+	if err != nil {
+		app.modelError(w, err)
 		return
 	}
 
+	// the uploaded image was replaced by a pasted URL or removed
+	if car.Image != previousImage && app.isUploadedCarImage(car.ID, previousImage) {
+		app.removeUploadedCarImage(r.Context(), car.ID)
+	}
+
 	app.writer.WriteJson(w, http.StatusOK, car, "car")
 }
+
+func (app *application) removeCarHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	err := app.models.DB.RemoveCar(car.ID)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Archives a car, optionally as sold with the sale date and price
+func (app *application) archiveCarHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	type archiveRequest struct {
+		SoldAt    *time.Time `json:"sold_at"`
+		SalePrice *int       `json:"sale_price"`
+	}
+
+	var req archiveRequest
+	// the body is optional, an empty one archives without a sale
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if req.SalePrice != nil && *req.SalePrice < 0 {
+		app.logger.Error("sale price must not be negative")
+		app.writer.ErrorJson(w, errors.New("sale price must not be negative"), http.StatusBadRequest)
+		return
+	}
+
+	if req.SalePrice != nil && req.SoldAt == nil {
+		app.logger.Error("sale price requires a sale date")
+		app.writer.ErrorJson(w, errors.New("sale price requires a sale date"), http.StatusBadRequest)
+		return
+	}
+
+	err = app.models.DB.ArchiveCar(car.ID, req.SoldAt, req.SalePrice)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	car, err = app.models.DB.GetCarByID(car.ID)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, car, "car")
+}
+
+func (app *application) unarchiveCarHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	err := app.models.DB.UnarchiveCar(car.ID)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	car.ArchivedAt = nil
+	car.SoldAt = nil
+	car.SalePrice = nil
+
+	app.writer.WriteJson(w, http.StatusOK, car, "car")
+}
+
+// Normalizes and validates the VIN of a car and rejects it when the
+// manufacturer decoded from the VIN is not the selected maker. Cars without a
+// VIN and VINs of manufacturers missing from the WMI table are accepted.
+func (app *application) checkCarVIN(w http.ResponseWriter, car *models.Car) bool {
+	car.VIN = vin.Normalize(car.VIN)
+	if car.VIN == "" {
+		return true
+	}
+
+	decoded, err := vin.Decode(car.VIN)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return false
+	}
+
+	if decoded.Manufacturer == "" {
+		return true
+	}
+
+	maker, err := app.models.DB.GetMakerByID(car.BrandID)
+	if errors.Is(err, sql.ErrNoRows) {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, errors.New("brand not found"), http.StatusBadRequest)
+		return false
+	} else if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return false
+	}
+
+	if !vin.SameMaker(decoded.Manufacturer, maker.Name) {
+		err = fmt.Errorf("VIN belongs to %s, not %s", decoded.Manufacturer, maker.Name)
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+// Decodes the VIN given by the vin query parameter and looks up the matching car maker
+func (app *application) decodeVINHandler(w http.ResponseWriter, r *http.Request) {
+	type decodeResponse struct {
+		vin.Decoded
+		// nil when the manufacturer is unknown or not in the car makers
+		MakerID *int `json:"maker_id"`
+	}
+
+	decoded, err := vin.Decode(r.URL.Query().Get("vin"))
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	resp := decodeResponse{Decoded: decoded}
+	if decoded.Manufacturer != "" {
+		makers, err := app.models.DB.GetAllCarMakers()
+		if err != nil {
+			app.logger.Error(err)
+			app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		for _, maker := range makers {
+			if vin.SameMaker(decoded.Manufacturer, maker.Name) {
+				id := maker.ID
+				resp.MakerID = &id
+				break
+			}
+		}
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, resp, "vin")
+}
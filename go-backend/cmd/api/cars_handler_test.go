@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/acornak/car-maintenance-tracker/writer"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestCheckCarVIN(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	app := &application{
+		logger: zap.NewNop().Sugar(),
+		writer: &writer.JsonWriter{},
+		models: models.NewModels(db),
+	}
+
+	tests := []struct {
+		name       string
+		car        models.Car
+		maker      string
+		ok         bool
+		statusCode int
+	}{
+		{name: "no VIN", car: models.Car{BrandID: 10}, ok: true},
+		{name: "invalid", car: models.Car{BrandID: 10, VIN: "1HGCM826"}, ok: false, statusCode: http.StatusBadRequest},
+		{name: "matching maker", car: models.Car{BrandID: 10, VIN: "1hgcm82633a004352"}, maker: "Honda", ok: true},
+		{name: "other maker", car: models.Car{BrandID: 27, VIN: "1HGCM82633A004352"}, maker: "Toyota", ok: false, statusCode: http.StatusBadRequest},
+		{name: "unknown manufacturer", car: models.Car{BrandID: 10, VIN: "ZZZZZZZZZZZZZZZZZ"}, ok: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.maker != "" {
+				mock.ExpectQuery("SELECT id, name, retired_at FROM car_makers WHERE id=").WithArgs(tt.car.BrandID).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "retired_at"}).AddRow(tt.car.BrandID, tt.maker, nil))
+			}
+
+			rr := httptest.NewRecorder()
+			car := tt.car
+			ok := app.checkCarVIN(rr, &car)
+
+			assert.Equal(t, tt.ok, ok)
+			if !tt.ok {
+				assert.Equal(t, tt.statusCode, rr.Code)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
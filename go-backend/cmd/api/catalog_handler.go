@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+)
+
+// Returns the generations of the model given by the model_id query parameter
+func (app *application) getGenerationsByModelIDHandler(w http.ResponseWriter, r *http.Request) {
+	modelID, err := strconv.Atoi(r.URL.Query().Get("model_id"))
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	generations, err := app.models.DB.GetGenerationsByModelID(modelID)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, generations, "generations")
+}
+
+// Returns the variants of the generation given by the generation_id query
+// parameter, optionally narrowed down by fuel_type and body_type
+func (app *application) getVariantsByGenerationIDHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	generationID, err := strconv.Atoi(query.Get("generation_id"))
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	filter := models.VariantFilter{
+		FuelType: query.Get("fuel_type"),
+		BodyType: query.Get("body_type"),
+	}
+
+	if filter.FuelType != "" && !models.IsFuelType(filter.FuelType) {
+		err = errors.New("invalid fuel type")
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if filter.BodyType != "" && !models.IsBodyType(filter.BodyType) {
+		err = errors.New("invalid body type")
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	variants, err := app.models.DB.GetVariantsByGenerationID(generationID, filter)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, variants, "variants")
+}
+
+func (app *application) getVariantByIDHandler(w http.ResponseWriter, r *http.Request) {
+	variantID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	variant, err := app.models.DB.GetVariantByID(variantID)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, variant, "variant")
+}
+
+func (app *application) getFuelTypesHandler(w http.ResponseWriter, r *http.Request) {
+	app.writer.WriteJson(w, http.StatusOK, models.FuelTypes, "fuel_types")
+}
+
+func (app *application) getBodyTypesHandler(w http.ResponseWriter, r *http.Request) {
+	app.writer.WriteJson(w, http.StatusOK, models.BodyTypes, "body_types")
+}
+
+// Checks that the variant of the car, if any, exists and belongs to its model.
+// Writes the error response and returns false otherwise.
+func (app *application) checkCarVariant(w http.ResponseWriter, car models.Car) bool {
+	if car.VariantID == nil {
+		return true
+	}
+
+	variant, err := app.models.DB.GetVariantByID(*car.VariantID)
+	if errors.Is(err, models.ErrRecordNotFound) {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, errors.New("variant not found"), http.StatusBadRequest)
+		return false
+	} else if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return false
+	}
+
+	if variant.ModelID != car.ModelID {
+		err = errors.New("variant does not belong to the model of the car")
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 50
+)
+
+// Typeahead search over the makers and models given by the q query parameter,
+// served from the in-memory catalog index
+func (app *application) searchCatalogHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		app.methodNotAllowed(w)
+		return
+	}
+
+	query := r.URL.Query()
+
+	limit := defaultSearchLimit
+	if l := query.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n <= 0 || n > maxSearchLimit {
+			err = errors.New("limit must be between 1 and 50")
+			app.logger.Error(err)
+			app.writer.ErrorJson(w, err, http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, app.catalogIndex.Search(query.Get("q"), limit), "results")
+}
+
+// Loads the makers and models that are not retired into the search index. The
+// lock is held from the load to the swap, a rebuild started after a catalog
+// change always finishes last.
+func (app *application) rebuildCatalogIndex() error {
+	app.catalogIndexMu.Lock()
+	defer app.catalogIndexMu.Unlock()
+
+	makers, carModels, err := app.models.DB.GetActiveCatalog()
+	if err != nil {
+		return err
+	}
+
+	app.catalogIndex.Rebuild(makers, carModels)
+	return nil
+}
+
+// Rebuilds the search index after the catalog changed. A failure is only
+// logged, the change itself is already stored.
+func (app *application) refreshCatalogIndex() {
+	err := app.rebuildCatalogIndex()
+	if err != nil {
+		app.logger.Error("failed to rebuild the catalog index: ", err)
+	}
+}
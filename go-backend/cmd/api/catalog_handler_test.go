@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/catalog"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/acornak/car-maintenance-tracker/writer"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestSearchCatalogHandler(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	app := &application{
+		logger:       zap.NewNop().Sugar(),
+		writer:       &writer.JsonWriter{},
+		models:       models.NewModels(db),
+		catalogIndex: catalog.NewIndex(),
+	}
+
+	mock.ExpectQuery("SELECT id, name FROM car_makers WHERE retired_at IS NULL").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(6, "Citroën").AddRow(28, "Volkswagen"))
+	mock.ExpectQuery("SELECT mo.id, mo.car_maker_id, mo.name FROM car_models mo JOIN car_makers ma").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "car_maker_id", "name"}).AddRow(41, 6, "C3").AddRow(226, 28, "Golf"))
+
+	assert.NoError(t, app.rebuildCatalogIndex())
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	rr := httptest.NewRecorder()
+	app.searchCatalogHandler(rr, httptest.NewRequest(http.MethodGet, "/api/v1/cars/search?q=citroen&limit=1", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"results":[{"kind":"maker","id":6,"name":"Citroën","maker_id":6,"maker_name":"Citroën"}]}`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	app.searchCatalogHandler(rr, httptest.NewRequest(http.MethodGet, "/api/v1/cars/search?q=golf&limit=100", nil))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
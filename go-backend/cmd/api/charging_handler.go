@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+)
+
+func (app *application) getChargingSessionsHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	dates, err := parseDateRange(r)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	sessions, err := app.models.DB.GetChargingSessionsByCarID(car.ID, dates)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, sessions, "charging")
+}
+
+func (app *application) getChargingSessionHandler(w http.ResponseWriter, r *http.Request, car models.Car, sessionId int) {
+	session, err := app.models.DB.GetChargingSessionByID(car.ID, sessionId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, session, "charging")
+}
+
+func (app *application) addChargingSessionHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	var session models.ChargingSession
+	err := json.NewDecoder(r.Body).Decode(&session)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = session.Validate()
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	session.CarID = car.ID
+	session.ID, err = app.models.DB.InsertChargingSession(session)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusCreated, session, "charging")
+}
+
+func (app *application) updateChargingSessionHandler(w http.ResponseWriter, r *http.Request, car models.Car, sessionId int) {
+	var session models.ChargingSession
+	err := json.NewDecoder(r.Body).Decode(&session)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = session.Validate()
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	session.ID = sessionId
+	session.CarID = car.ID
+	err = app.models.DB.UpdateChargingSession(session)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, session, "charging")
+}
+
+func (app *application) removeChargingSessionHandler(w http.ResponseWriter, r *http.Request, car models.Car, sessionId int) {
+	err := app.models.DB.RemoveChargingSession(car.ID, sessionId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Efficiency and cost statistics of the car, optionally limited to ?from= and ?to=
+func (app *application) getChargingStatsHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	dates, err := parseDateRange(r)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// the session before the range is needed for the starting point
+	sessions, err := app.models.DB.GetChargingSessionsByCarID(car.ID, models.DateRange{To: dates.To})
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	capacity, err := app.models.DB.GetLatestBatteryCapacity(car.ID)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, models.ComputeChargingStats(sessions, dates, capacity), "stats")
+}
+
+func (app *application) getBatteryHealthLogsHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	logs, err := app.models.DB.GetBatteryHealthLogsByCarID(car.ID)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, logs, "battery")
+}
+
+func (app *application) getBatteryHealthLogHandler(w http.ResponseWriter, r *http.Request, car models.Car, logId int) {
+	batteryLog, err := app.models.DB.GetBatteryHealthLogByID(car.ID, logId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, batteryLog, "battery")
+}
+
+func (app *application) addBatteryHealthLogHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	var batteryLog models.BatteryHealthLog
+	err := json.NewDecoder(r.Body).Decode(&batteryLog)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = batteryLog.Validate()
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	batteryLog.CarID = car.ID
+	batteryLog.ID, err = app.models.DB.InsertBatteryHealthLog(batteryLog)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusCreated, batteryLog, "battery")
+}
+
+func (app *application) updateBatteryHealthLogHandler(w http.ResponseWriter, r *http.Request, car models.Car, logId int) {
+	var batteryLog models.BatteryHealthLog
+	err := json.NewDecoder(r.Body).Decode(&batteryLog)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = batteryLog.Validate()
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	batteryLog.ID = logId
+	batteryLog.CarID = car.ID
+	err = app.models.DB.UpdateBatteryHealthLog(batteryLog)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, batteryLog, "battery")
+}
+
+func (app *application) removeBatteryHealthLogHandler(w http.ResponseWriter, r *http.Request, car models.Car, logId int) {
+	err := app.models.DB.RemoveBatteryHealthLog(car.ID, logId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Degradation trend of the battery capacity and range
+func (app *application) getBatteryHealthHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	logs, err := app.models.DB.GetBatteryHealthLogsByCarID(car.ID)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, models.ComputeBatteryHealth(logs), "stats")
+}
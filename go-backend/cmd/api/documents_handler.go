@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/acornak/car-maintenance-tracker/reminder"
+)
+
+// the longest notice period of the expiring documents
+const maxExpiringDays = 365
+
+func (app *application) getVehicleDocumentsHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	documents, err := app.models.DB.GetVehicleDocumentsByCarID(car.ID)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, documents, "documents")
+}
+
+func (app *application) getVehicleDocumentHandler(w http.ResponseWriter, r *http.Request, car models.Car, documentId int) {
+	document, err := app.models.DB.GetVehicleDocumentByID(car.ID, documentId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, document, "document")
+}
+
+func (app *application) addVehicleDocumentHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	var document models.VehicleDocument
+	err := json.NewDecoder(r.Body).Decode(&document)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = document.Validate()
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	document.CarID = car.ID
+	document.ID, err = app.models.DB.InsertVehicleDocument(document)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusCreated, document, "document")
+}
+
+func (app *application) updateVehicleDocumentHandler(w http.ResponseWriter, r *http.Request, car models.Car, documentId int) {
+	var document models.VehicleDocument
+	err := json.NewDecoder(r.Body).Decode(&document)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = document.Validate()
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	document.ID = documentId
+	document.CarID = car.ID
+	err = app.models.DB.UpdateVehicleDocument(document)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, document, "document")
+}
+
+func (app *application) removeVehicleDocumentHandler(w http.ResponseWriter, r *http.Request, car models.Car, documentId int) {
+	err := app.models.DB.RemoveVehicleDocument(car.ID, documentId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// The current documents of all the cars the user may access, fleet cars
+// included, that expire within ?days=, 30 by default, or have already expired
+func (app *application) getExpiringDocumentsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		app.methodNotAllowed(w)
+		return
+	}
+
+	userId, ok := app.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	days := reminder.DocumentNoticeDays
+	if value := r.URL.Query().Get("days"); value != "" {
+		var err error
+		days, err = strconv.Atoi(value)
+		if err != nil || days < 0 || days > maxExpiringDays {
+			err = errors.New("days must be a number between 0 and 365")
+			app.logger.Error(err)
+			app.writer.ErrorJson(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	documents, err := app.models.DB.GetDocumentExpiriesByUserID(userId, time.Now(), days)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	expiring := []models.DocumentExpiry{}
+	for _, document := range documents {
+		if document.Status != models.DueStatusUpcoming {
+			expiring = append(expiring, document)
+		}
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, expiring, "documents")
+}
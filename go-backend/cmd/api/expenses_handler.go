@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+)
+
+// Reads the optional from, to (YYYY-MM-DD) and category query parameters
+func parseExpenseFilter(r *http.Request) (models.ExpenseFilter, error) {
+	var filter models.ExpenseFilter
+
+	dates, err := parseDateRange(r)
+	if err != nil {
+		return filter, err
+	}
+	filter.From = dates.From
+	filter.To = dates.To
+
+	if category := r.URL.Query().Get("category"); category != "" {
+		if !models.IsExpenseCategory(category) {
+			return filter, errors.New("invalid category")
+		}
+		filter.Category = category
+	}
+
+	return filter, nil
+}
+
+func (app *application) getExpensesHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	filter, err := parseExpenseFilter(r)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	expenses, err := app.models.DB.GetExpensesByCarID(car.ID, filter)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, expenses, "expenses")
+}
+
+func (app *application) getExpenseHandler(w http.ResponseWriter, r *http.Request, car models.Car, expenseId int) {
+	expense, err := app.models.DB.GetExpenseByID(car.ID, expenseId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, expense, "expense")
+}
+
+func (app *application) addExpenseHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	expense, ok := app.readExpense(w, r, car)
+	if !ok {
+		return
+	}
+
+	var err error
+	expense.ID, err = app.models.DB.InsertExpense(expense)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusCreated, expense, "expense")
+}
+
+func (app *application) updateExpenseHandler(w http.ResponseWriter, r *http.Request, car models.Car, expenseId int) {
+	expense, ok := app.readExpense(w, r, car)
+	if !ok {
+		return
+	}
+
+	expense.ID = expenseId
+	err := app.models.DB.UpdateExpense(expense)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, expense, "expense")
+}
+
+func (app *application) removeExpenseHandler(w http.ResponseWriter, r *http.Request, car models.Car, expenseId int) {
+	err := app.models.DB.RemoveExpense(car.ID, expenseId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Decodes and validates an expense of the car from the request body. A linked
+// maintenance record has to belong to the same car.
+func (app *application) readExpense(w http.ResponseWriter, r *http.Request, car models.Car) (models.Expense, bool) {
+	var expense models.Expense
+	err := json.NewDecoder(r.Body).Decode(&expense)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return expense, false
+	}
+
+	err = expense.Validate()
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return expense, false
+	}
+
+	expense.CarID = car.ID
+	if expense.MaintenanceID != nil {
+		_, err = app.models.DB.GetMaintenanceRecordByID(car.ID, *expense.MaintenanceID)
+		if errors.Is(err, models.ErrRecordNotFound) {
+			app.writer.ErrorJson(w, errors.New("maintenance record not found"), http.StatusBadRequest)
+			return expense, false
+		} else if err != nil {
+			app.logger.Error(err)
+			app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+			return expense, false
+		}
+	}
+
+	return expense, true
+}
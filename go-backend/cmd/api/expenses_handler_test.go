@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseExpenseFilter(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/v1/cars/1/expenses?from=2023-01-01&to=2023-03-31&category=fuel", nil)
+	filter, err := parseExpenseFilter(r)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.ExpenseFilter{
+		From:     time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2023, 3, 31, 0, 0, 0, 0, time.UTC),
+		Category: "fuel",
+	}, filter)
+
+	r = httptest.NewRequest("GET", "/api/v1/cars/1/expenses", nil)
+	filter, err = parseExpenseFilter(r)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ExpenseFilter{}, filter)
+
+	invalid := []string{"from=01-01-2023", "category=snacks"}
+	for _, query := range invalid {
+		r = httptest.NewRequest("GET", "/api/v1/cars/1/expenses?"+query, nil)
+		_, err = parseExpenseFilter(r)
+		assert.Error(t, err, query)
+	}
+}
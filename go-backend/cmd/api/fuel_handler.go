@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+)
+
+func (app *application) getFuelLogsHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	dates, err := parseDateRange(r)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	logs, err := app.models.DB.GetFuelLogsByCarID(car.ID, dates)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, logs, "fuel")
+}
+
+func (app *application) getFuelLogHandler(w http.ResponseWriter, r *http.Request, car models.Car, fuelLogId int) {
+	fuelLog, err := app.models.DB.GetFuelLogByID(car.ID, fuelLogId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, fuelLog, "fuel")
+}
+
+func (app *application) addFuelLogHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	var fuelLog models.FuelLog
+	err := json.NewDecoder(r.Body).Decode(&fuelLog)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = fuelLog.Validate()
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	fuelLog.CarID = car.ID
+	fuelLog.ID, err = app.models.DB.InsertFuelLog(fuelLog)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusCreated, fuelLog, "fuel")
+}
+
+func (app *application) updateFuelLogHandler(w http.ResponseWriter, r *http.Request, car models.Car, fuelLogId int) {
+	var fuelLog models.FuelLog
+	err := json.NewDecoder(r.Body).Decode(&fuelLog)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = fuelLog.Validate()
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	fuelLog.ID = fuelLogId
+	fuelLog.CarID = car.ID
+	err = app.models.DB.UpdateFuelLog(fuelLog)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, fuelLog, "fuel")
+}
+
+func (app *application) removeFuelLogHandler(w http.ResponseWriter, r *http.Request, car models.Car, fuelLogId int) {
+	err := app.models.DB.RemoveFuelLog(car.ID, fuelLogId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Consumption and cost statistics of the car, optionally limited to ?from= and ?to=
+func (app *application) getFuelStatsHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	dates, err := parseDateRange(r)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// the fill-ups before the range are needed for the tank level at its start
+	logs, err := app.models.DB.GetFuelLogsByCarID(car.ID, models.DateRange{To: dates.To})
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, models.ComputeFuelStats(logs, dates), "stats")
+}
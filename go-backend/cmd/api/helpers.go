@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/acornak/car-maintenance-tracker/token"
+)
+
+// Reads the access token cookie and returns the id of the authenticated user.
+// When the request is not authenticated the response is written and ok is false.
+func (app *application) authenticate(w http.ResponseWriter, r *http.Request) (int, bool) {
+	// Get token from the cookie
+	cookie, err := r.Cookie("access_token")
+	if err != nil {
+		app.logger.Error(err)
+		if err == http.ErrNoCookie {
+			// If the cookie is not set, return an unauthorized status
+			app.logger.Error("no cookie found")
+			w.WriteHeader(http.StatusUnauthorized)
+			return 0, false
+		}
+		// For any other type of error, return a bad request status
+		w.WriteHeader(http.StatusBadRequest)
+		return 0, false
+	}
+
+	tokenString := cookie.Value
+	isValid, err := token.CheckTokenValidity(tokenString, app.config.jwtSigningKey)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return 0, false
+	}
+
+	if !isValid {
+		app.logger.Error("token is not valid")
+		w.WriteHeader(http.StatusUnauthorized)
+		return 0, false
+	}
+
+	userId, err := token.GetUserIdFromToken(tokenString, app.config.jwtSigningKey)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return 0, false
+	}
+
+	return userId, true
+}
+
+// Loads the car and checks that the user owns it, that it is shared with the
+// user or that it is a car of the user's organization, in a role that allows
+// what the request needs. Every car scoped handler goes through here, the role
+// of the user is set on the returned car.
+// When the user may not access the car the response is written and ok is false.
+func (app *application) authorizeCar(w http.ResponseWriter, userId, carId int, required string) (models.Car, bool) {
+	car, err := app.models.DB.GetCarByID(carId)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, errors.New("user is not authorized to access this car"), http.StatusUnauthorized)
+		return car, false
+	}
+
+	if car.OrganizationID == nil && car.UserId == userId {
+		car.Role = models.RoleOwner
+	} else {
+		car.Role, err = app.models.DB.GetCarRole(car, userId)
+		if errors.Is(err, models.ErrRecordNotFound) {
+			app.logger.Error("user is not authorized to access this car")
+			app.writer.ErrorJson(w, errors.New("user is not authorized to access this car"), http.StatusUnauthorized)
+			return car, false
+		} else if err != nil {
+			app.logger.Error(err)
+			app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+			return car, false
+		}
+	}
+
+	if !models.RoleAllows(car.Role, required) {
+		err = fmt.Errorf("the %s role does not allow this, it needs the %s role", car.Role, required)
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusForbidden)
+		return car, false
+	}
+
+	return car, true
+}
+
+// Loads the organization and checks that the user is a member in a role that
+// allows what the request needs. When the user may not access the organization
+// the response is written and ok is false.
+func (app *application) authorizeOrganization(w http.ResponseWriter, userId, orgId int, required string) (models.Organization, bool) {
+	org, err := app.models.DB.GetOrganization(orgId, userId)
+	if errors.Is(err, models.ErrRecordNotFound) {
+		app.logger.Error("user is not a member of this organization")
+		app.writer.ErrorJson(w, errors.New("user is not a member of this organization"), http.StatusUnauthorized)
+		return org, false
+	} else if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return org, false
+	}
+
+	if !models.OrganizationRoleAllows(org.Role, required) {
+		err = fmt.Errorf("the %s role does not allow this, it needs the %s role", org.Role, required)
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusForbidden)
+		return org, false
+	}
+
+	return org, true
+}
+
+// Writes a model error, answering with 404 when the record does not exist
+func (app *application) modelError(w http.ResponseWriter, err error) {
+	app.logger.Error(err)
+	if errors.Is(err, models.ErrRecordNotFound) {
+		app.writer.ErrorJson(w, err, http.StatusNotFound)
+		return
+	}
+
+	app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+}
+
+func (app *application) methodNotAllowed(w http.ResponseWriter) {
+	app.writer.ErrorJson(w, errors.New("method not allowed"), http.StatusMethodNotAllowed)
+}
+
+// Authenticates the request and checks that the user is an administrator.
+// Otherwise the response is written and ok is false.
+func (app *application) authenticateAdmin(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userId, ok := app.authenticate(w, r)
+	if !ok {
+		return 0, false
+	}
+
+	isAdmin, err := app.models.DB.IsAdmin(userId)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return 0, false
+	}
+
+	if !isAdmin {
+		app.logger.Error("user is not an administrator")
+		app.writer.ErrorJson(w, errors.New("user is not an administrator"), http.StatusForbidden)
+		return 0, false
+	}
+
+	return userId, true
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/acornak/car-maintenance-tracker/token"
+	"github.com/acornak/car-maintenance-tracker/writer"
+	"go.uber.org/zap"
+)
+
+func TestAuthenticate(t *testing.T) {
+	app := &application{
+		config: config{jwtSigningKey: []byte("secret")},
+		logger: zap.NewNop().Sugar(),
+		writer: &writer.JsonWriter{},
+	}
+
+	validToken, err := token.GenerateAccessToken(42, []byte("secret"))
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		cookie     *http.Cookie
+		ok         bool
+		userId     int
+		statusCode int
+	}{
+		{name: "NoCookie", cookie: nil, ok: false, statusCode: http.StatusUnauthorized},
+		{name: "InvalidToken", cookie: &http.Cookie{Name: "access_token", Value: "invalid"}, ok: false, statusCode: http.StatusInternalServerError},
+		{name: "ValidToken", cookie: &http.Cookie{Name: "access_token", Value: validToken}, ok: true, userId: 42, statusCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/cars/1/maintenance", nil)
+			if tt.cookie != nil {
+				req.AddCookie(tt.cookie)
+			}
+			rr := httptest.NewRecorder()
+
+			userId, ok := app.authenticate(rr, req)
+
+			if ok != tt.ok {
+				t.Errorf("expected ok to be %v, got %v", tt.ok, ok)
+			}
+			if userId != tt.userId {
+				t.Errorf("expected user id %d, got %d", tt.userId, userId)
+			}
+			if rr.Code != tt.statusCode {
+				t.Errorf("expected status %d, got %d", tt.statusCode, rr.Code)
+			}
+		})
+	}
+}
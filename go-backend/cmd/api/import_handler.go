@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/acornak/car-maintenance-tracker/importer"
+	"github.com/acornak/car-maintenance-tracker/models"
+)
+
+// largest CSV file accepted by the import
+const maxImportSize = 10 << 20
+
+type importResponse struct {
+	importer.Result
+	DryRun   bool `json:"dry_run"`
+	Imported int  `json:"imported"`
+}
+
+// Imports maintenance records or expenses of the car from a CSV file. The
+// multipart form holds the file and the column mapping as JSON. With
+// ?dry_run=true the rows are only validated. Nothing is stored unless every row
+// is valid.
+func (app *application) importCarHistoryHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	err := r.ParseMultipartForm(maxImportSize)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, errors.New("expected a multipart form with a CSV file of at most 10 MB"), http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	var mapping importer.Mapping
+	err = json.Unmarshal([]byte(r.FormValue("mapping")), &mapping)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, errors.New("invalid column mapping"), http.StatusBadRequest)
+		return
+	}
+
+	err = mapping.Validate()
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, errors.New("missing CSV file"), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	history, err := app.models.DB.GetOdometerReadingsByCarID(car.ID)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	result, err := importer.Parse(file, mapping, car.ID, history)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	response := importResponse{Result: result, DryRun: r.URL.Query().Get("dry_run") == "true"}
+	if !result.Valid() {
+		app.writer.WriteJson(w, http.StatusUnprocessableEntity, response, "import")
+		return
+	}
+
+	if response.DryRun {
+		app.writer.WriteJson(w, http.StatusOK, response, "import")
+		return
+	}
+
+	response.Imported, err = app.models.DB.ImportCarHistory(car.ID, result.Maintenance, result.Expenses)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusCreated, response, "import")
+}
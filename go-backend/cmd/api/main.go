@@ -1,15 +1,25 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/acornak/car-maintenance-tracker/catalog"
 	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/acornak/car-maintenance-tracker/notify"
+	"github.com/acornak/car-maintenance-tracker/reminder"
+	"github.com/acornak/car-maintenance-tracker/storage"
+	"github.com/acornak/car-maintenance-tracker/trash"
 	"github.com/acornak/car-maintenance-tracker/writer"
 	"github.com/joho/godotenv"
 	"go.uber.org/zap"
@@ -31,6 +41,12 @@ type application struct {
 	models     models.Models
 	writer     *writer.JsonWriter
 	apiVersion string
+	// makers and models for the typeahead search, rebuilt when the catalog changes
+	catalogIndex *catalog.Index
+	// serializes the rebuilds so an older catalog never replaces a newer one
+	catalogIndexMu sync.Mutex
+	// where the attached files are kept
+	storage storage.Storage
 }
 
 type config struct {
@@ -39,6 +55,35 @@ type config struct {
 	dbConn        dbConfig
 	allowedOrigin string
 	jwtSigningKey []byte
+	// how often the reminder scheduler evaluates due services
+	reminderInterval time.Duration
+	// how long deleted cars and records stay in the trash before they are purged
+	trashRetention time.Duration
+	// the legal minimum tread depth and the age tires are warned about
+	tireLimits models.TireLimits
+	notify     notifyConfig
+	storage    storageConfig
+}
+
+type storageConfig struct {
+	// "local" keeps the files in dir, "s3" in a bucket of an S3 compatible service
+	driver string
+	dir    string
+	s3     storage.S3Config
+	// the largest file that can be uploaded and how much a user can store, in bytes
+	maxUploadSize int64
+	quota         int64
+}
+
+type notifyConfig struct {
+	// "file" writes messages to dir, "smtp" delivers them
+	driver       string
+	dir          string
+	smtpHost     string
+	smtpPort     string
+	smtpUser     string
+	smtpPassword string
+	smtpFrom     string
 }
 
 type dbConfig struct {
@@ -60,6 +105,90 @@ func loadConfigFromEnv(cfg *config) error {
 	cfg.dbConn.dbname = os.Getenv("DB_NAME")
 	cfg.dbConn.sslmode = os.Getenv("SSL_MODE")
 	cfg.jwtSigningKey = []byte(os.Getenv("JWT_SECRET"))
+	cfg.notify.driver = os.Getenv("NOTIFIER")
+	cfg.notify.dir = os.Getenv("NOTIFY_DIR")
+	cfg.notify.smtpHost = os.Getenv("SMTP_HOST")
+	cfg.notify.smtpPort = os.Getenv("SMTP_PORT")
+	cfg.notify.smtpUser = os.Getenv("SMTP_USER")
+	cfg.notify.smtpPassword = os.Getenv("SMTP_PASS")
+	cfg.notify.smtpFrom = os.Getenv("SMTP_FROM")
+	cfg.storage.driver = os.Getenv("STORAGE")
+	cfg.storage.dir = os.Getenv("STORAGE_DIR")
+	cfg.storage.s3.Endpoint = os.Getenv("S3_ENDPOINT")
+	cfg.storage.s3.Region = os.Getenv("S3_REGION")
+	cfg.storage.s3.Bucket = os.Getenv("S3_BUCKET")
+	cfg.storage.s3.AccessKey = os.Getenv("S3_ACCESS_KEY")
+	cfg.storage.s3.SecretKey = os.Getenv("S3_SECRET_KEY")
+
+	if cfg.notify.driver == "" {
+		cfg.notify.driver = "file"
+	}
+	if cfg.notify.dir == "" {
+		cfg.notify.dir = "notifications"
+	}
+	if cfg.storage.driver == "" {
+		cfg.storage.driver = "local"
+	}
+	if cfg.storage.dir == "" {
+		cfg.storage.dir = "uploads"
+	}
+	if cfg.storage.s3.Region == "" {
+		cfg.storage.s3.Region = "us-east-1"
+	}
+
+	cfg.reminderInterval = time.Hour
+	if interval := os.Getenv("REMINDER_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid REMINDER_INTERVAL configuration: %q", interval)
+		}
+		cfg.reminderInterval = d
+	}
+
+	cfg.trashRetention = 30 * 24 * time.Hour
+	if days := os.Getenv("TRASH_RETENTION_DAYS"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid TRASH_RETENTION_DAYS configuration: %q", days)
+		}
+		cfg.trashRetention = time.Duration(n) * 24 * time.Hour
+	}
+
+	cfg.tireLimits.MinTreadDepthMm = 1.6
+	if depth := os.Getenv("TIRE_MIN_TREAD_MM"); depth != "" {
+		mm, err := strconv.ParseFloat(depth, 64)
+		if err != nil || mm <= 0 {
+			return fmt.Errorf("invalid TIRE_MIN_TREAD_MM configuration: %q", depth)
+		}
+		cfg.tireLimits.MinTreadDepthMm = mm
+	}
+
+	cfg.tireLimits.MaxAgeYears = 6
+	if years := os.Getenv("TIRE_MAX_AGE_YEARS"); years != "" {
+		n, err := strconv.Atoi(years)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid TIRE_MAX_AGE_YEARS configuration: %q", years)
+		}
+		cfg.tireLimits.MaxAgeYears = n
+	}
+
+	cfg.storage.maxUploadSize = 10 << 20
+	if size := os.Getenv("UPLOAD_MAX_SIZE_MB"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid UPLOAD_MAX_SIZE_MB configuration: %q", size)
+		}
+		cfg.storage.maxUploadSize = int64(n) << 20
+	}
+
+	cfg.storage.quota = 500 << 20
+	if quota := os.Getenv("UPLOAD_QUOTA_MB"); quota != "" {
+		n, err := strconv.Atoi(quota)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid UPLOAD_QUOTA_MB configuration: %q", quota)
+		}
+		cfg.storage.quota = int64(n) << 20
+	}
 
 	return validateConfig(cfg)
 }
@@ -104,13 +233,30 @@ func initializeDatabase(cfg *dbConfig, logger *zap.SugaredLogger) (*sql.DB, erro
 	return nil, errors.New("failed to connect to the database")
 }
 
+func initializeNotifier(cfg *notifyConfig) (notify.Notifier, error) {
+	if cfg.driver == "smtp" {
+		return notify.NewSMTPNotifier(cfg.smtpHost, cfg.smtpPort, cfg.smtpUser, cfg.smtpPassword, cfg.smtpFrom), nil
+	}
+
+	return notify.NewFileNotifier(cfg.dir)
+}
+
+func initializeStorage(cfg *storageConfig) (storage.Storage, error) {
+	if cfg.driver == "s3" {
+		return storage.NewS3(cfg.s3, nil)
+	}
+
+	return storage.NewLocal(cfg.dir)
+}
+
 func newApplication(cfg config, logger *zap.SugaredLogger, db *sql.DB) *application {
 	return &application{
-		config:     cfg,
-		logger:     logger,
-		models:     models.NewModels(db),
-		writer:     &writer.JsonWriter{},
-		apiVersion: "v1",
+		config:       cfg,
+		logger:       logger,
+		models:       models.NewModels(db),
+		writer:       &writer.JsonWriter{},
+		apiVersion:   "v1",
+		catalogIndex: catalog.NewIndex(),
 	}
 }
 
@@ -148,6 +294,43 @@ func main() {
 
 	app := newApplication(cfg, logger, db)
 
+	app.storage, err = initializeStorage(&cfg.storage)
+	if err != nil {
+		logger.Fatal("failed to initialize storage:", err)
+	}
+
+	err = app.rebuildCatalogIndex()
+	if err != nil {
+		logger.Fatal("failed to build the catalog index:", err)
+	}
+
+	// stop the server and the background jobs on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	notifier, err := initializeNotifier(&cfg.notify)
+	if err != nil {
+		logger.Fatal("failed to initialize notifier:", err)
+	}
+
+	var wg sync.WaitGroup
+	scheduler := reminder.NewScheduler(&app.models.DB, cfg.reminderInterval, logger)
+	dispatcher := notify.NewDispatcher(&app.models.DB, notifier, time.Minute, logger)
+	purger := trash.NewPurger(&app.models.DB, app.storage, cfg.trashRetention, time.Hour, logger)
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		scheduler.Run(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		dispatcher.Run(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		purger.Run(ctx)
+	}()
+
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%s", cfg.port),
 		Handler:      app.routes(),
@@ -156,8 +339,29 @@ func main() {
 		WriteTimeout: 30 * time.Second,
 	}
 
+	// closed once the in-flight requests are drained
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		logger.Info("shutting down the server")
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		err := srv.Shutdown(shutdownCtx)
+		if err != nil {
+			logger.Error("failed to shut down the server: ", err)
+		}
+	}()
+
 	err = srv.ListenAndServe()
-	if err != nil {
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		logger.Fatal("Failed to start the server:", err)
 	}
+
+	// ListenAndServe returns as soon as the shutdown starts, the database has
+	// to stay open until the requests and the background jobs are done
+	<-shutdownDone
+	wg.Wait()
 }
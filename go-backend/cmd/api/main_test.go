@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
@@ -83,6 +84,155 @@ func TestLoadConfigFromEnv(t *testing.T) {
 	if string(cfg.jwtSigningKey) != "secret" {
 		t.Errorf("Expected JWT_SECRET to be 'secret', got '%s'", string(cfg.jwtSigningKey))
 	}
+	if cfg.reminderInterval != time.Hour {
+		t.Errorf("Expected REMINDER_INTERVAL to default to 1h, got '%s'", cfg.reminderInterval)
+	}
+	if cfg.trashRetention != 30*24*time.Hour {
+		t.Errorf("Expected TRASH_RETENTION_DAYS to default to 30 days, got '%s'", cfg.trashRetention)
+	}
+	if cfg.notify.driver != "file" {
+		t.Errorf("Expected NOTIFIER to default to 'file', got '%s'", cfg.notify.driver)
+	}
+}
+
+func TestLoadConfigFromEnv_ReminderInterval(t *testing.T) {
+	defer os.Unsetenv("REMINDER_INTERVAL")
+
+	os.Setenv("REMINDER_INTERVAL", "15m")
+	cfg := config{}
+	err := loadConfigFromEnv(&cfg)
+	if err != nil {
+		t.Errorf("Unexpected error loading config: %v", err)
+	}
+	if cfg.reminderInterval != 15*time.Minute {
+		t.Errorf("Expected REMINDER_INTERVAL to be '15m', got '%s'", cfg.reminderInterval)
+	}
+
+	for _, invalid := range []string{"soon", "0s", "-1h"} {
+		os.Setenv("REMINDER_INTERVAL", invalid)
+		if err := loadConfigFromEnv(&config{}); err == nil {
+			t.Errorf("Expected an error for REMINDER_INTERVAL %q", invalid)
+		}
+	}
+}
+
+func TestLoadConfigFromEnv_TrashRetention(t *testing.T) {
+	defer os.Unsetenv("TRASH_RETENTION_DAYS")
+
+	os.Setenv("TRASH_RETENTION_DAYS", "7")
+	cfg := config{}
+	err := loadConfigFromEnv(&cfg)
+	if err != nil {
+		t.Errorf("Unexpected error loading config: %v", err)
+	}
+	if cfg.trashRetention != 7*24*time.Hour {
+		t.Errorf("Expected TRASH_RETENTION_DAYS to be 7 days, got '%s'", cfg.trashRetention)
+	}
+
+	for _, invalid := range []string{"week", "0", "-1"} {
+		os.Setenv("TRASH_RETENTION_DAYS", invalid)
+		if err := loadConfigFromEnv(&config{}); err == nil {
+			t.Errorf("Expected an error for TRASH_RETENTION_DAYS %q", invalid)
+		}
+	}
+}
+
+func TestLoadConfigFromEnv_TireLimits(t *testing.T) {
+	defer os.Unsetenv("TIRE_MIN_TREAD_MM")
+	defer os.Unsetenv("TIRE_MAX_AGE_YEARS")
+
+	cfg := config{}
+	err := loadConfigFromEnv(&cfg)
+	if err != nil {
+		t.Errorf("Unexpected error loading config: %v", err)
+	}
+	if cfg.tireLimits.MinTreadDepthMm != 1.6 || cfg.tireLimits.MaxAgeYears != 6 {
+		t.Errorf("Expected the default tire limits, got %+v", cfg.tireLimits)
+	}
+
+	os.Setenv("TIRE_MIN_TREAD_MM", "4")
+	os.Setenv("TIRE_MAX_AGE_YEARS", "8")
+	cfg = config{}
+	err = loadConfigFromEnv(&cfg)
+	if err != nil {
+		t.Errorf("Unexpected error loading config: %v", err)
+	}
+	if cfg.tireLimits.MinTreadDepthMm != 4 || cfg.tireLimits.MaxAgeYears != 8 {
+		t.Errorf("Expected TIRE_MIN_TREAD_MM 4 and TIRE_MAX_AGE_YEARS 8, got %+v", cfg.tireLimits)
+	}
+
+	for _, invalid := range []string{"deep", "0", "-1"} {
+		os.Setenv("TIRE_MIN_TREAD_MM", invalid)
+		if err := loadConfigFromEnv(&config{}); err == nil {
+			t.Errorf("Expected an error for TIRE_MIN_TREAD_MM %q", invalid)
+		}
+	}
+	os.Setenv("TIRE_MIN_TREAD_MM", "1.6")
+
+	for _, invalid := range []string{"old", "0", "-1"} {
+		os.Setenv("TIRE_MAX_AGE_YEARS", invalid)
+		if err := loadConfigFromEnv(&config{}); err == nil {
+			t.Errorf("Expected an error for TIRE_MAX_AGE_YEARS %q", invalid)
+		}
+	}
+}
+
+func TestLoadConfigFromEnv_Storage(t *testing.T) {
+	defer os.Unsetenv("STORAGE")
+	defer os.Unsetenv("S3_ENDPOINT")
+	defer os.Unsetenv("S3_BUCKET")
+	defer os.Unsetenv("S3_ACCESS_KEY")
+	defer os.Unsetenv("S3_SECRET_KEY")
+	defer os.Unsetenv("UPLOAD_MAX_SIZE_MB")
+	defer os.Unsetenv("UPLOAD_QUOTA_MB")
+
+	cfg := config{}
+	err := loadConfigFromEnv(&cfg)
+	if err != nil {
+		t.Errorf("Unexpected error loading config: %v", err)
+	}
+	if cfg.storage.driver != "local" || cfg.storage.dir != "uploads" {
+		t.Errorf("Expected the local storage in 'uploads', got %+v", cfg.storage)
+	}
+	if cfg.storage.maxUploadSize != 10<<20 || cfg.storage.quota != 500<<20 {
+		t.Errorf("Expected the default upload limits, got %d and %d", cfg.storage.maxUploadSize, cfg.storage.quota)
+	}
+
+	os.Setenv("STORAGE", "s3")
+	if err := loadConfigFromEnv(&config{}); err == nil {
+		t.Error("Expected an error for the S3 storage without an endpoint")
+	}
+
+	os.Setenv("S3_ENDPOINT", "http://minio:9000")
+	os.Setenv("S3_BUCKET", "uploads")
+	os.Setenv("S3_ACCESS_KEY", "minio")
+	os.Setenv("S3_SECRET_KEY", "minio123")
+	os.Setenv("UPLOAD_MAX_SIZE_MB", "5")
+	os.Setenv("UPLOAD_QUOTA_MB", "100")
+	cfg = config{}
+	err = loadConfigFromEnv(&cfg)
+	if err != nil {
+		t.Errorf("Unexpected error loading config: %v", err)
+	}
+	if cfg.storage.s3.Region != "us-east-1" {
+		t.Errorf("Expected S3_REGION to default to 'us-east-1', got '%s'", cfg.storage.s3.Region)
+	}
+	if cfg.storage.maxUploadSize != 5<<20 || cfg.storage.quota != 100<<20 {
+		t.Errorf("Expected UPLOAD_MAX_SIZE_MB 5 and UPLOAD_QUOTA_MB 100, got %d and %d", cfg.storage.maxUploadSize, cfg.storage.quota)
+	}
+
+	os.Setenv("STORAGE", "ftp")
+	if err := loadConfigFromEnv(&config{}); err == nil {
+		t.Error("Expected an error for STORAGE 'ftp'")
+	}
+	os.Setenv("STORAGE", "local")
+
+	for _, invalid := range []string{"big", "0", "-1"} {
+		os.Setenv("UPLOAD_MAX_SIZE_MB", invalid)
+		if err := loadConfigFromEnv(&config{}); err == nil {
+			t.Errorf("Expected an error for UPLOAD_MAX_SIZE_MB %q", invalid)
+		}
+	}
 }
 
 func TestInitializeLogger(t *testing.T) {
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+)
+
+func (app *application) getMaintenanceRecordsHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	records, err := app.models.DB.GetMaintenanceRecordsByCarID(car.ID)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, records, "maintenance")
+}
+
+func (app *application) getMaintenanceRecordHandler(w http.ResponseWriter, r *http.Request, car models.Car, recordId int) {
+	record, err := app.models.DB.GetMaintenanceRecordByID(car.ID, recordId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, record, "maintenance")
+}
+
+func (app *application) addMaintenanceRecordHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	var record models.MaintenanceRecord
+	err := json.NewDecoder(r.Body).Decode(&record)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = record.Validate()
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	record.CarID = car.ID
+	record.ID, err = app.models.DB.InsertMaintenanceRecord(record)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusCreated, record, "maintenance")
+}
+
+func (app *application) updateMaintenanceRecordHandler(w http.ResponseWriter, r *http.Request, car models.Car, recordId int) {
+	var record models.MaintenanceRecord
+	err := json.NewDecoder(r.Body).Decode(&record)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = record.Validate()
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// The ids always come from the URL, never from the body
+	record.ID = recordId
+	record.CarID = car.ID
+	err = app.models.DB.UpdateMaintenanceRecord(record)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, record, "maintenance")
+}
+
+func (app *application) removeMaintenanceRecordHandler(w http.ResponseWriter, r *http.Request, car models.Car, recordId int) {
+	err := app.models.DB.RemoveMaintenanceRecord(car.ID, recordId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
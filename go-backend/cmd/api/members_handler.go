@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+)
+
+// The role a request to /cars/{id}/... needs. Reading needs any role and
+// changing the car or its history needs an editor. Deleting and archiving the
+// car and managing who it is shared with is up to the owner, but a member may
+// always leave the car.
+func requiredCarRole(r *http.Request, userId int, parts []string) string {
+	read := r.Method == http.MethodGet || r.Method == http.MethodHead
+
+	if len(parts) == 0 {
+		switch {
+		case read:
+			return models.RoleViewer
+		case r.Method == http.MethodDelete:
+			return models.RoleOwner
+		default:
+			return models.RoleEditor
+		}
+	}
+
+	switch parts[0] {
+	case "archive", "invitations":
+		return models.RoleOwner
+	case "members":
+		if read {
+			return models.RoleViewer
+		}
+		if r.Method == http.MethodDelete && len(parts) == 2 && parts[1] == strconv.Itoa(userId) {
+			return models.RoleViewer
+		}
+		return models.RoleOwner
+	}
+
+	if read {
+		return models.RoleViewer
+	}
+	return models.RoleEditor
+}
+
+func (app *application) getCarMembersHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	members, err := app.models.DB.GetCarMembers(car.ID)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, members, "members")
+}
+
+// Changes the role of a user the car is shared with, the body is {"role": "viewer"}
+func (app *application) updateCarMemberHandler(w http.ResponseWriter, r *http.Request, car models.Car, memberId int) {
+	if car.OrganizationID == nil && memberId == car.UserId {
+		app.writer.ErrorJson(w, errors.New("the role of the owner cannot be changed"), http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Role string `json:"role"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	role, err := models.ValidateMemberRole(req.Role)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = app.models.DB.UpdateCarMemberRole(car.ID, memberId, role)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	app.getCarMembersHandler(w, r, car)
+}
+
+// Stops sharing the car with a user. The owner removes anyone, any other
+// member only themselves.
+func (app *application) removeCarMemberHandler(w http.ResponseWriter, r *http.Request, car models.Car, memberId int) {
+	if car.OrganizationID == nil && memberId == car.UserId {
+		app.writer.ErrorJson(w, errors.New("the owner cannot be removed from the car"), http.StatusBadRequest)
+		return
+	}
+
+	err := app.models.DB.RemoveCarMember(car.ID, memberId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getCarInvitationsHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	invitations, err := app.models.DB.GetCarInvitations(car.ID)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, invitations, "invitations")
+}
+
+// Invites a registered user to the car by their email or nickname, the body is
+// {"invitee": "jane@example.com", "role": "editor"}. The invitee is notified
+// and gets access once they accept.
+func (app *application) addCarInvitationHandler(w http.ResponseWriter, r *http.Request, car models.Car, userId int) {
+	var req struct {
+		Invitee string `json:"invitee"`
+		Role    string `json:"role"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	req.Invitee = strings.TrimSpace(req.Invitee)
+	if req.Invitee == "" {
+		app.writer.ErrorJson(w, errors.New("invitee email or nickname is required"), http.StatusBadRequest)
+		return
+	}
+
+	role, err := models.ValidateMemberRole(req.Role)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	invitee, err := app.models.DB.GetUserByEmailOrNickname(req.Invitee)
+	if errors.Is(err, models.ErrRecordNotFound) {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, errors.New("no user with this email or nickname"), http.StatusNotFound)
+		return
+	} else if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	inviter, err := app.models.DB.GetUserByID(userId)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	invitation := models.CarInvitation{
+		CarID:           car.ID,
+		InviterID:       userId,
+		InviterNickname: inviter.Nickname,
+		InviteeID:       invitee.ID,
+		InviteeNickname: invitee.Nickname,
+		Role:            role,
+		Status:          models.InvitationPending,
+	}
+
+	invitation.ID, err = app.models.DB.InsertCarInvitation(invitation, invitationNotice(invitation, car))
+	if errors.Is(err, models.ErrAlreadyMember) || errors.Is(err, models.ErrAlreadyInvited) {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusConflict)
+		return
+	} else if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusCreated, invitation, "invitation")
+}
+
+// The message telling the invitee about the invitation
+func invitationNotice(invitation models.CarInvitation, car models.Car) *models.NotificationContent {
+	name := "a car"
+	if car.LicensePlate != "" {
+		name = "the car " + car.LicensePlate
+	}
+
+	return &models.NotificationContent{
+		Subject: "A car was shared with you",
+		Body: fmt.Sprintf("%s invited you to %s as %s. Accept or decline the invitation in your car list.",
+			invitation.InviterNickname, name, invitation.Role),
+	}
+}
+
+// Withdraws a pending invitation of the car
+func (app *application) revokeCarInvitationHandler(w http.ResponseWriter, r *http.Request, car models.Car, invitationId int) {
+	err := app.models.DB.RevokeCarInvitation(car.ID, invitationId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Returns the pending invitations the authenticated user received
+func (app *application) getInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		app.methodNotAllowed(w)
+		return
+	}
+
+	userId, ok := app.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	invitations, err := app.models.DB.GetCarInvitationsByUserID(userId)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, invitations, "invitations")
+}
+
+func (app *application) acceptInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	app.respondToInvitation(w, r, app.models.DB.AcceptCarInvitation)
+}
+
+func (app *application) declineInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	app.respondToInvitation(w, r, app.models.DB.DeclineCarInvitation)
+}
+
+// Accepts or declines the invitation ?id= of the authenticated user
+func (app *application) respondToInvitation(w http.ResponseWriter, r *http.Request, respond func(userId, invitationId int) error) {
+	if r.Method != http.MethodPost {
+		app.methodNotAllowed(w)
+		return
+	}
+
+	userId, ok := app.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse the id parameter as an integer
+	invitationId, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = respond(userId, invitationId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequiredCarRole(t *testing.T) {
+	tests := []struct {
+		method string
+		parts  []string
+		role   string
+	}{
+		{"GET", nil, models.RoleViewer},
+		{"PUT", nil, models.RoleEditor},
+		{"PATCH", nil, models.RoleEditor},
+		{"DELETE", nil, models.RoleOwner},
+		{"POST", []string{"archive"}, models.RoleOwner},
+		{"GET", []string{"maintenance"}, models.RoleViewer},
+		{"POST", []string{"maintenance"}, models.RoleEditor},
+		{"DELETE", []string{"expenses", "3"}, models.RoleEditor},
+		{"GET", []string{"image", "thumb"}, models.RoleViewer},
+		{"POST", []string{"import"}, models.RoleEditor},
+		{"GET", []string{"members"}, models.RoleViewer},
+		{"PUT", []string{"members", "7"}, models.RoleOwner},
+		{"DELETE", []string{"members", "7"}, models.RoleOwner},
+		// leaving the car
+		{"DELETE", []string{"members", "5"}, models.RoleViewer},
+		{"GET", []string{"invitations"}, models.RoleOwner},
+		{"POST", []string{"invitations"}, models.RoleOwner},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, "/api/v1/cars/2", nil)
+		assert.Equal(t, tt.role, requiredCarRole(r, 5, tt.parts), "%s %v", tt.method, tt.parts)
+	}
+}
+
+func TestInvitationNotice(t *testing.T) {
+	invitation := models.CarInvitation{InviterNickname: "jane", Role: models.RoleEditor}
+
+	notice := invitationNotice(invitation, models.Car{LicensePlate: "BA123AB"})
+	assert.Equal(t, "jane invited you to the car BA123AB as editor. Accept or decline the invitation in your car list.", notice.Body)
+
+	notice = invitationNotice(invitation, models.Car{})
+	assert.Contains(t, notice.Body, "invited you to a car as editor")
+}
@@ -44,7 +44,7 @@ func TestEnableCORS(t *testing.T) {
 	expectedHeaders := map[string]string{
 		"Access-Control-Allow-Origin":      allowedOrigin,
 		"Access-Control-Allow-Headers":     "Content-Type,Authorization",
-		"Access-Control-Allow-Methods":     "GET,POST,PUT,DELETE,OPTIONS",
+		"Access-Control-Allow-Methods":     "GET,POST,PUT,PATCH,DELETE,OPTIONS",
 		"Access-Control-Allow-Credentials": "true",
 	}
 	for key, value := range expectedHeaders {
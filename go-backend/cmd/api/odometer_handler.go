@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+)
+
+func (app *application) getOdometerReadingsHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	readings, err := app.models.DB.GetOdometerReadingsByCarID(car.ID)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, readings, "readings")
+}
+
+func (app *application) addOdometerReadingHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	var reading models.OdometerReading
+	err := json.NewDecoder(r.Body).Decode(&reading)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = reading.Validate()
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	reading.CarID = car.ID
+	reading.ID, err = app.models.DB.InsertOdometerReading(reading)
+	if errors.Is(err, models.ErrOdometerDecrease) {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusCreated, reading, "reading")
+}
+
+func (app *application) removeOdometerReadingHandler(w http.ResponseWriter, r *http.Request, car models.Car, readingId int) {
+	err := app.models.DB.RemoveOdometerReading(car.ID, readingId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
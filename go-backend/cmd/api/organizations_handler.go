@@ -0,0 +1,482 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+)
+
+// The role a request to /orgs/{id}/... needs. Members see the organization,
+// its members and its cars, everything else is up to the admins, but a member
+// may always leave. The endpoints of a fleet car only need a member, the car
+// itself checks the role of the user on it.
+func requiredOrganizationRole(r *http.Request, userId int, parts []string) string {
+	read := r.Method == http.MethodGet || r.Method == http.MethodHead
+
+	if len(parts) == 0 {
+		if read {
+			return models.OrgRoleMember
+		}
+		return models.OrgRoleAdmin
+	}
+
+	switch parts[0] {
+	case "members":
+		if r.Method == http.MethodDelete && len(parts) == 2 && parts[1] == strconv.Itoa(userId) {
+			return models.OrgRoleMember
+		}
+	case "cars":
+		if len(parts) > 1 && !(len(parts) == 3 && parts[2] == "driver") {
+			return models.OrgRoleMember
+		}
+	case "report":
+		return models.OrgRoleAdmin
+	}
+
+	if read {
+		return models.OrgRoleMember
+	}
+	return models.OrgRoleAdmin
+}
+
+// Lists the organizations of the user and creates new ones, the body is
+// {"name": "Acme"} and the user becomes its admin
+func (app *application) organizationsHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := app.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		organizations, err := app.models.DB.GetOrganizationsByUserID(userId)
+		if err != nil {
+			app.logger.Error(err)
+			app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		app.writer.WriteJson(w, http.StatusOK, organizations, "organizations")
+	case http.MethodPost:
+		var org models.Organization
+		err := json.NewDecoder(r.Body).Decode(&org)
+		if err != nil {
+			app.logger.Error(err)
+			app.writer.ErrorJson(w, err, http.StatusBadRequest)
+			return
+		}
+
+		err = org.Validate()
+		if err != nil {
+			app.logger.Error(err)
+			app.writer.ErrorJson(w, err, http.StatusBadRequest)
+			return
+		}
+
+		org.ID, err = app.models.DB.InsertOrganization(org, userId)
+		if err != nil {
+			app.logger.Error(err)
+			app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		org, err = app.models.DB.GetOrganization(org.ID, userId)
+		if err != nil {
+			app.modelError(w, err)
+			return
+		}
+
+		app.writer.WriteJson(w, http.StatusCreated, org, "organization")
+	default:
+		app.methodNotAllowed(w)
+	}
+}
+
+// Dispatches the organization scoped endpoints under /orgs/{id}/ after checking
+// that the authenticated user has the role the request needs
+func (app *application) organizationRoutes(w http.ResponseWriter, r *http.Request) {
+	orgId, parts, err := parseIDPath(r.URL.Path, "/api/"+app.apiVersion+"/orgs/")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	userId, ok := app.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	org, ok := app.authorizeOrganization(w, userId, orgId, requiredOrganizationRole(r, userId, parts))
+	if !ok {
+		return
+	}
+
+	if len(parts) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			app.writer.WriteJson(w, http.StatusOK, org, "organization")
+		case http.MethodPut:
+			app.updateOrganizationHandler(w, r, org)
+		case http.MethodDelete:
+			app.removeOrganizationHandler(w, r, org)
+		default:
+			app.methodNotAllowed(w)
+		}
+		return
+	}
+
+	switch parts[0] {
+	case "members":
+		app.organizationMemberRoutes(w, r, org, parts[1:])
+	case "cars":
+		app.organizationCarRoutes(w, r, userId, org, parts[1:])
+	case "report":
+		if r.Method != http.MethodGet {
+			app.methodNotAllowed(w)
+			return
+		}
+		app.getFleetReportHandler(w, r, org)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Routes the members /orgs/{id}/members and a member /orgs/{id}/members/{userId}
+func (app *application) organizationMemberRoutes(w http.ResponseWriter, r *http.Request, org models.Organization, parts []string) {
+	if len(parts) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			app.getOrganizationMembersHandler(w, r, org)
+		case http.MethodPost:
+			app.addOrganizationMemberHandler(w, r, org)
+		default:
+			app.methodNotAllowed(w)
+		}
+		return
+	}
+
+	memberId, err := strconv.Atoi(parts[0])
+	if err != nil || len(parts) > 1 {
+		app.writer.ErrorJson(w, errors.New("invalid member id"), http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		app.updateOrganizationMemberHandler(w, r, org, memberId)
+	case http.MethodDelete:
+		app.removeOrganizationMemberHandler(w, r, org, memberId)
+	default:
+		app.methodNotAllowed(w)
+	}
+}
+
+// Routes the fleet /orgs/{id}/cars, the driver of a car
+// /orgs/{id}/cars/{carId}/driver and every other endpoint of a fleet car, which
+// are the same as those under /cars/{carId}
+func (app *application) organizationCarRoutes(w http.ResponseWriter, r *http.Request, userId int, org models.Organization, parts []string) {
+	if len(parts) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			app.getOrganizationCarsHandler(w, r, userId, org)
+		case http.MethodPost:
+			app.addOrganizationCarHandler(w, r, userId, org)
+		default:
+			app.methodNotAllowed(w)
+		}
+		return
+	}
+
+	carId, err := strconv.Atoi(parts[0])
+	if err != nil {
+		app.writer.ErrorJson(w, errors.New("invalid car id"), http.StatusNotFound)
+		return
+	}
+
+	if len(parts) == 2 && parts[1] == "driver" {
+		switch r.Method {
+		case http.MethodGet:
+			app.getDriverAssignmentsHandler(w, r, org, carId)
+		case http.MethodPut:
+			app.assignDriverHandler(w, r, org, carId)
+		case http.MethodDelete:
+			app.unassignDriverHandler(w, r, org, carId)
+		default:
+			app.methodNotAllowed(w)
+		}
+		return
+	}
+
+	car, err := app.models.DB.GetCarByID(carId)
+	if err != nil || car.OrganizationID == nil || *car.OrganizationID != org.ID {
+		app.writer.ErrorJson(w, errors.New("the car is not in the fleet of this organization"), http.StatusNotFound)
+		return
+	}
+
+	app.serveCar(w, r, userId, carId, parts[1:])
+}
+
+// Renames the organization, the body is {"name": "Acme"}
+func (app *application) updateOrganizationHandler(w http.ResponseWriter, r *http.Request, org models.Organization) {
+	var req models.Organization
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = req.Validate()
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	org.Name = req.Name
+	err = app.models.DB.UpdateOrganization(org)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, org, "organization")
+}
+
+func (app *application) removeOrganizationHandler(w http.ResponseWriter, r *http.Request, org models.Organization) {
+	err := app.models.DB.RemoveOrganization(org.ID)
+	if errors.Is(err, models.ErrOrganizationNotEmpty) {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusConflict)
+		return
+	} else if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getOrganizationMembersHandler(w http.ResponseWriter, r *http.Request, org models.Organization) {
+	members, err := app.models.DB.GetOrganizationMembers(org.ID)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, members, "members")
+}
+
+// Adds a registered user to the organization by their email or nickname, the
+// body is {"member": "jane@example.com", "role": "member"}
+func (app *application) addOrganizationMemberHandler(w http.ResponseWriter, r *http.Request, org models.Organization) {
+	var req struct {
+		Member string `json:"member"`
+		Role   string `json:"role"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	req.Member = strings.TrimSpace(req.Member)
+	if req.Member == "" {
+		app.writer.ErrorJson(w, errors.New("member email or nickname is required"), http.StatusBadRequest)
+		return
+	}
+
+	role, err := models.ValidateOrganizationRole(req.Role)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	user, err := app.models.DB.GetUserByEmailOrNickname(req.Member)
+	if errors.Is(err, models.ErrRecordNotFound) {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, errors.New("no user with this email or nickname"), http.StatusNotFound)
+		return
+	} else if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	err = app.models.DB.AddOrganizationMember(org.ID, user.ID, role)
+	if errors.Is(err, models.ErrAlreadyOrganizationMember) {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusConflict)
+		return
+	} else if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	// the member is added either way, a lost notice is only logged
+	err = app.models.DB.EnqueueNotification(user.ID, "You joined "+org.Name,
+		fmt.Sprintf("You were added to the organization %s as %s. Its cars are listed under your organizations.", org.Name, role))
+	if err != nil {
+		app.logger.Error(err)
+	}
+
+	app.writer.WriteJson(w, http.StatusCreated, models.OrganizationMember{UserID: user.ID, Nickname: user.Nickname, Role: role}, "member")
+}
+
+// Changes the role of a member, the body is {"role": "admin"}
+func (app *application) updateOrganizationMemberHandler(w http.ResponseWriter, r *http.Request, org models.Organization, memberId int) {
+	var req struct {
+		Role string `json:"role"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	role, err := models.ValidateOrganizationRole(req.Role)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = app.models.DB.UpdateOrganizationMemberRole(org.ID, memberId, role)
+	if errors.Is(err, models.ErrLastAdmin) {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusConflict)
+		return
+	} else if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	app.getOrganizationMembersHandler(w, r, org)
+}
+
+// Removes a member from the organization. Admins remove anyone, members only
+// themselves.
+func (app *application) removeOrganizationMemberHandler(w http.ResponseWriter, r *http.Request, org models.Organization, memberId int) {
+	err := app.models.DB.RemoveOrganizationMember(org.ID, memberId)
+	if errors.Is(err, models.ErrLastAdmin) {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusConflict)
+		return
+	} else if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Lists the fleet with the current driver of each car, archived cars only on
+// request
+func (app *application) getOrganizationCarsHandler(w http.ResponseWriter, r *http.Request, userId int, org models.Organization) {
+	includeArchived, _ := strconv.ParseBool(r.URL.Query().Get("include_archived"))
+
+	cars, err := app.models.DB.GetOrganizationCars(org.ID, userId, includeArchived)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, cars, "cars")
+}
+
+// Adds a car to the fleet, the body is the same as for /cars/add
+func (app *application) addOrganizationCarHandler(w http.ResponseWriter, r *http.Request, userId int, org models.Organization) {
+	car, ok := app.readNewCar(w, r, userId)
+	if !ok {
+		return
+	}
+
+	car.OrganizationID = &org.ID
+	err := app.models.DB.InsertCar(car)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// Returns the drivers of a fleet car, the current one first
+func (app *application) getDriverAssignmentsHandler(w http.ResponseWriter, r *http.Request, org models.Organization, carId int) {
+	assignments, err := app.models.DB.GetDriverAssignments(org.ID, carId)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, assignments, "drivers")
+}
+
+// Makes a member the driver of a fleet car from today on, the body is
+// {"user_id": 7}
+func (app *application) assignDriverHandler(w http.ResponseWriter, r *http.Request, org models.Organization, carId int) {
+	var req struct {
+		UserID int `json:"user_id"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = app.models.DB.AssignDriver(org.ID, carId, req.UserID)
+	if errors.Is(err, models.ErrNotOrganizationMember) {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	app.getDriverAssignmentsHandler(w, r, org, carId)
+}
+
+func (app *application) unassignDriverHandler(w http.ResponseWriter, r *http.Request, org models.Organization, carId int) {
+	err := app.models.DB.UnassignDriver(org.ID, carId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Returns the costs of the fleet per car, per driver and per category within
+// the optional from and to dates
+func (app *application) getFleetReportHandler(w http.ResponseWriter, r *http.Request, org models.Organization) {
+	dates, err := parseDateRange(r)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	report, err := app.models.DB.GetFleetReport(org.ID, dates)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, report, "report")
+}
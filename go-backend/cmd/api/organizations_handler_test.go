@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequiredOrganizationRole(t *testing.T) {
+	tests := []struct {
+		method string
+		parts  []string
+		role   string
+	}{
+		{"GET", nil, models.OrgRoleMember},
+		{"PUT", nil, models.OrgRoleAdmin},
+		{"DELETE", nil, models.OrgRoleAdmin},
+		{"GET", []string{"members"}, models.OrgRoleMember},
+		{"POST", []string{"members"}, models.OrgRoleAdmin},
+		{"PUT", []string{"members", "7"}, models.OrgRoleAdmin},
+		{"DELETE", []string{"members", "7"}, models.OrgRoleAdmin},
+		// leaving the organization
+		{"DELETE", []string{"members", "5"}, models.OrgRoleMember},
+		{"GET", []string{"cars"}, models.OrgRoleMember},
+		{"POST", []string{"cars"}, models.OrgRoleAdmin},
+		{"GET", []string{"cars", "2", "driver"}, models.OrgRoleMember},
+		{"PUT", []string{"cars", "2", "driver"}, models.OrgRoleAdmin},
+		{"DELETE", []string{"cars", "2", "driver"}, models.OrgRoleAdmin},
+		// the car checks the role of the user on it
+		{"DELETE", []string{"cars", "2"}, models.OrgRoleMember},
+		{"POST", []string{"cars", "2", "fuel"}, models.OrgRoleMember},
+		{"GET", []string{"report"}, models.OrgRoleAdmin},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, "/api/v1/orgs/4", nil)
+		assert.Equal(t, tt.role, requiredOrganizationRole(r, 5, tt.parts), "%s %v", tt.method, tt.parts)
+	}
+}
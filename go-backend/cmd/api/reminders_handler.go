@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+)
+
+func (app *application) getRemindersHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := app.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	reminders, err := app.models.DB.GetPendingRemindersByUserID(userId)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, reminders, "reminders")
+}
+
+func (app *application) dismissReminderHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		app.methodNotAllowed(w)
+		return
+	}
+
+	userId, ok := app.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse the id parameter as an integer
+	reminderId, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = app.models.DB.DismissReminder(userId, reminderId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/acornak/car-maintenance-tracker/report"
+)
+
+// Renders the vehicle history report of the car as a PDF download
+func (app *application) getCarReportHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	maker, err := app.models.DB.GetMakerByID(car.BrandID)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	model, err := app.models.DB.GetModelByID(car.ModelID)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	maintenance, err := app.models.DB.GetMaintenanceRecordsByCarID(car.ID)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	readings, err := app.models.DB.GetOdometerReadingsByCarID(car.ID)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	costs, err := app.models.DB.GetCostsByCategory(models.AnalyticsFilter{UserID: car.UserId, CarID: &car.ID})
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	// render into memory first, so a failure can still be reported as JSON
+	var buf bytes.Buffer
+	err = report.WriteCarReport(&buf, report.CarReport{
+		Car:         car,
+		Maker:       maker.Name,
+		Model:       model.Name,
+		Maintenance: maintenance,
+		Odometer:    readings,
+		Costs:       costs,
+		GeneratedAt: time.Now(),
+	})
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="car-%d-history.pdf"`, car.ID))
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	_, err = buf.WriteTo(w)
+	if err != nil {
+		app.logger.Error(err)
+	}
+}
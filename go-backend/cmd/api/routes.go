@@ -1,7 +1,11 @@
 package main
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+
+	"github.com/acornak/car-maintenance-tracker/models"
 )
 
 func (app *application) routes() http.Handler {
@@ -17,6 +21,8 @@ func (app *application) routes() http.Handler {
 	// mux.HandleFunc(prefix+"/update-user", app.registerHandler)
 
 	mux.HandleFunc(prefix+"/user", app.getUserHandler)
+	mux.HandleFunc(prefix+"/account/export", app.exportAccountHandler)
+	mux.HandleFunc(prefix+"/account/import", app.importAccountHandler)
 
 	mux.HandleFunc(prefix+"/cars/add", app.addCarHandler)
 	mux.HandleFunc(prefix+"/cars/makers", app.getAllCarMakersHandler)
@@ -24,9 +30,395 @@ func (app *application) routes() http.Handler {
 
 	mux.HandleFunc(prefix+"/cars/models", app.getAllModelsByMakerIDHandler)
 	mux.HandleFunc(prefix+"/cars/model", app.getModelByIDHandler)
+	mux.HandleFunc(prefix+"/cars/generations", app.getGenerationsByModelIDHandler)
+	mux.HandleFunc(prefix+"/cars/variants", app.getVariantsByGenerationIDHandler)
+	mux.HandleFunc(prefix+"/cars/variant", app.getVariantByIDHandler)
+	mux.HandleFunc(prefix+"/cars/fuel-types", app.getFuelTypesHandler)
+	mux.HandleFunc(prefix+"/cars/body-types", app.getBodyTypesHandler)
+	mux.HandleFunc(prefix+"/cars/search", app.searchCatalogHandler)
 	mux.HandleFunc(prefix+"/cars/get", app.getCarByIDHandler)
+	mux.HandleFunc(prefix+"/cars/vin", app.decodeVINHandler)
 
 	mux.HandleFunc(prefix+"/cars/get-by-user", app.getCarsByUserHandler)
+	mux.HandleFunc(prefix+"/cars/due", app.getAllDueServicesHandler)
+	mux.HandleFunc(prefix+"/cars/documents/expiring", app.getExpiringDocumentsHandler)
+	mux.HandleFunc(prefix+"/cars/model/schedules", app.getServiceScheduleTemplatesHandler)
+
+	mux.HandleFunc(prefix+"/analytics", app.getAnalyticsHandler)
+
+	mux.HandleFunc(prefix+"/calendar/token", app.calendarTokenHandler)
+	mux.HandleFunc(prefix+"/calendar/feed/", app.calendarFeedHandler)
+
+	mux.HandleFunc(prefix+"/reminders", app.getRemindersHandler)
+	mux.HandleFunc(prefix+"/reminders/dismiss", app.dismissReminderHandler)
+
+	// invitations to cars shared with the user
+	mux.HandleFunc(prefix+"/invitations", app.getInvitationsHandler)
+	mux.HandleFunc(prefix+"/invitations/accept", app.acceptInvitationHandler)
+	mux.HandleFunc(prefix+"/invitations/decline", app.declineInvitationHandler)
+
+	// catalog administration, only for administrators
+	mux.HandleFunc(prefix+"/admin/makers", app.createMakerHandler)
+	mux.HandleFunc(prefix+"/admin/makers/rename", app.renameMakerHandler)
+	mux.HandleFunc(prefix+"/admin/makers/merge", app.mergeMakerHandler)
+	mux.HandleFunc(prefix+"/admin/makers/retire", app.retireMakerHandler)
+	mux.HandleFunc(prefix+"/admin/models", app.createModelHandler)
+	mux.HandleFunc(prefix+"/admin/models/rename", app.renameModelHandler)
+	mux.HandleFunc(prefix+"/admin/models/merge", app.mergeModelHandler)
+	mux.HandleFunc(prefix+"/admin/models/retire", app.retireModelHandler)
+	mux.HandleFunc(prefix+"/admin/catalog/import", app.importCatalogHandler)
+
+	// organizations owning a fleet: /orgs/{id}/...
+	mux.HandleFunc(prefix+"/orgs", app.organizationsHandler)
+	mux.HandleFunc(prefix+"/orgs/", app.organizationRoutes)
+
+	mux.HandleFunc(prefix+"/trash", app.getTrashHandler)
+	mux.HandleFunc(prefix+"/trash/restore", app.restoreFromTrashHandler)
+
+	// car scoped resources: /cars/{id}/...
+	mux.HandleFunc(prefix+"/cars/", app.carRoutes)
 
 	return app.enableCORS(mux)
 }
+
+// Dispatches the car scoped endpoints under /cars/{id}/ after checking that the
+// authenticated user has the role the request needs on the car
+func (app *application) carRoutes(w http.ResponseWriter, r *http.Request) {
+	carId, parts, err := parseIDPath(r.URL.Path, "/api/"+app.apiVersion+"/cars/")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	userId, ok := app.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	app.serveCar(w, r, userId, carId, parts)
+}
+
+// Serves the endpoints of a car, parts is the path below /cars/{id}. The fleet
+// cars of an organization are served at /orgs/{orgId}/cars/{id} as well.
+func (app *application) serveCar(w http.ResponseWriter, r *http.Request, userId, carId int, parts []string) {
+	car, ok := app.authorizeCar(w, userId, carId, requiredCarRole(r, userId, parts))
+	if !ok {
+		return
+	}
+
+	if len(parts) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			app.writer.WriteJson(w, http.StatusOK, car, "car")
+		case http.MethodPut:
+			app.updateCarHandler(w, r, car)
+		case http.MethodPatch:
+			app.patchCarHandler(w, r, car)
+		case http.MethodDelete:
+			app.removeCarHandler(w, r, car)
+		default:
+			app.methodNotAllowed(w)
+		}
+		return
+	}
+
+	switch parts[0] {
+	case "archive":
+		if len(parts) != 1 {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			app.archiveCarHandler(w, r, car)
+		case http.MethodDelete:
+			app.unarchiveCarHandler(w, r, car)
+		default:
+			app.methodNotAllowed(w)
+		}
+	case "members":
+		app.serveCarResource(w, r, car, parts[1:], carResource{
+			name:   "member",
+			list:   app.getCarMembersHandler,
+			update: app.updateCarMemberHandler,
+			remove: app.removeCarMemberHandler,
+		})
+	case "invitations":
+		app.serveCarResource(w, r, car, parts[1:], carResource{
+			name: "invitation",
+			list: app.getCarInvitationsHandler,
+			add: func(w http.ResponseWriter, r *http.Request, car models.Car) {
+				app.addCarInvitationHandler(w, r, car, userId)
+			},
+			remove: app.revokeCarInvitationHandler,
+		})
+	case "maintenance":
+		app.serveCarResource(w, r, car, parts[1:], carResource{
+			name:   "maintenance record",
+			list:   app.getMaintenanceRecordsHandler,
+			add:    app.addMaintenanceRecordHandler,
+			get:    app.getMaintenanceRecordHandler,
+			update: app.updateMaintenanceRecordHandler,
+			remove: app.removeMaintenanceRecordHandler,
+		})
+	case "schedules":
+		if len(parts) == 2 && parts[1] == "defaults" {
+			if r.Method != http.MethodPost {
+				app.methodNotAllowed(w)
+				return
+			}
+			app.applyServiceScheduleTemplatesHandler(w, r, car)
+			return
+		}
+		app.serveCarResource(w, r, car, parts[1:], carResource{
+			name:   "schedule",
+			list:   app.getServiceSchedulesHandler,
+			add:    app.addServiceScheduleHandler,
+			get:    app.getServiceScheduleHandler,
+			update: app.updateServiceScheduleHandler,
+			remove: app.removeServiceScheduleHandler,
+		})
+	case "odometer":
+		app.serveCarResource(w, r, car, parts[1:], carResource{
+			name:   "odometer reading",
+			list:   app.getOdometerReadingsHandler,
+			add:    app.addOdometerReadingHandler,
+			remove: app.removeOdometerReadingHandler,
+		})
+	case "expenses":
+		app.serveCarResource(w, r, car, parts[1:], carResource{
+			name:   "expense",
+			list:   app.getExpensesHandler,
+			add:    app.addExpenseHandler,
+			get:    app.getExpenseHandler,
+			update: app.updateExpenseHandler,
+			remove: app.removeExpenseHandler,
+		})
+	case "fuel":
+		if len(parts) == 2 && parts[1] == "stats" {
+			if r.Method != http.MethodGet {
+				app.methodNotAllowed(w)
+				return
+			}
+			app.getFuelStatsHandler(w, r, car)
+			return
+		}
+		app.serveCarResource(w, r, car, parts[1:], carResource{
+			name:   "fuel log",
+			list:   app.getFuelLogsHandler,
+			add:    app.addFuelLogHandler,
+			get:    app.getFuelLogHandler,
+			update: app.updateFuelLogHandler,
+			remove: app.removeFuelLogHandler,
+		})
+	case "charging":
+		if len(parts) == 2 && parts[1] == "stats" {
+			if r.Method != http.MethodGet {
+				app.methodNotAllowed(w)
+				return
+			}
+			app.getChargingStatsHandler(w, r, car)
+			return
+		}
+		app.serveCarResource(w, r, car, parts[1:], carResource{
+			name:   "charging session",
+			list:   app.getChargingSessionsHandler,
+			add:    app.addChargingSessionHandler,
+			get:    app.getChargingSessionHandler,
+			update: app.updateChargingSessionHandler,
+			remove: app.removeChargingSessionHandler,
+		})
+	case "battery":
+		if len(parts) == 2 && parts[1] == "stats" {
+			if r.Method != http.MethodGet {
+				app.methodNotAllowed(w)
+				return
+			}
+			app.getBatteryHealthHandler(w, r, car)
+			return
+		}
+		app.serveCarResource(w, r, car, parts[1:], carResource{
+			name:   "battery health log",
+			list:   app.getBatteryHealthLogsHandler,
+			add:    app.addBatteryHealthLogHandler,
+			get:    app.getBatteryHealthLogHandler,
+			update: app.updateBatteryHealthLogHandler,
+			remove: app.removeBatteryHealthLogHandler,
+		})
+	case "tires":
+		if len(parts) == 2 && parts[1] == "warnings" {
+			if r.Method != http.MethodGet {
+				app.methodNotAllowed(w)
+				return
+			}
+			app.getTireWarningsHandler(w, r, car)
+			return
+		}
+		if len(parts) > 2 {
+			app.tireSetRoutes(w, r, car, parts[1:])
+			return
+		}
+		app.serveCarResource(w, r, car, parts[1:], carResource{
+			name:   "tire set",
+			list:   app.getTireSetsHandler,
+			add:    app.addTireSetHandler,
+			get:    app.getTireSetHandler,
+			update: app.updateTireSetHandler,
+			remove: app.removeTireSetHandler,
+		})
+	case "documents":
+		app.serveCarResource(w, r, car, parts[1:], carResource{
+			name:   "document",
+			list:   app.getVehicleDocumentsHandler,
+			add:    app.addVehicleDocumentHandler,
+			get:    app.getVehicleDocumentHandler,
+			update: app.updateVehicleDocumentHandler,
+			remove: app.removeVehicleDocumentHandler,
+		})
+	case "attachments":
+		app.serveCarResource(w, r, car, parts[1:], carResource{
+			name:   "attachment",
+			list:   app.getAttachmentsHandler,
+			add:    app.addAttachmentHandler,
+			get:    app.getAttachmentHandler,
+			remove: app.removeAttachmentHandler,
+		})
+	case "image":
+		app.carImageRoutes(w, r, car, parts[1:])
+	case "due":
+		if r.Method != http.MethodGet {
+			app.methodNotAllowed(w)
+			return
+		}
+		app.getDueServicesHandler(w, r, car)
+	case "import":
+		if r.Method != http.MethodPost {
+			app.methodNotAllowed(w)
+			return
+		}
+		app.importCarHistoryHandler(w, r, car)
+	case "report":
+		if r.Method != http.MethodGet {
+			app.methodNotAllowed(w)
+			return
+		}
+		app.getCarReportHandler(w, r, car)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Routes the events /cars/{id}/tires/{setId}/events and the tread depths
+// /cars/{id}/tires/{setId}/tread of a tire set
+func (app *application) tireSetRoutes(w http.ResponseWriter, r *http.Request, car models.Car, parts []string) {
+	setId, err := strconv.Atoi(parts[0])
+	if err != nil {
+		app.writer.ErrorJson(w, errors.New("invalid tire set id"), http.StatusNotFound)
+		return
+	}
+
+	set, err := app.models.DB.GetTireSetByID(car.ID, setId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	switch parts[1] {
+	case "events":
+		app.serveCarResource(w, r, car, parts[2:], carResource{
+			name: "tire event",
+			list: func(w http.ResponseWriter, r *http.Request, car models.Car) {
+				app.getTireEventsHandler(w, r, car, set)
+			},
+			add: func(w http.ResponseWriter, r *http.Request, car models.Car) {
+				app.addTireEventHandler(w, r, car, set)
+			},
+			remove: func(w http.ResponseWriter, r *http.Request, car models.Car, eventId int) {
+				app.removeTireEventHandler(w, r, car, set, eventId)
+			},
+		})
+	case "tread":
+		app.serveCarResource(w, r, car, parts[2:], carResource{
+			name: "tread measurement",
+			list: func(w http.ResponseWriter, r *http.Request, car models.Car) {
+				app.getTreadMeasurementsHandler(w, r, car, set)
+			},
+			add: func(w http.ResponseWriter, r *http.Request, car models.Car) {
+				app.addTreadMeasurementHandler(w, r, car, set)
+			},
+			remove: func(w http.ResponseWriter, r *http.Request, car models.Car, measurementId int) {
+				app.removeTreadMeasurementHandler(w, r, car, set, measurementId)
+			},
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Routes the uploaded photo /cars/{id}/image and its renditions
+// /cars/{id}/image/{size}
+func (app *application) carImageRoutes(w http.ResponseWriter, r *http.Request, car models.Car, parts []string) {
+	switch len(parts) {
+	case 0:
+		switch r.Method {
+		case http.MethodGet:
+			app.getCarImageHandler(w, r, car)
+		case http.MethodPost, http.MethodPut:
+			app.uploadCarImageHandler(w, r, car)
+		case http.MethodDelete:
+			app.removeCarImageHandler(w, r, car)
+		default:
+			app.methodNotAllowed(w)
+		}
+	case 1:
+		if r.Method != http.MethodGet {
+			app.methodNotAllowed(w)
+			return
+		}
+		app.serveCarImageHandler(w, r, car, parts[0])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Handlers of a car scoped collection such as /cars/{id}/maintenance and its items
+// /cars/{id}/maintenance/{itemId}. A nil handler answers with 405.
+type carResource struct {
+	name   string
+	list   func(http.ResponseWriter, *http.Request, models.Car)
+	add    func(http.ResponseWriter, *http.Request, models.Car)
+	get    func(http.ResponseWriter, *http.Request, models.Car, int)
+	update func(http.ResponseWriter, *http.Request, models.Car, int)
+	remove func(http.ResponseWriter, *http.Request, models.Car, int)
+}
+
+func (app *application) serveCarResource(w http.ResponseWriter, r *http.Request, car models.Car, parts []string, res carResource) {
+	if len(parts) == 0 {
+		switch {
+		case r.Method == http.MethodGet && res.list != nil:
+			res.list(w, r, car)
+		case r.Method == http.MethodPost && res.add != nil:
+			res.add(w, r, car)
+		default:
+			app.methodNotAllowed(w)
+		}
+		return
+	}
+
+	itemId, err := strconv.Atoi(parts[0])
+	if err != nil || len(parts) > 1 {
+		app.writer.ErrorJson(w, errors.New("invalid "+res.name+" id"), http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && res.get != nil:
+		res.get(w, r, car, itemId)
+	case r.Method == http.MethodPut && res.update != nil:
+		res.update(w, r, car, itemId)
+	case r.Method == http.MethodDelete && res.remove != nil:
+		res.remove(w, r, car, itemId)
+	default:
+		app.methodNotAllowed(w)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+)
+
+func (app *application) getServiceSchedulesHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	schedules, err := app.models.DB.GetServiceSchedulesByCarID(car.ID)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, schedules, "schedules")
+}
+
+func (app *application) getServiceScheduleHandler(w http.ResponseWriter, r *http.Request, car models.Car, scheduleId int) {
+	schedule, err := app.models.DB.GetServiceScheduleByID(car.ID, scheduleId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, schedule, "schedule")
+}
+
+func (app *application) addServiceScheduleHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	var schedule models.ServiceSchedule
+	err := json.NewDecoder(r.Body).Decode(&schedule)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = schedule.Validate()
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Without an explicit baseline the schedule starts today
+	if schedule.StartDate.IsZero() {
+		schedule.StartDate = time.Now()
+	}
+
+	schedule.CarID = car.ID
+	schedule.ID, err = app.models.DB.InsertServiceSchedule(schedule)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusCreated, schedule, "schedule")
+}
+
+func (app *application) updateServiceScheduleHandler(w http.ResponseWriter, r *http.Request, car models.Car, scheduleId int) {
+	var schedule models.ServiceSchedule
+	err := json.NewDecoder(r.Body).Decode(&schedule)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = schedule.Validate()
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if schedule.StartDate.IsZero() {
+		schedule.StartDate = time.Now()
+	}
+
+	schedule.ID = scheduleId
+	schedule.CarID = car.ID
+	err = app.models.DB.UpdateServiceSchedule(schedule)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, schedule, "schedule")
+}
+
+func (app *application) removeServiceScheduleHandler(w http.ResponseWriter, r *http.Request, car models.Car, scheduleId int) {
+	err := app.models.DB.RemoveServiceSchedule(car.ID, scheduleId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Attaches the default rule set of the car's model to the car
+func (app *application) applyServiceScheduleTemplatesHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	odometer, err := app.models.DB.GetCurrentOdometer(car.ID)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	created, err := app.models.DB.ApplyServiceScheduleTemplates(car, time.Now(), odometer)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusCreated, created, "created")
+}
+
+func (app *application) getServiceScheduleTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+
+	// Parse the id parameter as an integer
+	modelID, err := strconv.Atoi(id)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// without a fuel type only the templates for every engine are returned
+	fuelType := r.URL.Query().Get("fuel_type")
+	if fuelType != "" && !models.IsFuelType(fuelType) {
+		err = errors.New("invalid fuel type")
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	templates, err := app.models.DB.GetServiceScheduleTemplates(modelID, fuelType)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, templates, "templates")
+}
+
+func (app *application) getDueServicesHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	services, err := app.models.DB.GetDueServicesByCarID(car.ID, time.Now())
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, services, "due")
+}
+
+// Returns the upcoming and overdue services of all the user's cars, most urgent first
+func (app *application) getAllDueServicesHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := app.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	cars, err := app.models.DB.GetCarsByUserID(userId)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	var services []models.DueService
+	for _, car := range cars {
+		carServices, err := app.models.DB.GetDueServicesByCarID(car.ID, now)
+		if err != nil {
+			app.logger.Error(err)
+			app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+			return
+		}
+		services = append(services, carServices...)
+	}
+
+	models.SortDueServices(services)
+
+	app.writer.WriteJson(w, http.StatusOK, services, "due")
+}
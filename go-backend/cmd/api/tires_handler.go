@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+)
+
+// Tire sets of the car with the distance driven on each of them
+func (app *application) getTireSetsHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	sets, err := app.models.DB.GetTireSetsByCarID(car.ID)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	err = app.applyTireEvents(car, sets)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, sets, "tires")
+}
+
+func (app *application) getTireSetHandler(w http.ResponseWriter, r *http.Request, car models.Car, setId int) {
+	set, err := app.models.DB.GetTireSetByID(car.ID, setId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	sets := []models.TireSet{set}
+	err = app.applyTireEvents(car, sets)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, sets[0], "tires")
+}
+
+func (app *application) addTireSetHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	var set models.TireSet
+	err := json.NewDecoder(r.Body).Decode(&set)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = set.Validate()
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	set.CarID = car.ID
+	set.Mounted = false
+	set.DistanceKm = 0
+	set.ID, err = app.models.DB.InsertTireSet(set)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusCreated, set, "tires")
+}
+
+func (app *application) updateTireSetHandler(w http.ResponseWriter, r *http.Request, car models.Car, setId int) {
+	var set models.TireSet
+	err := json.NewDecoder(r.Body).Decode(&set)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = set.Validate()
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	set.ID = setId
+	set.CarID = car.ID
+	err = app.models.DB.UpdateTireSet(set)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	sets := []models.TireSet{set}
+	err = app.applyTireEvents(car, sets)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, sets[0], "tires")
+}
+
+func (app *application) removeTireSetHandler(w http.ResponseWriter, r *http.Request, car models.Car, setId int) {
+	err := app.models.DB.RemoveTireSet(car.ID, setId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Fills in whether the sets are mounted and the distance driven on them
+func (app *application) applyTireEvents(car models.Car, sets []models.TireSet) error {
+	events, err := app.models.DB.GetTireEventsByCarID(car.ID)
+	if err != nil {
+		return err
+	}
+
+	odometer, err := app.models.DB.GetCurrentOdometer(car.ID)
+	if err != nil {
+		return err
+	}
+
+	models.ApplyTireEvents(sets, events, odometer)
+
+	return nil
+}
+
+func (app *application) getTireEventsHandler(w http.ResponseWriter, r *http.Request, car models.Car, set models.TireSet) {
+	events, err := app.models.DB.GetTireEventsByCarID(car.ID)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	setEvents := []models.TireEvent{}
+	for _, e := range events {
+		if e.TireSetID == set.ID {
+			setEvents = append(setEvents, e)
+		}
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, setEvents, "events")
+}
+
+// Mounts or unmounts the tire set, mounting takes the set on the car off
+func (app *application) addTireEventHandler(w http.ResponseWriter, r *http.Request, car models.Car, set models.TireSet) {
+	var event models.TireEvent
+	err := json.NewDecoder(r.Body).Decode(&event)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = event.Validate()
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	event.CarID = car.ID
+	event.TireSetID = set.ID
+	event.ID, err = app.models.DB.InsertTireEvent(event)
+	if errors.Is(err, models.ErrTireEvent) {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusCreated, event, "event")
+}
+
+func (app *application) removeTireEventHandler(w http.ResponseWriter, r *http.Request, car models.Car, set models.TireSet, eventId int) {
+	err := app.models.DB.RemoveTireEvent(car.ID, set.ID, eventId)
+	if errors.Is(err, models.ErrTireEventNotLatest) {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusConflict)
+		return
+	} else if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getTreadMeasurementsHandler(w http.ResponseWriter, r *http.Request, car models.Car, set models.TireSet) {
+	measurements, err := app.models.DB.GetTreadMeasurementsByCarID(car.ID)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	setMeasurements := []models.TreadMeasurement{}
+	for _, t := range measurements {
+		if t.TireSetID == set.ID {
+			setMeasurements = append(setMeasurements, t)
+		}
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, setMeasurements, "tread")
+}
+
+func (app *application) addTreadMeasurementHandler(w http.ResponseWriter, r *http.Request, car models.Car, set models.TireSet) {
+	var measurement models.TreadMeasurement
+	err := json.NewDecoder(r.Body).Decode(&measurement)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = measurement.Validate()
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	measurement.TireSetID = set.ID
+	measurement.ID, err = app.models.DB.InsertTreadMeasurement(measurement)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusCreated, measurement, "tread")
+}
+
+func (app *application) removeTreadMeasurementHandler(w http.ResponseWriter, r *http.Request, car models.Car, set models.TireSet, measurementId int) {
+	err := app.models.DB.RemoveTreadMeasurement(car.ID, set.ID, measurementId)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Tread depths below the minimum and tires past the maximum age
+func (app *application) getTireWarningsHandler(w http.ResponseWriter, r *http.Request, car models.Car) {
+	sets, err := app.models.DB.GetTireSetsByCarID(car.ID)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	measurements, err := app.models.DB.GetTreadMeasurementsByCarID(car.ID)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, models.CheckTireSets(sets, measurements, app.config.tireLimits, time.Now()), "warnings")
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+)
+
+func (app *application) getTrashHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		app.methodNotAllowed(w)
+		return
+	}
+
+	userId, ok := app.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	trash, err := app.models.DB.GetTrashByUserID(userId)
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writer.WriteJson(w, http.StatusOK, trash, "trash")
+}
+
+// Restores a car, maintenance record or expense given by the kind and id
+// query parameters
+func (app *application) restoreFromTrashHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		app.methodNotAllowed(w)
+		return
+	}
+
+	userId, ok := app.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	kind := r.URL.Query().Get("kind")
+	switch kind {
+	case models.TrashKindCar, models.TrashKindMaintenance, models.TrashKindExpense:
+	default:
+		app.logger.Error("invalid trash kind: ", kind)
+		app.writer.ErrorJson(w, errors.New("invalid kind, expected car, maintenance or expense"), http.StatusBadRequest)
+		return
+	}
+
+	// Parse the id parameter as an integer
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		app.logger.Error(err)
+		app.writer.ErrorJson(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = app.models.DB.RestoreFromTrash(userId, kind, id)
+	if err != nil {
+		app.modelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
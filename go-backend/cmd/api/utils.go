@@ -2,7 +2,13 @@ package main
 
 import (
 	"errors"
+	"net/http"
 	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/acornak/car-maintenance-tracker/models"
 )
 
 // Checks if a password is valid according to the given rules
@@ -72,5 +78,86 @@ func validateConfig(cfg *config) error {
 		return errors.New("missing JWT_SECRET configuration")
 	}
 
+	switch cfg.notify.driver {
+	case "", "file":
+	case "smtp":
+		if cfg.notify.smtpHost == "" {
+			return errors.New("missing SMTP_HOST configuration")
+		}
+
+		if cfg.notify.smtpPort == "" {
+			return errors.New("missing SMTP_PORT configuration")
+		}
+
+		if cfg.notify.smtpFrom == "" {
+			return errors.New("missing SMTP_FROM configuration")
+		}
+	default:
+		return errors.New("unknown NOTIFIER configuration: " + cfg.notify.driver)
+	}
+
+	switch cfg.storage.driver {
+	case "", "local":
+	case "s3":
+		if cfg.storage.s3.Endpoint == "" {
+			return errors.New("missing S3_ENDPOINT configuration")
+		}
+
+		if cfg.storage.s3.Bucket == "" {
+			return errors.New("missing S3_BUCKET configuration")
+		}
+
+		if cfg.storage.s3.AccessKey == "" || cfg.storage.s3.SecretKey == "" {
+			return errors.New("missing S3_ACCESS_KEY or S3_SECRET_KEY configuration")
+		}
+	default:
+		return errors.New("unknown STORAGE configuration: " + cfg.storage.driver)
+	}
+
 	return nil
 }
+
+// Splits a path scoped to a car or an organization such as
+// /api/v1/cars/12/maintenance/3 into the id and the remaining path segments,
+// e.g. ["maintenance", "3"]
+func parseIDPath(path, prefix string) (int, []string, error) {
+	rest := strings.Trim(strings.TrimPrefix(path, prefix), "/")
+	if rest == "" {
+		return 0, nil, errors.New("missing id")
+	}
+
+	parts := strings.Split(rest, "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil || id <= 0 {
+		return 0, nil, errors.New("invalid id")
+	}
+
+	return id, parts[1:], nil
+}
+
+// Reads the optional from and to (YYYY-MM-DD) query parameters
+func parseDateRange(r *http.Request) (models.DateRange, error) {
+	var dates models.DateRange
+	var err error
+	query := r.URL.Query()
+
+	if from := query.Get("from"); from != "" {
+		dates.From, err = time.Parse("2006-01-02", from)
+		if err != nil {
+			return dates, errors.New("invalid from date, expected YYYY-MM-DD")
+		}
+	}
+
+	if to := query.Get("to"); to != "" {
+		dates.To, err = time.Parse("2006-01-02", to)
+		if err != nil {
+			return dates, errors.New("invalid to date, expected YYYY-MM-DD")
+		}
+	}
+
+	if !dates.From.IsZero() && !dates.To.IsZero() && dates.To.Before(dates.From) {
+		return dates, errors.New("to date must not be before from date")
+	}
+
+	return dates, nil
+}
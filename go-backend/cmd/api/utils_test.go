@@ -1,7 +1,12 @@
 package main
 
 import (
+	"net/http/httptest"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/acornak/car-maintenance-tracker/models"
 )
 
 func TestIsPasswordValid(t *testing.T) {
@@ -192,6 +197,42 @@ func TestValidateConfig(t *testing.T) {
 			},
 			valid: false,
 		},
+		{
+			name: "SmtpMissingHost",
+			cfg: config{
+				port:          "8080",
+				allowedOrigin: "http://example.com",
+				dbConn: dbConfig{
+					host:     "localhost",
+					port:     "5432",
+					user:     "user",
+					password: "password",
+					dbname:   "database",
+					sslmode:  "disable",
+				},
+				jwtSigningKey: []byte("secret"),
+				notify:        notifyConfig{driver: "smtp", smtpPort: "587", smtpFrom: "tracker@example.com"},
+			},
+			valid: false,
+		},
+		{
+			name: "UnknownNotifier",
+			cfg: config{
+				port:          "8080",
+				allowedOrigin: "http://example.com",
+				dbConn: dbConfig{
+					host:     "localhost",
+					port:     "5432",
+					user:     "user",
+					password: "password",
+					dbname:   "database",
+					sslmode:  "disable",
+				},
+				jwtSigningKey: []byte("secret"),
+				notify:        notifyConfig{driver: "pigeon"},
+			},
+			valid: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -206,3 +247,56 @@ func TestValidateConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestParseIDPath(t *testing.T) {
+	prefix := "/api/v1/cars/"
+	cases := []struct {
+		path  string
+		carId int
+		parts []string
+		valid bool
+	}{
+		{"/api/v1/cars/12/maintenance", 12, []string{"maintenance"}, true},
+		{"/api/v1/cars/12/maintenance/3/", 12, []string{"maintenance", "3"}, true},
+		{"/api/v1/cars/12", 12, []string{}, true},
+		{"/api/v1/cars/", 0, nil, false},
+		{"/api/v1/cars/abc/maintenance", 0, nil, false},
+		{"/api/v1/cars/-1/maintenance", 0, nil, false},
+	}
+
+	for _, c := range cases {
+		carId, parts, err := parseIDPath(c.path, prefix)
+		if c.valid && err != nil {
+			t.Errorf("parseIDPath(%q) returned unexpected error: %v", c.path, err)
+			continue
+		}
+		if !c.valid {
+			if err == nil {
+				t.Errorf("parseIDPath(%q) expected an error", c.path)
+			}
+			continue
+		}
+		if carId != c.carId || !reflect.DeepEqual(parts, c.parts) {
+			t.Errorf("parseIDPath(%q) == (%d, %v), expected (%d, %v)", c.path, carId, parts, c.carId, c.parts)
+		}
+	}
+}
+
+func TestParseDateRange(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/v1/cars/1/fuel?from=2023-01-01&to=2023-03-31", nil)
+	dates, err := parseDateRange(r)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := models.DateRange{From: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2023, 3, 31, 0, 0, 0, 0, time.UTC)}
+	if dates != expected {
+		t.Errorf("Expected %v, got %v", expected, dates)
+	}
+
+	for _, query := range []string{"from=01-01-2023", "to=yesterday", "from=2023-02-01&to=2023-01-01"} {
+		r := httptest.NewRequest("GET", "/api/v1/cars/1/fuel?"+query, nil)
+		if _, err := parseDateRange(r); err == nil {
+			t.Errorf("Expected an error for %q", query)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+// Package export writes and reads the archive with all data of an account.
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/acornak/car-maintenance-tracker/storage"
+)
+
+const (
+	// version of the JSON document, bumped on incompatible changes
+	SchemaVersion = 1
+
+	// name of the JSON document in the archive
+	DocumentName = "account.json"
+)
+
+var (
+	ErrNoDocument       = errors.New("the archive does not contain " + DocumentName)
+	ErrDocumentTooLarge = errors.New(DocumentName + " is too large")
+	// returned for an attachment whose file is not in the archive
+	ErrNoFile = errors.New("the archive does not contain the file of the attachment")
+)
+
+// The user profile without the password hash
+type Profile struct {
+	ID        int    `json:"id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Nickname  string `json:"nickname"`
+	Email     string `json:"email"`
+}
+
+type Document struct {
+	SchemaVersion int                 `json:"schema_version"`
+	ExportedAt    time.Time           `json:"exported_at"`
+	User          Profile             `json:"user"`
+	Cars          []models.CarHistory `json:"cars"`
+
+	// the attachment files of the archive the document was read from, by id
+	files map[int]*zip.File
+}
+
+func NewDocument(user models.User, cars []models.CarHistory, exportedAt time.Time) Document {
+	if cars == nil {
+		cars = []models.CarHistory{}
+	}
+
+	return Document{
+		SchemaVersion: SchemaVersion,
+		ExportedAt:    exportedAt,
+		User: Profile{
+			ID:        user.ID,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Nickname:  user.Nickname,
+			Email:     user.Email,
+		},
+		Cars: cars,
+	}
+}
+
+// Name of the file of an attachment in the archive
+func AttachmentName(a models.Attachment) string {
+	return fmt.Sprintf("attachments/%d/%s", a.ID, a.FileName)
+}
+
+// Streams the archive: the JSON document followed by one CSV file per entity
+// and the files of the attachments, read from the store. A file missing from
+// the store is left out.
+func Write(ctx context.Context, w io.Writer, doc Document, store storage.Storage) error {
+	zw := zip.NewWriter(w)
+
+	f, err := create(zw, DocumentName, doc.ExportedAt)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(doc)
+	if err != nil {
+		return err
+	}
+
+	for _, table := range tables(doc) {
+		f, err := create(zw, table.name, doc.ExportedAt)
+		if err != nil {
+			return err
+		}
+
+		cw := csv.NewWriter(f)
+		err = cw.Write(table.header)
+		if err != nil {
+			return err
+		}
+
+		err = cw.WriteAll(table.rows)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, h := range doc.Cars {
+		for _, a := range h.Attachments {
+			err = writeAttachment(ctx, zw, a, store, doc.ExportedAt)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return zw.Close()
+}
+
+func writeAttachment(ctx context.Context, zw *zip.Writer, a models.Attachment, store storage.Storage, modified time.Time) error {
+	content, err := store.Get(ctx, a.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	// PDFs and images are compressed already
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: AttachmentName(a), Method: zip.Store, Modified: modified})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, content)
+	return err
+}
+
+// Reads the JSON document of an archive created by Write. The document must
+// not be larger than maxSize bytes once decompressed. The files of the
+// attachments are opened with OpenAttachment.
+func Read(archive []byte, maxSize int64) (Document, error) {
+	var doc Document
+
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return doc, err
+	}
+
+	files := make(map[int]*zip.File)
+	for _, f := range zr.File {
+		parts := strings.Split(f.Name, "/")
+		if len(parts) != 3 || parts[0] != "attachments" {
+			continue
+		}
+
+		id, err := strconv.Atoi(parts[1])
+		if err == nil {
+			files[id] = f
+		}
+	}
+
+	for _, f := range zr.File {
+		if f.Name != DocumentName {
+			continue
+		}
+
+		// the header may lie about the size, the reader is limited as well
+		if f.UncompressedSize64 > uint64(maxSize) {
+			return doc, ErrDocumentTooLarge
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return doc, err
+		}
+		defer rc.Close()
+
+		lr := &io.LimitedReader{R: rc, N: maxSize + 1}
+		err = json.NewDecoder(lr).Decode(&doc)
+		if lr.N <= 0 {
+			return doc, ErrDocumentTooLarge
+		}
+		if err != nil {
+			return doc, fmt.Errorf("invalid %s: %w", DocumentName, err)
+		}
+
+		if doc.SchemaVersion < 1 || doc.SchemaVersion > SchemaVersion {
+			return doc, fmt.Errorf("unsupported schema version %d", doc.SchemaVersion)
+		}
+
+		doc.files = files
+		return doc, nil
+	}
+
+	return doc, ErrNoDocument
+}
+
+// Opens the file of an exported attachment together with its size, ErrNoFile
+// when the archive the document was read from does not contain it
+func (d Document) OpenAttachment(a models.Attachment) (io.ReadCloser, int64, error) {
+	f, ok := d.files[a.ID]
+	if !ok {
+		return nil, 0, ErrNoFile
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return rc, int64(f.UncompressedSize64), nil
+}
+
+func create(zw *zip.Writer, name string, modified time.Time) (io.Writer, error) {
+	return zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+}
+
+type table struct {
+	name   string
+	header []string
+	rows   [][]string
+}
+
+func tables(doc Document) []table {
+	user := table{
+		name:   "user.csv",
+		header: []string{"id", "first_name", "last_name", "nickname", "email"},
+		rows:   [][]string{{itoa(doc.User.ID), doc.User.FirstName, doc.User.LastName, doc.User.Nickname, doc.User.Email}},
+	}
+	cars := table{
+		name:   "cars.csv",
+		header: []string{"id", "brand_id", "model_id", "year", "color", "price", "image", "description", "license_plate", "vin", "variant_id", "created_at", "archived_at", "sold_at", "sale_price"},
+	}
+	maintenance := table{
+		name:   "maintenance.csv",
+		header: []string{"id", "car_id", "service_date", "odometer", "service_type", "description", "cost", "performed_by", "notes", "created_at"},
+	}
+	expenses := table{
+		name:   "expenses.csv",
+		header: []string{"id", "car_id", "category", "amount", "currency", "expense_date", "odometer", "maintenance_id", "description", "created_at"},
+	}
+	fuel := table{
+		name:   "fuel_logs.csv",
+		header: []string{"id", "car_id", "fill_date", "odometer", "volume", "price_per_unit", "full_tank", "missed_previous", "station", "notes", "created_at"},
+	}
+	charging := table{
+		name:   "charging_sessions.csv",
+		header: []string{"id", "car_id", "charge_date", "odometer", "energy_kwh", "cost", "charger_type", "location_type", "start_soc", "end_soc", "notes", "created_at"},
+	}
+	battery := table{
+		name:   "battery_health_logs.csv",
+		header: []string{"id", "car_id", "log_date", "odometer", "capacity_kwh", "range_km", "notes", "created_at"},
+	}
+	tireSets := table{
+		name:   "tire_sets.csv",
+		header: []string{"id", "car_id", "name", "season", "brand", "size", "dot_code", "purchase_cost", "notes", "created_at"},
+	}
+	tireEvents := table{
+		name:   "tire_events.csv",
+		header: []string{"id", "car_id", "tire_set_id", "event_type", "event_date", "odometer", "notes", "created_at"},
+	}
+	tread := table{
+		name:   "tread_measurements.csv",
+		header: []string{"id", "tire_set_id", "measured_on", "position", "depth_mm", "odometer", "created_at"},
+	}
+	documents := table{
+		name:   "documents.csv",
+		header: []string{"id", "car_id", "document_type", "issuer", "policy_number", "valid_from", "valid_until", "mileage_limit", "notes", "created_at"},
+	}
+	attachments := table{
+		name:   "attachments.csv",
+		header: []string{"id", "car_id", "record_type", "record_id", "file_name", "content_type", "size_bytes", "created_at"},
+	}
+	odometer := table{
+		name:   "odometer_readings.csv",
+		header: []string{"id", "car_id", "reading_date", "odometer", "is_replacement", "note", "created_at"},
+	}
+	schedules := table{
+		name:   "service_schedules.csv",
+		header: []string{"id", "car_id", "service_type", "interval_km", "interval_months", "description", "start_date", "start_odometer", "created_at"},
+	}
+
+	for _, h := range doc.Cars {
+		c := h.Car
+		cars.rows = append(cars.rows, []string{itoa(c.ID), itoa(c.BrandID), itoa(c.ModelID), itoa(c.Year), c.Color, itoa(c.Price), c.Image, c.Description, c.LicensePlate, c.VIN, optional(c.VariantID), c.CreatedAt, optionalTime(c.ArchivedAt, time.RFC3339), optionalTime(c.SoldAt, "2006-01-02"), optional(c.SalePrice)})
+
+		for _, r := range h.Maintenance {
+			maintenance.rows = append(maintenance.rows, []string{itoa(r.ID), itoa(c.ID), date(r.ServiceDate), itoa(r.Odometer), r.ServiceType, r.Description, money(r.Cost), r.PerformedBy, r.Notes, r.CreatedAt})
+		}
+		for _, e := range h.Expenses {
+			expenses.rows = append(expenses.rows, []string{itoa(e.ID), itoa(c.ID), e.Category, money(e.Amount), e.Currency, date(e.ExpenseDate), optional(e.Odometer), optional(e.MaintenanceID), e.Description, e.CreatedAt})
+		}
+		for _, f := range h.FuelLogs {
+			fuel.rows = append(fuel.rows, []string{itoa(f.ID), itoa(c.ID), date(f.FillDate), itoa(f.Odometer), float(f.Volume), float(f.PricePerUnit), strconv.FormatBool(f.FullTank), strconv.FormatBool(f.MissedPrevious), f.Station, f.Notes, f.CreatedAt})
+		}
+		for _, s := range h.ChargingSessions {
+			charging.rows = append(charging.rows, []string{itoa(s.ID), itoa(c.ID), date(s.ChargeDate), itoa(s.Odometer), float(s.EnergyKWh), money(s.Cost), s.ChargerType, s.LocationType, optional(s.StartSoC), optional(s.EndSoC), s.Notes, s.CreatedAt})
+		}
+		for _, b := range h.BatteryHealthLogs {
+			battery.rows = append(battery.rows, []string{itoa(b.ID), itoa(c.ID), date(b.LogDate), optional(b.Odometer), optionalFloat(b.CapacityKWh), optional(b.RangeKm), b.Notes, b.CreatedAt})
+		}
+		for _, t := range h.TireSets {
+			tireSets.rows = append(tireSets.rows, []string{itoa(t.ID), itoa(c.ID), t.Name, t.Season, t.Brand, t.Size, t.DOTCode, money(t.PurchaseCost), t.Notes, t.CreatedAt})
+		}
+		for _, e := range h.TireEvents {
+			tireEvents.rows = append(tireEvents.rows, []string{itoa(e.ID), itoa(c.ID), itoa(e.TireSetID), e.EventType, date(e.EventDate), itoa(e.Odometer), e.Notes, e.CreatedAt})
+		}
+		for _, t := range h.TreadMeasurements {
+			tread.rows = append(tread.rows, []string{itoa(t.ID), itoa(t.TireSetID), date(t.MeasuredOn), t.Position, float(t.DepthMm), optional(t.Odometer), t.CreatedAt})
+		}
+		for _, d := range h.Documents {
+			documents.rows = append(documents.rows, []string{itoa(d.ID), itoa(c.ID), d.DocumentType, d.Issuer, d.PolicyNumber, date(d.ValidFrom), date(d.ValidUntil), optional(d.MileageLimit), d.Notes, d.CreatedAt})
+		}
+		for _, a := range h.Attachments {
+			attachments.rows = append(attachments.rows, []string{itoa(a.ID), itoa(c.ID), a.RecordType, itoa(a.RecordID), a.FileName, a.ContentType, strconv.FormatInt(a.SizeBytes, 10), a.CreatedAt})
+		}
+		for _, r := range h.OdometerReadings {
+			odometer.rows = append(odometer.rows, []string{itoa(r.ID), itoa(c.ID), date(r.ReadingDate), itoa(r.Odometer), strconv.FormatBool(r.IsReplacement), r.Note, r.CreatedAt})
+		}
+		for _, s := range h.ServiceSchedules {
+			schedules.rows = append(schedules.rows, []string{itoa(s.ID), itoa(c.ID), s.ServiceType, itoa(s.IntervalKm), itoa(s.IntervalMonths), s.Description, date(s.StartDate), itoa(s.StartOdometer), s.CreatedAt})
+		}
+	}
+
+	return []table{user, cars, maintenance, expenses, fuel, charging, battery, tireSets, tireEvents, tread, documents, attachments, odometer, schedules}
+}
+
+func itoa(value int) string {
+	return strconv.Itoa(value)
+}
+
+func optional(value *int) string {
+	if value == nil {
+		return ""
+	}
+
+	return strconv.Itoa(*value)
+}
+
+func optionalFloat(value *float64) string {
+	if value == nil {
+		return ""
+	}
+
+	return float(*value)
+}
+
+func optionalTime(value *time.Time, layout string) string {
+	if value == nil {
+		return ""
+	}
+
+	return value.Format(layout)
+}
+
+func date(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+func money(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+func float(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,157 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/acornak/car-maintenance-tracker/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func testDocument() Document {
+	maintenanceId := 11
+	user := models.User{ID: 5, FirstName: "John", LastName: "Doe", Nickname: "jd", Email: "john@example.com", Password: "$2a$12$hash"}
+	cars := []models.CarHistory{{
+		Car: models.Car{ID: 3, UserId: 5, BrandID: 1, ModelID: 2, Year: 2015, LicensePlate: "BA123XY"},
+		Maintenance: []models.MaintenanceRecord{
+			{ID: 11, CarID: 3, ServiceDate: time.Date(2023, 6, 19, 0, 0, 0, 0, time.UTC), Odometer: 45000, ServiceType: "Oil change, filter", Cost: 120.5},
+		},
+		Expenses: []models.Expense{
+			{ID: 21, CarID: 3, Category: "maintenance", Amount: 120.5, Currency: "EUR", ExpenseDate: time.Date(2023, 6, 19, 0, 0, 0, 0, time.UTC), MaintenanceID: &maintenanceId},
+		},
+		Attachments: []models.Attachment{
+			{ID: 31, CarID: 3, RecordType: "expense", RecordID: 21, FileName: "invoice.pdf", ContentType: "application/pdf", SizeBytes: 13, StorageKey: "attachments/5/3/abc"},
+			{ID: 32, CarID: 3, RecordType: "expense", RecordID: 21, FileName: "lost.pdf", ContentType: "application/pdf", SizeBytes: 10, StorageKey: "attachments/5/3/def"},
+		},
+	}}
+
+	return NewDocument(user, cars, time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC))
+}
+
+// A store with the file of the first attachment of the test document, the
+// file of the second one is missing
+func testStore(t *testing.T) storage.Storage {
+	store, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create the store: %v", err)
+	}
+
+	err = store.Put(context.Background(), "attachments/5/3/abc", strings.NewReader("%PDF-1.4 test"), 13, "application/pdf")
+	if err != nil {
+		t.Fatalf("failed to store the file: %v", err)
+	}
+
+	return store
+}
+
+func readFile(t *testing.T, archive []byte, name string) []byte {
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+
+	for _, f := range zr.File {
+		if f.Name == name {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatalf("failed to open %s: %v", name, err)
+			}
+			defer rc.Close()
+
+			content, err := io.ReadAll(rc)
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", name, err)
+			}
+			return content
+		}
+	}
+
+	t.Fatalf("%s is missing in the archive", name)
+	return nil
+}
+
+func TestWriteAndRead(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(context.Background(), &buf, testDocument(), testStore(t))
+	assert.NoError(t, err)
+
+	doc, err := Read(buf.Bytes(), 1<<20)
+	assert.NoError(t, err)
+	assert.Equal(t, SchemaVersion, doc.SchemaVersion)
+	assert.Equal(t, "john@example.com", doc.User.Email)
+	assert.Len(t, doc.Cars, 1)
+	assert.Equal(t, 2015, doc.Cars[0].Car.Year)
+	assert.Equal(t, 11, *doc.Cars[0].Expenses[0].MaintenanceID)
+	assert.NotContains(t, buf.String(), "$2a$12$hash")
+
+	account := readFile(t, buf.Bytes(), DocumentName)
+	assert.NotContains(t, string(account), "password")
+
+	assert.Equal(t, "%PDF-1.4 test", string(readFile(t, buf.Bytes(), "attachments/31/invoice.pdf")))
+
+	content, size, err := doc.OpenAttachment(doc.Cars[0].Attachments[0])
+	assert.NoError(t, err)
+	defer content.Close()
+	assert.Equal(t, int64(13), size)
+	file, err := io.ReadAll(content)
+	assert.NoError(t, err)
+	assert.Equal(t, "%PDF-1.4 test", string(file))
+
+	_, _, err = doc.OpenAttachment(doc.Cars[0].Attachments[1])
+	assert.ErrorIs(t, err, ErrNoFile)
+}
+
+func TestWrite_CSVFiles(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, Write(context.Background(), &buf, testDocument(), testStore(t)))
+
+	rows, err := csv.NewReader(bytes.NewReader(readFile(t, buf.Bytes(), "maintenance.csv"))).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"id", "car_id", "service_date", "odometer", "service_type", "description", "cost", "performed_by", "notes", "created_at"},
+		{"11", "3", "2023-06-19", "45000", "Oil change, filter", "", "120.50", "", "", ""},
+	}, rows)
+
+	rows, err = csv.NewReader(bytes.NewReader(readFile(t, buf.Bytes(), "user.csv"))).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "first_name", "last_name", "nickname", "email"}, rows[0])
+
+	rows, err = csv.NewReader(bytes.NewReader(readFile(t, buf.Bytes(), "fuel_logs.csv"))).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+}
+
+func TestRead_Errors(t *testing.T) {
+	_, err := Read([]byte("not a zip"), 1<<20)
+	assert.Error(t, err)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	zw.Create("cars.csv")
+	zw.Close()
+	_, err = Read(buf.Bytes(), 1<<20)
+	assert.ErrorIs(t, err, ErrNoDocument)
+
+	buf.Reset()
+	zw = zip.NewWriter(&buf)
+	f, _ := zw.Create(DocumentName)
+	f.Write([]byte(`{"schema_version": 99}`))
+	zw.Close()
+	_, err = Read(buf.Bytes(), 1<<20)
+	assert.EqualError(t, err, "unsupported schema version 99")
+}
+
+func TestRead_DocumentTooLarge(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, Write(context.Background(), &buf, testDocument(), testStore(t)))
+
+	_, err := Read(buf.Bytes(), 64)
+	assert.ErrorIs(t, err, ErrDocumentTooLarge)
+}
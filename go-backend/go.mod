@@ -3,11 +3,13 @@ module github.com/acornak/car-maintenance-tracker
 go 1.20
 
 require (
+	github.com/go-pdf/fpdf v0.8.0
 	github.com/golang-jwt/jwt/v5 v5.0.0
 	github.com/joho/godotenv v1.5.1
 	github.com/lib/pq v1.10.9
 	go.uber.org/zap v1.24.0
 	golang.org/x/crypto v0.10.0
+	golang.org/x/image v0.10.0
 )
 
 require (
@@ -0,0 +1,132 @@
+// Package ical writes iCalendar (RFC 5545) feeds.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// longest content line in octets, excluding the line break
+const maxLineLength = 75
+
+type Calendar struct {
+	Name   string
+	Events []Event
+	// the time the feed was generated, written as DTSTAMP
+	Now time.Time
+}
+
+// An all-day event
+type Event struct {
+	// has to stay the same whenever the feed is regenerated, so calendar
+	// clients update the event instead of adding a copy
+	UID         string
+	Date        time.Time
+	Summary     string
+	Description string
+	// the event is announced this long before its start
+	Alarms []time.Duration
+}
+
+func Write(w io.Writer, cal Calendar) error {
+	bw := bufio.NewWriter(w)
+	l := &lineWriter{w: bw}
+
+	l.line("BEGIN:VCALENDAR")
+	l.line("VERSION:2.0")
+	l.line("PRODID:-//car-maintenance-tracker//EN")
+	l.line("CALSCALE:GREGORIAN")
+	l.line("METHOD:PUBLISH")
+	if cal.Name != "" {
+		l.line("X-WR-CALNAME:" + escape(cal.Name))
+	}
+
+	stamp := cal.Now.UTC().Format("20060102T150405Z")
+	for _, e := range cal.Events {
+		l.line("BEGIN:VEVENT")
+		l.line("UID:" + escape(e.UID))
+		l.line("DTSTAMP:" + stamp)
+		l.line("DTSTART;VALUE=DATE:" + e.Date.Format("20060102"))
+		l.line("DTEND;VALUE=DATE:" + e.Date.AddDate(0, 0, 1).Format("20060102"))
+		l.line("SUMMARY:" + escape(e.Summary))
+		if e.Description != "" {
+			l.line("DESCRIPTION:" + escape(e.Description))
+		}
+		l.line("TRANSP:TRANSPARENT")
+
+		for _, alarm := range e.Alarms {
+			l.line("BEGIN:VALARM")
+			l.line("ACTION:DISPLAY")
+			l.line("DESCRIPTION:" + escape(e.Summary))
+			l.line("TRIGGER:" + trigger(alarm))
+			l.line("END:VALARM")
+		}
+
+		l.line("END:VEVENT")
+	}
+
+	l.line("END:VCALENDAR")
+	if l.err != nil {
+		return l.err
+	}
+
+	return bw.Flush()
+}
+
+// Writes CRLF terminated content lines folded to maxLineLength octets,
+// remembering the first error
+type lineWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (l *lineWriter) line(content string) {
+	if l.err != nil {
+		return
+	}
+
+	limit := maxLineLength
+	for len(content) > limit {
+		// never split a multi-byte character
+		cut := limit
+		for cut > 0 && !isRuneStart(content[cut]) {
+			cut--
+		}
+
+		_, l.err = l.w.WriteString(content[:cut] + "\r\n ")
+		if l.err != nil {
+			return
+		}
+		content = content[cut:]
+		// continuation lines start with a space
+		limit = maxLineLength - 1
+	}
+
+	_, l.err = l.w.WriteString(content + "\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+func escape(text string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(text)
+}
+
+// Formats the alarm as a negative duration relative to the start of the event
+func trigger(before time.Duration) string {
+	if before%(24*time.Hour) == 0 {
+		return fmt.Sprintf("-P%dD", before/(24*time.Hour))
+	}
+
+	return fmt.Sprintf("-PT%dM", before/time.Minute)
+}
@@ -0,0 +1,60 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite(t *testing.T) {
+	cal := Calendar{
+		Name: "Car maintenance",
+		Now:  time.Date(2023, 7, 1, 12, 30, 0, 0, time.UTC),
+		Events: []Event{{
+			UID:         "service:1:2022-07-01:30000@car-maintenance-tracker",
+			Date:        time.Date(2023, 7, 31, 0, 0, 0, 0, time.UTC),
+			Summary:     "Škoda Octavia: Oil change, filter",
+			Description: "Last performed on 2022-07-01.\nDue at 45000 km.",
+			Alarms:      []time.Duration{7 * 24 * time.Hour, 90 * time.Minute},
+		}},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, Write(&buf, cal))
+
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(out, "END:VEVENT\r\nEND:VCALENDAR\r\n"))
+	assert.Contains(t, out, "DTSTAMP:20230701T123000Z\r\n")
+	assert.Contains(t, out, "DTSTART;VALUE=DATE:20230731\r\nDTEND;VALUE=DATE:20230801\r\n")
+	assert.Contains(t, out, "SUMMARY:Škoda Octavia: Oil change\\, filter\r\n")
+	assert.Contains(t, out, "DESCRIPTION:Last performed on 2022-07-01.\\nDue at 45000 km.\r\n")
+	assert.Contains(t, out, "TRIGGER:-P7D\r\n")
+	assert.Contains(t, out, "TRIGGER:-PT90M\r\n")
+	assert.Equal(t, 2, strings.Count(out, "BEGIN:VALARM"))
+}
+
+func TestWrite_FoldsLongLines(t *testing.T) {
+	cal := Calendar{Events: []Event{{
+		UID:     "long",
+		Summary: strings.Repeat("Údržba ", 30),
+	}}}
+
+	var buf bytes.Buffer
+	assert.NoError(t, Write(&buf, cal))
+
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75)
+		assert.True(t, strings.ToValidUTF8(line, "") == line, "a folded line splits a character: %q", line)
+	}
+
+	unfolded := strings.ReplaceAll(buf.String(), "\r\n ", "")
+	assert.Contains(t, unfolded, "SUMMARY:"+strings.Repeat("Údržba ", 30)+"\r\n")
+}
+
+func TestEscape(t *testing.T) {
+	assert.Equal(t, `a\\b\;c\,d\ne`, escape("a\\b;c,d\r\ne"))
+}
@@ -0,0 +1,155 @@
+// Package imaging turns uploaded photos into re-encoded JPEG renditions of
+// fixed sizes. Decoding and encoding again drops every piece of metadata the
+// camera wrote, such as the EXIF location, only the orientation is applied to
+// the pixels first.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png" // registers the PNG decoder
+	"io"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // registers the WebP decoder
+)
+
+// returned for content that is not a JPEG, PNG or WebP image
+var ErrUnsupported = errors.New("image must be a JPEG, PNG or WebP file")
+
+// returned for images with more pixels than MaxPixels
+var ErrTooLarge = errors.New("image has too many pixels")
+
+// images with more pixels are rejected before they are decoded, a small file
+// can otherwise claim dimensions that need gigabytes of memory
+const MaxPixels = 50_000_000
+
+const jpegQuality = 85
+
+// A rendition the image is scaled down to, the longer side is at most MaxSide
+type Size struct {
+	Name    string
+	MaxSide int
+}
+
+// The renditions of every upload, the largest first
+var Sizes = []Size{
+	{Name: "large", MaxSide: 1600},
+	{Name: "medium", MaxSide: 800},
+	{Name: "small", MaxSide: 400},
+	{Name: "thumb", MaxSide: 160},
+}
+
+func IsSize(name string) bool {
+	for _, s := range Sizes {
+		if s.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The processed image: its dimensions after the orientation was applied and
+// the encoded JPEG of each size
+type Result struct {
+	Width      int
+	Height     int
+	Renditions map[string][]byte
+}
+
+// Decodes a JPEG, PNG or WebP image and encodes every size of Sizes as a JPEG.
+// Images are never scaled up and transparent parts become white.
+func Process(r io.Reader) (Result, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Result{}, err
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || (format != "jpeg" && format != "png" && format != "webp") {
+		return Result{}, ErrUnsupported
+	}
+
+	if config.Width <= 0 || config.Height <= 0 {
+		return Result{}, ErrUnsupported
+	}
+
+	if config.Width*config.Height > MaxPixels {
+		return Result{}, fmt.Errorf("%w: %dx%d, at most %d", ErrTooLarge, config.Width, config.Height, MaxPixels)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+
+	orientation := 1
+	if format == "jpeg" {
+		orientation = jpegOrientation(data)
+	}
+
+	result := Result{Width: config.Width, Height: config.Height, Renditions: make(map[string][]byte)}
+	if orientation >= 5 {
+		// the orientations from 5 on turn the image by a quarter
+		result.Width, result.Height = config.Height, config.Width
+	}
+
+	// every size is scaled from the previous one, which is much cheaper than
+	// scaling the full image each time and looks the same
+	current := src
+	for _, size := range Sizes {
+		scaled := fit(current, size.MaxSide)
+		current = scaled
+		oriented := orient(scaled, orientation)
+
+		var buf bytes.Buffer
+		err = jpeg.Encode(&buf, oriented, &jpeg.Options{Quality: jpegQuality})
+		if err != nil {
+			return Result{}, err
+		}
+		result.Renditions[size.Name] = buf.Bytes()
+	}
+
+	return result, nil
+}
+
+// Scales the image down so that its longer side is at most maxSide, drawn over
+// white so that transparency does not turn black in the JPEG
+func fit(src image.Image, maxSide int) *image.RGBA {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	if width > maxSide || height > maxSide {
+		if width >= height {
+			height = maxInt(1, height*maxSide/width)
+			width = maxSide
+		} else {
+			width = maxInt(1, width*maxSide/height)
+			height = maxSide
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	if width == bounds.Dx() && height == bounds.Dy() {
+		draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Over)
+	} else {
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+	}
+
+	return dst
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
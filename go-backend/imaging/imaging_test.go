@@ -0,0 +1,185 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// An APP1 segment with the orientation and a made up GPS tag in little endian EXIF
+func exifSegment(orientation uint16) []byte {
+	var tiff bytes.Buffer
+	tiff.WriteString("II*\x00")
+	binary.Write(&tiff, binary.LittleEndian, uint32(8))
+	binary.Write(&tiff, binary.LittleEndian, uint16(2))
+	// orientation, SHORT, one value
+	binary.Write(&tiff, binary.LittleEndian, []uint16{0x0112, 3})
+	binary.Write(&tiff, binary.LittleEndian, uint32(1))
+	binary.Write(&tiff, binary.LittleEndian, []uint16{orientation, 0})
+	// GPS IFD pointer, LONG, pointing at the secret below
+	binary.Write(&tiff, binary.LittleEndian, []uint16{0x8825, 4})
+	binary.Write(&tiff, binary.LittleEndian, uint32(1))
+	binary.Write(&tiff, binary.LittleEndian, uint32(38))
+	binary.Write(&tiff, binary.LittleEndian, uint32(0))
+	tiff.WriteString("GPS 48.1486N 17.1077E")
+
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+
+	return append(segment, payload...)
+}
+
+// A JPEG of the size whose left half is red and right half blue, with the EXIF segment
+func photo(t *testing.T, width, height int, orientation uint16) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < width/2 {
+				img.Set(x, y, color.RGBA{R: 255, A: 255})
+			} else {
+				img.Set(x, y, color.RGBA{B: 255, A: 255})
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), exifSegment(orientation)...), data[2:]...)
+}
+
+func decode(t *testing.T, data []byte) image.Image {
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return img
+}
+
+func TestJpegOrientation(t *testing.T) {
+	assert.Equal(t, 6, jpegOrientation(photo(t, 8, 4, 6)))
+	assert.Equal(t, 1, jpegOrientation(photo(t, 8, 4, 0)))
+	assert.Equal(t, 1, jpegOrientation([]byte("not a jpeg")))
+	assert.Equal(t, 1, jpegOrientation([]byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF}))
+}
+
+func TestProcess_StripsExifAndAppliesOrientation(t *testing.T) {
+	result, err := Process(bytes.NewReader(photo(t, 2000, 1000, 6)))
+
+	assert.NoError(t, err)
+	// turned clockwise the photo stands upright
+	assert.Equal(t, 1000, result.Width)
+	assert.Equal(t, 2000, result.Height)
+	assert.Len(t, result.Renditions, len(Sizes))
+
+	for _, size := range Sizes {
+		data := result.Renditions[size.Name]
+		assert.NotContains(t, string(data), "Exif", size.Name)
+		assert.NotContains(t, string(data), "GPS", size.Name)
+
+		img := decode(t, data)
+		bounds := img.Bounds()
+		assert.Equal(t, size.MaxSide, bounds.Dy(), size.Name)
+		assert.Equal(t, size.MaxSide/2, bounds.Dx(), size.Name)
+
+		// the red left half ends up on top
+		r, _, b, _ := img.At(bounds.Dx()/2, bounds.Dy()/8).RGBA()
+		assert.Greater(t, r, b, size.Name)
+	}
+}
+
+func TestProcess_DoesNotScaleUp(t *testing.T) {
+	result, err := Process(bytes.NewReader(photo(t, 300, 200, 1)))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 300, result.Width)
+	assert.Equal(t, image.Rect(0, 0, 300, 200), decode(t, result.Renditions["large"]).Bounds())
+	assert.Equal(t, image.Rect(0, 0, 160, 106), decode(t, result.Renditions["thumb"]).Bounds())
+}
+
+func TestProcess_TransparencyBecomesWhite(t *testing.T) {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 10, 10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Process(&buf)
+
+	assert.NoError(t, err)
+	r, g, b, _ := decode(t, result.Renditions["thumb"]).At(5, 5).RGBA()
+	assert.Greater(t, r, uint32(0xF000))
+	assert.Greater(t, g, uint32(0xF000))
+	assert.Greater(t, b, uint32(0xF000))
+}
+
+func TestProcess_Unsupported(t *testing.T) {
+	for _, data := range []string{"GIF89a\x01\x00\x01\x00", "%PDF-1.7", ""} {
+		_, err := Process(bytes.NewReader([]byte(data)))
+		assert.True(t, errors.Is(err, ErrUnsupported), data)
+	}
+}
+
+func TestProcess_TooManyPixels(t *testing.T) {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// claim 20000x20000 pixels in the header and fix its checksum
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[16:], 20000)
+	binary.BigEndian.PutUint32(data[20:], 20000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	_, err = Process(bytes.NewReader(data))
+
+	assert.True(t, errors.Is(err, ErrTooLarge))
+}
+
+func TestOrient(t *testing.T) {
+	// 3x2 with distinct pixels, the value is the source position
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			src.SetRGBA(x, y, color.RGBA{R: uint8(x), G: uint8(y), A: 255})
+		}
+	}
+
+	at := func(img *image.RGBA, x, y int) [2]uint8 {
+		c := img.RGBAAt(x, y)
+		return [2]uint8{c.R, c.G}
+	}
+
+	assert.Equal(t, src, orient(src, 1))
+	assert.Equal(t, [2]uint8{2, 0}, at(orient(src, 2), 0, 0))
+	assert.Equal(t, [2]uint8{2, 1}, at(orient(src, 3), 0, 0))
+	assert.Equal(t, [2]uint8{0, 1}, at(orient(src, 4), 0, 0))
+
+	clockwise := orient(src, 6)
+	assert.Equal(t, image.Rect(0, 0, 2, 3), clockwise.Bounds())
+	assert.Equal(t, [2]uint8{0, 1}, at(clockwise, 0, 0))
+	assert.Equal(t, [2]uint8{0, 0}, at(clockwise, 1, 0))
+
+	counterclockwise := orient(src, 8)
+	assert.Equal(t, [2]uint8{2, 0}, at(counterclockwise, 0, 0))
+	assert.Equal(t, [2]uint8{0, 0}, at(counterclockwise, 0, 2))
+
+	assert.Equal(t, [2]uint8{1, 0}, at(orient(src, 5), 0, 1))
+	assert.Equal(t, [2]uint8{2, 1}, at(orient(src, 7), 0, 0))
+}
@@ -0,0 +1,410 @@
+// Package importer reads the maintenance and expense history of a car from a
+// spreadsheet export.
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+)
+
+const (
+	KindMaintenance = "maintenance"
+	KindExpense     = "expense"
+
+	// the most rows a single import may contain
+	MaxRows = 5000
+)
+
+// The date formats a mapping may use, in the notation users know from spreadsheets
+var dateFormats = map[string]string{
+	"YYYY-MM-DD": "2006-01-02",
+	"DD.MM.YYYY": "02.01.2006",
+	"D.M.YYYY":   "2.1.2006",
+	"DD/MM/YYYY": "02/01/2006",
+	"MM/DD/YYYY": "01/02/2006",
+}
+
+// The fields each kind of record can be imported into, the required ones first
+var fields = map[string][]string{
+	KindMaintenance: {"date", "service_type", "odometer", "cost", "description", "performed_by", "notes"},
+	KindExpense:     {"date", "category", "amount", "currency", "odometer", "description"},
+}
+
+var requiredFields = map[string][]string{
+	KindMaintenance: {"date", "service_type"},
+	KindExpense:     {"date", "category", "amount"},
+}
+
+// Describes how the columns of a CSV file map to the fields of a record
+type Mapping struct {
+	Kind string `json:"kind"`
+	// field name to CSV header, e.g. {"date": "Datum", "cost": "Price"}
+	Columns      map[string]string `json:"columns"`
+	DateFormat   string            `json:"date_format"`
+	Delimiter    string            `json:"delimiter"`
+	DecimalComma bool              `json:"decimal_comma"`
+}
+
+// A problem with one row, Row is the line number in the file
+type RowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+type Result struct {
+	Kind        string                     `json:"kind"`
+	Rows        int                        `json:"rows"`
+	Errors      []RowError                 `json:"errors"`
+	Maintenance []models.MaintenanceRecord `json:"-"`
+	Expenses    []models.Expense           `json:"-"`
+}
+
+func (r Result) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// Checks the mapping and fills in its defaults
+func (m *Mapping) Validate() error {
+	m.Kind = strings.ToLower(strings.TrimSpace(m.Kind))
+	if m.Kind == "" {
+		m.Kind = KindMaintenance
+	}
+
+	known, ok := fields[m.Kind]
+	if !ok {
+		return fmt.Errorf("invalid kind %q, expected %s or %s", m.Kind, KindMaintenance, KindExpense)
+	}
+
+	for field := range m.Columns {
+		if !contains(known, field) {
+			return fmt.Errorf("unknown field %q for %s import", field, m.Kind)
+		}
+	}
+
+	for _, field := range requiredFields[m.Kind] {
+		if m.Columns[field] == "" {
+			return fmt.Errorf("column of field %q is required", field)
+		}
+	}
+
+	if m.DateFormat == "" {
+		m.DateFormat = "YYYY-MM-DD"
+	}
+	if _, ok := dateFormats[m.DateFormat]; !ok {
+		return fmt.Errorf("unsupported date format %q", m.DateFormat)
+	}
+
+	if m.Delimiter == "" {
+		m.Delimiter = ","
+	}
+	if len([]rune(m.Delimiter)) != 1 {
+		return errors.New("delimiter must be a single character")
+	}
+
+	return nil
+}
+
+// Reads and validates every row of the file. history are the odometer readings
+// already recorded for the car, the imported odometer values must fit between
+// them. The returned records are only meant to be stored when the result has no
+// errors.
+func Parse(r io.Reader, mapping Mapping, carId int, history []models.OdometerReading) (Result, error) {
+	result := Result{Kind: mapping.Kind, Errors: []RowError{}}
+
+	reader := csv.NewReader(r)
+	reader.Comma = []rune(mapping.Delimiter)[0]
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return result, errors.New("the file is empty")
+	} else if err != nil {
+		return result, err
+	}
+
+	// spreadsheet exports often start with a byte order mark
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	columns := make(map[string]int)
+	for field, name := range mapping.Columns {
+		index := -1
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), strings.TrimSpace(name)) {
+				index = i
+				break
+			}
+		}
+		if index == -1 {
+			return result, fmt.Errorf("column %q is missing in the file", name)
+		}
+		columns[field] = index
+	}
+
+	var points []odometerPoint
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			result.Errors = append(result.Errors, RowError{Row: parseErr.Line, Message: parseErr.Err.Error()})
+			continue
+		} else if err != nil {
+			return result, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		if isBlank(record) {
+			continue
+		}
+
+		result.Rows++
+		if result.Rows > MaxRows {
+			return result, fmt.Errorf("the file has more than %d rows", MaxRows)
+		}
+
+		row := &row{line: line, record: record, columns: columns, mapping: mapping}
+		switch mapping.Kind {
+		case KindMaintenance:
+			rec := row.maintenance(carId)
+			if row.valid() {
+				result.Maintenance = append(result.Maintenance, rec)
+				points = append(points, odometerPoint{line: line, date: rec.ServiceDate, odometer: rec.Odometer})
+			}
+		case KindExpense:
+			e := row.expense(carId)
+			if row.valid() {
+				result.Expenses = append(result.Expenses, e)
+				if e.Odometer != nil {
+					points = append(points, odometerPoint{line: line, date: e.ExpenseDate, odometer: *e.Odometer})
+				}
+			}
+		}
+		result.Errors = append(result.Errors, row.errors...)
+	}
+
+	result.Errors = append(result.Errors, checkOdometerOrder(points, history)...)
+	sort.SliceStable(result.Errors, func(i, j int) bool {
+		return result.Errors[i].Row < result.Errors[j].Row
+	})
+
+	return result, nil
+}
+
+// Converts one CSV record, collecting every problem instead of stopping at the first one
+type row struct {
+	line    int
+	record  []string
+	columns map[string]int
+	mapping Mapping
+	errors  []RowError
+}
+
+func (r *row) valid() bool {
+	return len(r.errors) == 0
+}
+
+func (r *row) fail(field, message string) {
+	r.errors = append(r.errors, RowError{Row: r.line, Column: r.mapping.Columns[field], Message: message})
+}
+
+func (r *row) text(field string) string {
+	index, ok := r.columns[field]
+	if !ok || index >= len(r.record) {
+		return ""
+	}
+
+	return strings.TrimSpace(r.record[index])
+}
+
+func (r *row) date(field string) time.Time {
+	value := r.text(field)
+	if value == "" {
+		r.fail(field, "date is required")
+		return time.Time{}
+	}
+
+	t, err := time.Parse(dateFormats[r.mapping.DateFormat], value)
+	if err != nil {
+		r.fail(field, fmt.Sprintf("invalid date %q, expected %s", value, r.mapping.DateFormat))
+	}
+
+	return t
+}
+
+// Returns nil for an empty cell
+func (r *row) number(field string) *float64 {
+	value := r.text(field)
+	if value == "" {
+		return nil
+	}
+
+	n, err := parseNumber(value, r.mapping.DecimalComma)
+	if err != nil {
+		r.fail(field, fmt.Sprintf("invalid number %q", value))
+		return nil
+	}
+
+	return &n
+}
+
+func (r *row) odometer(field string) *int {
+	n := r.number(field)
+	if n == nil {
+		return nil
+	}
+
+	if *n != float64(int(*n)) {
+		r.fail(field, "odometer must be a whole number")
+		return nil
+	}
+
+	odometer := int(*n)
+	return &odometer
+}
+
+func (r *row) maintenance(carId int) models.MaintenanceRecord {
+	rec := models.MaintenanceRecord{
+		CarID:       carId,
+		ServiceDate: r.date("date"),
+		ServiceType: r.text("service_type"),
+		Description: r.text("description"),
+		PerformedBy: r.text("performed_by"),
+		Notes:       r.text("notes"),
+	}
+
+	if odometer := r.odometer("odometer"); odometer != nil {
+		rec.Odometer = *odometer
+	}
+	if cost := r.number("cost"); cost != nil {
+		rec.Cost = *cost
+	}
+
+	if r.valid() {
+		if err := rec.Validate(); err != nil {
+			r.fail("", err.Error())
+		}
+	}
+
+	return rec
+}
+
+func (r *row) expense(carId int) models.Expense {
+	e := models.Expense{
+		CarID:       carId,
+		ExpenseDate: r.date("date"),
+		Category:    r.text("category"),
+		Currency:    r.text("currency"),
+		Odometer:    r.odometer("odometer"),
+		Description: r.text("description"),
+	}
+
+	if amount := r.number("amount"); amount != nil {
+		e.Amount = *amount
+	} else if r.text("amount") == "" {
+		r.fail("amount", "amount is required")
+	}
+
+	if r.valid() {
+		if err := e.Validate(); err != nil {
+			r.fail("", err.Error())
+		}
+	}
+
+	return e
+}
+
+type odometerPoint struct {
+	line          int
+	date          time.Time
+	odometer      int
+	isReplacement bool
+}
+
+// Checks that the odometer never decreases over time across the imported rows
+// and the readings already recorded. Rows without an odometer value are skipped.
+func checkOdometerOrder(imported []odometerPoint, history []models.OdometerReading) []RowError {
+	points := make([]odometerPoint, 0, len(imported)+len(history))
+	for _, p := range imported {
+		if p.odometer > 0 {
+			points = append(points, p)
+		}
+	}
+	for _, h := range history {
+		points = append(points, odometerPoint{date: h.ReadingDate, odometer: h.Odometer, isReplacement: h.IsReplacement})
+	}
+
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].date.Before(points[j].date)
+	})
+
+	var errs []RowError
+	for i := 1; i < len(points); i++ {
+		prev, p := points[i-1], points[i]
+		if p.isReplacement || p.odometer >= prev.odometer {
+			continue
+		}
+
+		// blame the imported row, an existing reading is never wrong here
+		blamed, other := p, prev
+		if p.line == 0 {
+			blamed, other = prev, p
+		}
+
+		message := fmt.Sprintf("odometer %d km on %s does not fit %d km", blamed.odometer, blamed.date.Format("2006-01-02"), other.odometer)
+		if other.line == 0 {
+			message += " of the reading recorded on " + other.date.Format("2006-01-02")
+		} else {
+			message += fmt.Sprintf(" on row %d", other.line)
+		}
+		errs = append(errs, RowError{Row: blamed.line, Message: message})
+	}
+
+	return errs
+}
+
+func parseNumber(value string, decimalComma bool) (float64, error) {
+	value = strings.NewReplacer(" ", "", "\u00a0", "").Replace(value)
+	if decimalComma {
+		value = strings.ReplaceAll(value, ".", "")
+		value = strings.ReplaceAll(value, ",", ".")
+	} else {
+		value = strings.ReplaceAll(value, ",", "")
+	}
+
+	return strconv.ParseFloat(value, 64)
+}
+
+func isBlank(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
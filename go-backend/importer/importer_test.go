@@ -0,0 +1,163 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func maintenanceMapping() Mapping {
+	return Mapping{
+		Kind: "maintenance",
+		Columns: map[string]string{
+			"date":         "Date",
+			"service_type": "Service",
+			"odometer":     "Km",
+			"cost":         "Price",
+		},
+		DateFormat:   "DD.MM.YYYY",
+		Delimiter:    ";",
+		DecimalComma: true,
+	}
+}
+
+func TestMappingValidate(t *testing.T) {
+	m := Mapping{Columns: map[string]string{"date": "Date", "service_type": "Service"}}
+	assert.NoError(t, m.Validate())
+	assert.Equal(t, KindMaintenance, m.Kind)
+	assert.Equal(t, "YYYY-MM-DD", m.DateFormat)
+	assert.Equal(t, ",", m.Delimiter)
+
+	badKind := Mapping{Kind: "tires", Columns: map[string]string{"date": "Date"}}
+	assert.ErrorContains(t, badKind.Validate(), `invalid kind "tires"`)
+
+	unknownField := Mapping{Columns: map[string]string{"date": "Date", "service_type": "Service", "color": "Color"}}
+	assert.EqualError(t, unknownField.Validate(), `unknown field "color" for maintenance import`)
+
+	missingAmount := Mapping{Kind: "expense", Columns: map[string]string{"date": "Date", "category": "Category"}}
+	assert.EqualError(t, missingAmount.Validate(), `column of field "amount" is required`)
+
+	badFormat := Mapping{Columns: map[string]string{"date": "Date", "service_type": "Service"}, DateFormat: "YY"}
+	assert.EqualError(t, badFormat.Validate(), `unsupported date format "YY"`)
+}
+
+func TestParse_Maintenance(t *testing.T) {
+	csv := "\ufeffDate;Service;Km;Price;Ignored\n" +
+		"19.06.2021;Oil change;45 000;120,50;x\n" +
+		"\n" +
+		"01.07.2022;Brake pads;60000;1.240,00;\n"
+
+	mapping := maintenanceMapping()
+	assert.NoError(t, mapping.Validate())
+	result, err := Parse(strings.NewReader(csv), mapping, 3, nil)
+
+	assert.NoError(t, err)
+	assert.True(t, result.Valid())
+	assert.Equal(t, 2, result.Rows)
+	assert.Equal(t, []models.MaintenanceRecord{
+		{CarID: 3, ServiceDate: time.Date(2021, 6, 19, 0, 0, 0, 0, time.UTC), ServiceType: "Oil change", Odometer: 45000, Cost: 120.5},
+		{CarID: 3, ServiceDate: time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC), ServiceType: "Brake pads", Odometer: 60000, Cost: 1240},
+	}, result.Maintenance)
+}
+
+func TestParse_ReportsEveryRowError(t *testing.T) {
+	csv := "Date;Service;Km;Price\n" +
+		"2021-06-19;Oil change;45000;120\n" +
+		"19.06.2021;;abc;-5\n" +
+		"20.06.2021;Tires;45000,5;100\n"
+
+	mapping := maintenanceMapping()
+	assert.NoError(t, mapping.Validate())
+	result, err := Parse(strings.NewReader(csv), mapping, 3, nil)
+
+	assert.NoError(t, err)
+	assert.False(t, result.Valid())
+	assert.Equal(t, 3, result.Rows)
+	assert.Equal(t, []RowError{
+		{Row: 2, Column: "Date", Message: `invalid date "2021-06-19", expected DD.MM.YYYY`},
+		{Row: 3, Column: "Km", Message: `invalid number "abc"`},
+		{Row: 4, Column: "Km", Message: "odometer must be a whole number"},
+	}, result.Errors)
+}
+
+func TestParse_ModelValidation(t *testing.T) {
+	csv := "Date;Service;Km;Price\n19.06.2021;;45000;120\n"
+
+	mapping := maintenanceMapping()
+	assert.NoError(t, mapping.Validate())
+	result, err := Parse(strings.NewReader(csv), mapping, 3, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []RowError{{Row: 2, Message: "service type is required"}}, result.Errors)
+}
+
+func TestParse_OdometerOrder(t *testing.T) {
+	csv := "Date;Service;Km;Price\n" +
+		"01.01.2021;Oil change;40000;100\n" +
+		"01.01.2022;Oil change;35000;100\n" +
+		"01.01.2023;Oil change;70000;100\n"
+
+	history := []models.OdometerReading{
+		{ReadingDate: time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC), Odometer: 80000},
+	}
+
+	mapping := maintenanceMapping()
+	assert.NoError(t, mapping.Validate())
+	result, err := Parse(strings.NewReader(csv), mapping, 3, history)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []RowError{
+		{Row: 3, Message: "odometer 35000 km on 2022-01-01 does not fit 40000 km on row 2"},
+		{Row: 4, Message: "odometer 70000 km on 2023-01-01 does not fit 80000 km of the reading recorded on 2022-06-01"},
+	}, result.Errors)
+}
+
+func TestParse_Expenses(t *testing.T) {
+	csv := "date,category,amount,currency,km\n" +
+		"2023-01-15,Insurance,\"1,250.00\",,\n" +
+		"2023-02-01,tolls,12.5,czk,51000\n" +
+		"2023-02-02,snacks,3,,\n"
+
+	mapping := Mapping{Kind: "expense", Columns: map[string]string{
+		"date": "date", "category": "category", "amount": "amount", "currency": "currency", "odometer": "km",
+	}}
+	assert.NoError(t, mapping.Validate())
+	result, err := Parse(strings.NewReader(csv), mapping, 3, nil)
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Expenses, 2)
+	assert.Equal(t, 1250.0, result.Expenses[0].Amount)
+	assert.Equal(t, "insurance", result.Expenses[0].Category)
+	assert.Equal(t, "EUR", result.Expenses[0].Currency)
+	assert.Nil(t, result.Expenses[0].Odometer)
+	assert.Equal(t, "CZK", result.Expenses[1].Currency)
+	assert.Equal(t, 51000, *result.Expenses[1].Odometer)
+	assert.Len(t, result.Errors, 1)
+	assert.Equal(t, 4, result.Errors[0].Row)
+}
+
+func TestParse_MissingColumn(t *testing.T) {
+	mapping := maintenanceMapping()
+	assert.NoError(t, mapping.Validate())
+	_, err := Parse(strings.NewReader("Date;Service;Price\n"), mapping, 3, nil)
+
+	assert.EqualError(t, err, `column "Km" is missing in the file`)
+
+	_, err = Parse(strings.NewReader(""), mapping, 3, nil)
+	assert.EqualError(t, err, "the file is empty")
+}
+
+func TestParse_MalformedRow(t *testing.T) {
+	csv := "Date;Service;Km;Price\n" +
+		"19.06.2021;\"Oil change;45000;120\n"
+
+	mapping := maintenanceMapping()
+	assert.NoError(t, mapping.Validate())
+	result, err := Parse(strings.NewReader(csv), mapping, 3, nil)
+
+	assert.NoError(t, err)
+	assert.False(t, result.Valid())
+}
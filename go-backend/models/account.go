@@ -0,0 +1,469 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var ErrInvalidImport = errors.New("invalid import")
+
+// A car together with everything recorded for it, used by the account export
+type CarHistory struct {
+	Car               Car                 `json:"car"`
+	Maintenance       []MaintenanceRecord `json:"maintenance"`
+	Expenses          []Expense           `json:"expenses"`
+	FuelLogs          []FuelLog           `json:"fuel_logs"`
+	ChargingSessions  []ChargingSession   `json:"charging_sessions"`
+	BatteryHealthLogs []BatteryHealthLog  `json:"battery_health_logs"`
+	TireSets          []TireSet           `json:"tire_sets"`
+	TireEvents        []TireEvent         `json:"tire_events"`
+	TreadMeasurements []TreadMeasurement  `json:"tread_measurements"`
+	Documents         []VehicleDocument   `json:"documents"`
+	Attachments       []Attachment        `json:"attachments"`
+	OdometerReadings  []OdometerReading   `json:"odometer_readings"`
+	ServiceSchedules  []ServiceSchedule   `json:"service_schedules"`
+}
+
+func (m *DBModel) GetCarHistory(car Car) (CarHistory, error) {
+	var err error
+	history := CarHistory{Car: car}
+
+	history.Maintenance, err = m.GetMaintenanceRecordsByCarID(car.ID)
+	if err != nil {
+		return history, err
+	}
+
+	history.Expenses, err = m.GetExpensesByCarID(car.ID, ExpenseFilter{})
+	if err != nil {
+		return history, err
+	}
+
+	history.FuelLogs, err = m.GetFuelLogsByCarID(car.ID, DateRange{})
+	if err != nil {
+		return history, err
+	}
+
+	history.ChargingSessions, err = m.GetChargingSessionsByCarID(car.ID, DateRange{})
+	if err != nil {
+		return history, err
+	}
+
+	history.BatteryHealthLogs, err = m.GetBatteryHealthLogsByCarID(car.ID)
+	if err != nil {
+		return history, err
+	}
+
+	history.TireSets, err = m.GetTireSetsByCarID(car.ID)
+	if err != nil {
+		return history, err
+	}
+
+	history.TireEvents, err = m.GetTireEventsByCarID(car.ID)
+	if err != nil {
+		return history, err
+	}
+
+	history.TreadMeasurements, err = m.GetTreadMeasurementsByCarID(car.ID)
+	if err != nil {
+		return history, err
+	}
+
+	history.Documents, err = m.GetVehicleDocumentsByCarID(car.ID)
+	if err != nil {
+		return history, err
+	}
+
+	attachments, err := m.GetAttachmentsByCarID(car.ID, "", 0)
+	if err != nil {
+		return history, err
+	}
+
+	// attachments of records in the trash are left out together with the records
+	records := history.attachableRecords()
+	for _, a := range attachments {
+		if records[a.RecordType][a.RecordID] {
+			history.Attachments = append(history.Attachments, a)
+		}
+	}
+
+	history.OdometerReadings, err = m.GetOdometerReadingsByCarID(car.ID)
+	if err != nil {
+		return history, err
+	}
+
+	history.ServiceSchedules, err = m.GetServiceSchedulesByCarID(car.ID)
+	if err != nil {
+		return history, err
+	}
+
+	return history, nil
+}
+
+// The ids of the records of the history that can have attachments, by record type
+func (h CarHistory) attachableRecords() map[string]map[int]bool {
+	records := map[string]map[int]bool{
+		AttachmentMaintenance: make(map[int]bool),
+		AttachmentExpense:     make(map[int]bool),
+		AttachmentDocument:    make(map[int]bool),
+	}
+	for _, r := range h.Maintenance {
+		records[AttachmentMaintenance][r.ID] = true
+	}
+	for _, e := range h.Expenses {
+		records[AttachmentExpense][e.ID] = true
+	}
+	for _, d := range h.Documents {
+		records[AttachmentDocument][d.ID] = true
+	}
+
+	return records
+}
+
+// Stores the file of an imported attachment and returns its storage key. The
+// attachment still has its exported id, the car and record ids are those of
+// the import.
+type AttachmentStore func(a Attachment) (string, error)
+
+// Adds the cars of an export to the account of the user in a single
+// transaction. The cars and their records get new ids, the links between the
+// expenses and the maintenance records, those of the tire sets and those of
+// the attachments are kept. Tire events, tread measurements and attachments
+// of a record missing from the export are invalid. The files of the
+// attachments are saved with store and count against the quota of the user.
+// Returns the number of cars, ErrQuotaExceeded, or ErrInvalidImport naming
+// the first invalid record.
+func (m *DBModel) ImportCarHistories(userId int, histories []CarHistory, quotaBytes int64, store AttachmentStore) (int, error) {
+	err := m.validateCarHistories(histories)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var size int64
+	for _, h := range histories {
+		for _, a := range h.Attachments {
+			size += a.SizeBytes
+		}
+	}
+
+	if size > 0 {
+		// the user row is locked the same way as for an upload
+		var id int
+		err = tx.QueryRow(`SELECT id FROM users WHERE id=$1 FOR UPDATE`, userId).Scan(&id)
+		if err == sql.ErrNoRows {
+			return 0, ErrRecordNotFound
+		} else if err != nil {
+			return 0, err
+		}
+
+		var used int64
+		err = tx.QueryRow(userAttachmentUsageQuery, userId).Scan(&used)
+		if err != nil {
+			return 0, err
+		}
+
+		if used+size > quotaBytes {
+			return 0, fmt.Errorf("%w: %d of %d bytes used, the attachments take %d bytes", ErrQuotaExceeded, used, quotaBytes, size)
+		}
+	}
+
+	for _, h := range histories {
+		car := h.Car
+
+		var carId int
+		err = tx.QueryRow(`INSERT INTO users_cars (user_id, brand_id, model_id, year, color, price, image, description, license_plate, vin, variant_id, archived_at, sold_at, sale_price) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id`,
+			userId, car.BrandID, car.ModelID, car.Year, car.Color, car.Price, car.Image, car.Description, car.LicensePlate, car.VIN, car.VariantID, car.ArchivedAt, car.SoldAt, car.SalePrice).Scan(&carId)
+		if err != nil {
+			return 0, err
+		}
+
+		// exported maintenance id to the id of the imported record
+		maintenanceIds := make(map[int]int)
+		for _, rec := range h.Maintenance {
+			var id int
+			err = tx.QueryRow(`INSERT INTO maintenance (car_id, service_date, odometer, service_type, description, cost, performed_by, notes) VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+				carId, rec.ServiceDate, rec.Odometer, rec.ServiceType, rec.Description, rec.Cost, rec.PerformedBy, rec.Notes).Scan(&id)
+			if err != nil {
+				return 0, err
+			}
+			maintenanceIds[rec.ID] = id
+		}
+
+		expenseIds := make(map[int]int)
+		for _, e := range h.Expenses {
+			var maintenanceId *int
+			if e.MaintenanceID != nil {
+				if id, ok := maintenanceIds[*e.MaintenanceID]; ok {
+					maintenanceId = &id
+				}
+			}
+
+			var id int
+			err = tx.QueryRow(`INSERT INTO expenses (car_id, category, amount, currency, expense_date, odometer, maintenance_id, description) VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+				carId, e.Category, e.Amount, e.Currency, e.ExpenseDate, e.Odometer, maintenanceId, e.Description).Scan(&id)
+			if err != nil {
+				return 0, err
+			}
+			expenseIds[e.ID] = id
+		}
+
+		for _, f := range h.FuelLogs {
+			_, err = tx.Exec(`INSERT INTO fuel_logs (car_id, fill_date, odometer, volume, price_per_unit, full_tank, missed_previous, station, notes) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+				carId, f.FillDate, f.Odometer, f.Volume, f.PricePerUnit, f.FullTank, f.MissedPrevious, f.Station, f.Notes)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		for _, s := range h.ChargingSessions {
+			_, err = tx.Exec(`INSERT INTO charging_sessions (car_id, charge_date, odometer, energy_kwh, cost, charger_type, location_type, start_soc, end_soc, notes) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+				carId, s.ChargeDate, s.Odometer, s.EnergyKWh, s.Cost, s.ChargerType, s.LocationType, s.StartSoC, s.EndSoC, s.Notes)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		for _, b := range h.BatteryHealthLogs {
+			_, err = tx.Exec(`INSERT INTO battery_health_logs (car_id, log_date, odometer, capacity_kwh, range_km, notes) VALUES($1, $2, $3, $4, $5, $6)`,
+				carId, b.LogDate, b.Odometer, b.CapacityKWh, b.RangeKm, b.Notes)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		// exported tire set id to the id of the imported set
+		tireSetIds := make(map[int]int)
+		for _, t := range h.TireSets {
+			var id int
+			err = tx.QueryRow(`INSERT INTO tire_sets (car_id, name, season, brand, size, dot_code, purchase_cost, notes) VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+				carId, t.Name, t.Season, t.Brand, t.Size, t.DOTCode, t.PurchaseCost, t.Notes).Scan(&id)
+			if err != nil {
+				return 0, err
+			}
+			tireSetIds[t.ID] = id
+		}
+
+		for _, e := range h.TireEvents {
+			_, err = tx.Exec(`INSERT INTO tire_events (car_id, tire_set_id, event_type, event_date, odometer, notes) VALUES($1, $2, $3, $4, $5, $6)`,
+				carId, tireSetIds[e.TireSetID], e.EventType, e.EventDate, e.Odometer, e.Notes)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		for _, t := range h.TreadMeasurements {
+			_, err = tx.Exec(`INSERT INTO tread_measurements (tire_set_id, measured_on, position, depth_mm, odometer) VALUES($1, $2, $3, $4, $5)`,
+				tireSetIds[t.TireSetID], t.MeasuredOn, t.Position, t.DepthMm, t.Odometer)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		documentIds := make(map[int]int)
+		for _, d := range h.Documents {
+			var id int
+			err = tx.QueryRow(`INSERT INTO vehicle_documents (car_id, document_type, issuer, policy_number, valid_from, valid_until, mileage_limit, notes) VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+				carId, d.DocumentType, d.Issuer, d.PolicyNumber, d.ValidFrom, d.ValidUntil, d.MileageLimit, d.Notes).Scan(&id)
+			if err != nil {
+				return 0, err
+			}
+			documentIds[d.ID] = id
+		}
+
+		recordIds := map[string]map[int]int{
+			AttachmentMaintenance: maintenanceIds,
+			AttachmentExpense:     expenseIds,
+			AttachmentDocument:    documentIds,
+		}
+		for _, a := range h.Attachments {
+			a.UserID = userId
+			a.CarID = carId
+			a.RecordID = recordIds[a.RecordType][a.RecordID]
+
+			a.StorageKey, err = store(a)
+			if err != nil {
+				return 0, err
+			}
+
+			_, err = tx.Exec(`INSERT INTO attachments (user_id, car_id, record_type, record_id, file_name, content_type, size_bytes, storage_key) VALUES($1, $2, $3, $4, $5, $6, $7, $8)`,
+				a.UserID, a.CarID, a.RecordType, a.RecordID, a.FileName, a.ContentType, a.SizeBytes, a.StorageKey)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		for _, r := range h.OdometerReadings {
+			_, err = tx.Exec(`INSERT INTO odometer_readings (car_id, reading_date, odometer, is_replacement, note) VALUES($1, $2, $3, $4, $5)`,
+				carId, r.ReadingDate, r.Odometer, r.IsReplacement, r.Note)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		for _, s := range h.ServiceSchedules {
+			_, err = tx.Exec(`INSERT INTO service_schedules (car_id, service_type, interval_km, interval_months, description, start_date, start_odometer) VALUES($1, $2, $3, $4, $5, $6, $7)`,
+				carId, s.ServiceType, s.IntervalKm, s.IntervalMonths, s.Description, s.StartDate, s.StartOdometer)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return len(histories), nil
+}
+
+// Checks the records of an export the same way as when they are added one by
+// one and that the car catalog still has the maker, model and variant of each
+// car. The records are normalized in place.
+func (m *DBModel) validateCarHistories(histories []CarHistory) error {
+	stmt := `SELECT exists (SELECT 1 FROM car_models WHERE id=$2 AND car_maker_id=$1)
+		AND ($3::int IS NULL OR exists (SELECT 1 FROM car_variants v JOIN car_generations g ON g.id = v.car_generation_id WHERE v.id=$3 AND g.car_model_id=$2))`
+
+	for i := range histories {
+		h := &histories[i]
+
+		invalid := func(record string, id int, err error) error {
+			return fmt.Errorf("%w: car %d, %s %d: %v", ErrInvalidImport, h.Car.ID, record, id, err)
+		}
+
+		err := h.Car.Validate()
+		if err != nil {
+			return fmt.Errorf("%w: car %d: %v", ErrInvalidImport, h.Car.ID, err)
+		}
+
+		var exists bool
+		err = m.DB.QueryRow(stmt, h.Car.BrandID, h.Car.ModelID, h.Car.VariantID).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("%w: car %d: brand, model or variant not found in the catalog", ErrInvalidImport, h.Car.ID)
+		}
+
+		for j := range h.Maintenance {
+			if err := h.Maintenance[j].Validate(); err != nil {
+				return invalid("maintenance record", h.Maintenance[j].ID, err)
+			}
+		}
+		for j := range h.Expenses {
+			if err := h.Expenses[j].Validate(); err != nil {
+				return invalid("expense", h.Expenses[j].ID, err)
+			}
+		}
+		for j := range h.FuelLogs {
+			if err := h.FuelLogs[j].Validate(); err != nil {
+				return invalid("fuel log", h.FuelLogs[j].ID, err)
+			}
+		}
+		for j := range h.ChargingSessions {
+			if err := h.ChargingSessions[j].Validate(); err != nil {
+				return invalid("charging session", h.ChargingSessions[j].ID, err)
+			}
+		}
+		for j := range h.BatteryHealthLogs {
+			if err := h.BatteryHealthLogs[j].Validate(); err != nil {
+				return invalid("battery health log", h.BatteryHealthLogs[j].ID, err)
+			}
+		}
+		for j := range h.TireSets {
+			if err := h.TireSets[j].Validate(); err != nil {
+				return invalid("tire set", h.TireSets[j].ID, err)
+			}
+		}
+		sets := make(map[int]bool)
+		for _, t := range h.TireSets {
+			sets[t.ID] = true
+		}
+		for j := range h.TireEvents {
+			if err := h.TireEvents[j].Validate(); err != nil {
+				return invalid("tire event", h.TireEvents[j].ID, err)
+			}
+			if !sets[h.TireEvents[j].TireSetID] {
+				return invalid("tire event", h.TireEvents[j].ID, fmt.Errorf("unknown tire set %d", h.TireEvents[j].TireSetID))
+			}
+		}
+		for j := range h.TreadMeasurements {
+			if err := h.TreadMeasurements[j].Validate(); err != nil {
+				return invalid("tread measurement", h.TreadMeasurements[j].ID, err)
+			}
+			if !sets[h.TreadMeasurements[j].TireSetID] {
+				return invalid("tread measurement", h.TreadMeasurements[j].ID, fmt.Errorf("unknown tire set %d", h.TreadMeasurements[j].TireSetID))
+			}
+		}
+
+		// the events of each set have to follow each other the same way as
+		// when they are added one by one
+		events := make([]TireEvent, len(h.TireEvents))
+		copy(events, h.TireEvents)
+		sort.SliceStable(events, func(i, j int) bool {
+			if events[i].EventDate.Equal(events[j].EventDate) {
+				return events[i].ID < events[j].ID
+			}
+			return events[i].EventDate.Before(events[j].EventDate)
+		})
+		last := make(map[int]TireEvent)
+		for _, e := range events {
+			var previous *TireEvent
+			if l, ok := last[e.TireSetID]; ok {
+				previous = &l
+			}
+			if err := CheckTireEvent(e, previous); err != nil {
+				return invalid("tire event", e.ID, err)
+			}
+			last[e.TireSetID] = e
+		}
+		for j := range h.Documents {
+			if err := h.Documents[j].Validate(); err != nil {
+				return invalid("document", h.Documents[j].ID, err)
+			}
+		}
+		records := h.attachableRecords()
+		for j := range h.Attachments {
+			a := &h.Attachments[j]
+			if err := a.Validate(); err != nil {
+				return invalid("attachment", a.ID, err)
+			}
+			if !records[a.RecordType][a.RecordID] {
+				return invalid("attachment", a.ID, fmt.Errorf("unknown %s %d", a.RecordType, a.RecordID))
+			}
+		}
+
+		for j := range h.OdometerReadings {
+			if err := h.OdometerReadings[j].Validate(); err != nil {
+				return invalid("odometer reading", h.OdometerReadings[j].ID, err)
+			}
+		}
+
+		readings := make([]OdometerReading, len(h.OdometerReadings))
+		copy(readings, h.OdometerReadings)
+		sort.SliceStable(readings, func(i, j int) bool {
+			if readings[i].ReadingDate.Equal(readings[j].ReadingDate) {
+				return readings[i].ID < readings[j].ID
+			}
+			return readings[i].ReadingDate.Before(readings[j].ReadingDate)
+		})
+		for j := 1; j < len(readings); j++ {
+			if err := CheckOdometerOrder(readings[j], &readings[j-1], nil); err != nil {
+				return invalid("odometer reading", readings[j].ID, err)
+			}
+		}
+		for j := range h.ServiceSchedules {
+			if err := h.ServiceSchedules[j].Validate(); err != nil {
+				return invalid("service schedule", h.ServiceSchedules[j].ID, err)
+			}
+		}
+	}
+
+	return nil
+}
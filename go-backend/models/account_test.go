@@ -0,0 +1,296 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportCarHistories_RemapsIds(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	rec := testMaintenanceRecord()
+	rec.ID = 11
+	e := testExpense()
+	maintenanceId := 11
+	e.MaintenanceID = &maintenanceId
+	history := models.CarHistory{
+		Car:         models.Car{ID: 3, UserId: 9, BrandID: 1, ModelID: 2},
+		Maintenance: []models.MaintenanceRecord{rec},
+		Expenses:    []models.Expense{e},
+	}
+
+	mock.ExpectQuery(`SELECT exists \(SELECT 1 FROM car_models WHERE id=\$2 AND car_maker_id=\$1\)`).WithArgs(1, 2, nil).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO users_cars`).WithArgs(5, 1, 2, 0, "", 0, "", "", "", "", nil, nil, nil, nil).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(40))
+	mock.ExpectQuery(`INSERT INTO maintenance`).WithArgs(40, rec.ServiceDate, rec.Odometer, rec.ServiceType, rec.Description, rec.Cost, rec.PerformedBy, rec.Notes).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(70))
+	newMaintenanceId := 70
+	mock.ExpectQuery(`INSERT INTO expenses`).WithArgs(40, e.Category, e.Amount, e.Currency, e.ExpenseDate, e.Odometer, &newMaintenanceId, e.Description).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(80))
+	mock.ExpectCommit()
+
+	modelsDB := models.NewModels(db)
+	imported, err := modelsDB.DB.ImportCarHistories(5, []models.CarHistory{history}, 1000, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, imported)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportCarHistories_RollsBack(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT exists`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO users_cars`).WillReturnError(errors.New("mocked error"))
+	mock.ExpectRollback()
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.ImportCarHistories(5, []models.CarHistory{{Car: models.Car{BrandID: 1, ModelID: 2}}}, 1000, nil)
+
+	assert.EqualError(t, err, "mocked error")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportCarHistories_InvalidRecord(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	e := testExpense()
+	e.ID = 12
+	e.Amount = -10
+	history := models.CarHistory{Car: models.Car{ID: 3, BrandID: 1, ModelID: 2}, Expenses: []models.Expense{e}}
+
+	mock.ExpectQuery(`SELECT exists`).WithArgs(1, 2, nil).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.ImportCarHistories(5, []models.CarHistory{history}, 1000, nil)
+
+	assert.ErrorIs(t, err, models.ErrInvalidImport)
+	assert.EqualError(t, err, "invalid import: car 3, expense 12: amount must not be negative")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportCarHistories_UnknownCatalogEntry(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	variantId := 8
+	mock.ExpectQuery(`SELECT exists`).WithArgs(1, 2, &variantId).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.ImportCarHistories(5, []models.CarHistory{{Car: models.Car{ID: 3, BrandID: 1, ModelID: 2, VariantID: &variantId}}}, 1000, nil)
+
+	assert.ErrorIs(t, err, models.ErrInvalidImport)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetCarHistory_Error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM maintenance WHERE car_id=`).WithArgs(3).WillReturnRows(sqlmock.NewRows(maintenanceColumns))
+	mock.ExpectQuery(`FROM expenses WHERE car_id=`).WithArgs(3).WillReturnError(errors.New("mocked error"))
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.GetCarHistory(models.Car{ID: 3})
+
+	assert.EqualError(t, err, "mocked error")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportCarHistories_UnknownTireSet(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	history := models.CarHistory{
+		Car:        models.Car{ID: 3, BrandID: 1, ModelID: 2},
+		TireSets:   []models.TireSet{{ID: 4, Name: "Winter", Season: "winter"}},
+		TireEvents: []models.TireEvent{{ID: 6, TireSetID: 5, EventType: models.TireMount, EventDate: time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)}},
+	}
+
+	mock.ExpectQuery(`SELECT exists`).WithArgs(1, 2, nil).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.ImportCarHistories(5, []models.CarHistory{history}, 1000, nil)
+
+	assert.ErrorIs(t, err, models.ErrInvalidImport)
+	assert.EqualError(t, err, "invalid import: car 3, tire event 6: unknown tire set 5")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportCarHistories_TireEventsOutOfOrder(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	// listed out of order, the second mount in time comes before any unmount
+	history := models.CarHistory{
+		Car:      models.Car{ID: 3, BrandID: 1, ModelID: 2},
+		TireSets: []models.TireSet{{ID: 4, Name: "Winter", Season: "winter"}},
+		TireEvents: []models.TireEvent{
+			{ID: 8, TireSetID: 4, EventType: models.TireUnmount, EventDate: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), Odometer: 15000},
+			{ID: 6, TireSetID: 4, EventType: models.TireMount, EventDate: time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC), Odometer: 10000},
+			{ID: 7, TireSetID: 4, EventType: models.TireMount, EventDate: time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), Odometer: 12000},
+		},
+	}
+
+	mock.ExpectQuery(`SELECT exists`).WithArgs(1, 2, nil).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.ImportCarHistories(5, []models.CarHistory{history}, 1000, nil)
+
+	assert.ErrorIs(t, err, models.ErrInvalidImport)
+	assert.EqualError(t, err, "invalid import: car 3, tire event 7: invalid tire event: the tire set is already mounted")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportCarHistories_OdometerDecrease(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	history := models.CarHistory{
+		Car: models.Car{ID: 3, BrandID: 1, ModelID: 2},
+		OdometerReadings: []models.OdometerReading{
+			{ID: 9, ReadingDate: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), Odometer: 18000},
+			{ID: 10, ReadingDate: time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC), Odometer: 21000},
+			{ID: 11, ReadingDate: time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), Odometer: 20000},
+			{ID: 12, ReadingDate: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Odometer: 1000, IsReplacement: true},
+		},
+	}
+
+	mock.ExpectQuery(`SELECT exists`).WithArgs(1, 2, nil).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.ImportCarHistories(5, []models.CarHistory{history}, 1000, nil)
+
+	assert.ErrorIs(t, err, models.ErrInvalidImport)
+	assert.ErrorContains(t, err, "car 3, odometer reading 11: odometer reading is out of order: 20000 km is lower than 21000 km")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportCarHistories_Attachments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	e := testExpense()
+	e.ID = 12
+	history := models.CarHistory{
+		Car:         models.Car{ID: 3, BrandID: 1, ModelID: 2},
+		Expenses:    []models.Expense{e},
+		Attachments: []models.Attachment{{ID: 14, UserID: 9, CarID: 3, RecordType: models.AttachmentExpense, RecordID: 12, FileName: "invoice.pdf", ContentType: "application/pdf", SizeBytes: 300}},
+	}
+
+	mock.ExpectQuery(`SELECT exists`).WithArgs(1, 2, nil).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM users WHERE id=\$1 FOR UPDATE`).WithArgs(5).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectQuery(`FROM attachments a\s+LEFT JOIN users_cars c ON c.id = a.car_id WHERE a.user_id=\$1 AND c.organization_id IS NULL`).WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(700))
+	mock.ExpectQuery(`INSERT INTO users_cars`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(40))
+	mock.ExpectQuery(`INSERT INTO expenses`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(80))
+	mock.ExpectExec(`INSERT INTO attachments`).WithArgs(5, 40, "expense", 80, "invoice.pdf", "application/pdf", int64(300), "attachments/5/40/abc").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	var stored []models.Attachment
+	store := func(a models.Attachment) (string, error) {
+		stored = append(stored, a)
+		return "attachments/5/40/abc", nil
+	}
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.ImportCarHistories(5, []models.CarHistory{history}, 1000, store)
+
+	assert.NoError(t, err)
+	if assert.Len(t, stored, 1) {
+		assert.Equal(t, 14, stored[0].ID)
+		assert.Equal(t, 5, stored[0].UserID)
+		assert.Equal(t, 40, stored[0].CarID)
+		assert.Equal(t, 80, stored[0].RecordID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportCarHistories_AttachmentsOverQuota(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	e := testExpense()
+	e.ID = 12
+	history := models.CarHistory{
+		Car:         models.Car{ID: 3, BrandID: 1, ModelID: 2},
+		Expenses:    []models.Expense{e},
+		Attachments: []models.Attachment{{ID: 14, RecordType: models.AttachmentExpense, RecordID: 12, SizeBytes: 301}},
+	}
+
+	mock.ExpectQuery(`SELECT exists`).WithArgs(1, 2, nil).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM users WHERE id=\$1 FOR UPDATE`).WithArgs(5).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectQuery(`FROM attachments a`).WithArgs(5).WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(700))
+	mock.ExpectRollback()
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.ImportCarHistories(5, []models.CarHistory{history}, 1000, nil)
+
+	assert.ErrorIs(t, err, models.ErrQuotaExceeded)
+	assert.EqualError(t, err, "storage quota exceeded: 700 of 1000 bytes used, the attachments take 301 bytes")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportCarHistories_AttachmentOfUnknownRecord(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	history := models.CarHistory{
+		Car:         models.Car{ID: 3, BrandID: 1, ModelID: 2},
+		Attachments: []models.Attachment{{ID: 14, RecordType: models.AttachmentDocument, RecordID: 12, SizeBytes: 300}},
+	}
+
+	mock.ExpectQuery(`SELECT exists`).WithArgs(1, 2, nil).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.ImportCarHistories(5, []models.CarHistory{history}, 1000, nil)
+
+	assert.ErrorIs(t, err, models.ErrInvalidImport)
+	assert.EqualError(t, err, "invalid import: car 3, attachment 14: unknown document 12")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
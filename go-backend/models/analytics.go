@@ -0,0 +1,294 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+	PeriodYear  = "year"
+)
+
+// Every cost of every car: expenses, fuel-ups, charging sessions and the
+// maintenance records not already linked from an expense
+const costRecordsQuery = `SELECT car_id, expense_date AS cost_date, category, amount FROM expenses WHERE deleted_at IS NULL
+		UNION ALL
+		SELECT m.car_id, m.service_date, 'maintenance', m.cost FROM maintenance m
+			WHERE m.cost > 0 AND m.deleted_at IS NULL
+				AND NOT EXISTS (SELECT 1 FROM expenses e WHERE e.maintenance_id = m.id AND e.deleted_at IS NULL)
+		UNION ALL
+		SELECT car_id, fill_date, 'fuel', volume * price_per_unit FROM fuel_logs
+		UNION ALL
+		SELECT car_id, charge_date, 'charging', cost FROM charging_sessions`
+
+// All costs of the user's cars limited to the filter. Amounts are summed as
+// recorded, the totals assume the user records costs in one currency. Fleet
+// cars of an organization only count when the filter asks for the car.
+// $1 user id, $2 car id or NULL, $3 from or NULL, $4 to or NULL
+const analyticsCostsQuery = `WITH costs AS (
+		` + costRecordsQuery + `
+	), scoped AS (
+		SELECT costs.car_id, costs.cost_date, costs.category, costs.amount FROM costs
+		JOIN users_cars c ON c.id = costs.car_id
+		WHERE c.user_id=$1 AND c.deleted_at IS NULL AND (($2::int IS NULL AND c.organization_id IS NULL) OR costs.car_id=$2)
+			AND ($3::date IS NULL OR costs.cost_date >= $3) AND ($4::date IS NULL OR costs.cost_date <= $4)
+	)`
+
+// Distance driven by each car within the date range, from every record with an odometer value
+const analyticsDistanceQuery = `, distances AS (
+		SELECT car_id, MAX(odometer) - MIN(odometer) AS distance FROM (
+			SELECT car_id, reading_date AS day, odometer FROM odometer_readings
+			UNION ALL
+			SELECT car_id, service_date, odometer FROM maintenance WHERE odometer > 0 AND deleted_at IS NULL
+			UNION ALL
+			SELECT car_id, fill_date, odometer FROM fuel_logs
+			UNION ALL
+			SELECT car_id, charge_date, odometer FROM charging_sessions
+			UNION ALL
+			SELECT car_id, event_date, odometer FROM tire_events WHERE odometer > 0
+			UNION ALL
+			SELECT car_id, expense_date, odometer FROM expenses WHERE odometer IS NOT NULL AND deleted_at IS NULL
+		) o WHERE ($3::date IS NULL OR day >= $3) AND ($4::date IS NULL OR day <= $4)
+		GROUP BY car_id
+	)`
+
+type AnalyticsFilter struct {
+	UserID int
+	// nil for all cars of the user
+	CarID  *int
+	Period string
+	Dates  DateRange
+}
+
+// Total cost of a period, CarID is nil for the total across all cars
+type CostBucket struct {
+	Period time.Time `json:"period"`
+	CarID  *int      `json:"car_id"`
+	Total  float64   `json:"total"`
+}
+
+type CategoryTotal struct {
+	Category string  `json:"category"`
+	Total    float64 `json:"total"`
+	Count    int     `json:"count"`
+}
+
+// CarID is nil for the total across all cars
+type DistanceCost struct {
+	CarID     *int     `json:"car_id"`
+	Total     float64  `json:"total"`
+	Distance  int      `json:"distance"`
+	CostPerKm *float64 `json:"cost_per_km"`
+}
+
+// Yearly total compared with the previous year, the change is nil for the first year
+type YearTotal struct {
+	Year          int      `json:"year"`
+	Total         float64  `json:"total"`
+	Change        *float64 `json:"change"`
+	ChangePercent *float64 `json:"change_percent"`
+}
+
+type Analytics struct {
+	Period       string          `json:"period"`
+	Buckets      []CostBucket    `json:"buckets"`
+	Categories   []CategoryTotal `json:"categories"`
+	CostPerKm    []DistanceCost  `json:"cost_per_km"`
+	YearOverYear []YearTotal     `json:"year_over_year"`
+}
+
+func IsAnalyticsPeriod(period string) bool {
+	return period == PeriodWeek || period == PeriodMonth || period == PeriodYear
+}
+
+func (f AnalyticsFilter) args() []interface{} {
+	var carId, from, to interface{}
+	if f.CarID != nil {
+		carId = *f.CarID
+	}
+	if !f.Dates.From.IsZero() {
+		from = f.Dates.From
+	}
+	if !f.Dates.To.IsZero() {
+		to = f.Dates.To
+	}
+
+	return []interface{}{f.UserID, carId, from, to}
+}
+
+// Returns the costs grouped by period, per car and across all cars
+func (m *DBModel) GetCostBuckets(filter AnalyticsFilter) ([]CostBucket, error) {
+	if !IsAnalyticsPeriod(filter.Period) {
+		return nil, fmt.Errorf("invalid period %q", filter.Period)
+	}
+
+	stmt := analyticsCostsQuery + `
+		SELECT bucket, car_id, ROUND(SUM(amount), 2) FROM (
+			SELECT date_trunc($5, cost_date::timestamp)::date AS bucket, car_id, amount FROM scoped
+		) b
+		GROUP BY GROUPING SETS ((bucket, car_id), (bucket))
+		ORDER BY bucket, car_id NULLS LAST`
+
+	rows, err := m.DB.Query(stmt, append(filter.args(), filter.Period)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := []CostBucket{}
+
+	for rows.Next() {
+		var b CostBucket
+
+		err := rows.Scan(&b.Period, &b.CarID, &b.Total)
+		if err != nil {
+			return nil, err
+		}
+
+		buckets = append(buckets, b)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return buckets, nil
+}
+
+// Returns the costs per category, the most expensive category first
+func (m *DBModel) GetCostsByCategory(filter AnalyticsFilter) ([]CategoryTotal, error) {
+	stmt := analyticsCostsQuery + `
+		SELECT category, ROUND(SUM(amount), 2) AS total, COUNT(*) FROM scoped
+		GROUP BY category ORDER BY total DESC, category`
+
+	rows, err := m.DB.Query(stmt, filter.args()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []CategoryTotal{}
+
+	for rows.Next() {
+		var c CategoryTotal
+
+		err := rows.Scan(&c.Category, &c.Total, &c.Count)
+		if err != nil {
+			return nil, err
+		}
+
+		categories = append(categories, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+// Returns the cost per km of each car and of all cars together, using the
+// distance driven within the date range
+func (m *DBModel) GetCostPerKm(filter AnalyticsFilter) ([]DistanceCost, error) {
+	stmt := analyticsCostsQuery + analyticsDistanceQuery + `
+		SELECT t.car_id, ROUND(SUM(t.total), 2), COALESCE(SUM(d.distance), 0)
+		FROM (SELECT car_id, SUM(amount) AS total FROM scoped GROUP BY car_id) t
+		LEFT JOIN distances d ON d.car_id = t.car_id
+		GROUP BY ROLLUP (t.car_id) ORDER BY t.car_id NULLS LAST`
+
+	rows, err := m.DB.Query(stmt, filter.args()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	costs := []DistanceCost{}
+
+	for rows.Next() {
+		var c DistanceCost
+
+		err := rows.Scan(&c.CarID, &c.Total, &c.Distance)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.Distance > 0 {
+			costPerKm := round(c.Total/float64(c.Distance), 3)
+			c.CostPerKm = &costPerKm
+		}
+
+		costs = append(costs, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return costs, nil
+}
+
+// Returns the yearly totals, each compared with the year before
+func (m *DBModel) GetYearOverYear(filter AnalyticsFilter) ([]YearTotal, error) {
+	stmt := analyticsCostsQuery + `
+		SELECT year, total, total - LAG(total) OVER w,
+			ROUND(100 * (total - LAG(total) OVER w) / NULLIF(LAG(total) OVER w, 0), 2)
+		FROM (
+			SELECT EXTRACT(YEAR FROM cost_date)::int AS year, ROUND(SUM(amount), 2) AS total
+			FROM scoped GROUP BY year
+		) y
+		WINDOW w AS (ORDER BY year) ORDER BY year`
+
+	rows, err := m.DB.Query(stmt, filter.args()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	years := []YearTotal{}
+
+	for rows.Next() {
+		var y YearTotal
+
+		err := rows.Scan(&y.Year, &y.Total, &y.Change, &y.ChangePercent)
+		if err != nil {
+			return nil, err
+		}
+
+		years = append(years, y)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return years, nil
+}
+
+func (m *DBModel) GetAnalytics(filter AnalyticsFilter) (Analytics, error) {
+	var err error
+	analytics := Analytics{Period: filter.Period}
+
+	analytics.Buckets, err = m.GetCostBuckets(filter)
+	if err != nil {
+		return analytics, err
+	}
+
+	analytics.Categories, err = m.GetCostsByCategory(filter)
+	if err != nil {
+		return analytics, err
+	}
+
+	analytics.CostPerKm, err = m.GetCostPerKm(filter)
+	if err != nil {
+		return analytics, err
+	}
+
+	analytics.YearOverYear, err = m.GetYearOverYear(filter)
+	if err != nil {
+		return analytics, err
+	}
+
+	return analytics, nil
+}
@@ -0,0 +1,153 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCostBuckets_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	may := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	june := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"bucket", "car_id", "total"}).
+		AddRow(may, 1, 120.5).
+		AddRow(may, 2, 30.0).
+		AddRow(may, nil, 150.5).
+		AddRow(june, 1, 60.0).
+		AddRow(june, nil, 60.0)
+
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`WITH costs AS (.+) GROUP BY GROUPING SETS \(\(bucket, car_id\), \(bucket\)\)`).
+		WithArgs(5, nil, from, nil, "month").
+		WillReturnRows(rows)
+
+	modelsDB := models.NewModels(db)
+	buckets, err := modelsDB.DB.GetCostBuckets(models.AnalyticsFilter{UserID: 5, Period: "month", Dates: models.DateRange{From: from}})
+
+	assert.NoError(t, err)
+	assert.Len(t, buckets, 5)
+	assert.Equal(t, 1, *buckets[0].CarID)
+	assert.Nil(t, buckets[2].CarID)
+	assert.Equal(t, 150.5, buckets[2].Total)
+	assert.Equal(t, june, buckets[3].Period)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetCostBuckets_InvalidPeriod(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.GetCostBuckets(models.AnalyticsFilter{UserID: 5, Period: "day"})
+
+	assert.EqualError(t, err, `invalid period "day"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetCostsByCategory_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"category", "total", "count"}).
+		AddRow("fuel", 540.2, 9).
+		AddRow("maintenance", 320.0, 2)
+
+	carId := 1
+	mock.ExpectQuery(`WITH costs AS (.+) SELECT category, ROUND\(SUM\(amount\), 2\) AS total, COUNT\(\*\) FROM scoped GROUP BY category`).
+		WithArgs(5, 1, nil, nil).
+		WillReturnRows(rows)
+
+	modelsDB := models.NewModels(db)
+	categories, err := modelsDB.DB.GetCostsByCategory(models.AnalyticsFilter{UserID: 5, CarID: &carId, Period: "month"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.CategoryTotal{{Category: "fuel", Total: 540.2, Count: 9}, {Category: "maintenance", Total: 320, Count: 2}}, categories)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetCostPerKm_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"car_id", "total", "distance"}).
+		AddRow(1, 860.0, 10000).
+		AddRow(2, 40.0, 0).
+		AddRow(nil, 900.0, 10000)
+
+	mock.ExpectQuery(`WITH costs AS (.+), distances AS (.+) GROUP BY ROLLUP \(t.car_id\)`).
+		WithArgs(5, nil, nil, nil).
+		WillReturnRows(rows)
+
+	modelsDB := models.NewModels(db)
+	costs, err := modelsDB.DB.GetCostPerKm(models.AnalyticsFilter{UserID: 5, Period: "month"})
+
+	assert.NoError(t, err)
+	assert.Len(t, costs, 3)
+	assert.Equal(t, 0.086, *costs[0].CostPerKm)
+	assert.Nil(t, costs[1].CostPerKm)
+	assert.Nil(t, costs[2].CarID)
+	assert.Equal(t, 0.09, *costs[2].CostPerKm)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetYearOverYear_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"year", "total", "change", "change_percent"}).
+		AddRow(2022, 1000.0, nil, nil).
+		AddRow(2023, 1250.0, 250.0, 25.0)
+
+	mock.ExpectQuery(`WITH costs AS (.+) LAG\(total\) OVER w(.+) WINDOW w AS \(ORDER BY year\)`).
+		WithArgs(5, nil, nil, nil).
+		WillReturnRows(rows)
+
+	modelsDB := models.NewModels(db)
+	years, err := modelsDB.DB.GetYearOverYear(models.AnalyticsFilter{UserID: 5, Period: "year"})
+
+	assert.NoError(t, err)
+	assert.Len(t, years, 2)
+	assert.Nil(t, years[0].Change)
+	assert.Equal(t, 250.0, *years[1].Change)
+	assert.Equal(t, 25.0, *years[1].ChangePercent)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetAnalytics_Error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`GROUPING SETS`).WillReturnRows(sqlmock.NewRows([]string{"bucket", "car_id", "total"}))
+	mock.ExpectQuery(`GROUP BY category`).WillReturnError(errors.New("mocked error"))
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.GetAnalytics(models.AnalyticsFilter{UserID: 5, Period: "week"})
+
+	assert.EqualError(t, err, "mocked error")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
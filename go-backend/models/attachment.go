@@ -0,0 +1,268 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Kinds of records files can be attached to
+const (
+	AttachmentMaintenance = "maintenance"
+	AttachmentExpense     = "expense"
+	AttachmentDocument    = "document"
+)
+
+var AttachmentRecordTypes = []string{AttachmentMaintenance, AttachmentExpense, AttachmentDocument}
+
+// returned when an upload would take the owner of the car, or the organization
+// of a fleet car, over the storage quota
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+// A file such as an invoice, a photo or a scanned policy attached to a
+// maintenance record, an expense or a vehicle document. The content lives in
+// the blob storage under StorageKey, the quota is counted against the owner
+// of the car, or against the organization for a fleet car.
+type Attachment struct {
+	ID          int    `json:"id"`
+	UserID      int    `json:"-"`
+	CarID       int    `json:"car_id"`
+	RecordType  string `json:"record_type"`
+	RecordID    int    `json:"record_id"`
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	SizeBytes   int64  `json:"size_bytes"`
+	StorageKey  string `json:"-"`
+	CreatedAt   string `json:"created_at"`
+}
+
+func IsAttachmentRecordType(recordType string) bool {
+	for _, t := range AttachmentRecordTypes {
+		if t == recordType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Checks the record the file is attached to
+func (a *Attachment) Validate() error {
+	a.RecordType = strings.ToLower(strings.TrimSpace(a.RecordType))
+
+	if !IsAttachmentRecordType(a.RecordType) {
+		return fmt.Errorf("record type must be one of %s", strings.Join(AttachmentRecordTypes, ", "))
+	}
+
+	if a.RecordID <= 0 {
+		return errors.New("record id is required")
+	}
+
+	return nil
+}
+
+// Checks the record an attachment refers to belongs to the car and is not in the trash
+func (m *DBModel) AttachmentRecordExists(carId int, recordType string, recordId int) (bool, error) {
+	var err error
+
+	switch recordType {
+	case AttachmentMaintenance:
+		_, err = m.GetMaintenanceRecordByID(carId, recordId)
+	case AttachmentExpense:
+		_, err = m.GetExpenseByID(carId, recordId)
+	case AttachmentDocument:
+		_, err = m.GetVehicleDocumentByID(carId, recordId)
+	default:
+		return false, nil
+	}
+
+	if errors.Is(err, ErrRecordNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// attachments charged to a user, those of fleet cars count against the
+// organization instead
+const userAttachmentUsageQuery = `SELECT COALESCE(SUM(a.size_bytes), 0) FROM attachments a
+	LEFT JOIN users_cars c ON c.id = a.car_id WHERE a.user_id=$1 AND c.organization_id IS NULL`
+
+// attachments of the fleet cars of an organization
+const organizationAttachmentUsageQuery = `SELECT COALESCE(SUM(a.size_bytes), 0) FROM attachments a
+	JOIN users_cars c ON c.id = a.car_id WHERE c.organization_id=$1`
+
+// Returns how many bytes the attachments of the personal cars of a user take up
+func (m *DBModel) GetAttachmentUsage(userId int) (int64, error) {
+	var used int64
+	err := m.DB.QueryRow(userAttachmentUsageQuery, userId).Scan(&used)
+	if err != nil {
+		return 0, err
+	}
+
+	return used, nil
+}
+
+// Inserts the attachment unless it takes the owner of the car over
+// quotaBytes, or the organization when the car belongs to one. The user or the
+// organization row is locked so that concurrent uploads cannot both slip
+// under the quota.
+func (m *DBModel) InsertAttachment(a Attachment, quotaBytes int64) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var orgId *int
+	err = tx.QueryRow(`SELECT organization_id FROM users_cars WHERE id=$1`, a.CarID).Scan(&orgId)
+	if err == sql.ErrNoRows {
+		return 0, ErrRecordNotFound
+	} else if err != nil {
+		return 0, err
+	}
+
+	var used int64
+	if orgId != nil {
+		err = lockOrganization(tx, *orgId)
+		if err != nil {
+			return 0, err
+		}
+
+		err = tx.QueryRow(organizationAttachmentUsageQuery, *orgId).Scan(&used)
+		if err != nil {
+			return 0, err
+		}
+	} else {
+		var userId int
+		err = tx.QueryRow(`SELECT id FROM users WHERE id=$1 FOR UPDATE`, a.UserID).Scan(&userId)
+		if err == sql.ErrNoRows {
+			return 0, ErrRecordNotFound
+		} else if err != nil {
+			return 0, err
+		}
+
+		err = tx.QueryRow(userAttachmentUsageQuery, a.UserID).Scan(&used)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if used+a.SizeBytes > quotaBytes {
+		return 0, fmt.Errorf("%w: %d of %d bytes used", ErrQuotaExceeded, used, quotaBytes)
+	}
+
+	var id int
+	err = tx.QueryRow(`INSERT INTO attachments (user_id, car_id, record_type, record_id, file_name, content_type, size_bytes, storage_key) VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+		a.UserID, a.CarID, a.RecordType, a.RecordID, a.FileName, a.ContentType, a.SizeBytes, a.StorageKey).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (m *DBModel) GetAttachmentByID(carId, attachmentId int) (Attachment, error) {
+	var a Attachment
+	stmt := `SELECT id, user_id, car_id, record_type, record_id, file_name, content_type, size_bytes, storage_key, created_at FROM attachments WHERE id=$1 AND car_id=$2`
+
+	row := m.DB.QueryRow(stmt, attachmentId, carId)
+	err := row.Scan(&a.ID, &a.UserID, &a.CarID, &a.RecordType, &a.RecordID, &a.FileName, &a.ContentType, &a.SizeBytes, &a.StorageKey, &a.CreatedAt)
+	if err == sql.ErrNoRows {
+		return a, ErrRecordNotFound
+	} else if err != nil {
+		return a, err
+	}
+
+	return a, nil
+}
+
+// Returns the attachments of a car, optionally only those of one record, the newest first
+func (m *DBModel) GetAttachmentsByCarID(carId int, recordType string, recordId int) ([]Attachment, error) {
+	stmt := `SELECT id, user_id, car_id, record_type, record_id, file_name, content_type, size_bytes, storage_key, created_at FROM attachments WHERE car_id=$1`
+	args := []interface{}{carId}
+
+	if recordType != "" {
+		args = append(args, recordType)
+		stmt += fmt.Sprintf(" AND record_type=$%d", len(args))
+	}
+
+	if recordId > 0 {
+		args = append(args, recordId)
+		stmt += fmt.Sprintf(" AND record_id=$%d", len(args))
+	}
+
+	stmt += " ORDER BY created_at DESC, id DESC"
+
+	return m.queryAttachments(context.Background(), stmt, args...)
+}
+
+// Returns attachments whose car or record no longer exists, their files can be
+// deleted from the blob storage. Records in the trash keep their attachments
+// until they are purged.
+func (m *DBModel) GetOrphanedAttachments(ctx context.Context, limit int) ([]Attachment, error) {
+	stmt := `SELECT a.id, a.user_id, a.car_id, a.record_type, a.record_id, a.file_name, a.content_type, a.size_bytes, a.storage_key, a.created_at FROM attachments a
+		WHERE a.car_id IS NULL
+			OR (a.record_type = 'maintenance' AND NOT EXISTS (SELECT 1 FROM maintenance m WHERE m.id = a.record_id AND m.car_id = a.car_id))
+			OR (a.record_type = 'expense' AND NOT EXISTS (SELECT 1 FROM expenses e WHERE e.id = a.record_id AND e.car_id = a.car_id))
+			OR (a.record_type = 'document' AND NOT EXISTS (SELECT 1 FROM vehicle_documents d WHERE d.id = a.record_id AND d.car_id = a.car_id))
+		ORDER BY a.id LIMIT $1`
+
+	return m.queryAttachments(ctx, stmt, limit)
+}
+
+func (m *DBModel) queryAttachments(ctx context.Context, stmt string, args ...interface{}) ([]Attachment, error) {
+	rows, err := m.DB.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []Attachment
+
+	for rows.Next() {
+		var a Attachment
+		var carId sql.NullInt64
+
+		err := rows.Scan(&a.ID, &a.UserID, &carId, &a.RecordType, &a.RecordID, &a.FileName, &a.ContentType, &a.SizeBytes, &a.StorageKey, &a.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		a.CarID = int(carId.Int64)
+
+		attachments = append(attachments, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+func (m *DBModel) RemoveAttachment(carId, attachmentId int) error {
+	stmt := `DELETE FROM attachments WHERE id=$1 AND car_id=$2`
+
+	res, err := m.DB.Exec(stmt, attachmentId, carId)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// Deletes the row of an orphaned attachment once its file is gone
+func (m *DBModel) RemoveOrphanedAttachment(ctx context.Context, attachmentId int) error {
+	_, err := m.DB.ExecContext(ctx, `DELETE FROM attachments WHERE id=$1`, attachmentId)
+
+	return err
+}
@@ -0,0 +1,160 @@
+package models_test
+
+import (
+	"context"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var attachmentColumns = []string{"id", "user_id", "car_id", "record_type", "record_id", "file_name", "content_type", "size_bytes", "storage_key", "created_at"}
+
+func attachment() models.Attachment {
+	return models.Attachment{
+		UserID:      5,
+		CarID:       2,
+		RecordType:  models.AttachmentExpense,
+		RecordID:    7,
+		FileName:    "invoice.pdf",
+		ContentType: "application/pdf",
+		SizeBytes:   300,
+		StorageKey:  "attachments/5/2/abc",
+	}
+}
+
+func TestAttachmentValidate(t *testing.T) {
+	valid := models.Attachment{RecordType: " Maintenance ", RecordID: 3}
+	assert.NoError(t, valid.Validate())
+	assert.Equal(t, models.AttachmentMaintenance, valid.RecordType)
+
+	badType := models.Attachment{RecordType: "car", RecordID: 3}
+	assert.EqualError(t, badType.Validate(), "record type must be one of maintenance, expense, document")
+
+	noRecord := models.Attachment{RecordType: "expense"}
+	assert.EqualError(t, noRecord.Validate(), "record id is required")
+}
+
+func TestInsertAttachment_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	a := attachment()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT organization_id FROM users_cars WHERE id=\$1`).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"organization_id"}).AddRow(nil))
+	mock.ExpectQuery(`SELECT id FROM users WHERE id=\$1 FOR UPDATE`).WithArgs(5).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectQuery(`SELECT COALESCE\(SUM\(a.size_bytes\), 0\) FROM attachments a\s+LEFT JOIN users_cars c ON c.id = a.car_id WHERE a.user_id=\$1 AND c.organization_id IS NULL`).WithArgs(5).WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(700))
+	mock.ExpectQuery(`INSERT INTO attachments`).WithArgs(5, 2, "expense", 7, "invoice.pdf", "application/pdf", int64(300), "attachments/5/2/abc").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectCommit()
+
+	modelsDB := models.NewModels(db)
+	id, err := modelsDB.DB.InsertAttachment(a, 1000)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertAttachment_QuotaExceeded(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT organization_id FROM users_cars WHERE id=\$1`).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"organization_id"}).AddRow(nil))
+	mock.ExpectQuery(`SELECT id FROM users WHERE id=\$1 FOR UPDATE`).WithArgs(5).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectQuery(`SELECT COALESCE\(SUM\(a.size_bytes\), 0\) FROM attachments a\s+LEFT JOIN users_cars c ON c.id = a.car_id WHERE a.user_id=\$1 AND c.organization_id IS NULL`).WithArgs(5).WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(701))
+	mock.ExpectRollback()
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.InsertAttachment(attachment(), 1000)
+
+	assert.ErrorIs(t, err, models.ErrQuotaExceeded)
+	assert.EqualError(t, err, "storage quota exceeded: 701 of 1000 bytes used")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertAttachment_FleetCar(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT organization_id FROM users_cars WHERE id=\$1`).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"organization_id"}).AddRow(3))
+	mock.ExpectQuery(`SELECT id FROM organizations WHERE id=\$1 FOR UPDATE`).WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery(`FROM attachments a\s+JOIN users_cars c ON c.id = a.car_id WHERE c.organization_id=\$1`).WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(900))
+	mock.ExpectRollback()
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.InsertAttachment(attachment(), 1000)
+
+	assert.ErrorIs(t, err, models.ErrQuotaExceeded)
+	assert.EqualError(t, err, "storage quota exceeded: 900 of 1000 bytes used")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetAttachmentsByCarID_Filter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM attachments WHERE car_id=\$1 AND record_type=\$2 AND record_id=\$3 ORDER BY created_at DESC, id DESC`).WithArgs(2, "expense", 7).
+		WillReturnRows(sqlmock.NewRows(attachmentColumns).AddRow(4, 5, 2, "expense", 7, "invoice.pdf", "application/pdf", 300, "attachments/5/2/abc", "2023-07-01 12:00:00"))
+
+	modelsDB := models.NewModels(db)
+	attachments, err := modelsDB.DB.GetAttachmentsByCarID(2, models.AttachmentExpense, 7)
+
+	assert.NoError(t, err)
+	assert.Len(t, attachments, 1)
+	assert.Equal(t, "attachments/5/2/abc", attachments[0].StorageKey)
+	assert.Equal(t, int64(300), attachments[0].SizeBytes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetOrphanedAttachments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM attachments a\s+WHERE a.car_id IS NULL\s+OR (.+) LIMIT \$1`).WithArgs(100).
+		WillReturnRows(sqlmock.NewRows(attachmentColumns).AddRow(4, 5, nil, "expense", 7, "invoice.pdf", "application/pdf", 300, "attachments/5/2/abc", "2023-07-01 12:00:00"))
+
+	modelsDB := models.NewModels(db)
+	attachments, err := modelsDB.DB.GetOrphanedAttachments(context.Background(), 100)
+
+	assert.NoError(t, err)
+	assert.Len(t, attachments, 1)
+	assert.Equal(t, 0, attachments[0].CarID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAttachmentRecordExists(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT (.+) FROM vehicle_documents WHERE id=\$1 AND car_id=\$2`).WithArgs(7, 2).WillReturnRows(sqlmock.NewRows(vehicleDocumentColumns))
+
+	modelsDB := models.NewModels(db)
+	exists, err := modelsDB.DB.AttachmentRecordExists(2, models.AttachmentDocument, 7)
+
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
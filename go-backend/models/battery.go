@@ -0,0 +1,268 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+)
+
+const (
+	daysPerYear = 365.25
+
+	// distance the degradation per distance is expressed for
+	degradationDistance = 10000
+)
+
+// A battery health reading as reported by the car or a diagnostic tool
+type BatteryHealthLog struct {
+	ID       int       `json:"id"`
+	CarID    int       `json:"car_id"`
+	LogDate  time.Time `json:"log_date"`
+	Odometer *int      `json:"odometer,omitempty"`
+	// usable capacity of the battery
+	CapacityKWh *float64 `json:"capacity_kwh,omitempty"`
+	// estimated range at 100 % state of charge
+	RangeKm   *int   `json:"range_km,omitempty"`
+	Notes     string `json:"notes,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+// Checks the fields a client has to provide for a battery health reading
+func (b *BatteryHealthLog) Validate() error {
+	if b.LogDate.IsZero() {
+		return errors.New("log date is required")
+	}
+
+	if b.CapacityKWh == nil && b.RangeKm == nil {
+		return errors.New("capacity or range is required")
+	}
+
+	if b.CapacityKWh != nil && *b.CapacityKWh <= 0 {
+		return errors.New("capacity must be positive")
+	}
+
+	if b.RangeKm != nil && *b.RangeKm <= 0 {
+		return errors.New("range must be positive")
+	}
+
+	if b.Odometer != nil && *b.Odometer < 0 {
+		return errors.New("odometer must not be negative")
+	}
+
+	return nil
+}
+
+func (m *DBModel) InsertBatteryHealthLog(b BatteryHealthLog) (int, error) {
+	stmt := `INSERT INTO battery_health_logs (car_id, log_date, odometer, capacity_kwh, range_km, notes) VALUES($1, $2, $3, $4, $5, $6) RETURNING id`
+
+	var id int
+	err := m.DB.QueryRow(stmt, b.CarID, b.LogDate, b.Odometer, b.CapacityKWh, b.RangeKm, b.Notes).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (m *DBModel) GetBatteryHealthLogByID(carId, logId int) (BatteryHealthLog, error) {
+	var b BatteryHealthLog
+	stmt := `SELECT id, car_id, log_date, odometer, capacity_kwh, range_km, notes, created_at FROM battery_health_logs WHERE id=$1 AND car_id=$2`
+
+	row := m.DB.QueryRow(stmt, logId, carId)
+	err := row.Scan(&b.ID, &b.CarID, &b.LogDate, &b.Odometer, &b.CapacityKWh, &b.RangeKm, &b.Notes, &b.CreatedAt)
+	if err == sql.ErrNoRows {
+		return b, ErrRecordNotFound
+	} else if err != nil {
+		return b, err
+	}
+
+	return b, nil
+}
+
+// Returns the battery health readings of a car, the oldest first
+func (m *DBModel) GetBatteryHealthLogsByCarID(carId int) ([]BatteryHealthLog, error) {
+	stmt := `SELECT id, car_id, log_date, odometer, capacity_kwh, range_km, notes, created_at FROM battery_health_logs WHERE car_id=$1 ORDER BY log_date ASC, id ASC`
+
+	rows, err := m.DB.Query(stmt, carId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var logs []BatteryHealthLog
+
+	for rows.Next() {
+		var b BatteryHealthLog
+
+		err := rows.Scan(&b.ID, &b.CarID, &b.LogDate, &b.Odometer, &b.CapacityKWh, &b.RangeKm, &b.Notes, &b.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		logs = append(logs, b)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return logs, nil
+}
+
+// Returns the most recently reported battery capacity of a car, nil when none
+// was reported
+func (m *DBModel) GetLatestBatteryCapacity(carId int) (*float64, error) {
+	stmt := `SELECT capacity_kwh FROM battery_health_logs WHERE car_id=$1 AND capacity_kwh IS NOT NULL ORDER BY log_date DESC, id DESC LIMIT 1`
+
+	var capacity float64
+	err := m.DB.QueryRow(stmt, carId).Scan(&capacity)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &capacity, nil
+}
+
+func (m *DBModel) UpdateBatteryHealthLog(b BatteryHealthLog) error {
+	stmt := `UPDATE battery_health_logs SET log_date=$1, odometer=$2, capacity_kwh=$3, range_km=$4, notes=$5 WHERE id=$6 AND car_id=$7`
+
+	res, err := m.DB.Exec(stmt, b.LogDate, b.Odometer, b.CapacityKWh, b.RangeKm, b.Notes, b.ID, b.CarID)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+func (m *DBModel) RemoveBatteryHealthLog(carId, logId int) error {
+	stmt := `DELETE FROM battery_health_logs WHERE id=$1 AND car_id=$2`
+
+	res, err := m.DB.Exec(stmt, logId, carId)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// A reading on the degradation trend, Health is the value in percent of the baseline
+type DegradationPoint struct {
+	Date     time.Time `json:"date"`
+	Odometer *int      `json:"odometer"`
+	Value    float64   `json:"value"`
+	Health   float64   `json:"health"`
+}
+
+// The development of the capacity or the range. The baseline is the first
+// reading. The rates are the slope of a least squares fit through the health
+// of the readings, negative while the battery degrades, and nil when there
+// are not enough readings to fit.
+type DegradationTrend struct {
+	Baseline               float64            `json:"baseline"`
+	Latest                 float64            `json:"latest"`
+	Health                 float64            `json:"health"`
+	HealthChangePerYear    *float64           `json:"health_change_per_year"`
+	HealthChangePer10000Km *float64           `json:"health_change_per_10000km"`
+	Points                 []DegradationPoint `json:"points"`
+}
+
+type BatteryHealth struct {
+	Readings int               `json:"readings"`
+	Capacity *DegradationTrend `json:"capacity"`
+	Range    *DegradationTrend `json:"range"`
+}
+
+// Computes the degradation trends of the capacity and the range from the
+// battery health readings
+func ComputeBatteryHealth(logs []BatteryHealthLog) BatteryHealth {
+	sorted := make([]BatteryHealthLog, len(logs))
+	copy(sorted, logs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].LogDate.Before(sorted[j].LogDate)
+	})
+
+	var capacity, distance []DegradationPoint
+	for _, b := range sorted {
+		if b.CapacityKWh != nil {
+			capacity = append(capacity, DegradationPoint{Date: b.LogDate, Odometer: b.Odometer, Value: *b.CapacityKWh})
+		}
+		if b.RangeKm != nil {
+			distance = append(distance, DegradationPoint{Date: b.LogDate, Odometer: b.Odometer, Value: float64(*b.RangeKm)})
+		}
+	}
+
+	return BatteryHealth{
+		Readings: len(logs),
+		Capacity: degradationTrend(capacity),
+		Range:    degradationTrend(distance),
+	}
+}
+
+func degradationTrend(points []DegradationPoint) *DegradationTrend {
+	if len(points) == 0 {
+		return nil
+	}
+
+	baseline := points[0].Value
+	trend := &DegradationTrend{
+		Baseline: round(baseline, 2),
+		Latest:   round(points[len(points)-1].Value, 2),
+		Points:   points,
+	}
+
+	var years, health, km, kmHealth []float64
+	for i := range points {
+		points[i].Value = round(points[i].Value, 2)
+		points[i].Health = round(points[i].Value/baseline*100, 2)
+
+		years = append(years, points[i].Date.Sub(points[0].Date).Hours()/24/daysPerYear)
+		health = append(health, points[i].Health)
+		if points[i].Odometer != nil {
+			km = append(km, float64(*points[i].Odometer)/degradationDistance)
+			kmHealth = append(kmHealth, points[i].Health)
+		}
+	}
+	trend.Health = points[len(points)-1].Health
+
+	if slope, ok := leastSquaresSlope(years, health); ok {
+		slope = round(slope, 2)
+		trend.HealthChangePerYear = &slope
+	}
+
+	if slope, ok := leastSquaresSlope(km, kmHealth); ok {
+		slope = round(slope, 2)
+		trend.HealthChangePer10000Km = &slope
+	}
+
+	return trend
+}
+
+// Slope of the line fitting the points best, false when x does not vary
+func leastSquaresSlope(x, y []float64) (float64, bool) {
+	if len(x) < 2 {
+		return 0, false
+	}
+
+	var meanX, meanY float64
+	for i := range x {
+		meanX += x[i]
+		meanY += y[i]
+	}
+	meanX /= float64(len(x))
+	meanY /= float64(len(y))
+
+	var cov, variance float64
+	for i := range x {
+		cov += (x[i] - meanX) * (y[i] - meanY)
+		variance += (x[i] - meanX) * (x[i] - meanX)
+	}
+
+	if variance == 0 {
+		return 0, false
+	}
+
+	return cov / variance, true
+}
@@ -0,0 +1,78 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func batteryLog(date time.Time, odometer int, capacity float64) models.BatteryHealthLog {
+	return models.BatteryHealthLog{LogDate: date, Odometer: &odometer, CapacityKWh: &capacity}
+}
+
+func TestBatteryHealthLogValidate(t *testing.T) {
+	valid := batteryLog(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), 10000, 75)
+	assert.NoError(t, valid.Validate())
+
+	noDate := batteryLog(time.Time{}, 10000, 75)
+	assert.EqualError(t, noDate.Validate(), "log date is required")
+
+	noValue := models.BatteryHealthLog{LogDate: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+	assert.EqualError(t, noValue.Validate(), "capacity or range is required")
+
+	zeroCapacity := batteryLog(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), 10000, 0)
+	assert.EqualError(t, zeroCapacity.Validate(), "capacity must be positive")
+}
+
+func TestGetLatestBatteryCapacity_NoReadings(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT capacity_kwh FROM battery_health_logs WHERE car_id=(.+) AND capacity_kwh IS NOT NULL`).WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"capacity_kwh"}))
+
+	modelsDB := models.NewModels(db)
+	capacity, err := modelsDB.DB.GetLatestBatteryCapacity(2)
+
+	assert.NoError(t, err)
+	assert.Nil(t, capacity)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestComputeBatteryHealth(t *testing.T) {
+	rangeKm := 400
+	first := batteryLog(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), 10000, 75)
+	first.RangeKm = &rangeKm
+	logs := []models.BatteryHealthLog{
+		batteryLog(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 30000, 72),
+		first,
+	}
+
+	health := models.ComputeBatteryHealth(logs)
+
+	assert.Equal(t, 2, health.Readings)
+	assert.Equal(t, 75.0, health.Capacity.Baseline)
+	assert.Equal(t, 72.0, health.Capacity.Latest)
+	assert.Equal(t, 96.0, health.Capacity.Health)
+	assert.Equal(t, -4.0, *health.Capacity.HealthChangePerYear)
+	assert.Equal(t, -2.0, *health.Capacity.HealthChangePer10000Km)
+	assert.Len(t, health.Capacity.Points, 2)
+
+	// a single reading has no trend
+	assert.Equal(t, 100.0, health.Range.Health)
+	assert.Nil(t, health.Range.HealthChangePerYear)
+}
+
+func TestComputeBatteryHealth_NoReadings(t *testing.T) {
+	health := models.ComputeBatteryHealth(nil)
+
+	assert.Equal(t, 0, health.Readings)
+	assert.Nil(t, health.Capacity)
+	assert.Nil(t, health.Range)
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// Only the hash of a feed token is stored, the token itself is shown once
+func hashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Creates the calendar feed token of the user, replacing and so revoking the
+// previous one
+func (m *DBModel) CreateCalendarToken(userId int) (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	stmt := `INSERT INTO calendar_feeds (user_id, token_hash) VALUES($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET token_hash=EXCLUDED.token_hash, created_at=CURRENT_TIMESTAMP`
+
+	_, err = m.DB.Exec(stmt, userId, hashFeedToken(token))
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (m *DBModel) RevokeCalendarToken(userId int) error {
+	res, err := m.DB.Exec(`DELETE FROM calendar_feeds WHERE user_id=$1`, userId)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// Returns the owner of a feed token
+func (m *DBModel) GetUserIDByCalendarToken(token string) (int, error) {
+	var userId int
+
+	err := m.DB.QueryRow(`SELECT user_id FROM calendar_feeds WHERE token_hash=$1`, hashFeedToken(token)).Scan(&userId)
+	if err == sql.ErrNoRows {
+		return 0, ErrRecordNotFound
+	} else if err != nil {
+		return 0, err
+	}
+
+	return userId, nil
+}
@@ -0,0 +1,68 @@
+package models_test
+
+import (
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateCalendarToken_StoresHash(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`INSERT INTO calendar_feeds (.+) ON CONFLICT \(user_id\) DO UPDATE`).
+		WithArgs(5, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	modelsDB := models.NewModels(db)
+	token, err := modelsDB.DB.CreateCalendarToken(5)
+
+	assert.NoError(t, err)
+	assert.Len(t, token, 43)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetUserIDByCalendarToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	// sha256 of "secret"
+	hash := "2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b"
+	mock.ExpectQuery(`SELECT user_id FROM calendar_feeds WHERE token_hash=\$1`).WithArgs(hash).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(5))
+	mock.ExpectQuery(`SELECT user_id FROM calendar_feeds WHERE token_hash=\$1`).WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+
+	modelsDB := models.NewModels(db)
+	userId, err := modelsDB.DB.GetUserIDByCalendarToken("secret")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, userId)
+
+	_, err = modelsDB.DB.GetUserIDByCalendarToken("revoked")
+	assert.ErrorIs(t, err, models.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevokeCalendarToken_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`DELETE FROM calendar_feeds WHERE user_id=\$1`).WithArgs(5).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.RevokeCalendarToken(5)
+
+	assert.ErrorIs(t, err, models.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
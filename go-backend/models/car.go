@@ -1,5 +1,11 @@
 package models
 
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
 type Car struct {
 	ID           int    `json:"id"`
 	UserId       int    `json:"user_id"`
@@ -13,23 +19,43 @@ type Car struct {
 	LicensePlate string `json:"license_plate,omitempty"`
 	VIN          string `json:"vin,omitempty"`
 	CreatedAt    string `json:"created_at"`
+	Mileage      *int   `json:"mileage,omitempty"`
+	// optional trim of the catalog, it tells the engine and fuel type
+	VariantID *int `json:"variant_id,omitempty"`
+	// an archived car is kept with its history but hidden from the car list
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+	SoldAt     *time.Time `json:"sold_at,omitempty"`
+	SalePrice  *int       `json:"sale_price,omitempty"`
+	// only set on cars listed in the trash
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// set when the car belongs to the fleet of an organization, user_id is
+	// then the user who added it
+	OrganizationID *int `json:"organization_id,omitempty"`
+	// the current driver of a fleet car, only set in the car list of the organization
+	DriverID *int `json:"driver_id,omitempty"`
+	// the role of the requesting user, the car may be shared with them
+	Role string `json:"role,omitempty"`
 }
 
 type CarMaker struct {
 	ID   int    `json:"id"`
 	Name string `json:"name"`
+	// a retired maker is kept for the cars using it but hidden from the pick lists
+	RetiredAt *time.Time `json:"retired_at,omitempty"`
 }
 
 type CarModel struct {
 	ID         int    `json:"id"`
 	CarMakerID int    `json:"car_maker_id"`
 	Name       string `json:"name"`
+	// a retired model is kept for the cars using it but hidden from the pick lists
+	RetiredAt *time.Time `json:"retired_at,omitempty"`
 }
 
 func (m *DBModel) InsertCar(car Car) error {
-	stmt := `INSERT INTO users_cars (user_id, brand_id, model_id, year, color, price, image, description, license_plate, vin) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	stmt := `INSERT INTO users_cars (user_id, brand_id, model_id, year, color, price, image, description, license_plate, vin, variant_id, organization_id) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
 
-	_, err := m.DB.Exec(stmt, car.UserId, car.BrandID, car.ModelID, car.Year, car.Color, car.Price, car.Image, car.Description, car.LicensePlate, car.VIN)
+	_, err := m.DB.Exec(stmt, car.UserId, car.BrandID, car.ModelID, car.Year, car.Color, car.Price, car.Image, car.Description, car.LicensePlate, car.VIN, car.VariantID, car.OrganizationID)
 	if err != nil {
 		return err
 	}
@@ -37,19 +63,38 @@ func (m *DBModel) InsertCar(car Car) error {
 	return nil
 }
 
+// Moves a car to the trash, its history is kept until the car is purged
 func (m *DBModel) RemoveCar(carId int) error {
-	stmt := `DELETE FROM users_cars WHERE id=$1`
+	stmt := `UPDATE users_cars SET deleted_at=CURRENT_TIMESTAMP WHERE id=$1 AND deleted_at IS NULL`
 
-	_, err := m.DB.Exec(stmt, carId)
+	res, err := m.DB.Exec(stmt, carId)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return checkRowsAffected(res)
 }
 
+// Returns the cars of the user, the cars shared with the user and the fleet
+// cars the user drives that are not archived
 func (m *DBModel) GetCarsByUserID(userId int) ([]Car, error) {
-	stmt := `SELECT id, user_id, brand_id, model_id, year, color, price, image, description, license_plate, vin, created_at FROM users_cars WHERE user_id=$1`
+	return m.getCarsByUserID(userId, false)
+}
+
+// Returns all cars of the user, the cars shared with the user and the fleet
+// cars the user drives including the archived ones
+func (m *DBModel) GetAllCarsByUserID(userId int) ([]Car, error) {
+	return m.getCarsByUserID(userId, true)
+}
+
+func (m *DBModel) getCarsByUserID(userId int, includeArchived bool) ([]Car, error) {
+	stmt := `SELECT id, user_id, brand_id, model_id, year, color, price, image, description, license_plate, vin, variant_id, created_at, archived_at, sold_at, sale_price, organization_id,
+		` + fmt.Sprintf(carRoleQuery, "users_cars.id", "users_cars.user_id", "users_cars.organization_id", "$1") + `
+		FROM users_cars WHERE ((user_id=$1 AND organization_id IS NULL) OR id IN (SELECT car_id FROM car_members WHERE user_id=$1)
+			OR id IN (SELECT car_id FROM driver_assignments WHERE user_id=$1 AND ended_at IS NULL)) AND deleted_at IS NULL`
+	if !includeArchived {
+		stmt += ` AND archived_at IS NULL`
+	}
 
 	rows, err := m.DB.Query(stmt, userId)
 	if err != nil {
@@ -62,7 +107,7 @@ func (m *DBModel) GetCarsByUserID(userId int) ([]Car, error) {
 	for rows.Next() {
 		var car Car
 
-		err := rows.Scan(&car.ID, &car.UserId, &car.BrandID, &car.ModelID, &car.Year, &car.Color, &car.Price, &car.Image, &car.Description, &car.LicensePlate, &car.VIN, &car.CreatedAt)
+		err := rows.Scan(&car.ID, &car.UserId, &car.BrandID, &car.ModelID, &car.Year, &car.Color, &car.Price, &car.Image, &car.Description, &car.LicensePlate, &car.VIN, &car.VariantID, &car.CreatedAt, &car.ArchivedAt, &car.SoldAt, &car.SalePrice, &car.OrganizationID, &car.Role)
 		if err != nil {
 			return nil, err
 		}
@@ -78,7 +123,7 @@ func (m *DBModel) GetCarsByUserID(userId int) ([]Car, error) {
 }
 
 func (m *DBModel) GetAllCarMakers() ([]CarMaker, error) {
-	rows, err := m.DB.Query("SELECT id, name FROM car_makers ORDER BY name ASC")
+	rows, err := m.DB.Query("SELECT id, name FROM car_makers WHERE retired_at IS NULL ORDER BY name ASC")
 	if err != nil {
 		return nil, err
 	}
@@ -102,7 +147,7 @@ func (m *DBModel) GetAllCarMakers() ([]CarMaker, error) {
 }
 
 func (m *DBModel) GetModelsByMakerID(makerID int) ([]CarModel, error) {
-	rows, err := m.DB.Query("SELECT id, name FROM car_models WHERE car_maker_id=$1 ORDER BY name ASC", makerID)
+	rows, err := m.DB.Query("SELECT id, name FROM car_models WHERE car_maker_id=$1 AND retired_at IS NULL ORDER BY name ASC", makerID)
 	if err != nil {
 		return nil, err
 	}
@@ -127,8 +172,8 @@ func (m *DBModel) GetModelsByMakerID(makerID int) ([]CarModel, error) {
 
 func (m *DBModel) GetMakerByID(makerID int) (CarMaker, error) {
 	var maker CarMaker
-	row := m.DB.QueryRow("SELECT id, name FROM car_makers WHERE id=$1", makerID)
-	err := row.Scan(&maker.ID, &maker.Name)
+	row := m.DB.QueryRow("SELECT id, name, retired_at FROM car_makers WHERE id=$1", makerID)
+	err := row.Scan(&maker.ID, &maker.Name, &maker.RetiredAt)
 	if err != nil {
 		return maker, err
 	}
@@ -138,8 +183,8 @@ func (m *DBModel) GetMakerByID(makerID int) (CarMaker, error) {
 
 func (m *DBModel) GetModelByID(modelID int) (CarModel, error) {
 	var model CarModel
-	row := m.DB.QueryRow("SELECT id, name FROM car_models WHERE id=$1", modelID)
-	err := row.Scan(&model.ID, &model.Name)
+	row := m.DB.QueryRow("SELECT id, car_maker_id, name, retired_at FROM car_models WHERE id=$1", modelID)
+	err := row.Scan(&model.ID, &model.CarMakerID, &model.Name, &model.RetiredAt)
 	if err != nil {
 		return model, err
 	}
@@ -149,11 +194,71 @@ func (m *DBModel) GetModelByID(modelID int) (CarModel, error) {
 
 func (m *DBModel) GetCarByID(carID int) (Car, error) {
 	var car Car
-	row := m.DB.QueryRow("SELECT id, user_id, brand_id, model_id, year, color, price, image, description, license_plate, vin, created_at FROM users_cars WHERE id=$1", carID)
-	err := row.Scan(&car.ID, &car.UserId, &car.BrandID, &car.ModelID, &car.Year, &car.Color, &car.Price, &car.Image, &car.Description, &car.LicensePlate, &car.VIN, &car.CreatedAt)
+	stmt := `SELECT id, user_id, brand_id, model_id, year, color, price, image, description, license_plate, vin, variant_id, created_at, archived_at, sold_at, sale_price, organization_id, (` + fmt.Sprintf(currentOdometerQuery, "users_cars.id") + `) FROM users_cars WHERE id=$1 AND deleted_at IS NULL`
+	row := m.DB.QueryRow(stmt, carID)
+	err := row.Scan(&car.ID, &car.UserId, &car.BrandID, &car.ModelID, &car.Year, &car.Color, &car.Price, &car.Image, &car.Description, &car.LicensePlate, &car.VIN, &car.VariantID, &car.CreatedAt, &car.ArchivedAt, &car.SoldAt, &car.SalePrice, &car.OrganizationID, &car.Mileage)
 	if err != nil {
 		return car, err
 	}
 
 	return car, nil
 }
+
+// Checks the fields a client has to provide for a car
+func (car *Car) Validate() error {
+	if car.BrandID <= 0 {
+		return errors.New("brand is required")
+	}
+
+	if car.ModelID <= 0 {
+		return errors.New("model is required")
+	}
+
+	if car.Year < 0 {
+		return errors.New("year must not be negative")
+	}
+
+	if car.Price < 0 {
+		return errors.New("price must not be negative")
+	}
+
+	return nil
+}
+
+// Updates the editable fields of a car, the owner and the archived state are
+// left as they are
+func (m *DBModel) UpdateCar(car Car) error {
+	stmt := `UPDATE users_cars SET brand_id=$1, model_id=$2, year=$3, color=$4, price=$5, image=$6, description=$7, license_plate=$8, vin=$9, variant_id=$10 WHERE id=$11 AND deleted_at IS NULL`
+
+	res, err := m.DB.Exec(stmt, car.BrandID, car.ModelID, car.Year, car.Color, car.Price, car.Image, car.Description, car.LicensePlate, car.VIN, car.VariantID, car.ID)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// Archives a car, optionally recording that it was sold. Archiving it again
+// keeps a recorded sale unless a new one is given, only UnarchiveCar clears it.
+func (m *DBModel) ArchiveCar(carId int, soldAt *time.Time, salePrice *int) error {
+	stmt := `UPDATE users_cars SET archived_at=COALESCE(archived_at, CURRENT_TIMESTAMP), sold_at=COALESCE($1, sold_at), sale_price=COALESCE($2, sale_price) WHERE id=$3 AND deleted_at IS NULL`
+
+	res, err := m.DB.Exec(stmt, soldAt, salePrice, carId)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// Brings an archived car back to the car list and clears its sale
+func (m *DBModel) UnarchiveCar(carId int) error {
+	stmt := `UPDATE users_cars SET archived_at=NULL, sold_at=NULL, sale_price=NULL WHERE id=$1 AND deleted_at IS NULL`
+
+	res, err := m.DB.Exec(stmt, carId)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+)
+
+// An uploaded photo of a car. The renditions are kept in the blob storage
+// under StoragePrefix, the token is part of the URLs so that a new upload
+// gets new URLs and cached renditions never go stale.
+type CarImage struct {
+	ID            int    `json:"-"`
+	CarID         int    `json:"car_id"`
+	UserID        int    `json:"-"`
+	Token         string `json:"-"`
+	StoragePrefix string `json:"-"`
+	Width         int    `json:"width"`
+	Height        int    `json:"height"`
+	CreatedAt     string `json:"created_at"`
+}
+
+// The storage key of a rendition of the image
+func (i CarImage) StorageKey(size string) string {
+	return i.StoragePrefix + "/" + size + ".jpg"
+}
+
+// Stores the uploaded image of a car and points the image of the car at
+// imageURL. Returns the image it replaces, if any, so that its renditions can
+// be deleted.
+func (m *DBModel) ReplaceCarImage(img CarImage, imageURL string) (*CarImage, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var carId int
+	err = tx.QueryRow(`SELECT id FROM users_cars WHERE id=$1 AND deleted_at IS NULL FOR UPDATE`, img.CarID).Scan(&carId)
+	if err == sql.ErrNoRows {
+		return nil, ErrRecordNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	var previous *CarImage
+	var old CarImage
+	err = tx.QueryRow(`DELETE FROM car_images WHERE car_id=$1 RETURNING id, car_id, user_id, token, storage_prefix, width, height, created_at`, img.CarID).
+		Scan(&old.ID, &old.CarID, &old.UserID, &old.Token, &old.StoragePrefix, &old.Width, &old.Height, &old.CreatedAt)
+	if err == nil {
+		previous = &old
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`INSERT INTO car_images (car_id, user_id, token, storage_prefix, width, height) VALUES($1, $2, $3, $4, $5, $6)`,
+		img.CarID, img.UserID, img.Token, img.StoragePrefix, img.Width, img.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`UPDATE users_cars SET image=$1 WHERE id=$2`, imageURL, img.CarID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return previous, nil
+}
+
+func (m *DBModel) GetCarImage(carId int) (CarImage, error) {
+	var img CarImage
+	stmt := `SELECT id, car_id, user_id, token, storage_prefix, width, height, created_at FROM car_images WHERE car_id=$1`
+
+	err := m.DB.QueryRow(stmt, carId).Scan(&img.ID, &img.CarID, &img.UserID, &img.Token, &img.StoragePrefix, &img.Width, &img.Height, &img.CreatedAt)
+	if err == sql.ErrNoRows {
+		return img, ErrRecordNotFound
+	} else if err != nil {
+		return img, err
+	}
+
+	return img, nil
+}
+
+// Deletes the uploaded image of a car and returns it, the image of the car
+// itself is left as it is
+func (m *DBModel) RemoveCarImage(carId int) (CarImage, error) {
+	var img CarImage
+	stmt := `DELETE FROM car_images WHERE car_id=$1 RETURNING id, car_id, user_id, token, storage_prefix, width, height, created_at`
+
+	err := m.DB.QueryRow(stmt, carId).Scan(&img.ID, &img.CarID, &img.UserID, &img.Token, &img.StoragePrefix, &img.Width, &img.Height, &img.CreatedAt)
+	if err == sql.ErrNoRows {
+		return img, ErrRecordNotFound
+	} else if err != nil {
+		return img, err
+	}
+
+	return img, nil
+}
+
+// Returns images whose car was purged, their renditions can be deleted
+func (m *DBModel) GetOrphanedCarImages(ctx context.Context, limit int) ([]CarImage, error) {
+	stmt := `SELECT id, user_id, token, storage_prefix, width, height, created_at FROM car_images WHERE car_id IS NULL ORDER BY id LIMIT $1`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var images []CarImage
+
+	for rows.Next() {
+		var img CarImage
+
+		err := rows.Scan(&img.ID, &img.UserID, &img.Token, &img.StoragePrefix, &img.Width, &img.Height, &img.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		images = append(images, img)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+// Deletes the row of an orphaned image once its renditions are gone
+func (m *DBModel) RemoveOrphanedCarImage(ctx context.Context, imageId int) error {
+	_, err := m.DB.ExecContext(ctx, `DELETE FROM car_images WHERE id=$1`, imageId)
+
+	return err
+}
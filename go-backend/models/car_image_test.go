@@ -0,0 +1,75 @@
+package models_test
+
+import (
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var carImageColumns = []string{"id", "car_id", "user_id", "token", "storage_prefix", "width", "height", "created_at"}
+
+func TestReplaceCarImage_ReturnsPrevious(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	img := models.CarImage{CarID: 2, UserID: 5, Token: "new", StoragePrefix: "cars/5/2/new", Width: 1600, Height: 1200}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM users_cars WHERE id=\$1 AND deleted_at IS NULL FOR UPDATE`).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery(`DELETE FROM car_images WHERE car_id=\$1 RETURNING`).WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(carImageColumns).AddRow(1, 2, 5, "old", "cars/5/2/old", 800, 600, "2023-07-01 12:00:00"))
+	mock.ExpectExec(`INSERT INTO car_images`).WithArgs(2, 5, "new", "cars/5/2/new", 1600, 1200).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec(`UPDATE users_cars SET image=\$1 WHERE id=\$2`).WithArgs("/api/v1/cars/2/image/large?v=new", 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	modelsDB := models.NewModels(db)
+	previous, err := modelsDB.DB.ReplaceCarImage(img, "/api/v1/cars/2/image/large?v=new")
+
+	assert.NoError(t, err)
+	assert.NotNil(t, previous)
+	assert.Equal(t, "cars/5/2/old/thumb.jpg", previous.StorageKey("thumb"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReplaceCarImage_First(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM users_cars`).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery(`DELETE FROM car_images`).WithArgs(2).WillReturnRows(sqlmock.NewRows(carImageColumns))
+	mock.ExpectExec(`INSERT INTO car_images`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`UPDATE users_cars SET image`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	modelsDB := models.NewModels(db)
+	previous, err := modelsDB.DB.ReplaceCarImage(models.CarImage{CarID: 2, UserID: 5}, "/api/v1/cars/2/image/large?v=new")
+
+	assert.NoError(t, err)
+	assert.Nil(t, previous)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRemoveCarImage_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`DELETE FROM car_images WHERE car_id=\$1 RETURNING`).WithArgs(2).WillReturnRows(sqlmock.NewRows(carImageColumns))
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.RemoveCarImage(2)
+
+	assert.ErrorIs(t, err, models.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -29,6 +29,8 @@ func TestInsertCar(t *testing.T) {
 		"This is a test car", // description
 		"ABC123",             // license_plate
 		"1HGCM82633A123456",  // vin
+		nil,                  // variant_id
+		nil,                  // organization_id
 	).WillReturnResult(sqlmock.NewResult(1, 1))
 
 	modelsDB := models.NewModels(db)
@@ -69,6 +71,8 @@ func TestInsertCar_Error(t *testing.T) {
 		"This is a test car", // description
 		"ABC123",             // license_plate
 		"1HGCM82633A123456",  // vin
+		nil,                  // variant_id
+		nil,                  // organization_id
 	).WillReturnError(errors.New("mocked error"))
 
 	modelsDB := models.NewModels(db)
@@ -99,7 +103,7 @@ func TestRemoveCar_Success(t *testing.T) {
 	}
 	defer db.Close()
 
-	mock.ExpectExec(`DELETE FROM users_cars WHERE id`).WithArgs(1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`UPDATE users_cars SET deleted_at=CURRENT_TIMESTAMP WHERE id=\$1 AND deleted_at IS NULL`).WithArgs(1).WillReturnResult(sqlmock.NewResult(1, 1))
 
 	modelsDB := models.NewModels(db)
 	err = modelsDB.DB.RemoveCar(1)
@@ -115,7 +119,7 @@ func TestRemoveCar_Error(t *testing.T) {
 	}
 	defer db.Close()
 
-	mock.ExpectExec(`DELETE FROM users_cars WHERE id`).WithArgs(1).WillReturnError(errors.New("mocked error"))
+	mock.ExpectExec(`UPDATE users_cars SET deleted_at=CURRENT_TIMESTAMP WHERE id=\$1 AND deleted_at IS NULL`).WithArgs(1).WillReturnError(errors.New("mocked error"))
 
 	modelsDB := models.NewModels(db)
 	err = modelsDB.DB.RemoveCar(1)
@@ -132,11 +136,11 @@ func TestGetCarsByUserID_Success(t *testing.T) {
 	}
 	defer db.Close()
 
-	rows := sqlmock.NewRows([]string{"id", "user_id", "brand_id", "model_id", "year", "color", "price", "image", "description", "license_plate", "vin", "created_at"}).
-		AddRow(1, 1, 1, 1, 2022, "red", 50000, "image.jpg", "This is car 1", "ABC123", "1HGCM82633A123456", "2023-06-19 12:00:00").
-		AddRow(2, 1, 2, 2, 2023, "blue", 60000, "image2.jpg", "This is car 2", "DEF456", "2HGCM82633A654321", "2023-06-19 13:00:00")
+	rows := sqlmock.NewRows([]string{"id", "user_id", "brand_id", "model_id", "year", "color", "price", "image", "description", "license_plate", "vin", "variant_id", "created_at", "archived_at", "sold_at", "sale_price", "organization_id", "role"}).
+		AddRow(1, 1, 1, 1, 2022, "red", 50000, "image.jpg", "This is car 1", "ABC123", "1HGCM82633A123456", nil, "2023-06-19 12:00:00", nil, nil, nil, nil, "owner").
+		AddRow(2, 5, 2, 2, 2023, "blue", 60000, "image2.jpg", "This is car 2", "DEF456", "2HGCM82633A654321", nil, "2023-06-19 13:00:00", nil, nil, nil, nil, "viewer")
 
-	mock.ExpectQuery(`SELECT (.+) FROM users_cars WHERE user_id=`).WithArgs(1).WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT (.+) FROM users_cars WHERE \(\(user_id=\$1 AND organization_id IS NULL\) OR (.+) AND deleted_at IS NULL AND archived_at IS NULL`).WithArgs(1).WillReturnRows(rows)
 
 	modelsDB := models.NewModels(db)
 	cars, err := modelsDB.DB.GetCarsByUserID(1)
@@ -155,10 +159,11 @@ func TestGetCarsByUserID_Success(t *testing.T) {
 			LicensePlate: "ABC123",
 			VIN:          "1HGCM82633A123456",
 			CreatedAt:    "2023-06-19 12:00:00",
+			Role:         "owner",
 		},
 		{
 			ID:           2,
-			UserId:       1,
+			UserId:       5,
 			BrandID:      2,
 			ModelID:      2,
 			Year:         2023,
@@ -169,6 +174,7 @@ func TestGetCarsByUserID_Success(t *testing.T) {
 			LicensePlate: "DEF456",
 			VIN:          "2HGCM82633A654321",
 			CreatedAt:    "2023-06-19 13:00:00",
+			Role:         "viewer",
 		},
 	}
 
@@ -184,9 +190,9 @@ func TestGetCarsByUserID_NoRows(t *testing.T) {
 	}
 	defer db.Close()
 
-	rows := sqlmock.NewRows([]string{"id", "user_id", "brand_id", "model_id", "year", "color", "price", "image", "description", "license_plate", "vin", "created_at"})
+	rows := sqlmock.NewRows([]string{"id", "user_id", "brand_id", "model_id", "year", "color", "price", "image", "description", "license_plate", "vin", "variant_id", "created_at", "archived_at", "sold_at", "sale_price", "organization_id", "role"})
 
-	mock.ExpectQuery(`SELECT (.+) FROM users_cars WHERE user_id=`).WithArgs(1).WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT (.+) FROM users_cars WHERE \(\(user_id=\$1 AND organization_id IS NULL\) OR (.+) AND deleted_at IS NULL AND archived_at IS NULL`).WithArgs(1).WillReturnRows(rows)
 
 	modelsDB := models.NewModels(db)
 	cars, err := modelsDB.DB.GetCarsByUserID(1)
@@ -203,7 +209,7 @@ func TestGetCarsByUserID_Error(t *testing.T) {
 	}
 	defer db.Close()
 
-	mock.ExpectQuery(`SELECT (.+) FROM users_cars WHERE user_id=`).WithArgs(1).WillReturnError(errors.New("mocked error"))
+	mock.ExpectQuery(`SELECT (.+) FROM users_cars WHERE \(\(user_id=\$1 AND organization_id IS NULL\) OR (.+) AND deleted_at IS NULL AND archived_at IS NULL`).WithArgs(1).WillReturnError(errors.New("mocked error"))
 
 	modelsDB := models.NewModels(db)
 	cars, err := modelsDB.DB.GetCarsByUserID(1)
@@ -369,10 +375,10 @@ func TestGetMakerByID_Success(t *testing.T) {
 	}
 	defer db.Close()
 
-	row := sqlmock.NewRows([]string{"id", "name"}).
-		AddRow(1, "Maker A")
+	row := sqlmock.NewRows([]string{"id", "name", "retired_at"}).
+		AddRow(1, "Maker A", nil)
 
-	mock.ExpectQuery("SELECT id, name FROM car_makers WHERE id=").WithArgs(1).WillReturnRows(row)
+	mock.ExpectQuery("SELECT id, name, retired_at FROM car_makers WHERE id=").WithArgs(1).WillReturnRows(row)
 
 	modelsDB := models.NewModels(db)
 	carMaker, err := modelsDB.DB.GetMakerByID(1)
@@ -394,7 +400,7 @@ func TestGetMakerByID_NotFound(t *testing.T) {
 	}
 	defer db.Close()
 
-	mock.ExpectQuery("SELECT id, name FROM car_makers WHERE id=").WithArgs(1).WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("SELECT id, name, retired_at FROM car_makers WHERE id=").WithArgs(1).WillReturnError(sql.ErrNoRows)
 
 	modelsDB := models.NewModels(db)
 	carMaker, err := modelsDB.DB.GetMakerByID(1)
@@ -412,7 +418,7 @@ func TestGetMakerByID_Error(t *testing.T) {
 	}
 	defer db.Close()
 
-	mock.ExpectQuery("SELECT id, name FROM car_makers WHERE id=").WithArgs(1).WillReturnError(errors.New("mocked error"))
+	mock.ExpectQuery("SELECT id, name, retired_at FROM car_makers WHERE id=").WithArgs(1).WillReturnError(errors.New("mocked error"))
 
 	modelsDB := models.NewModels(db)
 	carMaker, err := modelsDB.DB.GetMakerByID(1)
@@ -430,17 +436,18 @@ func TestGetModelByID_Success(t *testing.T) {
 	}
 	defer db.Close()
 
-	row := sqlmock.NewRows([]string{"id", "name"}).
-		AddRow(1, "Model A")
+	row := sqlmock.NewRows([]string{"id", "car_maker_id", "name", "retired_at"}).
+		AddRow(1, 2, "Model A", nil)
 
-	mock.ExpectQuery("SELECT id, name FROM car_models WHERE id=").WithArgs(1).WillReturnRows(row)
+	mock.ExpectQuery("SELECT id, car_maker_id, name, retired_at FROM car_models WHERE id=").WithArgs(1).WillReturnRows(row)
 
 	modelsDB := models.NewModels(db)
 	carModel, err := modelsDB.DB.GetModelByID(1)
 
 	expectedCarModel := models.CarModel{
-		ID:   1,
-		Name: "Model A",
+		ID:         1,
+		CarMakerID: 2,
+		Name:       "Model A",
 	}
 
 	assert.NoError(t, err)
@@ -455,7 +462,7 @@ func TestGetModelByID_NotFound(t *testing.T) {
 	}
 	defer db.Close()
 
-	mock.ExpectQuery("SELECT id, name FROM car_models WHERE id=").WithArgs(1).WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("SELECT id, car_maker_id, name, retired_at FROM car_models WHERE id=").WithArgs(1).WillReturnError(sql.ErrNoRows)
 
 	modelsDB := models.NewModels(db)
 	carModel, err := modelsDB.DB.GetModelByID(1)
@@ -473,7 +480,7 @@ func TestGetModelByID_Error(t *testing.T) {
 	}
 	defer db.Close()
 
-	mock.ExpectQuery("SELECT id, name FROM car_models WHERE id=").WithArgs(1).WillReturnError(errors.New("mocked error"))
+	mock.ExpectQuery("SELECT id, car_maker_id, name, retired_at FROM car_models WHERE id=").WithArgs(1).WillReturnError(errors.New("mocked error"))
 
 	modelsDB := models.NewModels(db)
 	carModel, err := modelsDB.DB.GetModelByID(1)
@@ -496,17 +503,18 @@ func TestGetCarByID_Success(t *testing.T) {
 
 	row := sqlmock.NewRows([]string{
 		"id", "user_id", "brand_id", "model_id", "year", "color", "price",
-		"image", "description", "license_plate", "vin", "created_at",
+		"image", "description", "license_plate", "vin", "variant_id", "created_at", "archived_at", "sold_at", "sale_price", "organization_id", "mileage",
 	}).AddRow(
 		1, 1, 1, 1, 2022, "red", 50000,
-		"image.jpg", "This is a test car", "ABC123", "1HGCM82633A123456", formattedTime,
+		"image.jpg", "This is a test car", "ABC123", "1HGCM82633A123456", nil, formattedTime, nil, nil, nil, nil, 120000,
 	)
 
-	mock.ExpectQuery("SELECT id, user_id, brand_id, model_id, year, color, price, image, description, license_plate, vin, created_at FROM users_cars WHERE id=").WithArgs(1).WillReturnRows(row)
+	mock.ExpectQuery(`SELECT id, user_id, brand_id, model_id, year, color, price, image, description, license_plate, vin, variant_id, created_at, archived_at, sold_at, sale_price, organization_id, \((.+)\) FROM users_cars WHERE id=`).WithArgs(1).WillReturnRows(row)
 
 	modelsDB := models.NewModels(db)
 	car, err := modelsDB.DB.GetCarByID(1)
 
+	mileage := 120000
 	expectedCar := models.Car{
 		ID:           1,
 		UserId:       1,
@@ -520,6 +528,7 @@ func TestGetCarByID_Success(t *testing.T) {
 		LicensePlate: "ABC123",
 		VIN:          "1HGCM82633A123456",
 		CreatedAt:    formattedTime,
+		Mileage:      &mileage,
 	}
 
 	assert.NoError(t, err)
@@ -534,7 +543,7 @@ func TestGetCarByID_NotFound(t *testing.T) {
 	}
 	defer db.Close()
 
-	mock.ExpectQuery("SELECT id, user_id, brand_id, model_id, year, color, price, image, description, license_plate, vin, created_at FROM users_cars WHERE id=").WithArgs(1).WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`SELECT id, user_id, brand_id, model_id, year, color, price, image, description, license_plate, vin, variant_id, created_at, archived_at, sold_at, sale_price, organization_id, \((.+)\) FROM users_cars WHERE id=`).WithArgs(1).WillReturnError(sql.ErrNoRows)
 
 	modelsDB := models.NewModels(db)
 	car, err := modelsDB.DB.GetCarByID(1)
@@ -552,7 +561,7 @@ func TestGetCarByID_Error(t *testing.T) {
 	}
 	defer db.Close()
 
-	mock.ExpectQuery("SELECT id, user_id, brand_id, model_id, year, color, price, image, description, license_plate, vin, created_at FROM users_cars WHERE id=").WithArgs(1).WillReturnError(errors.New("mocked error"))
+	mock.ExpectQuery(`SELECT id, user_id, brand_id, model_id, year, color, price, image, description, license_plate, vin, variant_id, created_at, archived_at, sold_at, sale_price, organization_id, \((.+)\) FROM users_cars WHERE id=`).WithArgs(1).WillReturnError(errors.New("mocked error"))
 
 	modelsDB := models.NewModels(db)
 	car, err := modelsDB.DB.GetCarByID(1)
@@ -562,3 +571,134 @@ func TestGetCarByID_Error(t *testing.T) {
 	assert.Equal(t, models.Car{}, car)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestGetAllCarsByUserID_IncludesArchived(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	archivedAt := time.Date(2023, 8, 1, 10, 0, 0, 0, time.UTC)
+	soldAt := time.Date(2023, 7, 30, 0, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"id", "user_id", "brand_id", "model_id", "year", "color", "price", "image", "description", "license_plate", "vin", "variant_id", "created_at", "archived_at", "sold_at", "sale_price", "organization_id", "role"}).
+		AddRow(1, 1, 1, 1, 2022, "red", 50000, "image.jpg", "This is car 1", "ABC123", "1HGCM82633A123456", 10, "2023-06-19 12:00:00", archivedAt, soldAt, 42000, nil, "owner")
+
+	mock.ExpectQuery(`SELECT (.+) FROM users_cars WHERE \(\(user_id=\$1 AND organization_id IS NULL\) OR (.+) AND deleted_at IS NULL$`).WithArgs(1).WillReturnRows(rows)
+
+	modelsDB := models.NewModels(db)
+	cars, err := modelsDB.DB.GetAllCarsByUserID(1)
+
+	assert.NoError(t, err)
+	assert.Len(t, cars, 1)
+	assert.Equal(t, &archivedAt, cars[0].ArchivedAt)
+	assert.Equal(t, &soldAt, cars[0].SoldAt)
+	assert.Equal(t, 42000, *cars[0].SalePrice)
+	assert.Equal(t, 10, *cars[0].VariantID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateCar_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	car := models.Car{ID: 3, BrandID: 1, ModelID: 2, Year: 2020, Color: "black", Price: 30000, Image: "car.jpg", Description: "Daily", LicensePlate: "BA123XY", VIN: "1HGCM82633A123456"}
+
+	mock.ExpectExec(`UPDATE users_cars SET (.+) WHERE id=\$11`).
+		WithArgs(1, 2, 2020, "black", 30000, "car.jpg", "Daily", "BA123XY", "1HGCM82633A123456", nil, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.UpdateCar(car)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateCar_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE users_cars SET`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.UpdateCar(models.Car{ID: 3})
+
+	assert.ErrorIs(t, err, models.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestArchiveCar_Sold(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	soldAt := time.Date(2023, 7, 30, 0, 0, 0, 0, time.UTC)
+	salePrice := 42000
+
+	mock.ExpectExec(`UPDATE users_cars SET archived_at=COALESCE\(archived_at, CURRENT_TIMESTAMP\), sold_at=COALESCE\(\$1, sold_at\), sale_price=COALESCE\(\$2, sale_price\) WHERE id=\$3`).
+		WithArgs(&soldAt, &salePrice, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.ArchiveCar(3, &soldAt, &salePrice)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestArchiveCar_KeepsSale(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE users_cars SET (.+) sold_at=COALESCE\(\$1, sold_at\), sale_price=COALESCE\(\$2, sale_price\)`).
+		WithArgs(nil, nil, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.ArchiveCar(3, nil, nil)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUnarchiveCar_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE users_cars SET archived_at=NULL, sold_at=NULL, sale_price=NULL WHERE id=\$1`).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.UnarchiveCar(3)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCarValidate(t *testing.T) {
+	car := models.Car{BrandID: 1, ModelID: 2, Year: 2020}
+	assert.NoError(t, car.Validate())
+
+	car.ModelID = 0
+	assert.EqualError(t, car.Validate(), "model is required")
+
+	car = models.Car{ModelID: 2}
+	assert.EqualError(t, car.Validate(), "brand is required")
+
+	car = models.Car{BrandID: 1, ModelID: 2, Price: -1}
+	assert.EqualError(t, car.Validate(), "price must not be negative")
+}
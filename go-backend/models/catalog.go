@@ -0,0 +1,168 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Fuel types of an engine
+const (
+	FuelPetrol       = "petrol"
+	FuelDiesel       = "diesel"
+	FuelHybrid       = "hybrid"
+	FuelPlugInHybrid = "plug_in_hybrid"
+	FuelElectric     = "electric"
+	FuelLPG          = "lpg"
+)
+
+var FuelTypes = []string{FuelPetrol, FuelDiesel, FuelHybrid, FuelPlugInHybrid, FuelElectric, FuelLPG}
+
+// Body types of a variant
+const (
+	BodySedan       = "sedan"
+	BodyHatchback   = "hatchback"
+	BodyWagon       = "wagon"
+	BodySUV         = "suv"
+	BodyCoupe       = "coupe"
+	BodyConvertible = "convertible"
+	BodyMinivan     = "minivan"
+	BodyPickup      = "pickup"
+	BodyVan         = "van"
+)
+
+var BodyTypes = []string{BodySedan, BodyHatchback, BodyWagon, BodySUV, BodyCoupe, BodyConvertible, BodyMinivan, BodyPickup, BodyVan}
+
+func IsFuelType(fuelType string) bool {
+	for _, f := range FuelTypes {
+		if f == fuelType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func IsBodyType(bodyType string) bool {
+	for _, b := range BodyTypes {
+		if b == bodyType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// A generation of a model, YearTo is nil while it is still produced
+type CarGeneration struct {
+	ID       int    `json:"id"`
+	ModelID  int    `json:"car_model_id"`
+	Name     string `json:"name"`
+	YearFrom int    `json:"year_from"`
+	YearTo   *int   `json:"year_to"`
+}
+
+type Engine struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	FuelType string `json:"fuel_type"`
+	// nil for electric motors
+	DisplacementCC *int `json:"displacement_cc"`
+	PowerKW        int  `json:"power_kw"`
+}
+
+// A trim of a generation with its engine and body
+type CarVariant struct {
+	ID           int    `json:"id"`
+	GenerationID int    `json:"car_generation_id"`
+	ModelID      int    `json:"car_model_id"`
+	Name         string `json:"name"`
+	BodyType     string `json:"body_type"`
+	Engine       Engine `json:"engine"`
+}
+
+// Narrows down the variants of a generation, zero values are ignored
+type VariantFilter struct {
+	FuelType string
+	BodyType string
+}
+
+const variantColumns = `v.id, v.car_generation_id, g.car_model_id, v.name, v.body_type, e.id, e.name, e.fuel_type, e.displacement_cc, e.power_kw
+	FROM car_variants v JOIN car_generations g ON g.id = v.car_generation_id JOIN engines e ON e.id = v.engine_id`
+
+// Returns the generations of a model, the oldest first
+func (m *DBModel) GetGenerationsByModelID(modelId int) ([]CarGeneration, error) {
+	rows, err := m.DB.Query("SELECT id, car_model_id, name, year_from, year_to FROM car_generations WHERE car_model_id=$1 ORDER BY year_from ASC, id ASC", modelId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var generations []CarGeneration
+	for rows.Next() {
+		var g CarGeneration
+		err = rows.Scan(&g.ID, &g.ModelID, &g.Name, &g.YearFrom, &g.YearTo)
+		if err != nil {
+			return nil, err
+		}
+		generations = append(generations, g)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return generations, nil
+}
+
+// Returns the variants of a generation matching the filter
+func (m *DBModel) GetVariantsByGenerationID(generationId int, filter VariantFilter) ([]CarVariant, error) {
+	stmt := `SELECT ` + variantColumns + ` WHERE v.car_generation_id=$1`
+	args := []interface{}{generationId}
+
+	if filter.FuelType != "" {
+		args = append(args, filter.FuelType)
+		stmt += fmt.Sprintf(" AND e.fuel_type = $%d", len(args))
+	}
+
+	if filter.BodyType != "" {
+		args = append(args, filter.BodyType)
+		stmt += fmt.Sprintf(" AND v.body_type = $%d", len(args))
+	}
+
+	stmt += " ORDER BY v.name ASC, v.id ASC"
+
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var variants []CarVariant
+	for rows.Next() {
+		var v CarVariant
+		err = rows.Scan(&v.ID, &v.GenerationID, &v.ModelID, &v.Name, &v.BodyType, &v.Engine.ID, &v.Engine.Name, &v.Engine.FuelType, &v.Engine.DisplacementCC, &v.Engine.PowerKW)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, v)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return variants, nil
+}
+
+func (m *DBModel) GetVariantByID(variantId int) (CarVariant, error) {
+	var v CarVariant
+	row := m.DB.QueryRow(`SELECT `+variantColumns+` WHERE v.id=$1`, variantId)
+	err := row.Scan(&v.ID, &v.GenerationID, &v.ModelID, &v.Name, &v.BodyType, &v.Engine.ID, &v.Engine.Name, &v.Engine.FuelType, &v.Engine.DisplacementCC, &v.Engine.PowerKW)
+	if err == sql.ErrNoRows {
+		return v, ErrRecordNotFound
+	} else if err != nil {
+		return v, err
+	}
+
+	return v, nil
+}
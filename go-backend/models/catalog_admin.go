@@ -0,0 +1,394 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// returned when a maker, or a model of the same maker, already has the name
+var ErrDuplicateName = errors.New("an entry with this name already exists")
+
+// Reports whether the statement failed on a unique index. The name checks run
+// before the writes, a concurrent request may still take the name in between.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// One maker and model pair of a catalog dump, Model is empty for a maker
+// without models
+type CatalogEntry struct {
+	Maker string
+	Model string
+}
+
+type CatalogImportResult struct {
+	Rows          int `json:"rows"`
+	MakersCreated int `json:"makers_created"`
+	ModelsCreated int `json:"models_created"`
+}
+
+// Returns the makers and models that are not retired, the models of a retired
+// maker are left out
+func (m *DBModel) GetActiveCatalog() ([]CarMaker, []CarModel, error) {
+	makers, err := m.GetAllCarMakers()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := m.DB.Query(`SELECT mo.id, mo.car_maker_id, mo.name FROM car_models mo JOIN car_makers ma ON ma.id = mo.car_maker_id
+		WHERE mo.retired_at IS NULL AND ma.retired_at IS NULL ORDER BY mo.id ASC`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var carModels []CarModel
+	for rows.Next() {
+		var model CarModel
+		err = rows.Scan(&model.ID, &model.CarMakerID, &model.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		carModels = append(carModels, model)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return makers, carModels, nil
+}
+
+func (m *DBModel) InsertMaker(name string) (CarMaker, error) {
+	maker := CarMaker{Name: name}
+
+	var exists bool
+	err := m.DB.QueryRow(`SELECT exists (SELECT 1 FROM car_makers WHERE LOWER(name)=LOWER($1))`, name).Scan(&exists)
+	if err != nil {
+		return maker, err
+	}
+	if exists {
+		return maker, ErrDuplicateName
+	}
+
+	err = m.DB.QueryRow(`INSERT INTO car_makers (name) VALUES ($1) RETURNING id`, name).Scan(&maker.ID)
+	if isUniqueViolation(err) {
+		return maker, ErrDuplicateName
+	} else if err != nil {
+		return maker, err
+	}
+
+	return maker, nil
+}
+
+func (m *DBModel) RenameMaker(makerId int, name string) error {
+	var exists bool
+	err := m.DB.QueryRow(`SELECT exists (SELECT 1 FROM car_makers WHERE LOWER(name)=LOWER($1) AND id<>$2)`, name, makerId).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrDuplicateName
+	}
+
+	res, err := m.DB.Exec(`UPDATE car_makers SET name=$1 WHERE id=$2`, name, makerId)
+	if isUniqueViolation(err) {
+		return ErrDuplicateName
+	} else if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// Hides a maker from the pick lists, the cars using it keep it
+func (m *DBModel) RetireMaker(makerId int) error {
+	res, err := m.DB.Exec(`UPDATE car_makers SET retired_at=COALESCE(retired_at, CURRENT_TIMESTAMP) WHERE id=$1`, makerId)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// Moves the models and cars of a maker to another one and retires it. A model
+// whose name the target already has is merged into the target's model.
+func (m *DBModel) MergeMaker(sourceId, targetId int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var dummy int
+	err = tx.QueryRow(`SELECT 1 FROM car_makers WHERE id=$1 AND retired_at IS NULL`, targetId).Scan(&dummy)
+	if err == sql.ErrNoRows {
+		return ErrRecordNotFound
+	} else if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`SELECT s.id, t.id FROM car_models s JOIN car_models t ON t.car_maker_id=$1 AND LOWER(t.name)=LOWER(s.name) WHERE s.car_maker_id=$2`, targetId, sourceId)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// the rows have to be read before the transaction runs other statements
+	var pairs [][2]int
+	for rows.Next() {
+		var pair [2]int
+		err = rows.Scan(&pair[0], &pair[1])
+		if err != nil {
+			return err
+		}
+		pairs = append(pairs, pair)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, pair := range pairs {
+		err = mergeModel(tx, pair[0], pair[1], targetId)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE car_models s SET car_maker_id=$1 WHERE s.car_maker_id=$2
+		AND NOT EXISTS (SELECT 1 FROM car_models t WHERE t.car_maker_id=$1 AND LOWER(t.name)=LOWER(s.name))`, targetId, sourceId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE users_cars SET brand_id=$1 WHERE brand_id=$2`, targetId, sourceId)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(`UPDATE car_makers SET retired_at=COALESCE(retired_at, CURRENT_TIMESTAMP) WHERE id=$1`, sourceId)
+	if err != nil {
+		return err
+	}
+
+	err = checkRowsAffected(res)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Returns ErrRecordNotFound when the maker does not exist
+func (m *DBModel) InsertModel(makerId int, name string) (CarModel, error) {
+	model := CarModel{CarMakerID: makerId, Name: name}
+
+	var dummy int
+	err := m.DB.QueryRow(`SELECT 1 FROM car_makers WHERE id=$1`, makerId).Scan(&dummy)
+	if err == sql.ErrNoRows {
+		return model, ErrRecordNotFound
+	} else if err != nil {
+		return model, err
+	}
+
+	var exists bool
+	err = m.DB.QueryRow(`SELECT exists (SELECT 1 FROM car_models WHERE car_maker_id=$1 AND LOWER(name)=LOWER($2))`, makerId, name).Scan(&exists)
+	if err != nil {
+		return model, err
+	}
+	if exists {
+		return model, ErrDuplicateName
+	}
+
+	err = m.DB.QueryRow(`INSERT INTO car_models (car_maker_id, name) VALUES ($1, $2) RETURNING id`, makerId, name).Scan(&model.ID)
+	if isUniqueViolation(err) {
+		return model, ErrDuplicateName
+	} else if err != nil {
+		return model, err
+	}
+
+	return model, nil
+}
+
+func (m *DBModel) RenameModel(modelId int, name string) error {
+	var exists bool
+	err := m.DB.QueryRow(`SELECT exists (SELECT 1 FROM car_models o JOIN car_models m ON m.id=$2
+		WHERE o.car_maker_id=m.car_maker_id AND LOWER(o.name)=LOWER($1) AND o.id<>$2)`, name, modelId).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrDuplicateName
+	}
+
+	res, err := m.DB.Exec(`UPDATE car_models SET name=$1 WHERE id=$2`, name, modelId)
+	if isUniqueViolation(err) {
+		return ErrDuplicateName
+	} else if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// Hides a model from the pick lists, the cars using it keep it
+func (m *DBModel) RetireModel(modelId int) error {
+	res, err := m.DB.Exec(`UPDATE car_models SET retired_at=COALESCE(retired_at, CURRENT_TIMESTAMP) WHERE id=$1`, modelId)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// Moves the cars, generations and service templates of a model to another
+// one and retires it. The target may belong to a different maker.
+func (m *DBModel) MergeModel(sourceId, targetId int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var makerId int
+	err = tx.QueryRow(`SELECT car_maker_id FROM car_models WHERE id=$1 AND retired_at IS NULL`, targetId).Scan(&makerId)
+	if err == sql.ErrNoRows {
+		return ErrRecordNotFound
+	} else if err != nil {
+		return err
+	}
+
+	err = mergeModel(tx, sourceId, targetId, makerId)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func mergeModel(tx *sql.Tx, sourceId, targetId, targetMakerId int) error {
+	_, err := tx.Exec(`UPDATE users_cars SET model_id=$1, brand_id=$2 WHERE model_id=$3`, targetId, targetMakerId, sourceId)
+	if err != nil {
+		return err
+	}
+
+	for _, stmt := range []string{
+		`UPDATE car_generations SET car_model_id=$1 WHERE car_model_id=$2`,
+		// the target keeps its own rule where both models have one
+		`DELETE FROM service_schedule_templates s WHERE s.car_model_id=$2 AND EXISTS (SELECT 1 FROM service_schedule_templates t
+			WHERE t.car_model_id=$1 AND LOWER(t.service_type)=LOWER(s.service_type) AND t.fuel_type IS NOT DISTINCT FROM s.fuel_type)`,
+		`UPDATE service_schedule_templates SET car_model_id=$1 WHERE car_model_id=$2`,
+	} {
+		_, err = tx.Exec(stmt, targetId, sourceId)
+		if err != nil {
+			return err
+		}
+	}
+
+	res, err := tx.Exec(`UPDATE car_models SET retired_at=COALESCE(retired_at, CURRENT_TIMESTAMP) WHERE id=$1`, sourceId)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+type catalogModelKey struct {
+	makerId int
+	name    string
+}
+
+// Adds the makers and models of a dump that are not in the catalog yet. Names
+// are matched case insensitively and existing rows, retired ones included,
+// are never changed, so importing the same dump again does nothing.
+func (m *DBModel) ImportCatalog(entries []CatalogEntry) (CatalogImportResult, error) {
+	result := CatalogImportResult{Rows: len(entries)}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	makers := make(map[string]int)
+	rows, err := tx.Query(`SELECT id, name FROM car_makers`)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var name string
+		err = rows.Scan(&id, &name)
+		if err != nil {
+			return result, err
+		}
+		makers[strings.ToLower(name)] = id
+	}
+
+	if err = rows.Err(); err != nil {
+		return result, err
+	}
+
+	carModels := make(map[catalogModelKey]bool)
+	rows, err = tx.Query(`SELECT car_maker_id, name FROM car_models`)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key catalogModelKey
+		err = rows.Scan(&key.makerId, &key.name)
+		if err != nil {
+			return result, err
+		}
+		key.name = strings.ToLower(key.name)
+		carModels[key] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return result, err
+	}
+
+	for _, entry := range entries {
+		makerId, ok := makers[strings.ToLower(entry.Maker)]
+		if !ok {
+			err = tx.QueryRow(`INSERT INTO car_makers (name) VALUES ($1) RETURNING id`, entry.Maker).Scan(&makerId)
+			if err != nil {
+				return result, err
+			}
+			makers[strings.ToLower(entry.Maker)] = makerId
+			result.MakersCreated++
+		}
+
+		if entry.Model == "" {
+			continue
+		}
+
+		key := catalogModelKey{makerId: makerId, name: strings.ToLower(entry.Model)}
+		if carModels[key] {
+			continue
+		}
+
+		_, err = tx.Exec(`INSERT INTO car_models (car_maker_id, name) VALUES ($1, $2)`, makerId, entry.Model)
+		if err != nil {
+			return result, err
+		}
+		carModels[key] = true
+		result.ModelsCreated++
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
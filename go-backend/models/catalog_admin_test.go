@@ -0,0 +1,258 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertMaker_Duplicate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT exists \(SELECT 1 FROM car_makers WHERE LOWER\(name\)=LOWER\(\$1\)\)`).WithArgs("skoda").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.InsertMaker("skoda")
+
+	assert.ErrorIs(t, err, models.ErrDuplicateName)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertMaker_ConcurrentDuplicate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT exists \(SELECT 1 FROM car_makers`).WithArgs("Skoda").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery(`INSERT INTO car_makers \(name\) VALUES \(\$1\) RETURNING id`).WithArgs("Skoda").
+		WillReturnError(&pq.Error{Code: "23505", Message: "duplicate key value violates unique constraint"})
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.InsertMaker("Skoda")
+
+	assert.ErrorIs(t, err, models.ErrDuplicateName)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRenameModel_ConcurrentDuplicate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT exists \(SELECT 1 FROM car_models o`).WithArgs("Octavia", 12).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec(`UPDATE car_models SET name=\$1 WHERE id=\$2`).WithArgs("Octavia", 12).
+		WillReturnError(&pq.Error{Code: "23505", Message: "duplicate key value violates unique constraint"})
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.RenameModel(12, "Octavia")
+
+	assert.ErrorIs(t, err, models.ErrDuplicateName)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertModel_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT 1 FROM car_makers WHERE id=\$1`).WithArgs(28).WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	mock.ExpectQuery(`SELECT exists \(SELECT 1 FROM car_models WHERE car_maker_id=\$1`).WithArgs(28, "ID.3").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery(`INSERT INTO car_models \(car_maker_id, name\) VALUES \(\$1, \$2\) RETURNING id`).WithArgs(28, "ID.3").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(239))
+
+	modelsDB := models.NewModels(db)
+	model, err := modelsDB.DB.InsertModel(28, "ID.3")
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.CarModel{ID: 239, CarMakerID: 28, Name: "ID.3"}, model)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertModel_MakerNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT 1 FROM car_makers WHERE id=\$1`).WithArgs(99).WillReturnRows(sqlmock.NewRows([]string{"1"}))
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.InsertModel(99, "ID.3")
+
+	assert.ErrorIs(t, err, models.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRenameModel_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT exists \(SELECT 1 FROM car_models o JOIN car_models m`).WithArgs("Golf", 226).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec(`UPDATE car_models SET name=\$1 WHERE id=\$2`).WithArgs("Golf", 226).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.RenameModel(226, "Golf")
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRetireMaker_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE car_makers SET retired_at=COALESCE\(retired_at, CURRENT_TIMESTAMP\) WHERE id=\$1`).WithArgs(99).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.RetireMaker(99)
+
+	assert.ErrorIs(t, err, models.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMergeModel_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT car_maker_id FROM car_models WHERE id=\$1 AND retired_at IS NULL`).WithArgs(226).
+		WillReturnRows(sqlmock.NewRows([]string{"car_maker_id"}).AddRow(28))
+	mock.ExpectExec(`UPDATE users_cars SET model_id=\$1, brand_id=\$2 WHERE model_id=\$3`).WithArgs(226, 28, 240).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`UPDATE car_generations SET car_model_id=\$1 WHERE car_model_id=\$2`).WithArgs(226, 240).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM service_schedule_templates s WHERE s.car_model_id=\$2 AND EXISTS (.+) t.fuel_type IS NOT DISTINCT FROM s.fuel_type`).WithArgs(226, 240).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE service_schedule_templates SET car_model_id=\$1 WHERE car_model_id=\$2`).WithArgs(226, 240).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE car_models SET retired_at=(.+) WHERE id=\$1`).WithArgs(240).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.MergeModel(240, 226)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMergeMaker_MergesModelsWithTheSameName(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT 1 FROM car_makers WHERE id=\$1 AND retired_at IS NULL`).WithArgs(28).WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	mock.ExpectQuery(`SELECT s.id, t.id FROM car_models s JOIN car_models t`).WithArgs(28, 30).
+		WillReturnRows(sqlmock.NewRows([]string{"s_id", "t_id"}).AddRow(240, 226))
+	mock.ExpectExec(`UPDATE users_cars SET model_id=\$1, brand_id=\$2 WHERE model_id=\$3`).WithArgs(226, 28, 240).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE car_generations`).WithArgs(226, 240).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM service_schedule_templates`).WithArgs(226, 240).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE service_schedule_templates`).WithArgs(226, 240).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE car_models SET retired_at=(.+) WHERE id=\$1`).WithArgs(240).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE car_models s SET car_maker_id=\$1 WHERE s.car_maker_id=\$2 AND NOT EXISTS`).WithArgs(28, 30).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(`UPDATE users_cars SET brand_id=\$1 WHERE brand_id=\$2`).WithArgs(28, 30).WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec(`UPDATE car_makers SET retired_at=(.+) WHERE id=\$1`).WithArgs(30).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.MergeMaker(30, 28)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMergeMaker_RetiredTarget(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT 1 FROM car_makers WHERE id=\$1 AND retired_at IS NULL`).WithArgs(30).WillReturnRows(sqlmock.NewRows([]string{"1"}))
+	mock.ExpectRollback()
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.MergeMaker(28, 30)
+
+	assert.ErrorIs(t, err, models.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportCatalog_OnlyAddsMissingEntries(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, name FROM car_makers`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(28, "Volkswagen"))
+	mock.ExpectQuery(`SELECT car_maker_id, name FROM car_models`).
+		WillReturnRows(sqlmock.NewRows([]string{"car_maker_id", "name"}).AddRow(28, "Golf"))
+	mock.ExpectExec(`INSERT INTO car_models \(car_maker_id, name\) VALUES \(\$1, \$2\)`).WithArgs(28, "Jetta").WillReturnResult(sqlmock.NewResult(239, 1))
+	mock.ExpectQuery(`INSERT INTO car_makers \(name\) VALUES \(\$1\) RETURNING id`).WithArgs("Lada").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(30))
+	mock.ExpectExec(`INSERT INTO car_models \(car_maker_id, name\) VALUES \(\$1, \$2\)`).WithArgs(30, "Niva").WillReturnResult(sqlmock.NewResult(240, 1))
+	mock.ExpectCommit()
+
+	modelsDB := models.NewModels(db)
+	result, err := modelsDB.DB.ImportCatalog([]models.CatalogEntry{
+		{Maker: "VOLKSWAGEN", Model: "GOLF"},
+		{Maker: "VOLKSWAGEN", Model: "Jetta"},
+		{Maker: "Lada", Model: "Niva"},
+		{Maker: "LADA"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.CatalogImportResult{Rows: 4, MakersCreated: 1, ModelsCreated: 2}, result)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIsAdmin(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_admin FROM users WHERE id=\$1`).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"is_admin"}).AddRow(true))
+	mock.ExpectQuery(`SELECT is_admin FROM users WHERE id=\$1`).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"is_admin"}))
+
+	modelsDB := models.NewModels(db)
+	isAdmin, err := modelsDB.DB.IsAdmin(1)
+	assert.NoError(t, err)
+	assert.True(t, isAdmin)
+
+	isAdmin, err = modelsDB.DB.IsAdmin(2)
+	assert.NoError(t, err)
+	assert.False(t, isAdmin)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
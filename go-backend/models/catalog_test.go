@@ -0,0 +1,79 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var variantRowColumns = []string{"id", "car_generation_id", "car_model_id", "name", "body_type", "engine_id", "engine_name", "fuel_type", "displacement_cc", "power_kw"}
+
+func TestGetGenerationsByModelID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "car_model_id", "name", "year_from", "year_to"}).
+		AddRow(3, 226, "Golf VII", 2012, 2020).
+		AddRow(4, 226, "Golf VIII", 2019, nil)
+	mock.ExpectQuery(`SELECT (.+) FROM car_generations WHERE car_model_id=\$1`).WithArgs(226).WillReturnRows(rows)
+
+	modelsDB := models.NewModels(db)
+	generations, err := modelsDB.DB.GetGenerationsByModelID(226)
+
+	assert.NoError(t, err)
+	assert.Len(t, generations, 2)
+	assert.Equal(t, 2020, *generations[0].YearTo)
+	assert.Nil(t, generations[1].YearTo)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetVariantsByGenerationID_Filter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows(variantRowColumns).
+		AddRow(4, 3, 226, "2.0 TDI Highline", "hatchback", 3, "2.0 TDI", "diesel", 1968, 110)
+	mock.ExpectQuery(`SELECT (.+) FROM car_variants v (.+) WHERE v.car_generation_id=\$1 AND e.fuel_type = \$2 AND v.body_type = \$3 ORDER BY`).
+		WithArgs(3, "diesel", "hatchback").WillReturnRows(rows)
+
+	modelsDB := models.NewModels(db)
+	variants, err := modelsDB.DB.GetVariantsByGenerationID(3, models.VariantFilter{FuelType: "diesel", BodyType: "hatchback"})
+
+	assert.NoError(t, err)
+	assert.Len(t, variants, 1)
+	assert.Equal(t, 226, variants[0].ModelID)
+	assert.Equal(t, "diesel", variants[0].Engine.FuelType)
+	assert.Equal(t, 1968, *variants[0].Engine.DisplacementCC)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetVariantByID_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT (.+) WHERE v.id=\$1`).WithArgs(99).WillReturnRows(sqlmock.NewRows(variantRowColumns))
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.GetVariantByID(99)
+
+	assert.ErrorIs(t, err, models.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIsFuelType(t *testing.T) {
+	assert.True(t, models.IsFuelType("electric"))
+	assert.False(t, models.IsFuelType("steam"))
+	assert.True(t, models.IsBodyType("wagon"))
+	assert.False(t, models.IsBodyType("tank"))
+}
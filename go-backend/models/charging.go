@@ -0,0 +1,201 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Kinds of chargers
+const (
+	ChargerAC = "ac"
+	ChargerDC = "dc"
+)
+
+var ChargerTypes = []string{ChargerAC, ChargerDC}
+
+// Where a car was charged
+const (
+	ChargingHome   = "home"
+	ChargingWork   = "work"
+	ChargingPublic = "public"
+	ChargingOther  = "other"
+)
+
+var ChargingLocations = []string{ChargingHome, ChargingWork, ChargingPublic, ChargingOther}
+
+// A charging session of an electric or plug-in hybrid car. Energy is the kWh
+// delivered by the charger and the state of charge is in percent.
+type ChargingSession struct {
+	ID           int       `json:"id"`
+	CarID        int       `json:"car_id"`
+	ChargeDate   time.Time `json:"charge_date"`
+	Odometer     int       `json:"odometer"`
+	EnergyKWh    float64   `json:"energy_kwh"`
+	Cost         float64   `json:"cost"`
+	ChargerType  string    `json:"charger_type"`
+	LocationType string    `json:"location_type"`
+	StartSoC     *int      `json:"start_soc,omitempty"`
+	EndSoC       *int      `json:"end_soc,omitempty"`
+	Notes        string    `json:"notes,omitempty"`
+	CreatedAt    string    `json:"created_at"`
+}
+
+func IsChargerType(chargerType string) bool {
+	for _, c := range ChargerTypes {
+		if c == chargerType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func IsChargingLocation(location string) bool {
+	for _, l := range ChargingLocations {
+		if l == location {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Checks the fields a client has to provide for a charging session
+func (s *ChargingSession) Validate() error {
+	s.ChargerType = strings.ToLower(strings.TrimSpace(s.ChargerType))
+	s.LocationType = strings.ToLower(strings.TrimSpace(s.LocationType))
+	if s.LocationType == "" {
+		s.LocationType = ChargingOther
+	}
+
+	if s.ChargeDate.IsZero() {
+		return errors.New("charge date is required")
+	}
+
+	if s.Odometer <= 0 {
+		return errors.New("odometer is required")
+	}
+
+	if s.EnergyKWh <= 0 {
+		return errors.New("energy must be positive")
+	}
+
+	if s.Cost < 0 {
+		return errors.New("cost must not be negative")
+	}
+
+	if !IsChargerType(s.ChargerType) {
+		return errors.New("charger type must be ac or dc")
+	}
+
+	if !IsChargingLocation(s.LocationType) {
+		return fmt.Errorf("location type must be one of %s", strings.Join(ChargingLocations, ", "))
+	}
+
+	for _, soc := range []*int{s.StartSoC, s.EndSoC} {
+		if soc != nil && (*soc < 0 || *soc > 100) {
+			return errors.New("state of charge must be between 0 and 100")
+		}
+	}
+
+	if s.StartSoC != nil && s.EndSoC != nil && *s.StartSoC > *s.EndSoC {
+		return errors.New("state of charge at the end must not be lower than at the start")
+	}
+
+	return nil
+}
+
+func (m *DBModel) InsertChargingSession(s ChargingSession) (int, error) {
+	stmt := `INSERT INTO charging_sessions (car_id, charge_date, odometer, energy_kwh, cost, charger_type, location_type, start_soc, end_soc, notes) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`
+
+	var id int
+	err := m.DB.QueryRow(stmt, s.CarID, s.ChargeDate, s.Odometer, s.EnergyKWh, s.Cost, s.ChargerType, s.LocationType, s.StartSoC, s.EndSoC, s.Notes).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (m *DBModel) GetChargingSessionByID(carId, sessionId int) (ChargingSession, error) {
+	var s ChargingSession
+	stmt := `SELECT id, car_id, charge_date, odometer, energy_kwh, cost, charger_type, location_type, start_soc, end_soc, notes, created_at FROM charging_sessions WHERE id=$1 AND car_id=$2`
+
+	row := m.DB.QueryRow(stmt, sessionId, carId)
+	err := row.Scan(&s.ID, &s.CarID, &s.ChargeDate, &s.Odometer, &s.EnergyKWh, &s.Cost, &s.ChargerType, &s.LocationType, &s.StartSoC, &s.EndSoC, &s.Notes, &s.CreatedAt)
+	if err == sql.ErrNoRows {
+		return s, ErrRecordNotFound
+	} else if err != nil {
+		return s, err
+	}
+
+	return s, nil
+}
+
+// Returns the charging sessions of a car within the range in the order they were made
+func (m *DBModel) GetChargingSessionsByCarID(carId int, dates DateRange) ([]ChargingSession, error) {
+	stmt := `SELECT id, car_id, charge_date, odometer, energy_kwh, cost, charger_type, location_type, start_soc, end_soc, notes, created_at FROM charging_sessions WHERE car_id=$1`
+	args := []interface{}{carId}
+
+	if !dates.From.IsZero() {
+		args = append(args, dates.From)
+		stmt += fmt.Sprintf(" AND charge_date >= $%d", len(args))
+	}
+
+	if !dates.To.IsZero() {
+		args = append(args, dates.To)
+		stmt += fmt.Sprintf(" AND charge_date <= $%d", len(args))
+	}
+
+	stmt += " ORDER BY charge_date ASC, odometer ASC, id ASC"
+
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []ChargingSession
+
+	for rows.Next() {
+		var s ChargingSession
+
+		err := rows.Scan(&s.ID, &s.CarID, &s.ChargeDate, &s.Odometer, &s.EnergyKWh, &s.Cost, &s.ChargerType, &s.LocationType, &s.StartSoC, &s.EndSoC, &s.Notes, &s.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (m *DBModel) UpdateChargingSession(s ChargingSession) error {
+	stmt := `UPDATE charging_sessions SET charge_date=$1, odometer=$2, energy_kwh=$3, cost=$4, charger_type=$5, location_type=$6, start_soc=$7, end_soc=$8, notes=$9 WHERE id=$10 AND car_id=$11`
+
+	res, err := m.DB.Exec(stmt, s.ChargeDate, s.Odometer, s.EnergyKWh, s.Cost, s.ChargerType, s.LocationType, s.StartSoC, s.EndSoC, s.Notes, s.ID, s.CarID)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+func (m *DBModel) RemoveChargingSession(carId, sessionId int) error {
+	stmt := `DELETE FROM charging_sessions WHERE id=$1 AND car_id=$2`
+
+	res, err := m.DB.Exec(stmt, sessionId, carId)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
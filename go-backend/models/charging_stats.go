@@ -0,0 +1,112 @@
+package models
+
+import "sort"
+
+// Sessions, energy and cost of one kind of charger or location
+type ChargingBreakdown struct {
+	Sessions  int     `json:"sessions"`
+	EnergyKWh float64 `json:"energy_kwh"`
+	Cost      float64 `json:"cost"`
+}
+
+type ChargingStats struct {
+	Sessions       int      `json:"sessions"`
+	TotalEnergyKWh float64  `json:"total_energy_kwh"`
+	TotalCost      float64  `json:"total_cost"`
+	CostPerKWh     *float64 `json:"cost_per_kwh"`
+	// distance between the first and the last session
+	Distance      int                          `json:"distance"`
+	KWhPer100Km   *float64                     `json:"kwh_per_100km"`
+	CostPerKm     *float64                     `json:"cost_per_km"`
+	ByChargerType map[string]ChargingBreakdown `json:"by_charger_type"`
+	ByLocation    map[string]ChargingBreakdown `json:"by_location"`
+}
+
+// Computes the efficiency of an electric car from its charging sessions. The
+// energy charged after the first session was used over the distance up to the
+// last one. When the battery capacity is known and both sessions have a state
+// of charge at their end, the difference of the battery level is accounted for
+// as well. The energy is measured at the charger, so the efficiency includes
+// the charging losses. Only the sessions within dates are counted, the last
+// session before them only provides the starting point.
+func ComputeChargingStats(sessions []ChargingSession, dates DateRange, capacityKWh *float64) ChargingStats {
+	sorted := make([]ChargingSession, len(sessions))
+	copy(sorted, sessions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].ChargeDate.Equal(sorted[j].ChargeDate) {
+			return sorted[i].ChargeDate.Before(sorted[j].ChargeDate)
+		}
+		return sorted[i].Odometer < sorted[j].Odometer
+	})
+
+	stats := ChargingStats{
+		ByChargerType: map[string]ChargingBreakdown{},
+		ByLocation:    map[string]ChargingBreakdown{},
+	}
+
+	var anchor, last *ChargingSession
+	var energy, cost float64
+
+	for i := range sorted {
+		s := sorted[i]
+
+		if !dates.Contains(s.ChargeDate) {
+			// the sessions after the range are left out
+			if !dates.From.IsZero() && s.ChargeDate.Before(dates.From) {
+				anchor = &sorted[i]
+			}
+			continue
+		}
+
+		stats.Sessions++
+		stats.TotalEnergyKWh += s.EnergyKWh
+		stats.TotalCost += s.Cost
+		stats.ByChargerType[s.ChargerType] = addCharge(stats.ByChargerType[s.ChargerType], s)
+		stats.ByLocation[s.LocationType] = addCharge(stats.ByLocation[s.LocationType], s)
+
+		if anchor == nil {
+			anchor = &sorted[i]
+			continue
+		}
+
+		energy += s.EnergyKWh
+		cost += s.Cost
+		last = &sorted[i]
+	}
+
+	stats.TotalEnergyKWh = round(stats.TotalEnergyKWh, 2)
+	stats.TotalCost = round(stats.TotalCost, 2)
+
+	if stats.TotalEnergyKWh > 0 {
+		costPerKWh := round(stats.TotalCost/stats.TotalEnergyKWh, 3)
+		stats.CostPerKWh = &costPerKWh
+	}
+
+	if last == nil || last.Odometer <= anchor.Odometer {
+		return stats
+	}
+
+	stats.Distance = last.Odometer - anchor.Odometer
+
+	if capacityKWh != nil && anchor.EndSoC != nil && last.EndSoC != nil {
+		energy += float64(*anchor.EndSoC-*last.EndSoC) / 100 * *capacityKWh
+	}
+
+	if energy > 0 {
+		efficiency := round(energy/float64(stats.Distance)*100, 2)
+		costPerKm := round(cost/float64(stats.Distance), 3)
+
+		stats.KWhPer100Km = &efficiency
+		stats.CostPerKm = &costPerKm
+	}
+
+	return stats
+}
+
+func addCharge(b ChargingBreakdown, s ChargingSession) ChargingBreakdown {
+	b.Sessions++
+	b.EnergyKWh = round(b.EnergyKWh+s.EnergyKWh, 2)
+	b.Cost = round(b.Cost+s.Cost, 2)
+
+	return b
+}
@@ -0,0 +1,181 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var chargingSessionColumns = []string{"id", "car_id", "charge_date", "odometer", "energy_kwh", "cost", "charger_type", "location_type", "start_soc", "end_soc", "notes", "created_at"}
+
+func charge(day, odometer int, energy, cost float64, chargerType, location string) models.ChargingSession {
+	return models.ChargingSession{
+		ChargeDate:   time.Date(2023, 5, day, 0, 0, 0, 0, time.UTC),
+		Odometer:     odometer,
+		EnergyKWh:    energy,
+		Cost:         cost,
+		ChargerType:  chargerType,
+		LocationType: location,
+	}
+}
+
+func soc(value int) *int {
+	return &value
+}
+
+func TestChargingSessionValidate(t *testing.T) {
+	valid := charge(1, 10000, 30, 9, " DC ", "")
+	assert.NoError(t, valid.Validate())
+	assert.Equal(t, models.ChargerDC, valid.ChargerType)
+	assert.Equal(t, models.ChargingOther, valid.LocationType)
+
+	noDate := charge(1, 10000, 30, 9, "ac", "home")
+	noDate.ChargeDate = time.Time{}
+	assert.EqualError(t, noDate.Validate(), "charge date is required")
+
+	noEnergy := charge(1, 10000, 0, 9, "ac", "home")
+	assert.EqualError(t, noEnergy.Validate(), "energy must be positive")
+
+	badCharger := charge(1, 10000, 30, 9, "wireless", "home")
+	assert.EqualError(t, badCharger.Validate(), "charger type must be ac or dc")
+
+	badLocation := charge(1, 10000, 30, 9, "ac", "garage")
+	assert.EqualError(t, badLocation.Validate(), "location type must be one of home, work, public, other")
+
+	badSoC := charge(1, 10000, 30, 9, "ac", "home")
+	badSoC.EndSoC = soc(101)
+	assert.EqualError(t, badSoC.Validate(), "state of charge must be between 0 and 100")
+
+	reversedSoC := charge(1, 10000, 30, 9, "ac", "home")
+	reversedSoC.StartSoC = soc(80)
+	reversedSoC.EndSoC = soc(20)
+	assert.EqualError(t, reversedSoC.Validate(), "state of charge at the end must not be lower than at the start")
+}
+
+func TestInsertChargingSession_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	s := charge(1, 10000, 30, 9, "dc", "public")
+	s.CarID = 2
+	s.EndSoC = soc(80)
+	mock.ExpectQuery(`INSERT INTO charging_sessions`).WithArgs(
+		2, s.ChargeDate, 10000, 30.0, 9.0, "dc", "public", nil, s.EndSoC, "",
+	).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+	modelsDB := models.NewModels(db)
+	id, err := modelsDB.DB.InsertChargingSession(s)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetChargingSessionsByCarID_DateRange(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	from := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows(chargingSessionColumns).
+		AddRow(1, 2, time.Date(2023, 5, 2, 0, 0, 0, 0, time.UTC), 10000, 30.0, 9.0, "dc", "public", nil, 80, "", "2023-05-02 10:00:00")
+
+	mock.ExpectQuery(`FROM charging_sessions WHERE car_id=\$1 AND charge_date >= \$2 ORDER BY charge_date ASC`).WithArgs(2, from).WillReturnRows(rows)
+
+	modelsDB := models.NewModels(db)
+	sessions, err := modelsDB.DB.GetChargingSessionsByCarID(2, models.DateRange{From: from})
+
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	assert.Nil(t, sessions[0].StartSoC)
+	assert.Equal(t, 80, *sessions[0].EndSoC)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRemoveChargingSession_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`DELETE FROM charging_sessions WHERE id=(.+) AND car_id=`).WithArgs(5, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.RemoveChargingSession(2, 5)
+
+	assert.ErrorIs(t, err, models.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestComputeChargingStats(t *testing.T) {
+	sessions := []models.ChargingSession{
+		charge(1, 10000, 30, 9, "dc", "public"),
+		charge(10, 10200, 36, 7.2, "ac", "home"),
+		charge(20, 10500, 54, 10.8, "ac", "home"),
+	}
+
+	stats := models.ComputeChargingStats(sessions, models.DateRange{}, nil)
+
+	assert.Equal(t, 3, stats.Sessions)
+	assert.Equal(t, 120.0, stats.TotalEnergyKWh)
+	assert.Equal(t, 27.0, stats.TotalCost)
+	assert.Equal(t, 0.225, *stats.CostPerKWh)
+	assert.Equal(t, 500, stats.Distance)
+	// 90 kWh charged after the first session over 500 km
+	assert.Equal(t, 18.0, *stats.KWhPer100Km)
+	assert.Equal(t, 0.036, *stats.CostPerKm)
+	assert.Equal(t, models.ChargingBreakdown{Sessions: 2, EnergyKWh: 90, Cost: 18}, stats.ByChargerType["ac"])
+	assert.Equal(t, models.ChargingBreakdown{Sessions: 1, EnergyKWh: 30, Cost: 9}, stats.ByLocation["public"])
+}
+
+func TestComputeChargingStats_StateOfCharge(t *testing.T) {
+	sessions := []models.ChargingSession{
+		charge(1, 10000, 30, 9, "dc", "public"),
+		charge(10, 10200, 36, 7.2, "ac", "home"),
+		charge(20, 10500, 54, 10.8, "ac", "home"),
+	}
+	sessions[0].EndSoC = soc(80)
+	sessions[2].EndSoC = soc(70)
+	capacity := 60.0
+
+	stats := models.ComputeChargingStats(sessions, models.DateRange{}, &capacity)
+
+	// the battery ended 10 % lower, 6 kWh more were used
+	assert.Equal(t, 19.2, *stats.KWhPer100Km)
+}
+
+func TestComputeChargingStats_DateRange(t *testing.T) {
+	sessions := []models.ChargingSession{
+		charge(1, 10000, 30, 9, "dc", "public"),
+		charge(10, 10200, 36, 7.2, "ac", "home"),
+		charge(20, 10500, 54, 10.8, "ac", "home"),
+	}
+	dates := models.DateRange{From: time.Date(2023, 5, 5, 0, 0, 0, 0, time.UTC)}
+
+	stats := models.ComputeChargingStats(sessions, dates, nil)
+
+	// the session before the range is the starting point only
+	assert.Equal(t, 2, stats.Sessions)
+	assert.Equal(t, 90.0, stats.TotalEnergyKWh)
+	assert.Equal(t, 500, stats.Distance)
+	assert.Equal(t, 18.0, *stats.KWhPer100Km)
+	assert.NotContains(t, stats.ByChargerType, "dc")
+}
+
+func TestComputeChargingStats_SingleSession(t *testing.T) {
+	stats := models.ComputeChargingStats([]models.ChargingSession{charge(1, 10000, 30, 9, "dc", "public")}, models.DateRange{}, nil)
+
+	assert.Equal(t, 1, stats.Sessions)
+	assert.Equal(t, 0, stats.Distance)
+	assert.Nil(t, stats.KWhPer100Km)
+	assert.Nil(t, stats.CostPerKm)
+}
@@ -2,8 +2,12 @@ package models
 
 import (
 	"database/sql"
+	"errors"
 )
 
+// returned when an update or delete did not match any row
+var ErrRecordNotFound = errors.New("record not found")
+
 // wrapper for the database
 type Models struct {
 	DB DBModel
@@ -15,3 +19,17 @@ func NewModels(db *sql.DB) Models {
 		DB: DBModel{DB: db},
 	}
 }
+
+// turns an update or delete that touched no rows into ErrRecordNotFound
+func checkRowsAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,298 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kinds of vehicle documents
+const (
+	DocumentInspection   = "inspection"
+	DocumentEmissions    = "emissions"
+	DocumentRegistration = "registration"
+	DocumentInsurance    = "insurance"
+	DocumentVignette     = "vignette"
+	DocumentWarranty     = "warranty"
+)
+
+var DocumentTypes = []string{DocumentInspection, DocumentEmissions, DocumentRegistration, DocumentInsurance, DocumentVignette, DocumentWarranty}
+
+var documentTypeNames = map[string]string{
+	DocumentInspection:   "Technical inspection",
+	DocumentEmissions:    "Emissions test",
+	DocumentRegistration: "Registration",
+	DocumentInsurance:    "Insurance",
+	DocumentVignette:     "Road vignette",
+	DocumentWarranty:     "Extended warranty",
+}
+
+// A dated document of a car such as an insurance policy or the technical
+// inspection. Warranties may also end at a mileage limit.
+type VehicleDocument struct {
+	ID           int       `json:"id"`
+	CarID        int       `json:"car_id"`
+	DocumentType string    `json:"document_type"`
+	Issuer       string    `json:"issuer,omitempty"`
+	PolicyNumber string    `json:"policy_number,omitempty"`
+	ValidFrom    time.Time `json:"valid_from"`
+	ValidUntil   time.Time `json:"valid_until"`
+	MileageLimit *int      `json:"mileage_limit,omitempty"`
+	Notes        string    `json:"notes,omitempty"`
+	CreatedAt    string    `json:"created_at"`
+}
+
+func IsDocumentType(documentType string) bool {
+	for _, d := range DocumentTypes {
+		if d == documentType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the human readable name of a document type
+func DocumentTypeName(documentType string) string {
+	if name, ok := documentTypeNames[documentType]; ok {
+		return name
+	}
+
+	return documentType
+}
+
+// Checks the fields a client has to provide for a document
+func (d *VehicleDocument) Validate() error {
+	d.DocumentType = strings.ToLower(strings.TrimSpace(d.DocumentType))
+	d.Issuer = strings.TrimSpace(d.Issuer)
+	d.PolicyNumber = strings.TrimSpace(d.PolicyNumber)
+
+	if !IsDocumentType(d.DocumentType) {
+		return fmt.Errorf("document type must be one of %s", strings.Join(DocumentTypes, ", "))
+	}
+
+	if len(d.Issuer) > 100 || len(d.PolicyNumber) > 100 {
+		return errors.New("issuer and policy number must not be longer than 100 characters")
+	}
+
+	if d.ValidFrom.IsZero() || d.ValidUntil.IsZero() {
+		return errors.New("validity start and end are required")
+	}
+
+	if d.ValidUntil.Before(d.ValidFrom) {
+		return errors.New("validity must not end before it starts")
+	}
+
+	if d.MileageLimit != nil {
+		if d.DocumentType != DocumentWarranty {
+			return errors.New("mileage limit only applies to warranties")
+		}
+		if *d.MileageLimit <= 0 {
+			return errors.New("mileage limit must be positive")
+		}
+	}
+
+	return nil
+}
+
+func (m *DBModel) InsertVehicleDocument(d VehicleDocument) (int, error) {
+	stmt := `INSERT INTO vehicle_documents (car_id, document_type, issuer, policy_number, valid_from, valid_until, mileage_limit, notes) VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+
+	var id int
+	err := m.DB.QueryRow(stmt, d.CarID, d.DocumentType, d.Issuer, d.PolicyNumber, d.ValidFrom, d.ValidUntil, d.MileageLimit, d.Notes).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (m *DBModel) GetVehicleDocumentByID(carId, documentId int) (VehicleDocument, error) {
+	var d VehicleDocument
+	stmt := `SELECT id, car_id, document_type, issuer, policy_number, valid_from, valid_until, mileage_limit, notes, created_at FROM vehicle_documents WHERE id=$1 AND car_id=$2`
+
+	row := m.DB.QueryRow(stmt, documentId, carId)
+	err := row.Scan(&d.ID, &d.CarID, &d.DocumentType, &d.Issuer, &d.PolicyNumber, &d.ValidFrom, &d.ValidUntil, &d.MileageLimit, &d.Notes, &d.CreatedAt)
+	if err == sql.ErrNoRows {
+		return d, ErrRecordNotFound
+	} else if err != nil {
+		return d, err
+	}
+
+	return d, nil
+}
+
+// Returns the documents of a car, those valid the longest first
+func (m *DBModel) GetVehicleDocumentsByCarID(carId int) ([]VehicleDocument, error) {
+	stmt := `SELECT id, car_id, document_type, issuer, policy_number, valid_from, valid_until, mileage_limit, notes, created_at FROM vehicle_documents WHERE car_id=$1 ORDER BY valid_until DESC, id DESC`
+
+	return m.queryVehicleDocuments(stmt, carId)
+}
+
+// Returns the current document of each type of a car, the one valid the
+// longest. A renewed document supersedes the previous one of its type.
+func (m *DBModel) GetCurrentVehicleDocumentsByCarID(carId int) ([]VehicleDocument, error) {
+	stmt := `SELECT DISTINCT ON (document_type) id, car_id, document_type, issuer, policy_number, valid_from, valid_until, mileage_limit, notes, created_at
+		FROM vehicle_documents WHERE car_id=$1 ORDER BY document_type, valid_until DESC, id DESC`
+
+	return m.queryVehicleDocuments(stmt, carId)
+}
+
+func (m *DBModel) queryVehicleDocuments(stmt string, args ...interface{}) ([]VehicleDocument, error) {
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var documents []VehicleDocument
+
+	for rows.Next() {
+		var d VehicleDocument
+
+		err := rows.Scan(&d.ID, &d.CarID, &d.DocumentType, &d.Issuer, &d.PolicyNumber, &d.ValidFrom, &d.ValidUntil, &d.MileageLimit, &d.Notes, &d.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		documents = append(documents, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return documents, nil
+}
+
+func (m *DBModel) UpdateVehicleDocument(d VehicleDocument) error {
+	stmt := `UPDATE vehicle_documents SET document_type=$1, issuer=$2, policy_number=$3, valid_from=$4, valid_until=$5, mileage_limit=$6, notes=$7 WHERE id=$8 AND car_id=$9`
+
+	res, err := m.DB.Exec(stmt, d.DocumentType, d.Issuer, d.PolicyNumber, d.ValidFrom, d.ValidUntil, d.MileageLimit, d.Notes, d.ID, d.CarID)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+func (m *DBModel) RemoveVehicleDocument(carId, documentId int) error {
+	stmt := `DELETE FROM vehicle_documents WHERE id=$1 AND car_id=$2`
+
+	res, err := m.DB.Exec(stmt, documentId, carId)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// When a document lapses. The status uses the due statuses of the services:
+// overdue once the document has expired, due soon within the notice period or
+// the last 1000 km of a warranty's mileage limit.
+type DocumentExpiry struct {
+	VehicleDocument
+	DaysLeft int    `json:"days_left"`
+	KmLeft   *int   `json:"km_left,omitempty"`
+	Status   string `json:"status"`
+}
+
+func ComputeDocumentExpiry(d VehicleDocument, currentOdometer int, now time.Time, noticeDays int) DocumentExpiry {
+	expiry := DocumentExpiry{
+		VehicleDocument: d,
+		DaysLeft:        int(math.Round(truncateToDay(d.ValidUntil).Sub(truncateToDay(now)).Hours() / 24)),
+	}
+
+	if d.MileageLimit != nil {
+		kmLeft := *d.MileageLimit - currentOdometer
+		expiry.KmLeft = &kmLeft
+	}
+
+	switch {
+	case expiry.DaysLeft < 0 || (expiry.KmLeft != nil && *expiry.KmLeft < 0):
+		expiry.Status = DueStatusOverdue
+	case expiry.DaysLeft <= noticeDays || (expiry.KmLeft != nil && *expiry.KmLeft <= dueSoonKm):
+		expiry.Status = DueStatusDueSoon
+	default:
+		expiry.Status = DueStatusUpcoming
+	}
+
+	return expiry
+}
+
+// Sorts the documents lapsing first to the front
+func SortDocumentExpiries(expiries []DocumentExpiry) {
+	sort.SliceStable(expiries, func(i, j int) bool {
+		return expiries[i].DaysLeft < expiries[j].DaysLeft
+	})
+}
+
+// Returns the expiry of the current documents of a car, sorted by how soon they
+// lapse. noticeDays is how long before its end a document is due soon.
+func (m *DBModel) GetDocumentExpiriesByCarID(carId int, now time.Time, noticeDays int) ([]DocumentExpiry, error) {
+	documents, err := m.GetCurrentVehicleDocumentsByCarID(carId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(documents) == 0 {
+		return nil, nil
+	}
+
+	odometer, err := m.GetCurrentOdometer(carId)
+	if err != nil {
+		return nil, err
+	}
+
+	var expiries []DocumentExpiry
+	for _, d := range documents {
+		expiries = append(expiries, ComputeDocumentExpiry(d, odometer, now, noticeDays))
+	}
+
+	SortDocumentExpiries(expiries)
+
+	return expiries, nil
+}
+
+// Returns the expiry of the current documents of every car the user may access,
+// the fleet cars of the user's organizations included, in a single query.
+// Archived cars are left out.
+func (m *DBModel) GetDocumentExpiriesByUserID(userId int, now time.Time, noticeDays int) ([]DocumentExpiry, error) {
+	stmt := `SELECT DISTINCT ON (d.car_id, d.document_type) d.id, d.car_id, d.document_type, d.issuer, d.policy_number, d.valid_from, d.valid_until, d.mileage_limit, d.notes, d.created_at,
+			COALESCE((` + fmt.Sprintf(currentOdometerQuery, "c.id") + `), 0)
+		FROM vehicle_documents d JOIN users_cars c ON c.id = d.car_id
+		WHERE ` + fmt.Sprintf(userCarsFilter, "$1") + ` AND c.deleted_at IS NULL AND c.archived_at IS NULL
+		ORDER BY d.car_id, d.document_type, d.valid_until DESC, d.id DESC`
+
+	rows, err := m.DB.Query(stmt, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var expiries []DocumentExpiry
+
+	for rows.Next() {
+		var d VehicleDocument
+		var odometer int
+
+		err := rows.Scan(&d.ID, &d.CarID, &d.DocumentType, &d.Issuer, &d.PolicyNumber, &d.ValidFrom, &d.ValidUntil, &d.MileageLimit, &d.Notes, &d.CreatedAt, &odometer)
+		if err != nil {
+			return nil, err
+		}
+
+		expiries = append(expiries, ComputeDocumentExpiry(d, odometer, now, noticeDays))
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	SortDocumentExpiries(expiries)
+
+	return expiries, nil
+}
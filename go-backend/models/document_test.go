@@ -0,0 +1,122 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var vehicleDocumentColumns = []string{"id", "car_id", "document_type", "issuer", "policy_number", "valid_from", "valid_until", "mileage_limit", "notes", "created_at"}
+
+func vehicleDocument(documentType string, validUntil time.Time) models.VehicleDocument {
+	return models.VehicleDocument{
+		CarID:        2,
+		DocumentType: documentType,
+		ValidFrom:    validUntil.AddDate(-1, 0, 0),
+		ValidUntil:   validUntil,
+	}
+}
+
+func TestVehicleDocumentValidate(t *testing.T) {
+	validUntil := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	valid := vehicleDocument(" Insurance ", validUntil)
+	assert.NoError(t, valid.Validate())
+	assert.Equal(t, models.DocumentInsurance, valid.DocumentType)
+
+	badType := vehicleDocument("passport", validUntil)
+	assert.EqualError(t, badType.Validate(), "document type must be one of inspection, emissions, registration, insurance, vignette, warranty")
+
+	noEnd := vehicleDocument("insurance", validUntil)
+	noEnd.ValidUntil = time.Time{}
+	assert.EqualError(t, noEnd.Validate(), "validity start and end are required")
+
+	reversed := vehicleDocument("insurance", validUntil)
+	reversed.ValidFrom = validUntil.AddDate(0, 0, 1)
+	assert.EqualError(t, reversed.Validate(), "validity must not end before it starts")
+
+	limit := 150000
+	insuranceLimit := vehicleDocument("insurance", validUntil)
+	insuranceLimit.MileageLimit = &limit
+	assert.EqualError(t, insuranceLimit.Validate(), "mileage limit only applies to warranties")
+
+	warranty := vehicleDocument("warranty", validUntil)
+	warranty.MileageLimit = &limit
+	assert.NoError(t, warranty.Validate())
+}
+
+func TestComputeDocumentExpiry(t *testing.T) {
+	now := time.Date(2023, 6, 1, 15, 0, 0, 0, time.UTC)
+
+	expired := models.ComputeDocumentExpiry(vehicleDocument("inspection", time.Date(2023, 5, 31, 0, 0, 0, 0, time.UTC)), 0, now, 30)
+	assert.Equal(t, -1, expired.DaysLeft)
+	assert.Equal(t, models.DueStatusOverdue, expired.Status)
+
+	soon := models.ComputeDocumentExpiry(vehicleDocument("insurance", time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)), 0, now, 30)
+	assert.Equal(t, 30, soon.DaysLeft)
+	assert.Equal(t, models.DueStatusDueSoon, soon.Status)
+
+	upcoming := models.ComputeDocumentExpiry(vehicleDocument("insurance", time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)), 0, now, 14)
+	assert.Equal(t, models.DueStatusUpcoming, upcoming.Status)
+
+	// a warranty ends at its mileage limit even when it is valid for years
+	limit := 150000
+	warranty := vehicleDocument("warranty", time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC))
+	warranty.MileageLimit = &limit
+	byDistance := models.ComputeDocumentExpiry(warranty, 149500, now, 30)
+	assert.Equal(t, 500, *byDistance.KmLeft)
+	assert.Equal(t, models.DueStatusDueSoon, byDistance.Status)
+}
+
+func TestGetDocumentExpiriesByCarID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows(vehicleDocumentColumns).
+		AddRow(1, 2, "insurance", "Allianz", "P-123", time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC), nil, "", "2022-07-01 10:00:00").
+		AddRow(2, 2, "inspection", "", "", time.Date(2021, 6, 10, 0, 0, 0, 0, time.UTC), time.Date(2023, 6, 10, 0, 0, 0, 0, time.UTC), nil, "", "2021-06-10 10:00:00")
+
+	mock.ExpectQuery(`SELECT DISTINCT ON \(document_type\) (.+) FROM vehicle_documents WHERE car_id=`).WithArgs(2).WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT COALESCE`).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"odometer"}).AddRow(45000))
+
+	modelsDB := models.NewModels(db)
+	expiries, err := modelsDB.DB.GetDocumentExpiriesByCarID(2, time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), 30)
+
+	assert.NoError(t, err)
+	assert.Len(t, expiries, 2)
+	assert.Equal(t, models.DocumentInspection, expiries[0].DocumentType)
+	assert.Equal(t, 9, expiries[0].DaysLeft)
+	assert.Equal(t, 30, expiries[1].DaysLeft)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetDocumentExpiriesByUserID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows(append(vehicleDocumentColumns, "odometer")).
+		AddRow(1, 2, "insurance", "Allianz", "P-123", time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC), nil, "", "2022-07-01 10:00:00", 45000).
+		AddRow(2, 3, "warranty", "", "", time.Date(2021, 6, 10, 0, 0, 0, 0, time.UTC), time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC), 100000, "", "2021-06-10 10:00:00", 99500)
+
+	mock.ExpectQuery(`SELECT DISTINCT ON \(d.car_id, d.document_type\) (.+) FROM vehicle_documents d JOIN users_cars c (.+) c.organization_id IN \(SELECT organization_id FROM organization_members WHERE user_id=\$1\)`).
+		WithArgs(5).WillReturnRows(rows)
+
+	modelsDB := models.NewModels(db)
+	expiries, err := modelsDB.DB.GetDocumentExpiriesByUserID(5, time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), 30)
+
+	assert.NoError(t, err)
+	assert.Len(t, expiries, 2)
+	assert.Equal(t, 30, expiries[0].DaysLeft)
+	assert.Equal(t, models.DueStatusDueSoon, expiries[1].Status)
+	assert.Equal(t, 500, *expiries[1].KmLeft)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
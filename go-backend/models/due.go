@@ -0,0 +1,171 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	DueStatusOverdue  = "overdue"
+	DueStatusDueSoon  = "due_soon"
+	DueStatusUpcoming = "upcoming"
+
+	// a service is "due soon" when it is closer than either threshold
+	dueSoonDays = 30
+	dueSoonKm   = 1000
+)
+
+// The next occurrence of a service schedule
+type DueService struct {
+	ScheduleID      int        `json:"schedule_id"`
+	CarID           int        `json:"car_id"`
+	ServiceType     string     `json:"service_type"`
+	LastServiceDate time.Time  `json:"last_service_date"`
+	LastOdometer    int        `json:"last_odometer"`
+	DueDate         *time.Time `json:"due_date,omitempty"`
+	DueOdometer     *int       `json:"due_odometer,omitempty"`
+	DaysLeft        *int       `json:"days_left,omitempty"`
+	KmLeft          *int       `json:"km_left,omitempty"`
+	Status          string     `json:"status"`
+	// share of the interval that is left, negative when overdue
+	Urgency float64 `json:"urgency"`
+}
+
+// Computes when a schedule is due next, whichever of the time and distance
+// interval comes first. last is the latest matching maintenance record, if any.
+func ComputeDueService(s ServiceSchedule, last *MaintenanceRecord, currentOdometer int, now time.Time) DueService {
+	due := DueService{
+		ScheduleID:      s.ID,
+		CarID:           s.CarID,
+		ServiceType:     s.ServiceType,
+		LastServiceDate: s.StartDate,
+		LastOdometer:    s.StartOdometer,
+		Urgency:         math.Inf(1),
+	}
+
+	if last != nil && !last.ServiceDate.Before(s.StartDate) {
+		due.LastServiceDate = last.ServiceDate
+		due.LastOdometer = last.Odometer
+	}
+
+	today := truncateToDay(now)
+	soon := false
+
+	if s.IntervalMonths > 0 {
+		dueDate := truncateToDay(due.LastServiceDate).AddDate(0, s.IntervalMonths, 0)
+		daysLeft := int(math.Round(dueDate.Sub(today).Hours() / 24))
+		intervalDays := dueDate.Sub(truncateToDay(due.LastServiceDate)).Hours() / 24
+
+		due.DueDate = &dueDate
+		due.DaysLeft = &daysLeft
+		due.Urgency = math.Min(due.Urgency, float64(daysLeft)/intervalDays)
+		soon = soon || daysLeft <= dueSoonDays
+	}
+
+	if s.IntervalKm > 0 {
+		dueOdometer := due.LastOdometer + s.IntervalKm
+		kmLeft := dueOdometer - currentOdometer
+
+		due.DueOdometer = &dueOdometer
+		due.KmLeft = &kmLeft
+		due.Urgency = math.Min(due.Urgency, float64(kmLeft)/float64(s.IntervalKm))
+		soon = soon || kmLeft <= dueSoonKm
+	}
+
+	switch {
+	case (due.DaysLeft != nil && *due.DaysLeft < 0) || (due.KmLeft != nil && *due.KmLeft < 0):
+		due.Status = DueStatusOverdue
+	case soon:
+		due.Status = DueStatusDueSoon
+	default:
+		due.Status = DueStatusUpcoming
+	}
+
+	// a schedule without any interval never becomes due
+	if math.IsInf(due.Urgency, 1) {
+		due.Urgency = math.MaxFloat64
+	}
+
+	return due
+}
+
+// Sorts the most urgent services first
+func SortDueServices(services []DueService) {
+	sort.SliceStable(services, func(i, j int) bool {
+		return services[i].Urgency < services[j].Urgency
+	})
+}
+
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Returns the latest maintenance record of every service type of a car,
+// keyed by the lower case service type
+func (m *DBModel) GetLatestMaintenanceByType(carId int) (map[string]MaintenanceRecord, error) {
+	stmt := `SELECT DISTINCT ON (LOWER(service_type)) id, car_id, service_date, odometer, service_type, description, cost, performed_by, notes, created_at
+		FROM maintenance WHERE car_id=$1 AND deleted_at IS NULL ORDER BY LOWER(service_type), service_date DESC, id DESC`
+
+	rows, err := m.DB.Query(stmt, carId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	latest := make(map[string]MaintenanceRecord)
+
+	for rows.Next() {
+		var rec MaintenanceRecord
+
+		err := rows.Scan(&rec.ID, &rec.CarID, &rec.ServiceDate, &rec.Odometer, &rec.ServiceType, &rec.Description, &rec.Cost, &rec.PerformedBy, &rec.Notes, &rec.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		latest[strings.ToLower(rec.ServiceType)] = rec
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return latest, nil
+}
+
+// Returns the upcoming and overdue services of a car, most urgent first
+func (m *DBModel) GetDueServicesByCarID(carId int, now time.Time) ([]DueService, error) {
+	schedules, err := m.GetServiceSchedulesByCarID(carId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(schedules) == 0 {
+		return nil, nil
+	}
+
+	latest, err := m.GetLatestMaintenanceByType(carId)
+	if err != nil {
+		return nil, err
+	}
+
+	odometer, err := m.GetCurrentOdometer(carId)
+	if err != nil {
+		return nil, err
+	}
+
+	var services []DueService
+	for _, s := range schedules {
+		var last *MaintenanceRecord
+		if rec, ok := latest[strings.ToLower(s.ServiceType)]; ok {
+			last = &rec
+		}
+
+		services = append(services, ComputeDueService(s, last, odometer, now))
+	}
+
+	SortDueServices(services)
+
+	return services, nil
+}
@@ -0,0 +1,126 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeDueService_UsesBaselineWithoutRecord(t *testing.T) {
+	s := testServiceSchedule()
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	due := models.ComputeDueService(s, nil, 35000, now)
+
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), *due.DueDate)
+	assert.Equal(t, 45000, *due.DueOdometer)
+	assert.Equal(t, 214, *due.DaysLeft)
+	assert.Equal(t, 10000, *due.KmLeft)
+	assert.Equal(t, models.DueStatusUpcoming, due.Status)
+}
+
+func TestComputeDueService_WhicheverComesFirst(t *testing.T) {
+	s := testServiceSchedule()
+	last := &models.MaintenanceRecord{ServiceDate: time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC), Odometer: 40000}
+
+	// plenty of time left, but the distance interval is almost used up
+	due := models.ComputeDueService(s, last, 54500, time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, models.DueStatusDueSoon, due.Status)
+	assert.Equal(t, 500, *due.KmLeft)
+	assert.InDelta(t, 500.0/15000.0, due.Urgency, 0.0001)
+
+	// the time interval has passed while the distance has not
+	due = models.ComputeDueService(s, last, 41000, time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, models.DueStatusOverdue, due.Status)
+	assert.Equal(t, -14, *due.DaysLeft)
+	assert.Less(t, due.Urgency, 0.0)
+}
+
+func TestComputeDueService_IgnoresRecordBeforeBaseline(t *testing.T) {
+	s := testServiceSchedule()
+	last := &models.MaintenanceRecord{ServiceDate: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), Odometer: 10000}
+
+	due := models.ComputeDueService(s, last, 30000, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	assert.Equal(t, s.StartDate, due.LastServiceDate)
+	assert.Equal(t, 45000, *due.DueOdometer)
+}
+
+func TestComputeDueService_DistanceOnly(t *testing.T) {
+	s := testServiceSchedule()
+	s.IntervalMonths = 0
+
+	due := models.ComputeDueService(s, nil, 40000, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	assert.Nil(t, due.DueDate)
+	assert.Nil(t, due.DaysLeft)
+	assert.Equal(t, models.DueStatusUpcoming, due.Status)
+}
+
+func TestSortDueServices(t *testing.T) {
+	services := []models.DueService{
+		{ScheduleID: 1, Urgency: 0.5},
+		{ScheduleID: 2, Urgency: -0.1},
+		{ScheduleID: 3, Urgency: 0.05},
+	}
+
+	models.SortDueServices(services)
+
+	assert.Equal(t, 2, services[0].ScheduleID)
+	assert.Equal(t, 3, services[1].ScheduleID)
+	assert.Equal(t, 1, services[2].ScheduleID)
+}
+
+func TestGetDueServicesByCarID_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT (.+) FROM service_schedules WHERE car_id=`).WithArgs(2).WillReturnRows(
+		sqlmock.NewRows(scheduleColumns).
+			AddRow(1, 2, "Oil change", 15000, 12, "", start, 30000, "").
+			AddRow(2, 2, "Brake fluid", 0, 24, "", start, 30000, ""),
+	)
+	mock.ExpectQuery(`SELECT DISTINCT ON \(LOWER\(service_type\)\) (.+) FROM maintenance WHERE car_id=`).WithArgs(2).WillReturnRows(
+		sqlmock.NewRows(maintenanceColumns).
+			AddRow(5, 2, time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC), 38000, "oil change", "", 100, "", "", ""),
+	)
+	mock.ExpectQuery(`SELECT COALESCE\(\(SELECT odometer FROM (.+)\), 0\)`).WithArgs(2).WillReturnRows(
+		sqlmock.NewRows([]string{"odometer"}).AddRow(52500),
+	)
+
+	modelsDB := models.NewModels(db)
+	services, err := modelsDB.DB.GetDueServicesByCarID(2, time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC))
+
+	assert.NoError(t, err)
+	assert.Len(t, services, 2)
+	// the oil change matches the record case insensitively and is due first
+	assert.Equal(t, 1, services[0].ScheduleID)
+	assert.Equal(t, 53000, *services[0].DueOdometer)
+	assert.Equal(t, models.DueStatusDueSoon, services[0].Status)
+	assert.Equal(t, 2, services[1].ScheduleID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetDueServicesByCarID_NoSchedules(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT (.+) FROM service_schedules WHERE car_id=`).WithArgs(2).WillReturnRows(sqlmock.NewRows(scheduleColumns))
+
+	modelsDB := models.NewModels(db)
+	services, err := modelsDB.DB.GetDueServicesByCarID(2, time.Now())
+
+	assert.NoError(t, err)
+	assert.Empty(t, services)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,197 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	ExpenseCategoryFuel        = "fuel"
+	ExpenseCategoryMaintenance = "maintenance"
+	ExpenseCategoryInsurance   = "insurance"
+	ExpenseCategoryTolls       = "tolls"
+	ExpenseCategoryParking     = "parking"
+	ExpenseCategoryTaxes       = "taxes"
+	ExpenseCategoryWash        = "wash"
+	ExpenseCategoryFines       = "fines"
+	ExpenseCategoryOther       = "other"
+
+	DefaultCurrency = "EUR"
+)
+
+// The categories an expense can be recorded under
+var ExpenseCategories = []string{
+	ExpenseCategoryFuel,
+	ExpenseCategoryMaintenance,
+	ExpenseCategoryInsurance,
+	ExpenseCategoryTolls,
+	ExpenseCategoryParking,
+	ExpenseCategoryTaxes,
+	ExpenseCategoryWash,
+	ExpenseCategoryFines,
+	ExpenseCategoryOther,
+}
+
+type Expense struct {
+	ID            int       `json:"id"`
+	CarID         int       `json:"car_id"`
+	Category      string    `json:"category"`
+	Amount        float64   `json:"amount"`
+	Currency      string    `json:"currency"`
+	ExpenseDate   time.Time `json:"expense_date"`
+	Odometer      *int      `json:"odometer,omitempty"`
+	MaintenanceID *int      `json:"maintenance_id,omitempty"`
+	Description   string    `json:"description,omitempty"`
+	CreatedAt     string    `json:"created_at"`
+	// only set on expenses listed in the trash
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Narrows down the expenses of a car, zero values are ignored
+type ExpenseFilter struct {
+	From     time.Time
+	To       time.Time
+	Category string
+}
+
+func IsExpenseCategory(category string) bool {
+	for _, c := range ExpenseCategories {
+		if c == category {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Checks the fields a client has to provide for an expense
+func (e *Expense) Validate() error {
+	e.Category = strings.ToLower(strings.TrimSpace(e.Category))
+	e.Currency = strings.ToUpper(strings.TrimSpace(e.Currency))
+	if e.Currency == "" {
+		e.Currency = DefaultCurrency
+	}
+
+	if !IsExpenseCategory(e.Category) {
+		return fmt.Errorf("invalid category %q, expected one of %s", e.Category, strings.Join(ExpenseCategories, ", "))
+	}
+
+	if e.Amount < 0 {
+		return errors.New("amount must not be negative")
+	}
+
+	if len(e.Currency) != 3 {
+		return errors.New("currency must be a 3 letter ISO 4217 code")
+	}
+
+	if e.ExpenseDate.IsZero() {
+		return errors.New("expense date is required")
+	}
+
+	if e.Odometer != nil && *e.Odometer < 0 {
+		return errors.New("odometer must not be negative")
+	}
+
+	return nil
+}
+
+func (m *DBModel) InsertExpense(e Expense) (int, error) {
+	stmt := `INSERT INTO expenses (car_id, category, amount, currency, expense_date, odometer, maintenance_id, description) VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+
+	var id int
+	err := m.DB.QueryRow(stmt, e.CarID, e.Category, e.Amount, e.Currency, e.ExpenseDate, e.Odometer, e.MaintenanceID, e.Description).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (m *DBModel) GetExpenseByID(carId, expenseId int) (Expense, error) {
+	var e Expense
+	stmt := `SELECT id, car_id, category, amount, currency, expense_date, odometer, maintenance_id, description, created_at FROM expenses WHERE id=$1 AND car_id=$2 AND deleted_at IS NULL`
+
+	row := m.DB.QueryRow(stmt, expenseId, carId)
+	err := row.Scan(&e.ID, &e.CarID, &e.Category, &e.Amount, &e.Currency, &e.ExpenseDate, &e.Odometer, &e.MaintenanceID, &e.Description, &e.CreatedAt)
+	if err == sql.ErrNoRows {
+		return e, ErrRecordNotFound
+	} else if err != nil {
+		return e, err
+	}
+
+	return e, nil
+}
+
+// Returns the expenses of a car matching the filter, the most recent first
+func (m *DBModel) GetExpensesByCarID(carId int, filter ExpenseFilter) ([]Expense, error) {
+	stmt := `SELECT id, car_id, category, amount, currency, expense_date, odometer, maintenance_id, description, created_at FROM expenses WHERE car_id=$1 AND deleted_at IS NULL`
+	args := []interface{}{carId}
+
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		stmt += fmt.Sprintf(" AND expense_date >= $%d", len(args))
+	}
+
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		stmt += fmt.Sprintf(" AND expense_date <= $%d", len(args))
+	}
+
+	if filter.Category != "" {
+		args = append(args, filter.Category)
+		stmt += fmt.Sprintf(" AND category = $%d", len(args))
+	}
+
+	stmt += " ORDER BY expense_date DESC, id DESC"
+
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var expenses []Expense
+
+	for rows.Next() {
+		var e Expense
+
+		err := rows.Scan(&e.ID, &e.CarID, &e.Category, &e.Amount, &e.Currency, &e.ExpenseDate, &e.Odometer, &e.MaintenanceID, &e.Description, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		expenses = append(expenses, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return expenses, nil
+}
+
+func (m *DBModel) UpdateExpense(e Expense) error {
+	stmt := `UPDATE expenses SET category=$1, amount=$2, currency=$3, expense_date=$4, odometer=$5, maintenance_id=$6, description=$7 WHERE id=$8 AND car_id=$9 AND deleted_at IS NULL`
+
+	res, err := m.DB.Exec(stmt, e.Category, e.Amount, e.Currency, e.ExpenseDate, e.Odometer, e.MaintenanceID, e.Description, e.ID, e.CarID)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// Moves an expense to the trash
+func (m *DBModel) RemoveExpense(carId, expenseId int) error {
+	stmt := `UPDATE expenses SET deleted_at=CURRENT_TIMESTAMP WHERE id=$1 AND car_id=$2 AND deleted_at IS NULL`
+
+	res, err := m.DB.Exec(stmt, expenseId, carId)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
@@ -0,0 +1,196 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var expenseColumns = []string{"id", "car_id", "category", "amount", "currency", "expense_date", "odometer", "maintenance_id", "description", "created_at"}
+
+func testExpense() models.Expense {
+	odometer := 45100
+	return models.Expense{
+		ID:          1,
+		CarID:       2,
+		Category:    "parking",
+		Amount:      12.5,
+		Currency:    "EUR",
+		ExpenseDate: time.Date(2023, 6, 19, 0, 0, 0, 0, time.UTC),
+		Odometer:    &odometer,
+		Description: "Airport parking",
+	}
+}
+
+func TestExpenseValidate(t *testing.T) {
+	valid := testExpense()
+	valid.Category = " Parking "
+	valid.Currency = ""
+	assert.NoError(t, valid.Validate())
+	assert.Equal(t, "parking", valid.Category)
+	assert.Equal(t, "EUR", valid.Currency)
+
+	lowerCurrency := testExpense()
+	lowerCurrency.Currency = "czk"
+	assert.NoError(t, lowerCurrency.Validate())
+	assert.Equal(t, "CZK", lowerCurrency.Currency)
+
+	badCategory := testExpense()
+	badCategory.Category = "snacks"
+	assert.ErrorContains(t, badCategory.Validate(), `invalid category "snacks"`)
+
+	negativeAmount := testExpense()
+	negativeAmount.Amount = -1
+	assert.EqualError(t, negativeAmount.Validate(), "amount must not be negative")
+
+	badCurrency := testExpense()
+	badCurrency.Currency = "EURO"
+	assert.EqualError(t, badCurrency.Validate(), "currency must be a 3 letter ISO 4217 code")
+
+	noDate := testExpense()
+	noDate.ExpenseDate = time.Time{}
+	assert.EqualError(t, noDate.Validate(), "expense date is required")
+
+	negativeOdometer := testExpense()
+	odometer := -1
+	negativeOdometer.Odometer = &odometer
+	assert.EqualError(t, negativeOdometer.Validate(), "odometer must not be negative")
+}
+
+func TestInsertExpense_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	e := testExpense()
+	mock.ExpectQuery(`INSERT INTO expenses`).WithArgs(
+		e.CarID, e.Category, e.Amount, e.Currency, e.ExpenseDate, e.Odometer, e.MaintenanceID, e.Description,
+	).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+	modelsDB := models.NewModels(db)
+	id, err := modelsDB.DB.InsertExpense(e)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 7, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetExpenseByID_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT (.+) FROM expenses WHERE id=\$1 AND car_id=\$2`).WithArgs(1, 2).WillReturnRows(sqlmock.NewRows(expenseColumns))
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.GetExpenseByID(2, 1)
+
+	assert.ErrorIs(t, err, models.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetExpensesByCarID_NoFilter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	e := testExpense()
+	rows := sqlmock.NewRows(expenseColumns).
+		AddRow(e.ID, e.CarID, e.Category, e.Amount, e.Currency, e.ExpenseDate, 45100, nil, e.Description, "2023-06-19 10:00:00").
+		AddRow(2, e.CarID, "tolls", 5.0, "EUR", e.ExpenseDate, nil, 3, "", "2023-06-19 11:00:00")
+
+	mock.ExpectQuery(`SELECT (.+) FROM expenses WHERE car_id=\$1 AND deleted_at IS NULL ORDER BY expense_date DESC, id DESC`).WithArgs(2).WillReturnRows(rows)
+
+	modelsDB := models.NewModels(db)
+	expenses, err := modelsDB.DB.GetExpensesByCarID(2, models.ExpenseFilter{})
+
+	assert.NoError(t, err)
+	assert.Len(t, expenses, 2)
+	assert.Equal(t, 45100, *expenses[0].Odometer)
+	assert.Nil(t, expenses[0].MaintenanceID)
+	assert.Nil(t, expenses[1].Odometer)
+	assert.Equal(t, 3, *expenses[1].MaintenanceID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetExpensesByCarID_Filtered(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`WHERE car_id=\$1 AND deleted_at IS NULL AND expense_date >= \$2 AND expense_date <= \$3 AND category = \$4 ORDER BY`).
+		WithArgs(2, from, to, "fuel").
+		WillReturnRows(sqlmock.NewRows(expenseColumns))
+
+	modelsDB := models.NewModels(db)
+	expenses, err := modelsDB.DB.GetExpensesByCarID(2, models.ExpenseFilter{From: from, To: to, Category: "fuel"})
+
+	assert.NoError(t, err)
+	assert.Empty(t, expenses)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetExpensesByCarID_Error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT (.+) FROM expenses`).WillReturnError(errors.New("mocked error"))
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.GetExpensesByCarID(2, models.ExpenseFilter{Category: "tolls"})
+
+	assert.EqualError(t, err, "mocked error")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateExpense_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	e := testExpense()
+	mock.ExpectExec(`UPDATE expenses SET`).WithArgs(
+		e.Category, e.Amount, e.Currency, e.ExpenseDate, e.Odometer, e.MaintenanceID, e.Description, e.ID, e.CarID,
+	).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.UpdateExpense(e)
+
+	assert.ErrorIs(t, err, models.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRemoveExpense_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE expenses SET deleted_at=CURRENT_TIMESTAMP WHERE id=\$1 AND car_id=\$2 AND deleted_at IS NULL`).WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.RemoveExpense(2, 1)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,273 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// A member of an organization driving a fleet car. The assignment is open
+// until the car gets another driver, costs dated within it count for the driver.
+type DriverAssignment struct {
+	ID        int        `json:"id"`
+	CarID     int        `json:"car_id"`
+	UserID    int        `json:"user_id"`
+	Nickname  string     `json:"nickname"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+}
+
+type FleetCarCost struct {
+	CarID        int     `json:"car_id"`
+	LicensePlate string  `json:"license_plate"`
+	Total        float64 `json:"total"`
+	Count        int     `json:"count"`
+}
+
+// Costs of the cars while the member drove them, DriverID is nil for the
+// costs of cars without a driver
+type DriverCost struct {
+	DriverID *int    `json:"driver_id"`
+	Nickname string  `json:"nickname,omitempty"`
+	Total    float64 `json:"total"`
+	Count    int     `json:"count"`
+}
+
+type FleetReport struct {
+	Total      float64         `json:"total"`
+	Cars       []FleetCarCost  `json:"cars"`
+	Drivers    []DriverCost    `json:"drivers"`
+	Categories []CategoryTotal `json:"categories"`
+}
+
+// All costs of the cars of an organization with the driver of the car on the
+// day of the cost, limited to the date range.
+// $1 organization id, $2 from or NULL, $3 to or NULL
+const fleetCostsQuery = `WITH costs AS (
+		` + costRecordsQuery + `
+	), scoped AS (
+		SELECT costs.car_id, costs.cost_date, costs.category, costs.amount, d.user_id AS driver_id FROM costs
+		JOIN users_cars c ON c.id = costs.car_id
+		LEFT JOIN driver_assignments d ON d.car_id = costs.car_id
+			AND d.started_at <= costs.cost_date AND (d.ended_at IS NULL OR d.ended_at > costs.cost_date)
+		WHERE c.organization_id=$1 AND c.deleted_at IS NULL
+			AND ($2::date IS NULL OR costs.cost_date >= $2) AND ($3::date IS NULL OR costs.cost_date <= $3)
+	)`
+
+// Returns the cars of the organization with their current driver and the role
+// of the user on each of them
+func (m *DBModel) GetOrganizationCars(orgId, userId int, includeArchived bool) ([]Car, error) {
+	stmt := `SELECT c.id, c.user_id, c.brand_id, c.model_id, c.year, c.color, c.price, c.image, c.description, c.license_plate, c.vin, c.variant_id,
+		c.created_at, c.archived_at, c.sold_at, c.sale_price, c.organization_id, d.user_id,
+		` + fmt.Sprintf(carRoleQuery, "c.id", "c.user_id", "c.organization_id", "$2") + `
+		FROM users_cars c LEFT JOIN driver_assignments d ON d.car_id = c.id AND d.ended_at IS NULL
+		WHERE c.organization_id=$1 AND c.deleted_at IS NULL`
+	if !includeArchived {
+		stmt += ` AND c.archived_at IS NULL`
+	}
+	stmt += ` ORDER BY c.id`
+
+	rows, err := m.DB.Query(stmt, orgId, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cars := []Car{}
+
+	for rows.Next() {
+		var car Car
+
+		err := rows.Scan(&car.ID, &car.UserId, &car.BrandID, &car.ModelID, &car.Year, &car.Color, &car.Price, &car.Image, &car.Description, &car.LicensePlate, &car.VIN, &car.VariantID,
+			&car.CreatedAt, &car.ArchivedAt, &car.SoldAt, &car.SalePrice, &car.OrganizationID, &car.DriverID, &car.Role)
+		if err != nil {
+			return nil, err
+		}
+
+		cars = append(cars, car)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return cars, nil
+}
+
+// Makes the member the driver of the fleet car from today on, ending the
+// assignment of the previous driver. Fails with ErrNotOrganizationMember when
+// the user is not a member of the organization.
+func (m *DBModel) AssignDriver(orgId, carId, userId int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRow(`SELECT id FROM users_cars WHERE id=$1 AND organization_id=$2 AND deleted_at IS NULL FOR UPDATE`, carId, orgId).Scan(&id)
+	if err == sql.ErrNoRows {
+		return ErrRecordNotFound
+	} else if err != nil {
+		return err
+	}
+
+	var isMember bool
+	err = tx.QueryRow(`SELECT exists (SELECT 1 FROM organization_members WHERE organization_id=$1 AND user_id=$2)`, orgId, userId).Scan(&isMember)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return ErrNotOrganizationMember
+	}
+
+	_, err = tx.Exec(`UPDATE driver_assignments SET ended_at=CURRENT_DATE WHERE car_id=$1 AND ended_at IS NULL AND user_id<>$2`, carId, userId)
+	if err != nil {
+		return err
+	}
+
+	// the driver may already be assigned, the open assignment is then kept
+	_, err = tx.Exec(`INSERT INTO driver_assignments (car_id, user_id, started_at) VALUES($1, $2, CURRENT_DATE) ON CONFLICT DO NOTHING`, carId, userId)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Ends the assignment of the current driver of the fleet car
+func (m *DBModel) UnassignDriver(orgId, carId int) error {
+	stmt := `UPDATE driver_assignments SET ended_at=CURRENT_DATE
+		WHERE car_id=$1 AND ended_at IS NULL AND car_id IN (SELECT id FROM users_cars WHERE organization_id=$2)`
+
+	res, err := m.DB.Exec(stmt, carId, orgId)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// Returns the drivers of the fleet car, the current one first
+func (m *DBModel) GetDriverAssignments(orgId, carId int) ([]DriverAssignment, error) {
+	stmt := `SELECT d.id, d.car_id, d.user_id, u.nickname, d.started_at, d.ended_at FROM driver_assignments d
+		JOIN users u ON u.id = d.user_id
+		JOIN users_cars c ON c.id = d.car_id
+		WHERE d.car_id=$1 AND c.organization_id=$2
+		ORDER BY d.ended_at DESC NULLS FIRST, d.started_at DESC, d.id DESC`
+
+	rows, err := m.DB.Query(stmt, carId, orgId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assignments := []DriverAssignment{}
+
+	for rows.Next() {
+		var a DriverAssignment
+
+		err := rows.Scan(&a.ID, &a.CarID, &a.UserID, &a.Nickname, &a.StartedAt, &a.EndedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		assignments = append(assignments, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return assignments, nil
+}
+
+// Returns the costs of the fleet in the date range per car, per driver and per
+// category, the most expensive first
+func (m *DBModel) GetFleetReport(orgId int, dates DateRange) (FleetReport, error) {
+	report := FleetReport{Cars: []FleetCarCost{}, Drivers: []DriverCost{}, Categories: []CategoryTotal{}}
+
+	var from, to interface{}
+	if !dates.From.IsZero() {
+		from = dates.From
+	}
+	if !dates.To.IsZero() {
+		to = dates.To
+	}
+
+	rows, err := m.DB.Query(fleetCostsQuery+`
+		SELECT s.car_id, c.license_plate, ROUND(SUM(s.amount), 2) AS total, COUNT(*) FROM scoped s
+		JOIN users_cars c ON c.id = s.car_id
+		GROUP BY s.car_id, c.license_plate ORDER BY total DESC, s.car_id`, orgId, from, to)
+	if err != nil {
+		return report, err
+	}
+	defer rows.Close()
+
+	var total float64
+	for rows.Next() {
+		var c FleetCarCost
+
+		err := rows.Scan(&c.CarID, &c.LicensePlate, &c.Total, &c.Count)
+		if err != nil {
+			return report, err
+		}
+
+		total += c.Total
+		report.Cars = append(report.Cars, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return report, err
+	}
+	report.Total = round(total, 2)
+
+	rows, err = m.DB.Query(fleetCostsQuery+`
+		SELECT s.driver_id, COALESCE(u.nickname, ''), ROUND(SUM(s.amount), 2) AS total, COUNT(*) FROM scoped s
+		LEFT JOIN users u ON u.id = s.driver_id
+		GROUP BY s.driver_id, u.nickname ORDER BY total DESC, s.driver_id NULLS LAST`, orgId, from, to)
+	if err != nil {
+		return report, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d DriverCost
+
+		err := rows.Scan(&d.DriverID, &d.Nickname, &d.Total, &d.Count)
+		if err != nil {
+			return report, err
+		}
+
+		report.Drivers = append(report.Drivers, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return report, err
+	}
+
+	rows, err = m.DB.Query(fleetCostsQuery+`
+		SELECT category, ROUND(SUM(amount), 2) AS total, COUNT(*) FROM scoped
+		GROUP BY category ORDER BY total DESC, category`, orgId, from, to)
+	if err != nil {
+		return report, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c CategoryTotal
+
+		err := rows.Scan(&c.Category, &c.Total, &c.Count)
+		if err != nil {
+			return report, err
+		}
+
+		report.Categories = append(report.Categories, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
@@ -0,0 +1,128 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOrganizationCars(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "user_id", "brand_id", "model_id", "year", "color", "price", "image", "description", "license_plate", "vin", "variant_id",
+		"created_at", "archived_at", "sold_at", "sale_price", "organization_id", "driver_id", "role"}).
+		AddRow(2, 5, 1, 1, 2022, "white", 30000, "", "", "BA123AB", "", nil, "2023-06-19 12:00:00", nil, nil, nil, 4, 7, "editor")
+	mock.ExpectQuery(`SELECT c.id, (.+) FROM users_cars c LEFT JOIN driver_assignments d (.+) WHERE c.organization_id=\$1 AND c.deleted_at IS NULL AND c.archived_at IS NULL ORDER BY c.id`).
+		WithArgs(4, 7).WillReturnRows(rows)
+
+	modelsDB := models.NewModels(db)
+	cars, err := modelsDB.DB.GetOrganizationCars(4, 7, false)
+
+	assert.NoError(t, err)
+	assert.Len(t, cars, 1)
+	assert.Equal(t, 4, *cars[0].OrganizationID)
+	assert.Equal(t, 7, *cars[0].DriverID)
+	assert.Equal(t, models.RoleEditor, cars[0].Role)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAssignDriver_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM users_cars WHERE id=\$1 AND organization_id=\$2 AND deleted_at IS NULL FOR UPDATE`).WithArgs(2, 4).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery(`SELECT exists \(SELECT 1 FROM organization_members`).WithArgs(4, 7).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec(`UPDATE driver_assignments SET ended_at=CURRENT_DATE WHERE car_id=\$1 AND ended_at IS NULL AND user_id<>\$2`).WithArgs(2, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO driver_assignments (.+) ON CONFLICT DO NOTHING`).WithArgs(2, 7).WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectCommit()
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.AssignDriver(4, 2, 7)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAssignDriver_NotMember(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM users_cars`).WithArgs(2, 4).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery(`SELECT exists \(SELECT 1 FROM organization_members`).WithArgs(4, 9).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectRollback()
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.AssignDriver(4, 2, 9)
+
+	assert.True(t, errors.Is(err, models.ErrNotOrganizationMember))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAssignDriver_CarNotInFleet(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM users_cars`).WithArgs(2, 4).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.AssignDriver(4, 2, 7)
+
+	assert.True(t, errors.Is(err, models.ErrRecordNotFound))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetFleetReport(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`WITH costs AS (.+) LEFT JOIN driver_assignments d (.+) SELECT s.car_id, c.license_plate`).WithArgs(4, from, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"car_id", "license_plate", "total", "count"}).
+			AddRow(2, "BA123AB", 300.10, 3).
+			AddRow(3, "BA456CD", 99.95, 1))
+	mock.ExpectQuery(`WITH costs AS (.+) SELECT s.driver_id`).WithArgs(4, from, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"driver_id", "nickname", "total", "count"}).
+			AddRow(7, "john", 250.10, 2).
+			AddRow(nil, "", 149.95, 2))
+	mock.ExpectQuery(`WITH costs AS (.+) SELECT category`).WithArgs(4, from, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"category", "total", "count"}).AddRow("fuel", 400.05, 4))
+
+	modelsDB := models.NewModels(db)
+	report, err := modelsDB.DB.GetFleetReport(4, models.DateRange{From: from})
+
+	driverId := 7
+	assert.NoError(t, err)
+	assert.Equal(t, 400.05, report.Total)
+	assert.Len(t, report.Cars, 2)
+	assert.Equal(t, []models.DriverCost{
+		{DriverID: &driverId, Nickname: "john", Total: 250.10, Count: 2},
+		{Total: 149.95, Count: 2},
+	}, report.Drivers)
+	assert.Equal(t, []models.CategoryTotal{{Category: "fuel", Total: 400.05, Count: 4}}, report.Categories)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
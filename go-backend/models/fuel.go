@@ -0,0 +1,156 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A fill-up at the pump. Volumes are in litres and distances in kilometres.
+type FuelLog struct {
+	ID           int       `json:"id"`
+	CarID        int       `json:"car_id"`
+	FillDate     time.Time `json:"fill_date"`
+	Odometer     int       `json:"odometer"`
+	Volume       float64   `json:"volume"`
+	PricePerUnit float64   `json:"price_per_unit"`
+	FullTank     bool      `json:"full_tank"`
+	// set when an earlier fill-up was not recorded, the consumption up to this
+	// fill-up is unknown and left out of the averages
+	MissedPrevious bool   `json:"missed_previous"`
+	Station        string `json:"station,omitempty"`
+	Notes          string `json:"notes,omitempty"`
+	CreatedAt      string `json:"created_at"`
+}
+
+// Limits a query to the records dated within the range, zero bounds are open
+type DateRange struct {
+	From time.Time
+	To   time.Time
+}
+
+func (d DateRange) Contains(t time.Time) bool {
+	return (d.From.IsZero() || !t.Before(d.From)) && (d.To.IsZero() || !t.After(d.To))
+}
+
+// Checks the fields a client has to provide for a fill-up
+func (f *FuelLog) Validate() error {
+	f.Station = strings.TrimSpace(f.Station)
+
+	if f.FillDate.IsZero() {
+		return errors.New("fill date is required")
+	}
+
+	if f.Odometer <= 0 {
+		return errors.New("odometer is required")
+	}
+
+	if f.Volume <= 0 {
+		return errors.New("volume must be positive")
+	}
+
+	if f.PricePerUnit < 0 {
+		return errors.New("price per unit must not be negative")
+	}
+
+	return nil
+}
+
+// The amount paid for the fill-up
+func (f FuelLog) Cost() float64 {
+	return f.Volume * f.PricePerUnit
+}
+
+func (m *DBModel) InsertFuelLog(f FuelLog) (int, error) {
+	stmt := `INSERT INTO fuel_logs (car_id, fill_date, odometer, volume, price_per_unit, full_tank, missed_previous, station, notes) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`
+
+	var id int
+	err := m.DB.QueryRow(stmt, f.CarID, f.FillDate, f.Odometer, f.Volume, f.PricePerUnit, f.FullTank, f.MissedPrevious, f.Station, f.Notes).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (m *DBModel) GetFuelLogByID(carId, fuelLogId int) (FuelLog, error) {
+	var f FuelLog
+	stmt := `SELECT id, car_id, fill_date, odometer, volume, price_per_unit, full_tank, missed_previous, station, notes, created_at FROM fuel_logs WHERE id=$1 AND car_id=$2`
+
+	row := m.DB.QueryRow(stmt, fuelLogId, carId)
+	err := row.Scan(&f.ID, &f.CarID, &f.FillDate, &f.Odometer, &f.Volume, &f.PricePerUnit, &f.FullTank, &f.MissedPrevious, &f.Station, &f.Notes, &f.CreatedAt)
+	if err == sql.ErrNoRows {
+		return f, ErrRecordNotFound
+	} else if err != nil {
+		return f, err
+	}
+
+	return f, nil
+}
+
+// Returns the fill-ups of a car within the range in the order they were made
+func (m *DBModel) GetFuelLogsByCarID(carId int, dates DateRange) ([]FuelLog, error) {
+	stmt := `SELECT id, car_id, fill_date, odometer, volume, price_per_unit, full_tank, missed_previous, station, notes, created_at FROM fuel_logs WHERE car_id=$1`
+	args := []interface{}{carId}
+
+	if !dates.From.IsZero() {
+		args = append(args, dates.From)
+		stmt += fmt.Sprintf(" AND fill_date >= $%d", len(args))
+	}
+
+	if !dates.To.IsZero() {
+		args = append(args, dates.To)
+		stmt += fmt.Sprintf(" AND fill_date <= $%d", len(args))
+	}
+
+	stmt += " ORDER BY fill_date ASC, odometer ASC, id ASC"
+
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var logs []FuelLog
+
+	for rows.Next() {
+		var f FuelLog
+
+		err := rows.Scan(&f.ID, &f.CarID, &f.FillDate, &f.Odometer, &f.Volume, &f.PricePerUnit, &f.FullTank, &f.MissedPrevious, &f.Station, &f.Notes, &f.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		logs = append(logs, f)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return logs, nil
+}
+
+func (m *DBModel) UpdateFuelLog(f FuelLog) error {
+	stmt := `UPDATE fuel_logs SET fill_date=$1, odometer=$2, volume=$3, price_per_unit=$4, full_tank=$5, missed_previous=$6, station=$7, notes=$8 WHERE id=$9 AND car_id=$10`
+
+	res, err := m.DB.Exec(stmt, f.FillDate, f.Odometer, f.Volume, f.PricePerUnit, f.FullTank, f.MissedPrevious, f.Station, f.Notes, f.ID, f.CarID)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+func (m *DBModel) RemoveFuelLog(carId, fuelLogId int) error {
+	stmt := `DELETE FROM fuel_logs WHERE id=$1 AND car_id=$2`
+
+	res, err := m.DB.Exec(stmt, fuelLogId, carId)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
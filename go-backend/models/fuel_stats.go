@@ -0,0 +1,156 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// number of consumption intervals in a rolling average
+	RollingWindow = 5
+
+	// L/100 km to US miles per gallon: 100 km / 1 L expressed in mi/gal
+	litersPer100KmToMPG = 235.214583
+)
+
+// Consumption between two consecutive full-tank fill-ups
+type FuelInterval struct {
+	FromDate       time.Time `json:"from_date"`
+	ToDate         time.Time `json:"to_date"`
+	Distance       int       `json:"distance"`
+	Volume         float64   `json:"volume"`
+	Cost           float64   `json:"cost"`
+	LitersPer100Km float64   `json:"liters_per_100km"`
+	MPG            float64   `json:"mpg"`
+	// distance weighted average of this and the previous intervals
+	RollingLitersPer100Km float64 `json:"rolling_liters_per_100km"`
+}
+
+type FuelStats struct {
+	FillUps     int     `json:"fill_ups"`
+	TotalVolume float64 `json:"total_volume"`
+	TotalCost   float64 `json:"total_cost"`
+	// distance covered by the intervals with a known consumption
+	Distance       int            `json:"distance"`
+	LitersPer100Km *float64       `json:"liters_per_100km"`
+	MPG            *float64       `json:"mpg"`
+	CostPerKm      *float64       `json:"cost_per_km"`
+	Intervals      []FuelInterval `json:"intervals"`
+}
+
+func LitersPer100KmToMPG(litersPer100Km float64) float64 {
+	if litersPer100Km == 0 {
+		return 0
+	}
+
+	return litersPer100KmToMPG / litersPer100Km
+}
+
+// Computes the consumption of a car from its fill-ups. Consumption is only
+// known between two full tanks: the fuel put in after the first full tank up to
+// and including the second one was burnt over the distance between them.
+// Partial fill-ups in between are added to the interval. An interval with a
+// missed fill-up, or a decreasing odometer, is skipped so it cannot distort the
+// averages. Only the intervals ending, and the fill-ups made, within dates are
+// counted, the earlier fill-ups only provide the starting tank.
+func ComputeFuelStats(logs []FuelLog, dates DateRange) FuelStats {
+	sorted := make([]FuelLog, len(logs))
+	copy(sorted, logs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].FillDate.Equal(sorted[j].FillDate) {
+			return sorted[i].FillDate.Before(sorted[j].FillDate)
+		}
+		return sorted[i].Odometer < sorted[j].Odometer
+	})
+
+	stats := FuelStats{Intervals: []FuelInterval{}}
+
+	var anchor *FuelLog
+	var volume, cost float64
+	broken := false
+
+	for i := range sorted {
+		f := sorted[i]
+
+		if dates.Contains(f.FillDate) {
+			stats.FillUps++
+			stats.TotalVolume += f.Volume
+			stats.TotalCost += f.Cost()
+		}
+
+		volume += f.Volume
+		cost += f.Cost()
+		if f.MissedPrevious {
+			broken = true
+		}
+
+		if !f.FullTank {
+			continue
+		}
+
+		if anchor != nil && !broken && f.Odometer > anchor.Odometer && dates.Contains(f.FillDate) {
+			distance := f.Odometer - anchor.Odometer
+			consumption := volume / float64(distance) * 100
+			stats.Intervals = append(stats.Intervals, FuelInterval{
+				FromDate:       anchor.FillDate,
+				ToDate:         f.FillDate,
+				Distance:       distance,
+				Volume:         round(volume, 2),
+				Cost:           round(cost, 2),
+				LitersPer100Km: round(consumption, 2),
+				MPG:            round(LitersPer100KmToMPG(consumption), 2),
+			})
+		}
+
+		anchor = &sorted[i]
+		volume, cost = 0, 0
+		broken = false
+	}
+
+	var volumeSum, costSum float64
+	for i := range stats.Intervals {
+		stats.Distance += stats.Intervals[i].Distance
+		volumeSum += stats.Intervals[i].Volume
+		costSum += stats.Intervals[i].Cost
+		stats.Intervals[i].RollingLitersPer100Km = rollingConsumption(stats.Intervals[:i+1])
+	}
+
+	stats.TotalVolume = round(stats.TotalVolume, 2)
+	stats.TotalCost = round(stats.TotalCost, 2)
+
+	if stats.Distance > 0 {
+		consumption := volumeSum / float64(stats.Distance) * 100
+		mpg := round(LitersPer100KmToMPG(consumption), 2)
+		costPerKm := round(costSum/float64(stats.Distance), 3)
+		consumption = round(consumption, 2)
+
+		stats.LitersPer100Km = &consumption
+		stats.MPG = &mpg
+		stats.CostPerKm = &costPerKm
+	}
+
+	return stats
+}
+
+// Distance weighted consumption of the last RollingWindow intervals
+func rollingConsumption(intervals []FuelInterval) float64 {
+	if len(intervals) > RollingWindow {
+		intervals = intervals[len(intervals)-RollingWindow:]
+	}
+
+	var volume float64
+	var distance int
+	for _, i := range intervals {
+		volume += i.Volume
+		distance += i.Distance
+	}
+
+	return round(volume/float64(distance)*100, 2)
+}
+
+func round(value float64, places int) float64 {
+	factor := math.Pow(10, float64(places))
+
+	return math.Round(value*factor) / factor
+}
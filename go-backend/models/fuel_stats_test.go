@@ -0,0 +1,109 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func fillUp(day, odometer int, volume float64, fullTank bool) models.FuelLog {
+	return models.FuelLog{
+		FillDate:     time.Date(2023, 5, day, 0, 0, 0, 0, time.UTC),
+		Odometer:     odometer,
+		Volume:       volume,
+		PricePerUnit: 1.5,
+		FullTank:     fullTank,
+	}
+}
+
+func TestComputeFuelStats_FullTanks(t *testing.T) {
+	logs := []models.FuelLog{
+		fillUp(1, 10000, 40, true),
+		fillUp(10, 10500, 30, true),
+		fillUp(20, 11100, 42, true),
+	}
+
+	stats := models.ComputeFuelStats(logs, models.DateRange{})
+
+	assert.Equal(t, 3, stats.FillUps)
+	assert.Equal(t, 112.0, stats.TotalVolume)
+	assert.Equal(t, 168.0, stats.TotalCost)
+	assert.Equal(t, 1100, stats.Distance)
+	assert.Len(t, stats.Intervals, 2)
+	assert.Equal(t, 6.0, stats.Intervals[0].LitersPer100Km)
+	assert.Equal(t, 39.2, stats.Intervals[0].MPG)
+	assert.Equal(t, 7.0, stats.Intervals[1].LitersPer100Km)
+	// (30 + 42) / 1100 km
+	assert.Equal(t, 6.55, stats.Intervals[1].RollingLitersPer100Km)
+	assert.Equal(t, 6.55, *stats.LitersPer100Km)
+	assert.Equal(t, 35.94, *stats.MPG)
+	// 108 EUR / 1100 km
+	assert.Equal(t, 0.098, *stats.CostPerKm)
+}
+
+func TestComputeFuelStats_PartialFillUps(t *testing.T) {
+	logs := []models.FuelLog{
+		fillUp(1, 10000, 40, true),
+		fillUp(5, 10300, 10, false),
+		fillUp(10, 10500, 20, true),
+	}
+
+	stats := models.ComputeFuelStats(logs, models.DateRange{})
+
+	assert.Len(t, stats.Intervals, 1)
+	assert.Equal(t, 30.0, stats.Intervals[0].Volume)
+	assert.Equal(t, 6.0, stats.Intervals[0].LitersPer100Km)
+}
+
+func TestComputeFuelStats_MissedFillUpIsSkipped(t *testing.T) {
+	missed := fillUp(10, 11000, 35, true)
+	missed.MissedPrevious = true
+	logs := []models.FuelLog{
+		fillUp(1, 10000, 40, true),
+		missed,
+		fillUp(20, 11500, 30, true),
+	}
+
+	stats := models.ComputeFuelStats(logs, models.DateRange{})
+
+	assert.Equal(t, 3, stats.FillUps)
+	assert.Len(t, stats.Intervals, 1)
+	assert.Equal(t, 500, stats.Distance)
+	assert.Equal(t, 6.0, *stats.LitersPer100Km)
+}
+
+func TestComputeFuelStats_DateRange(t *testing.T) {
+	logs := []models.FuelLog{
+		fillUp(1, 10000, 40, true),
+		fillUp(10, 10500, 30, true),
+		fillUp(20, 11100, 42, true),
+	}
+
+	stats := models.ComputeFuelStats(logs, models.DateRange{From: time.Date(2023, 5, 5, 0, 0, 0, 0, time.UTC)})
+
+	// the first fill-up only anchors the first interval
+	assert.Equal(t, 2, stats.FillUps)
+	assert.Len(t, stats.Intervals, 2)
+	assert.Equal(t, 1100, stats.Distance)
+
+	stats = models.ComputeFuelStats(logs, models.DateRange{From: time.Date(2023, 5, 15, 0, 0, 0, 0, time.UTC)})
+	assert.Equal(t, 1, stats.FillUps)
+	assert.Len(t, stats.Intervals, 1)
+	assert.Equal(t, 7.0, *stats.LitersPer100Km)
+}
+
+func TestComputeFuelStats_NotEnoughData(t *testing.T) {
+	stats := models.ComputeFuelStats([]models.FuelLog{fillUp(1, 10000, 40, true)}, models.DateRange{})
+
+	assert.Equal(t, 1, stats.FillUps)
+	assert.Empty(t, stats.Intervals)
+	assert.Nil(t, stats.LitersPer100Km)
+	assert.Nil(t, stats.CostPerKm)
+}
+
+func TestLitersPer100KmToMPG(t *testing.T) {
+	assert.InDelta(t, 23.52, models.LitersPer100KmToMPG(10), 0.01)
+	assert.Equal(t, 0.0, models.LitersPer100KmToMPG(0))
+}
@@ -0,0 +1,108 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var fuelLogColumns = []string{"id", "car_id", "fill_date", "odometer", "volume", "price_per_unit", "full_tank", "missed_previous", "station", "notes", "created_at"}
+
+func TestFuelLogValidate(t *testing.T) {
+	valid := fillUp(1, 10000, 40, true)
+	valid.Station = "  Shell  "
+	assert.NoError(t, valid.Validate())
+	assert.Equal(t, "Shell", valid.Station)
+
+	noDate := fillUp(1, 10000, 40, true)
+	noDate.FillDate = time.Time{}
+	assert.EqualError(t, noDate.Validate(), "fill date is required")
+
+	noOdometer := fillUp(1, 0, 40, true)
+	assert.EqualError(t, noOdometer.Validate(), "odometer is required")
+
+	noVolume := fillUp(1, 10000, 0, true)
+	assert.EqualError(t, noVolume.Validate(), "volume must be positive")
+
+	negativePrice := fillUp(1, 10000, 40, true)
+	negativePrice.PricePerUnit = -1
+	assert.EqualError(t, negativePrice.Validate(), "price per unit must not be negative")
+}
+
+func TestInsertFuelLog_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	f := fillUp(1, 10000, 40, true)
+	f.CarID = 2
+	mock.ExpectQuery(`INSERT INTO fuel_logs`).WithArgs(
+		2, f.FillDate, 10000, 40.0, 1.5, true, false, "", "",
+	).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+	modelsDB := models.NewModels(db)
+	id, err := modelsDB.DB.InsertFuelLog(f)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetFuelLogsByCarID_DateRange(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	to := time.Date(2023, 5, 31, 0, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows(fuelLogColumns).
+		AddRow(1, 2, time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC), 10000, 40.0, 1.5, true, false, "Shell", "", "2023-05-01 10:00:00")
+
+	mock.ExpectQuery(`FROM fuel_logs WHERE car_id=\$1 AND fill_date <= \$2 ORDER BY fill_date ASC`).WithArgs(2, to).WillReturnRows(rows)
+
+	modelsDB := models.NewModels(db)
+	logs, err := modelsDB.DB.GetFuelLogsByCarID(2, models.DateRange{To: to})
+
+	assert.NoError(t, err)
+	assert.Len(t, logs, 1)
+	assert.Equal(t, "Shell", logs[0].Station)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetFuelLogByID_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM fuel_logs WHERE id=\$1 AND car_id=\$2`).WithArgs(1, 2).WillReturnRows(sqlmock.NewRows(fuelLogColumns))
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.GetFuelLogByID(2, 1)
+
+	assert.ErrorIs(t, err, models.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRemoveFuelLog_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`DELETE FROM fuel_logs WHERE id=\$1 AND car_id=\$2`).WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.RemoveFuelLog(2, 1)
+
+	assert.ErrorIs(t, err, models.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,42 @@
+package models
+
+// Stores imported maintenance records and expenses of a car in a single
+// transaction, either all of them are stored or none
+func (m *DBModel) ImportCarHistory(carId int, records []MaintenanceRecord, expenses []Expense) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// serialize with odometer readings added at the same time
+	_, err = tx.Exec(`SELECT id FROM users_cars WHERE id=$1 FOR UPDATE`, carId)
+	if err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	for _, rec := range records {
+		_, err = tx.Exec(`INSERT INTO maintenance (car_id, service_date, odometer, service_type, description, cost, performed_by, notes) VALUES($1, $2, $3, $4, $5, $6, $7, $8)`,
+			carId, rec.ServiceDate, rec.Odometer, rec.ServiceType, rec.Description, rec.Cost, rec.PerformedBy, rec.Notes)
+		if err != nil {
+			return 0, err
+		}
+		imported++
+	}
+
+	for _, e := range expenses {
+		_, err = tx.Exec(`INSERT INTO expenses (car_id, category, amount, currency, expense_date, odometer, maintenance_id, description) VALUES($1, $2, $3, $4, $5, $6, $7, $8)`,
+			carId, e.Category, e.Amount, e.Currency, e.ExpenseDate, e.Odometer, e.MaintenanceID, e.Description)
+		if err != nil {
+			return 0, err
+		}
+		imported++
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return imported, nil
+}
@@ -0,0 +1,58 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportCarHistory_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	rec := testMaintenanceRecord()
+	e := testExpense()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT id FROM users_cars WHERE id=\$1 FOR UPDATE`).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO maintenance`).WithArgs(
+		2, rec.ServiceDate, rec.Odometer, rec.ServiceType, rec.Description, rec.Cost, rec.PerformedBy, rec.Notes,
+	).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO expenses`).WithArgs(
+		2, e.Category, e.Amount, e.Currency, e.ExpenseDate, e.Odometer, e.MaintenanceID, e.Description,
+	).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	modelsDB := models.NewModels(db)
+	imported, err := modelsDB.DB.ImportCarHistory(2, []models.MaintenanceRecord{rec}, []models.Expense{e})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, imported)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportCarHistory_RollsBack(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT id FROM users_cars`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO maintenance`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO maintenance`).WillReturnError(errors.New("mocked error"))
+	mock.ExpectRollback()
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.ImportCarHistory(2, []models.MaintenanceRecord{testMaintenanceRecord(), testMaintenanceRecord()}, nil)
+
+	assert.EqualError(t, err, "mocked error")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+type MaintenanceRecord struct {
+	ID          int       `json:"id"`
+	CarID       int       `json:"car_id"`
+	ServiceDate time.Time `json:"service_date"`
+	Odometer    int       `json:"odometer"`
+	ServiceType string    `json:"service_type"`
+	Description string    `json:"description,omitempty"`
+	Cost        float64   `json:"cost"`
+	PerformedBy string    `json:"performed_by,omitempty"`
+	Notes       string    `json:"notes,omitempty"`
+	CreatedAt   string    `json:"created_at"`
+	// only set on records listed in the trash
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Checks the fields a client has to provide for a maintenance record
+func (rec *MaintenanceRecord) Validate() error {
+	rec.ServiceType = strings.TrimSpace(rec.ServiceType)
+
+	if rec.ServiceDate.IsZero() {
+		return errors.New("service date is required")
+	}
+
+	if rec.ServiceType == "" {
+		return errors.New("service type is required")
+	}
+
+	if rec.Odometer < 0 {
+		return errors.New("odometer must not be negative")
+	}
+
+	if rec.Cost < 0 {
+		return errors.New("cost must not be negative")
+	}
+
+	return nil
+}
+
+func (m *DBModel) InsertMaintenanceRecord(rec MaintenanceRecord) (int, error) {
+	stmt := `INSERT INTO maintenance (car_id, service_date, odometer, service_type, description, cost, performed_by, notes) VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+
+	var id int
+	err := m.DB.QueryRow(stmt, rec.CarID, rec.ServiceDate, rec.Odometer, rec.ServiceType, rec.Description, rec.Cost, rec.PerformedBy, rec.Notes).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (m *DBModel) GetMaintenanceRecordByID(carId, recordId int) (MaintenanceRecord, error) {
+	var rec MaintenanceRecord
+	stmt := `SELECT id, car_id, service_date, odometer, service_type, description, cost, performed_by, notes, created_at FROM maintenance WHERE id=$1 AND car_id=$2 AND deleted_at IS NULL`
+
+	row := m.DB.QueryRow(stmt, recordId, carId)
+	err := row.Scan(&rec.ID, &rec.CarID, &rec.ServiceDate, &rec.Odometer, &rec.ServiceType, &rec.Description, &rec.Cost, &rec.PerformedBy, &rec.Notes, &rec.CreatedAt)
+	if err == sql.ErrNoRows {
+		return rec, ErrRecordNotFound
+	} else if err != nil {
+		return rec, err
+	}
+
+	return rec, nil
+}
+
+func (m *DBModel) GetMaintenanceRecordsByCarID(carId int) ([]MaintenanceRecord, error) {
+	stmt := `SELECT id, car_id, service_date, odometer, service_type, description, cost, performed_by, notes, created_at FROM maintenance WHERE car_id=$1 AND deleted_at IS NULL ORDER BY service_date DESC, id DESC`
+
+	rows, err := m.DB.Query(stmt, carId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []MaintenanceRecord
+
+	for rows.Next() {
+		var rec MaintenanceRecord
+
+		err := rows.Scan(&rec.ID, &rec.CarID, &rec.ServiceDate, &rec.Odometer, &rec.ServiceType, &rec.Description, &rec.Cost, &rec.PerformedBy, &rec.Notes, &rec.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, rec)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func (m *DBModel) UpdateMaintenanceRecord(rec MaintenanceRecord) error {
+	stmt := `UPDATE maintenance SET service_date=$1, odometer=$2, service_type=$3, description=$4, cost=$5, performed_by=$6, notes=$7 WHERE id=$8 AND car_id=$9 AND deleted_at IS NULL`
+
+	res, err := m.DB.Exec(stmt, rec.ServiceDate, rec.Odometer, rec.ServiceType, rec.Description, rec.Cost, rec.PerformedBy, rec.Notes, rec.ID, rec.CarID)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// Moves a maintenance record to the trash
+func (m *DBModel) RemoveMaintenanceRecord(carId, recordId int) error {
+	stmt := `UPDATE maintenance SET deleted_at=CURRENT_TIMESTAMP WHERE id=$1 AND car_id=$2 AND deleted_at IS NULL`
+
+	res, err := m.DB.Exec(stmt, recordId, carId)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
@@ -0,0 +1,234 @@
+package models_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var maintenanceColumns = []string{"id", "car_id", "service_date", "odometer", "service_type", "description", "cost", "performed_by", "notes", "created_at"}
+
+func testMaintenanceRecord() models.MaintenanceRecord {
+	return models.MaintenanceRecord{
+		ID:          1,
+		CarID:       2,
+		ServiceDate: time.Date(2023, 6, 19, 0, 0, 0, 0, time.UTC),
+		Odometer:    45000,
+		ServiceType: "oil change",
+		Description: "Engine oil and filter",
+		Cost:        120.5,
+		PerformedBy: "Local garage",
+		Notes:       "5W-30",
+	}
+}
+
+func TestMaintenanceRecordValidate(t *testing.T) {
+	valid := testMaintenanceRecord()
+	valid.ServiceType = "  oil change  "
+	assert.NoError(t, valid.Validate())
+	assert.Equal(t, "oil change", valid.ServiceType)
+
+	noDate := testMaintenanceRecord()
+	noDate.ServiceDate = time.Time{}
+	assert.EqualError(t, noDate.Validate(), "service date is required")
+
+	noType := testMaintenanceRecord()
+	noType.ServiceType = " "
+	assert.EqualError(t, noType.Validate(), "service type is required")
+
+	negativeOdometer := testMaintenanceRecord()
+	negativeOdometer.Odometer = -1
+	assert.EqualError(t, negativeOdometer.Validate(), "odometer must not be negative")
+
+	negativeCost := testMaintenanceRecord()
+	negativeCost.Cost = -1
+	assert.EqualError(t, negativeCost.Validate(), "cost must not be negative")
+}
+
+func TestInsertMaintenanceRecord_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	rec := testMaintenanceRecord()
+	mock.ExpectQuery(`INSERT INTO maintenance`).WithArgs(
+		rec.CarID, rec.ServiceDate, rec.Odometer, rec.ServiceType, rec.Description, rec.Cost, rec.PerformedBy, rec.Notes,
+	).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+	modelsDB := models.NewModels(db)
+	id, err := modelsDB.DB.InsertMaintenanceRecord(rec)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 7, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertMaintenanceRecord_Error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`INSERT INTO maintenance`).WillReturnError(errors.New("mocked error"))
+
+	modelsDB := models.NewModels(db)
+	id, err := modelsDB.DB.InsertMaintenanceRecord(testMaintenanceRecord())
+
+	assert.EqualError(t, err, "mocked error")
+	assert.Equal(t, 0, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMaintenanceRecordByID_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	expected := testMaintenanceRecord()
+	expected.CreatedAt = "2023-06-19 12:00:00"
+	rows := sqlmock.NewRows(maintenanceColumns).
+		AddRow(1, 2, expected.ServiceDate, 45000, "oil change", "Engine oil and filter", 120.5, "Local garage", "5W-30", "2023-06-19 12:00:00")
+
+	mock.ExpectQuery(`SELECT (.+) FROM maintenance WHERE id=(.+) AND car_id=`).WithArgs(1, 2).WillReturnRows(rows)
+
+	modelsDB := models.NewModels(db)
+	rec, err := modelsDB.DB.GetMaintenanceRecordByID(2, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, rec)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMaintenanceRecordByID_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT (.+) FROM maintenance WHERE id=(.+) AND car_id=`).WithArgs(1, 2).WillReturnError(sql.ErrNoRows)
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.GetMaintenanceRecordByID(2, 1)
+
+	assert.ErrorIs(t, err, models.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMaintenanceRecordsByCarID_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	date := time.Date(2023, 6, 19, 0, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows(maintenanceColumns).
+		AddRow(2, 2, date, 50000, "brakes", "", 300, "", "", "2023-06-19 13:00:00").
+		AddRow(1, 2, date, 45000, "oil change", "", 120.5, "", "", "2023-06-19 12:00:00")
+
+	mock.ExpectQuery(`SELECT (.+) FROM maintenance WHERE car_id=(.+) ORDER BY service_date DESC`).WithArgs(2).WillReturnRows(rows)
+
+	modelsDB := models.NewModels(db)
+	records, err := modelsDB.DB.GetMaintenanceRecordsByCarID(2)
+
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, "brakes", records[0].ServiceType)
+	assert.Equal(t, 45000, records[1].Odometer)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMaintenanceRecordsByCarID_Error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT (.+) FROM maintenance WHERE car_id=`).WithArgs(2).WillReturnError(errors.New("mocked error"))
+
+	modelsDB := models.NewModels(db)
+	records, err := modelsDB.DB.GetMaintenanceRecordsByCarID(2)
+
+	assert.EqualError(t, err, "mocked error")
+	assert.Nil(t, records)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateMaintenanceRecord_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	rec := testMaintenanceRecord()
+	mock.ExpectExec(`UPDATE maintenance SET (.+) WHERE id=(.+) AND car_id=`).WithArgs(
+		rec.ServiceDate, rec.Odometer, rec.ServiceType, rec.Description, rec.Cost, rec.PerformedBy, rec.Notes, rec.ID, rec.CarID,
+	).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.UpdateMaintenanceRecord(rec)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateMaintenanceRecord_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE maintenance SET`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.UpdateMaintenanceRecord(testMaintenanceRecord())
+
+	assert.ErrorIs(t, err, models.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRemoveMaintenanceRecord_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE maintenance SET deleted_at=CURRENT_TIMESTAMP WHERE id=(.+) AND car_id=`).WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.RemoveMaintenanceRecord(2, 1)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRemoveMaintenanceRecord_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE maintenance SET deleted_at=CURRENT_TIMESTAMP WHERE id=(.+) AND car_id=`).WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.RemoveMaintenanceRecord(2, 1)
+
+	assert.ErrorIs(t, err, models.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,374 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Roles of the users sharing a car. The owner is the user the car belongs to,
+// editors may change everything but the car's sharing and viewers only read.
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// The roles the owner can give to the users a car is shared with
+var MemberRoles = []string{RoleEditor, RoleViewer}
+
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationDeclined = "declined"
+)
+
+var ErrAlreadyMember = errors.New("the user already has access to this car")
+var ErrAlreadyInvited = errors.New("the user is already invited to this car")
+
+func IsMemberRole(role string) bool {
+	for _, r := range MemberRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func roleRank(role string) int {
+	switch role {
+	case RoleOwner:
+		return 3
+	case RoleEditor:
+		return 2
+	case RoleViewer:
+		return 1
+	}
+	return 0
+}
+
+// Reports whether a user with the role may do what needs the required role
+func RoleAllows(role, required string) bool {
+	return roleRank(role) > 0 && roleRank(role) >= roleRank(required)
+}
+
+// A user a car is shared with, the owner is listed as a member too
+type CarMember struct {
+	UserID    int    `json:"user_id"`
+	Nickname  string `json:"nickname"`
+	Role      string `json:"role"`
+	CreatedAt string `json:"created_at"`
+}
+
+// An invitation to share a car, sent to a user by email or nickname
+type CarInvitation struct {
+	ID              int        `json:"id"`
+	CarID           int        `json:"car_id"`
+	InviterID       int        `json:"-"`
+	InviterNickname string     `json:"inviter"`
+	InviteeID       int        `json:"-"`
+	InviteeNickname string     `json:"invitee"`
+	Role            string     `json:"role"`
+	Status          string     `json:"status"`
+	CreatedAt       string     `json:"created_at"`
+	RespondedAt     *time.Time `json:"responded_at,omitempty"`
+}
+
+// Checks the role of a membership or an invitation
+func ValidateMemberRole(role string) (string, error) {
+	role = strings.ToLower(strings.TrimSpace(role))
+	if !IsMemberRole(role) {
+		return role, fmt.Errorf("role must be one of: %s", strings.Join(MemberRoles, ", "))
+	}
+
+	return role, nil
+}
+
+// The role of a user on a car, NULL when the user may not access it. On a
+// fleet car the admins of the organization have the rights of the owner, the
+// current driver those of an editor and the other members may view it, unless
+// the car is shared with them in a higher role.
+// Formatted with the car id, the owner id, the organization id and the user id.
+const carRoleQuery = `CASE
+		WHEN %[3]s IS NULL AND %[2]s=%[4]s THEN 'owner'
+		WHEN EXISTS (SELECT 1 FROM organization_members WHERE organization_id=%[3]s AND user_id=%[4]s AND role='admin') THEN 'owner'
+		WHEN EXISTS (SELECT 1 FROM driver_assignments WHERE car_id=%[1]s AND user_id=%[4]s AND ended_at IS NULL) THEN 'editor'
+		ELSE COALESCE((SELECT role FROM car_members WHERE car_id=%[1]s AND user_id=%[4]s),
+			(SELECT 'viewer' FROM organization_members WHERE organization_id=%[3]s AND user_id=%[4]s))
+	END`
+
+// Users who may have a role on the car aliased c: the owner of a personal car,
+// the members it is shared with, the members of its organization and its driver
+const carUsersQuery = `SELECT c.user_id WHERE c.organization_id IS NULL
+	UNION SELECT user_id FROM car_members WHERE car_id=c.id
+	UNION SELECT user_id FROM organization_members WHERE organization_id=c.organization_id
+	UNION SELECT user_id FROM driver_assignments WHERE car_id=c.id AND ended_at IS NULL`
+
+// SQL condition for the car aliased c being one the user, given as a
+// placeholder, may have a role on, see carUsersQuery
+const userCarsFilter = `((c.user_id=%[1]s AND c.organization_id IS NULL)
+	OR c.id IN (SELECT car_id FROM car_members WHERE user_id=%[1]s)
+	OR c.organization_id IN (SELECT organization_id FROM organization_members WHERE user_id=%[1]s)
+	OR c.id IN (SELECT car_id FROM driver_assignments WHERE user_id=%[1]s AND ended_at IS NULL))`
+
+// SQL condition for the user, given as a placeholder, having one of the roles
+// on the car aliased c
+func carRoleIn(userParam string, roles ...string) string {
+	return `(` + fmt.Sprintf(carRoleQuery, "c.id", "c.user_id", "c.organization_id", userParam) + `) IN ('` + strings.Join(roles, `', '`) + `')`
+}
+
+// Returns the role of the user on the car, ErrRecordNotFound when the user may
+// not access it
+func (m *DBModel) GetCarRole(car Car, userId int) (string, error) {
+	var role sql.NullString
+	stmt := `SELECT ` + fmt.Sprintf(carRoleQuery, "$1", "$2", "$3::int", "$4")
+
+	err := m.DB.QueryRow(stmt, car.ID, car.UserId, car.OrganizationID, userId).Scan(&role)
+	if err != nil {
+		return "", err
+	}
+
+	if !role.Valid {
+		return "", ErrRecordNotFound
+	}
+
+	return role.String, nil
+}
+
+// Returns the owner of the car followed by the users it is shared with. A
+// fleet car has no owner, the user who added it is no member of its own.
+func (m *DBModel) GetCarMembers(carId int) ([]CarMember, error) {
+	stmt := `SELECT user_id, nickname, role, created_at FROM (
+			SELECT u.id AS user_id, u.nickname, 'owner' AS role, c.created_at FROM users_cars c JOIN users u ON u.id = c.user_id WHERE c.id=$1 AND c.organization_id IS NULL
+			UNION ALL
+			SELECT u.id, u.nickname, cm.role, cm.created_at FROM car_members cm JOIN users u ON u.id = cm.user_id WHERE cm.car_id=$1
+		) members ORDER BY role <> 'owner', created_at, user_id`
+
+	rows, err := m.DB.Query(stmt, carId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []CarMember
+
+	for rows.Next() {
+		var member CarMember
+
+		err := rows.Scan(&member.UserID, &member.Nickname, &member.Role, &member.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		members = append(members, member)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+func (m *DBModel) UpdateCarMemberRole(carId, userId int, role string) error {
+	res, err := m.DB.Exec(`UPDATE car_members SET role=$1 WHERE car_id=$2 AND user_id=$3`, role, carId, userId)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// Stops sharing the car with the user, used both by the owner and by a member
+// leaving the car
+func (m *DBModel) RemoveCarMember(carId, userId int) error {
+	res, err := m.DB.Exec(`DELETE FROM car_members WHERE car_id=$1 AND user_id=$2`, carId, userId)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// Finds the user to invite by the email, compared case insensitively, or by
+// the nickname. The password is left out.
+func (m *DBModel) GetUserByEmailOrNickname(invitee string) (User, error) {
+	var user User
+	stmt := `SELECT id, first_name, last_name, nickname, email FROM users WHERE LOWER(email)=LOWER($1) OR nickname=$1 ORDER BY nickname=$1 DESC LIMIT 1`
+
+	err := m.DB.QueryRow(stmt, invitee).Scan(&user.ID, &user.FirstName, &user.LastName, &user.Nickname, &user.Email)
+	if err == sql.ErrNoRows {
+		return user, ErrRecordNotFound
+	} else if err != nil {
+		return user, err
+	}
+
+	return user, nil
+}
+
+// Invites a user to the car and queues the notification of the invitee in the
+// same transaction. Fails with ErrAlreadyMember when the invitee has access to
+// the car and with ErrAlreadyInvited while an earlier invitation is pending.
+func (m *DBModel) InsertCarInvitation(inv CarInvitation, notice *NotificationContent) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// locking the car serializes invitations of the same car
+	var ownerId int
+	var orgId *int
+	err = tx.QueryRow(`SELECT user_id, organization_id FROM users_cars WHERE id=$1 AND deleted_at IS NULL FOR UPDATE`, inv.CarID).Scan(&ownerId, &orgId)
+	if err == sql.ErrNoRows {
+		return 0, ErrRecordNotFound
+	} else if err != nil {
+		return 0, err
+	}
+
+	// the user who added a fleet car does not own it
+	if orgId == nil && ownerId == inv.InviteeID {
+		return 0, ErrAlreadyMember
+	}
+
+	var exists bool
+	err = tx.QueryRow(`SELECT exists (SELECT 1 FROM car_members WHERE car_id=$1 AND user_id=$2)`, inv.CarID, inv.InviteeID).Scan(&exists)
+	if err != nil {
+		return 0, err
+	}
+	if exists {
+		return 0, ErrAlreadyMember
+	}
+
+	err = tx.QueryRow(`SELECT exists (SELECT 1 FROM car_invitations WHERE car_id=$1 AND invitee_id=$2 AND status=$3)`, inv.CarID, inv.InviteeID, InvitationPending).Scan(&exists)
+	if err != nil {
+		return 0, err
+	}
+	if exists {
+		return 0, ErrAlreadyInvited
+	}
+
+	var id int
+	stmt := `INSERT INTO car_invitations (car_id, inviter_id, invitee_id, role, status) VALUES($1, $2, $3, $4, $5) RETURNING id`
+	err = tx.QueryRow(stmt, inv.CarID, inv.InviterID, inv.InviteeID, inv.Role, InvitationPending).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	if notice != nil {
+		_, err = tx.Exec(`INSERT INTO notification_outbox (user_id, recipient, subject, body) SELECT id, email, $2, $3 FROM users WHERE id=$1`,
+			inv.InviteeID, notice.Subject, notice.Body)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+const carInvitationQuery = `SELECT i.id, i.car_id, i.inviter_id, inviter.nickname, i.invitee_id, invitee.nickname, i.role, i.status, i.created_at, i.responded_at
+	FROM car_invitations i
+	JOIN users inviter ON inviter.id = i.inviter_id
+	JOIN users invitee ON invitee.id = i.invitee_id`
+
+// Returns the pending invitations of the car
+func (m *DBModel) GetCarInvitations(carId int) ([]CarInvitation, error) {
+	return m.queryCarInvitations(carInvitationQuery+` WHERE i.car_id=$1 AND i.status=$2 ORDER BY i.created_at, i.id`, carId, InvitationPending)
+}
+
+// Returns the pending invitations the user received to cars that are not in
+// the trash
+func (m *DBModel) GetCarInvitationsByUserID(userId int) ([]CarInvitation, error) {
+	return m.queryCarInvitations(carInvitationQuery+` JOIN users_cars c ON c.id = i.car_id
+		WHERE i.invitee_id=$1 AND i.status=$2 AND c.deleted_at IS NULL ORDER BY i.created_at, i.id`, userId, InvitationPending)
+}
+
+func (m *DBModel) queryCarInvitations(stmt string, args ...interface{}) ([]CarInvitation, error) {
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invitations []CarInvitation
+
+	for rows.Next() {
+		var inv CarInvitation
+
+		err := rows.Scan(&inv.ID, &inv.CarID, &inv.InviterID, &inv.InviterNickname, &inv.InviteeID, &inv.InviteeNickname, &inv.Role, &inv.Status, &inv.CreatedAt, &inv.RespondedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		invitations = append(invitations, inv)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+// Withdraws a pending invitation of the car
+func (m *DBModel) RevokeCarInvitation(carId, invitationId int) error {
+	res, err := m.DB.Exec(`DELETE FROM car_invitations WHERE id=$1 AND car_id=$2 AND status=$3`, invitationId, carId, InvitationPending)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// Accepts a pending invitation of the user, the car is then shared with the
+// user in the role of the invitation
+func (m *DBModel) AcceptCarInvitation(userId, invitationId int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var carId int
+	var role string
+	stmt := `SELECT i.car_id, i.role FROM car_invitations i JOIN users_cars c ON c.id = i.car_id
+		WHERE i.id=$1 AND i.invitee_id=$2 AND i.status=$3 AND c.deleted_at IS NULL FOR UPDATE OF i`
+	err = tx.QueryRow(stmt, invitationId, userId, InvitationPending).Scan(&carId, &role)
+	if err == sql.ErrNoRows {
+		return ErrRecordNotFound
+	} else if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE car_invitations SET status=$1, responded_at=CURRENT_TIMESTAMP WHERE id=$2`, InvitationAccepted, invitationId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO car_members (car_id, user_id, role) VALUES($1, $2, $3)
+		ON CONFLICT (car_id, user_id) DO UPDATE SET role=EXCLUDED.role`, carId, userId, role)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Declines a pending invitation of the user
+func (m *DBModel) DeclineCarInvitation(userId, invitationId int) error {
+	stmt := `UPDATE car_invitations SET status=$1, responded_at=CURRENT_TIMESTAMP WHERE id=$2 AND invitee_id=$3 AND status=$4`
+
+	res, err := m.DB.Exec(stmt, InvitationDeclined, invitationId, userId, InvitationPending)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
@@ -0,0 +1,244 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleAllows(t *testing.T) {
+	assert.True(t, models.RoleAllows(models.RoleOwner, models.RoleOwner))
+	assert.True(t, models.RoleAllows(models.RoleOwner, models.RoleViewer))
+	assert.True(t, models.RoleAllows(models.RoleEditor, models.RoleEditor))
+	assert.True(t, models.RoleAllows(models.RoleViewer, models.RoleViewer))
+	assert.False(t, models.RoleAllows(models.RoleEditor, models.RoleOwner))
+	assert.False(t, models.RoleAllows(models.RoleViewer, models.RoleEditor))
+	assert.False(t, models.RoleAllows("", models.RoleViewer))
+	assert.False(t, models.RoleAllows("admin", ""))
+}
+
+func TestValidateMemberRole(t *testing.T) {
+	role, err := models.ValidateMemberRole(" Editor ")
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleEditor, role)
+
+	_, err = models.ValidateMemberRole("owner")
+	assert.Error(t, err)
+}
+
+func TestGetCarRole_FleetDriver(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	orgId := 4
+	mock.ExpectQuery(`SELECT CASE (.+) FROM driver_assignments (.+) FROM car_members`).WithArgs(2, 5, 4, 7).WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("editor"))
+
+	modelsDB := models.NewModels(db)
+	role, err := modelsDB.DB.GetCarRole(models.Car{ID: 2, UserId: 5, OrganizationID: &orgId}, 7)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleEditor, role)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetCarRole_NoAccess(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT CASE`).WithArgs(2, 5, nil, 7).WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(nil))
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.GetCarRole(models.Car{ID: 2, UserId: 5}, 7)
+
+	assert.True(t, errors.Is(err, models.ErrRecordNotFound))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetCarMembers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"user_id", "nickname", "role", "created_at"}).
+		AddRow(5, "jane", "owner", "2023-06-19 12:00:00").
+		AddRow(7, "john", "viewer", "2023-07-01 12:00:00")
+	mock.ExpectQuery(`SELECT user_id, nickname, role, created_at FROM (.+) WHERE c.id=\$1 AND c.organization_id IS NULL (.+) ORDER BY role <> 'owner'`).WithArgs(2).WillReturnRows(rows)
+
+	modelsDB := models.NewModels(db)
+	members, err := modelsDB.DB.GetCarMembers(2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.CarMember{
+		{UserID: 5, Nickname: "jane", Role: models.RoleOwner, CreatedAt: "2023-06-19 12:00:00"},
+		{UserID: 7, Nickname: "john", Role: models.RoleViewer, CreatedAt: "2023-07-01 12:00:00"},
+	}, members)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRemoveCarMember_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`DELETE FROM car_members WHERE car_id=\$1 AND user_id=\$2`).WithArgs(2, 7).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.RemoveCarMember(2, 7)
+
+	assert.True(t, errors.Is(err, models.ErrRecordNotFound))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertCarInvitation_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	inv := models.CarInvitation{CarID: 2, InviterID: 5, InviteeID: 7, Role: models.RoleEditor}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT user_id, organization_id FROM users_cars WHERE id=\$1 AND deleted_at IS NULL FOR UPDATE`).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"user_id", "organization_id"}).AddRow(5, nil))
+	mock.ExpectQuery(`SELECT exists \(SELECT 1 FROM car_members`).WithArgs(2, 7).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery(`SELECT exists \(SELECT 1 FROM car_invitations`).WithArgs(2, 7, "pending").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery(`INSERT INTO car_invitations`).WithArgs(2, 5, 7, "editor", "pending").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectExec(`INSERT INTO notification_outbox`).WithArgs(7, "Shared", "Body").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	modelsDB := models.NewModels(db)
+	id, err := modelsDB.DB.InsertCarInvitation(inv, &models.NotificationContent{Subject: "Shared", Body: "Body"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertCarInvitation_Owner(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT user_id, organization_id FROM users_cars`).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"user_id", "organization_id"}).AddRow(5, nil))
+	mock.ExpectRollback()
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.InsertCarInvitation(models.CarInvitation{CarID: 2, InviterID: 5, InviteeID: 5, Role: models.RoleViewer}, nil)
+
+	assert.True(t, errors.Is(err, models.ErrAlreadyMember))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertCarInvitation_FleetCarCreator(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT user_id, organization_id FROM users_cars`).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"user_id", "organization_id"}).AddRow(5, 4))
+	mock.ExpectQuery(`SELECT exists \(SELECT 1 FROM car_members`).WithArgs(2, 5).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery(`SELECT exists \(SELECT 1 FROM car_invitations`).WithArgs(2, 5, "pending").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery(`INSERT INTO car_invitations`).WithArgs(2, 7, 5, "viewer", "pending").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectCommit()
+
+	modelsDB := models.NewModels(db)
+	id, err := modelsDB.DB.InsertCarInvitation(models.CarInvitation{CarID: 2, InviterID: 7, InviteeID: 5, Role: models.RoleViewer}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertCarInvitation_AlreadyInvited(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT user_id, organization_id FROM users_cars`).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"user_id", "organization_id"}).AddRow(5, nil))
+	mock.ExpectQuery(`SELECT exists \(SELECT 1 FROM car_members`).WithArgs(2, 7).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery(`SELECT exists \(SELECT 1 FROM car_invitations`).WithArgs(2, 7, "pending").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.InsertCarInvitation(models.CarInvitation{CarID: 2, InviterID: 5, InviteeID: 7, Role: models.RoleViewer}, nil)
+
+	assert.True(t, errors.Is(err, models.ErrAlreadyInvited))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAcceptCarInvitation_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT i.car_id, i.role FROM car_invitations i (.+) FOR UPDATE OF i`).WithArgs(3, 7, "pending").
+		WillReturnRows(sqlmock.NewRows([]string{"car_id", "role"}).AddRow(2, "editor"))
+	mock.ExpectExec(`UPDATE car_invitations SET status=\$1`).WithArgs("accepted", 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO car_members (.+) ON CONFLICT`).WithArgs(2, 7, "editor").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.AcceptCarInvitation(7, 3)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAcceptCarInvitation_NotPending(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT i.car_id, i.role FROM car_invitations`).WithArgs(3, 7, "pending").WillReturnRows(sqlmock.NewRows([]string{"car_id", "role"}))
+	mock.ExpectRollback()
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.AcceptCarInvitation(7, 3)
+
+	assert.True(t, errors.Is(err, models.ErrRecordNotFound))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeclineCarInvitation_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE car_invitations SET status=\$1, responded_at=CURRENT_TIMESTAMP WHERE id=\$2 AND invitee_id=\$3 AND status=\$4`).
+		WithArgs("declined", 3, 7, "pending").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.DeclineCarInvitation(7, 3)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
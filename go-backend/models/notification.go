@@ -0,0 +1,127 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+const (
+	NotificationStatusPending = "pending"
+	NotificationStatusSent    = "sent"
+	NotificationStatusFailed  = "failed"
+
+	// how long a claimed notification is hidden from other dispatchers
+	notificationLease = 5 * time.Minute
+)
+
+// A message waiting in the outbox. It stays in the table after delivery, and
+// after the last failed attempt, so that no message is silently lost.
+type Notification struct {
+	ID          int       `json:"id"`
+	UserID      *int      `json:"user_id,omitempty"`
+	Recipient   string    `json:"recipient"`
+	Subject     string    `json:"subject"`
+	Body        string    `json:"body"`
+	Status      string    `json:"status"`
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"next_attempt_at"`
+	LastError   string    `json:"last_error,omitempty"`
+	CreatedAt   string    `json:"created_at"`
+}
+
+// The content of a notification whose recipient is resolved by the database
+type NotificationContent struct {
+	Subject string
+	Body    string
+}
+
+// Adds a message for the user to the outbox
+func (m *DBModel) EnqueueNotification(userId int, subject, body string) error {
+	stmt := `INSERT INTO notification_outbox (user_id, recipient, subject, body) SELECT id, email, $2, $3 FROM users WHERE id=$1`
+
+	res, err := m.DB.Exec(stmt, userId, subject, body)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// Claims up to limit pending notifications that are due for an attempt. A
+// claimed notification is not handed out again until its lease expires, so
+// several dispatchers can run at once.
+func (m *DBModel) ClaimNotifications(limit int, now time.Time) ([]Notification, error) {
+	stmt := `UPDATE notification_outbox SET next_attempt_at=$3
+		WHERE id IN (
+			SELECT id FROM notification_outbox WHERE status=$1 AND next_attempt_at <= $2
+			ORDER BY next_attempt_at ASC, id ASC LIMIT $4 FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id, recipient, subject, body, status, attempts, next_attempt_at, last_error, created_at`
+
+	rows, err := m.DB.Query(stmt, NotificationStatusPending, now, now.Add(notificationLease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []Notification
+
+	for rows.Next() {
+		var n Notification
+
+		err := rows.Scan(&n.ID, &n.UserID, &n.Recipient, &n.Subject, &n.Body, &n.Status, &n.Attempts, &n.NextAttempt, &n.LastError, &n.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		notifications = append(notifications, n)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+func (m *DBModel) MarkNotificationSent(id int) error {
+	stmt := `UPDATE notification_outbox SET status=$1, attempts=attempts+1, last_error='', sent_at=CURRENT_TIMESTAMP WHERE id=$2`
+
+	res, err := m.DB.Exec(stmt, NotificationStatusSent, id)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// Records a failed attempt. The notification is retried at nextAttempt, or
+// marked as failed for good when final is set.
+func (m *DBModel) MarkNotificationFailed(id int, lastError string, nextAttempt time.Time, final bool) error {
+	status := NotificationStatusPending
+	if final {
+		status = NotificationStatusFailed
+	}
+
+	stmt := `UPDATE notification_outbox SET status=$1, attempts=attempts+1, last_error=$2, next_attempt_at=$3 WHERE id=$4`
+
+	res, err := m.DB.Exec(stmt, status, lastError, nextAttempt, id)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// Inserts the notification of a new reminder in the same transaction as the
+// reminder, addressed to every user who may edit the car: the owner and the
+// editors of a personal car, the admins and the driver of a fleet car
+func enqueueCarNotification(tx *sql.Tx, carId int, content NotificationContent) error {
+	stmt := `INSERT INTO notification_outbox (user_id, recipient, subject, body)
+		SELECT u.id, u.email, $2, $3 FROM users_cars c JOIN users u ON u.id IN (` + carUsersQuery + `)
+		WHERE c.id=$1 AND ` + carRoleIn("u.id", RoleOwner, RoleEditor)
+
+	_, err := tx.Exec(stmt, carId, content.Subject, content.Body)
+
+	return err
+}
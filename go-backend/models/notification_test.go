@@ -0,0 +1,83 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnqueueNotification_UnknownUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`INSERT INTO notification_outbox (.+) FROM users WHERE id=`).WithArgs(5, "Subject", "Body").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.EnqueueNotification(5, "Subject", "Body")
+
+	assert.ErrorIs(t, err, models.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClaimNotifications_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	now := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"id", "user_id", "recipient", "subject", "body", "status", "attempts", "next_attempt_at", "last_error", "created_at"}).
+		AddRow(1, 5, "john@example.com", "Subject", "Body", "pending", 0, now.Add(5*time.Minute), "", "2023-07-01 11:00:00")
+
+	mock.ExpectQuery(`UPDATE notification_outbox SET next_attempt_at=(.+) FOR UPDATE SKIP LOCKED`).
+		WithArgs("pending", now, now.Add(5*time.Minute), 10).
+		WillReturnRows(rows)
+
+	modelsDB := models.NewModels(db)
+	notifications, err := modelsDB.DB.ClaimNotifications(10, now)
+
+	assert.NoError(t, err)
+	assert.Len(t, notifications, 1)
+	assert.Equal(t, "john@example.com", notifications[0].Recipient)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMarkNotificationSent_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE notification_outbox SET status=`).WithArgs("sent", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.MarkNotificationSent(1)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMarkNotificationFailed_Final(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	next := time.Date(2023, 7, 1, 13, 0, 0, 0, time.UTC)
+	mock.ExpectExec(`UPDATE notification_outbox SET status=`).WithArgs("failed", "connection refused", next, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.MarkNotificationFailed(1, "connection refused", next, true)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,176 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// returned when a reading would make the odometer go backwards
+var ErrOdometerDecrease = errors.New("odometer reading is out of order")
+
+// Latest odometer value of a car. Readings, maintenance records, fill-ups,
+// charging sessions and tire swaps all count, the most recent one wins so that an odometer replacement resets the value.
+// The placeholder is the car id expression.
+const currentOdometerQuery = `SELECT odometer FROM (
+		SELECT reading_date, odometer FROM odometer_readings WHERE car_id=%[1]s
+		UNION ALL
+		SELECT service_date, odometer FROM maintenance WHERE car_id=%[1]s AND odometer > 0 AND deleted_at IS NULL
+		UNION ALL
+		SELECT fill_date, odometer FROM fuel_logs WHERE car_id=%[1]s
+		UNION ALL
+		SELECT charge_date, odometer FROM charging_sessions WHERE car_id=%[1]s
+		UNION ALL
+		SELECT event_date, odometer FROM tire_events WHERE car_id=%[1]s AND odometer > 0
+	) o ORDER BY reading_date DESC, odometer DESC LIMIT 1`
+
+type OdometerReading struct {
+	ID          int       `json:"id"`
+	CarID       int       `json:"car_id"`
+	ReadingDate time.Time `json:"reading_date"`
+	Odometer    int       `json:"odometer"`
+	// set when the instrument cluster was replaced and the odometer starts over
+	IsReplacement bool   `json:"is_replacement"`
+	Note          string `json:"note,omitempty"`
+	CreatedAt     string `json:"created_at"`
+}
+
+// Checks the fields a client has to provide for an odometer reading
+func (r *OdometerReading) Validate() error {
+	if r.ReadingDate.IsZero() {
+		return errors.New("reading date is required")
+	}
+
+	if r.Odometer < 0 {
+		return errors.New("odometer must not be negative")
+	}
+
+	return nil
+}
+
+// Checks that a reading fits between its chronological neighbours. prev is the
+// latest reading on or before the reading date, next the earliest one after it.
+func CheckOdometerOrder(reading OdometerReading, prev, next *OdometerReading) error {
+	if prev != nil && !reading.IsReplacement && reading.Odometer < prev.Odometer {
+		return fmt.Errorf("%w: %d km is lower than %d km recorded on %s, mark the reading as an odometer replacement if the odometer was changed",
+			ErrOdometerDecrease, reading.Odometer, prev.Odometer, prev.ReadingDate.Format("2006-01-02"))
+	}
+
+	if next != nil && !next.IsReplacement && next.Odometer < reading.Odometer {
+		return fmt.Errorf("%w: %d km is higher than %d km recorded later on %s",
+			ErrOdometerDecrease, reading.Odometer, next.Odometer, next.ReadingDate.Format("2006-01-02"))
+	}
+
+	return nil
+}
+
+// Inserts a reading after checking it against the readings around its date
+func (m *DBModel) InsertOdometerReading(reading OdometerReading) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// serialize concurrent readings of the same car
+	_, err = tx.Exec(`SELECT id FROM users_cars WHERE id=$1 FOR UPDATE`, reading.CarID)
+	if err != nil {
+		return 0, err
+	}
+
+	prev, err := scanOdometerNeighbour(tx.QueryRow(`SELECT id, car_id, reading_date, odometer, is_replacement, note, created_at FROM odometer_readings
+		WHERE car_id=$1 AND reading_date <= $2 ORDER BY reading_date DESC, id DESC LIMIT 1`, reading.CarID, reading.ReadingDate))
+	if err != nil {
+		return 0, err
+	}
+
+	next, err := scanOdometerNeighbour(tx.QueryRow(`SELECT id, car_id, reading_date, odometer, is_replacement, note, created_at FROM odometer_readings
+		WHERE car_id=$1 AND reading_date > $2 ORDER BY reading_date ASC, id ASC LIMIT 1`, reading.CarID, reading.ReadingDate))
+	if err != nil {
+		return 0, err
+	}
+
+	err = CheckOdometerOrder(reading, prev, next)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	stmt := `INSERT INTO odometer_readings (car_id, reading_date, odometer, is_replacement, note) VALUES($1, $2, $3, $4, $5) RETURNING id`
+	err = tx.QueryRow(stmt, reading.CarID, reading.ReadingDate, reading.Odometer, reading.IsReplacement, reading.Note).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func scanOdometerNeighbour(row *sql.Row) (*OdometerReading, error) {
+	var r OdometerReading
+	err := row.Scan(&r.ID, &r.CarID, &r.ReadingDate, &r.Odometer, &r.IsReplacement, &r.Note, &r.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+func (m *DBModel) GetOdometerReadingsByCarID(carId int) ([]OdometerReading, error) {
+	stmt := `SELECT id, car_id, reading_date, odometer, is_replacement, note, created_at FROM odometer_readings WHERE car_id=$1 ORDER BY reading_date DESC, id DESC`
+
+	rows, err := m.DB.Query(stmt, carId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var readings []OdometerReading
+
+	for rows.Next() {
+		var r OdometerReading
+
+		err := rows.Scan(&r.ID, &r.CarID, &r.ReadingDate, &r.Odometer, &r.IsReplacement, &r.Note, &r.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		readings = append(readings, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return readings, nil
+}
+
+func (m *DBModel) RemoveOdometerReading(carId, readingId int) error {
+	stmt := `DELETE FROM odometer_readings WHERE id=$1 AND car_id=$2`
+
+	res, err := m.DB.Exec(stmt, readingId, carId)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// Returns the current odometer value of a car, 0 when nothing is known
+func (m *DBModel) GetCurrentOdometer(carId int) (int, error) {
+	var odometer int
+	stmt := `SELECT COALESCE((` + fmt.Sprintf(currentOdometerQuery, "$1") + `), 0)`
+
+	err := m.DB.QueryRow(stmt, carId).Scan(&odometer)
+	if err != nil {
+		return 0, err
+	}
+
+	return odometer, nil
+}
@@ -0,0 +1,170 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var odometerColumns = []string{"id", "car_id", "reading_date", "odometer", "is_replacement", "note", "created_at"}
+
+func TestCheckOdometerOrder(t *testing.T) {
+	jan := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	jun := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	prev := &models.OdometerReading{ReadingDate: jan, Odometer: 10000}
+	next := &models.OdometerReading{ReadingDate: jun, Odometer: 20000}
+
+	tests := []struct {
+		name    string
+		reading models.OdometerReading
+		prev    *models.OdometerReading
+		next    *models.OdometerReading
+		valid   bool
+	}{
+		{name: "FirstReading", reading: models.OdometerReading{Odometer: 5}, valid: true},
+		{name: "BetweenNeighbours", reading: models.OdometerReading{Odometer: 15000}, prev: prev, next: next, valid: true},
+		{name: "EqualToPrevious", reading: models.OdometerReading{Odometer: 10000}, prev: prev, valid: true},
+		{name: "LowerThanPrevious", reading: models.OdometerReading{Odometer: 9000}, prev: prev, valid: false},
+		{name: "ReplacementLowerThanPrevious", reading: models.OdometerReading{Odometer: 10, IsReplacement: true}, prev: prev, valid: true},
+		{name: "HigherThanNext", reading: models.OdometerReading{Odometer: 25000}, prev: prev, next: next, valid: false},
+		{name: "HigherThanNextReplacement", reading: models.OdometerReading{Odometer: 25000}, next: &models.OdometerReading{ReadingDate: jun, Odometer: 10, IsReplacement: true}, valid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := models.CheckOdometerOrder(tt.reading, tt.prev, tt.next)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, models.ErrOdometerDecrease)
+			}
+		})
+	}
+}
+
+func TestCheckOdometerOrder_Message(t *testing.T) {
+	prev := &models.OdometerReading{ReadingDate: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), Odometer: 10000}
+
+	err := models.CheckOdometerOrder(models.OdometerReading{Odometer: 9000}, prev, nil)
+
+	assert.EqualError(t, err, "odometer reading is out of order: 9000 km is lower than 10000 km recorded on 2023-01-01, mark the reading as an odometer replacement if the odometer was changed")
+}
+
+func TestInsertOdometerReading_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	date := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	reading := models.OdometerReading{CarID: 2, ReadingDate: date, Odometer: 15000, Note: "annual check"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT id FROM users_cars WHERE id=(.+) FOR UPDATE`).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT (.+) FROM odometer_readings WHERE car_id=(.+) AND reading_date <=`).WithArgs(2, date).WillReturnRows(
+		sqlmock.NewRows(odometerColumns).AddRow(1, 2, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), 10000, false, "", ""),
+	)
+	mock.ExpectQuery(`SELECT (.+) FROM odometer_readings WHERE car_id=(.+) AND reading_date >`).WithArgs(2, date).WillReturnRows(sqlmock.NewRows(odometerColumns))
+	mock.ExpectQuery(`INSERT INTO odometer_readings`).WithArgs(2, date, 15000, false, "annual check").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectCommit()
+
+	modelsDB := models.NewModels(db)
+	id, err := modelsDB.DB.InsertOdometerReading(reading)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertOdometerReading_Decrease(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	date := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	reading := models.OdometerReading{CarID: 2, ReadingDate: date, Odometer: 9000}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT id FROM users_cars WHERE id=(.+) FOR UPDATE`).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT (.+) FROM odometer_readings WHERE car_id=(.+) AND reading_date <=`).WithArgs(2, date).WillReturnRows(
+		sqlmock.NewRows(odometerColumns).AddRow(1, 2, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), 10000, false, "", ""),
+	)
+	mock.ExpectQuery(`SELECT (.+) FROM odometer_readings WHERE car_id=(.+) AND reading_date >`).WithArgs(2, date).WillReturnRows(sqlmock.NewRows(odometerColumns))
+	mock.ExpectRollback()
+
+	modelsDB := models.NewModels(db)
+	id, err := modelsDB.DB.InsertOdometerReading(reading)
+
+	assert.ErrorIs(t, err, models.ErrOdometerDecrease)
+	assert.Equal(t, 0, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetOdometerReadingsByCarID_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	date := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT (.+) FROM odometer_readings WHERE car_id=(.+) ORDER BY reading_date DESC`).WithArgs(2).WillReturnRows(
+		sqlmock.NewRows(odometerColumns).
+			AddRow(2, 2, date, 15, true, "new cluster", "").
+			AddRow(1, 2, date, 10000, false, "", ""),
+	)
+
+	modelsDB := models.NewModels(db)
+	readings, err := modelsDB.DB.GetOdometerReadingsByCarID(2)
+
+	assert.NoError(t, err)
+	assert.Len(t, readings, 2)
+	assert.True(t, readings[0].IsReplacement)
+	assert.Equal(t, 10000, readings[1].Odometer)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRemoveOdometerReading_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`DELETE FROM odometer_readings WHERE id=(.+) AND car_id=`).WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.RemoveOdometerReading(2, 1)
+
+	assert.ErrorIs(t, err, models.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetCurrentOdometer(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT COALESCE\(\(SELECT odometer FROM (.+) odometer_readings (.+) UNION ALL (.+) maintenance (.+)\), 0\)`).WithArgs(2).WillReturnRows(
+		sqlmock.NewRows([]string{"odometer"}).AddRow(52500),
+	)
+	mock.ExpectQuery(`SELECT COALESCE`).WithArgs(3).WillReturnError(errors.New("mocked error"))
+
+	modelsDB := models.NewModels(db)
+	odometer, err := modelsDB.DB.GetCurrentOdometer(2)
+	assert.NoError(t, err)
+	assert.Equal(t, 52500, odometer)
+
+	_, err = modelsDB.DB.GetCurrentOdometer(3)
+	assert.EqualError(t, err, "mocked error")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
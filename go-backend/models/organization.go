@@ -0,0 +1,355 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Roles in an organization. Admins manage the members and the fleet, members
+// see the fleet cars and edit the ones they drive.
+const (
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+var OrganizationRoles = []string{OrgRoleAdmin, OrgRoleMember}
+
+var ErrAlreadyOrganizationMember = errors.New("the user is already a member of this organization")
+var ErrNotOrganizationMember = errors.New("the user is not a member of this organization")
+var ErrLastAdmin = errors.New("an organization needs at least one admin")
+var ErrOrganizationNotEmpty = errors.New("an organization with cars cannot be deleted")
+
+// A company or a team owning a fleet of cars
+type Organization struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// the role of the requesting user
+	Role      string `json:"role,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+type OrganizationMember struct {
+	UserID    int    `json:"user_id"`
+	Nickname  string `json:"nickname"`
+	Role      string `json:"role"`
+	CreatedAt string `json:"created_at"`
+}
+
+func IsOrganizationRole(role string) bool {
+	for _, r := range OrganizationRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports whether a member with the role may do what needs the required role
+func OrganizationRoleAllows(role, required string) bool {
+	switch required {
+	case OrgRoleAdmin:
+		return role == OrgRoleAdmin
+	case OrgRoleMember:
+		return IsOrganizationRole(role)
+	}
+	return false
+}
+
+// Checks the role of an organization member
+func ValidateOrganizationRole(role string) (string, error) {
+	role = strings.ToLower(strings.TrimSpace(role))
+	if !IsOrganizationRole(role) {
+		return role, fmt.Errorf("role must be one of: %s", strings.Join(OrganizationRoles, ", "))
+	}
+
+	return role, nil
+}
+
+// Checks the fields a client has to provide for an organization
+func (o *Organization) Validate() error {
+	o.Name = strings.TrimSpace(o.Name)
+	if o.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if len(o.Name) > 100 {
+		return errors.New("name must not be longer than 100 characters")
+	}
+
+	return nil
+}
+
+// Creates the organization with the user as its first admin
+func (m *DBModel) InsertOrganization(org Organization, userId int) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRow(`INSERT INTO organizations (name) VALUES($1) RETURNING id`, org.Name).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`INSERT INTO organization_members (organization_id, user_id, role) VALUES($1, $2, $3)`, id, userId, OrgRoleAdmin)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// Returns the organizations the user is a member of with the user's role
+func (m *DBModel) GetOrganizationsByUserID(userId int) ([]Organization, error) {
+	stmt := `SELECT o.id, o.name, om.role, o.created_at FROM organizations o
+		JOIN organization_members om ON om.organization_id = o.id
+		WHERE om.user_id=$1 ORDER BY o.name, o.id`
+
+	rows, err := m.DB.Query(stmt, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	organizations := []Organization{}
+
+	for rows.Next() {
+		var org Organization
+
+		err := rows.Scan(&org.ID, &org.Name, &org.Role, &org.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		organizations = append(organizations, org)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return organizations, nil
+}
+
+// Returns the organization with the role of the user, ErrRecordNotFound when
+// the user is not a member
+func (m *DBModel) GetOrganization(orgId, userId int) (Organization, error) {
+	var org Organization
+	stmt := `SELECT o.id, o.name, om.role, o.created_at FROM organizations o
+		JOIN organization_members om ON om.organization_id = o.id
+		WHERE o.id=$1 AND om.user_id=$2`
+
+	err := m.DB.QueryRow(stmt, orgId, userId).Scan(&org.ID, &org.Name, &org.Role, &org.CreatedAt)
+	if err == sql.ErrNoRows {
+		return org, ErrRecordNotFound
+	} else if err != nil {
+		return org, err
+	}
+
+	return org, nil
+}
+
+func (m *DBModel) UpdateOrganization(org Organization) error {
+	res, err := m.DB.Exec(`UPDATE organizations SET name=$1 WHERE id=$2`, org.Name, org.ID)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// Deletes an organization and its memberships. Its cars have to be gone
+// first, those in the trash are purged with it.
+func (m *DBModel) RemoveOrganization(orgId int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockOrganization(tx, orgId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM users_cars WHERE organization_id=$1 AND deleted_at IS NOT NULL`, orgId)
+	if err != nil {
+		return err
+	}
+
+	var hasCars bool
+	err = tx.QueryRow(`SELECT exists (SELECT 1 FROM users_cars WHERE organization_id=$1)`, orgId).Scan(&hasCars)
+	if err != nil {
+		return err
+	}
+	if hasCars {
+		return ErrOrganizationNotEmpty
+	}
+
+	_, err = tx.Exec(`DELETE FROM organizations WHERE id=$1`, orgId)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Locks the organization so that changes of its members and its fleet are
+// serialized
+func lockOrganization(tx *sql.Tx, orgId int) error {
+	var id int
+	err := tx.QueryRow(`SELECT id FROM organizations WHERE id=$1 FOR UPDATE`, orgId).Scan(&id)
+	if err == sql.ErrNoRows {
+		return ErrRecordNotFound
+	}
+
+	return err
+}
+
+// Returns the members of the organization, the admins first
+func (m *DBModel) GetOrganizationMembers(orgId int) ([]OrganizationMember, error) {
+	stmt := `SELECT u.id, u.nickname, om.role, om.created_at FROM organization_members om
+		JOIN users u ON u.id = om.user_id
+		WHERE om.organization_id=$1 ORDER BY om.role <> 'admin', u.nickname`
+
+	rows, err := m.DB.Query(stmt, orgId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []OrganizationMember{}
+
+	for rows.Next() {
+		var member OrganizationMember
+
+		err := rows.Scan(&member.UserID, &member.Nickname, &member.Role, &member.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		members = append(members, member)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+func (m *DBModel) AddOrganizationMember(orgId, userId int, role string) error {
+	stmt := `INSERT INTO organization_members (organization_id, user_id, role) VALUES($1, $2, $3) ON CONFLICT DO NOTHING`
+
+	res, err := m.DB.Exec(stmt, orgId, userId, role)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrAlreadyOrganizationMember
+	}
+
+	return nil
+}
+
+// Changes the role of a member, the last admin cannot become a member
+func (m *DBModel) UpdateOrganizationMemberRole(orgId, userId int, role string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockOrganization(tx, orgId)
+	if err != nil {
+		return err
+	}
+
+	if role != OrgRoleAdmin {
+		err = checkOtherAdmins(tx, orgId, userId)
+		if err != nil {
+			return err
+		}
+	}
+
+	res, err := tx.Exec(`UPDATE organization_members SET role=$1 WHERE organization_id=$2 AND user_id=$3`, role, orgId, userId)
+	if err != nil {
+		return err
+	}
+
+	err = checkRowsAffected(res)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Removes a member from the organization and ends the member's driver
+// assignments. The last admin cannot leave.
+func (m *DBModel) RemoveOrganizationMember(orgId, userId int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockOrganization(tx, orgId)
+	if err != nil {
+		return err
+	}
+
+	err = checkOtherAdmins(tx, orgId, userId)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(`DELETE FROM organization_members WHERE organization_id=$1 AND user_id=$2`, orgId, userId)
+	if err != nil {
+		return err
+	}
+
+	err = checkRowsAffected(res)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE driver_assignments SET ended_at=CURRENT_DATE
+		WHERE user_id=$1 AND ended_at IS NULL AND car_id IN (SELECT id FROM users_cars WHERE organization_id=$2)`, userId, orgId)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Fails with ErrLastAdmin when the user is the only admin of the organization
+func checkOtherAdmins(tx *sql.Tx, orgId, userId int) error {
+	var lastAdmin bool
+	stmt := `SELECT exists (SELECT 1 FROM organization_members WHERE organization_id=$1 AND user_id=$2 AND role=$3)
+		AND NOT exists (SELECT 1 FROM organization_members WHERE organization_id=$1 AND user_id<>$2 AND role=$3)`
+
+	err := tx.QueryRow(stmt, orgId, userId, OrgRoleAdmin).Scan(&lastAdmin)
+	if err != nil {
+		return err
+	}
+	if lastAdmin {
+		return ErrLastAdmin
+	}
+
+	return nil
+}
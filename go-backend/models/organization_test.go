@@ -0,0 +1,140 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrganizationRoleAllows(t *testing.T) {
+	assert.True(t, models.OrganizationRoleAllows(models.OrgRoleAdmin, models.OrgRoleAdmin))
+	assert.True(t, models.OrganizationRoleAllows(models.OrgRoleAdmin, models.OrgRoleMember))
+	assert.True(t, models.OrganizationRoleAllows(models.OrgRoleMember, models.OrgRoleMember))
+	assert.False(t, models.OrganizationRoleAllows(models.OrgRoleMember, models.OrgRoleAdmin))
+	assert.False(t, models.OrganizationRoleAllows("", models.OrgRoleMember))
+}
+
+func TestOrganizationValidate(t *testing.T) {
+	org := models.Organization{Name: "  Acme  "}
+	assert.NoError(t, org.Validate())
+	assert.Equal(t, "Acme", org.Name)
+
+	org = models.Organization{Name: " "}
+	assert.Error(t, org.Validate())
+}
+
+func TestInsertOrganization(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO organizations \(name\) VALUES\(\$1\) RETURNING id`).WithArgs("Acme").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectExec(`INSERT INTO organization_members`).WithArgs(4, 5, "admin").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	modelsDB := models.NewModels(db)
+	id, err := modelsDB.DB.InsertOrganization(models.Organization{Name: "Acme"}, 5)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetOrganization_NotMember(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT o.id, o.name, om.role, o.created_at FROM organizations o (.+) WHERE o.id=\$1 AND om.user_id=\$2`).WithArgs(4, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "role", "created_at"}))
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.GetOrganization(4, 7)
+
+	assert.True(t, errors.Is(err, models.ErrRecordNotFound))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRemoveOrganization_WithCars(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM organizations WHERE id=\$1 FOR UPDATE`).WithArgs(4).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectExec(`DELETE FROM users_cars WHERE organization_id=\$1 AND deleted_at IS NOT NULL`).WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery(`SELECT exists \(SELECT 1 FROM users_cars WHERE organization_id=\$1\)`).WithArgs(4).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.RemoveOrganization(4)
+
+	assert.True(t, errors.Is(err, models.ErrOrganizationNotEmpty))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddOrganizationMember_AlreadyMember(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`INSERT INTO organization_members (.+) ON CONFLICT DO NOTHING`).WithArgs(4, 7, "member").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.AddOrganizationMember(4, 7, models.OrgRoleMember)
+
+	assert.True(t, errors.Is(err, models.ErrAlreadyOrganizationMember))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateOrganizationMemberRole_LastAdmin(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM organizations`).WithArgs(4).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectQuery(`SELECT exists (.+) AND NOT exists`).WithArgs(4, 5, "admin").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.UpdateOrganizationMemberRole(4, 5, models.OrgRoleMember)
+
+	assert.True(t, errors.Is(err, models.ErrLastAdmin))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRemoveOrganizationMember_EndsDriverAssignments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM organizations`).WithArgs(4).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectQuery(`SELECT exists (.+) AND NOT exists`).WithArgs(4, 7, "admin").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec(`DELETE FROM organization_members WHERE organization_id=\$1 AND user_id=\$2`).WithArgs(4, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE driver_assignments SET ended_at=CURRENT_DATE`).WithArgs(7, 4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.RemoveOrganizationMember(4, 7)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,179 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	ReminderKindService  = "service"
+	ReminderKindDocument = "document"
+)
+
+// A pending notice about a service or a document that is due soon or overdue. DedupeKey
+// identifies the occurrence, so evaluating the same occurrence again updates
+// the existing reminder instead of creating another one.
+type Reminder struct {
+	ID          int        `json:"id"`
+	CarID       int        `json:"car_id"`
+	Kind        string     `json:"kind"`
+	SourceID    int        `json:"source_id"`
+	Title       string     `json:"title"`
+	Status      string     `json:"status"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	DueOdometer *int       `json:"due_odometer,omitempty"`
+	DedupeKey   string     `json:"-"`
+	CreatedAt   string     `json:"created_at"`
+}
+
+// Runs fn while holding a Postgres session level advisory lock, so that only
+// one API replica runs it at a time. Returns false without running fn when
+// another session holds the lock.
+func (m *DBModel) WithAdvisoryLock(ctx context.Context, key int64, fn func() error) (bool, error) {
+	// the lock belongs to the session, so keep one connection for its lifetime
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	var locked bool
+	err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked)
+	if err != nil {
+		return false, err
+	}
+
+	if !locked {
+		return false, nil
+	}
+
+	// unlock even when ctx has been cancelled in the meantime
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key)
+
+	return true, fn()
+}
+
+// Returns the ids of all cars
+func (m *DBModel) GetAllCarIDs() ([]int, error) {
+	rows, err := m.DB.Query("SELECT id FROM users_cars WHERE archived_at IS NULL AND deleted_at IS NULL ORDER BY id ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// Stores a reminder, updating the status of an existing reminder with the same
+// dedupe key. When a new reminder is created and notice is set, the notice is
+// queued for the users who may edit the car in the same transaction. Returns
+// true when a new reminder was created.
+func (m *DBModel) UpsertReminder(reminder Reminder, notice *NotificationContent) (bool, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	stmt := `INSERT INTO reminders (car_id, kind, source_id, title, status, due_date, due_odometer, dedupe_key) VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (dedupe_key) DO UPDATE SET title=EXCLUDED.title, status=EXCLUDED.status, due_date=EXCLUDED.due_date, due_odometer=EXCLUDED.due_odometer, resolved_at=NULL
+		RETURNING (xmax = 0)`
+
+	var inserted bool
+	err = tx.QueryRow(stmt, reminder.CarID, reminder.Kind, reminder.SourceID, reminder.Title, reminder.Status, reminder.DueDate, reminder.DueOdometer, reminder.DedupeKey).Scan(&inserted)
+	if err != nil {
+		return false, err
+	}
+
+	if inserted && notice != nil {
+		err = enqueueCarNotification(tx, reminder.CarID, *notice)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return inserted, nil
+}
+
+// Resolves the pending reminders of a car that are no longer due, e.g. because
+// the service has been performed. activeKeys are the dedupe keys still due.
+func (m *DBModel) ResolveStaleReminders(carId int, activeKeys []string) error {
+	stmt := `UPDATE reminders SET resolved_at=CURRENT_TIMESTAMP WHERE car_id=$1 AND resolved_at IS NULL AND NOT (dedupe_key = ANY($2))`
+
+	_, err := m.DB.Exec(stmt, carId, pq.Array(activeKeys))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Returns the reminders that are neither resolved nor dismissed of the cars the
+// user may edit, the same users are notified about them
+func (m *DBModel) GetPendingRemindersByUserID(userId int) ([]Reminder, error) {
+	stmt := `SELECT r.id, r.car_id, r.kind, r.source_id, r.title, r.status, r.due_date, r.due_odometer, r.dedupe_key, r.created_at
+		FROM reminders r JOIN users_cars c ON c.id = r.car_id
+		WHERE ` + fmt.Sprintf(userCarsFilter, "$1") + ` AND ` + carRoleIn("$1", RoleOwner, RoleEditor) + `
+			AND c.deleted_at IS NULL AND r.resolved_at IS NULL AND r.dismissed_at IS NULL
+		ORDER BY r.due_date ASC NULLS LAST, r.id ASC`
+
+	rows, err := m.DB.Query(stmt, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reminders []Reminder
+
+	for rows.Next() {
+		var r Reminder
+
+		err := rows.Scan(&r.ID, &r.CarID, &r.Kind, &r.SourceID, &r.Title, &r.Status, &r.DueDate, &r.DueOdometer, &r.DedupeKey, &r.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		reminders = append(reminders, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reminders, nil
+}
+
+// Hides a reminder of a car the user may edit, for everyone it is listed for
+func (m *DBModel) DismissReminder(userId, reminderId int) error {
+	stmt := `UPDATE reminders r SET dismissed_at=CURRENT_TIMESTAMP FROM users_cars c
+		WHERE r.id=$1 AND c.id = r.car_id AND r.dismissed_at IS NULL
+			AND ` + fmt.Sprintf(userCarsFilter, "$2") + ` AND ` + carRoleIn("$2", RoleOwner, RoleEditor)
+
+	res, err := m.DB.Exec(stmt, reminderId, userId)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
@@ -0,0 +1,201 @@
+package models_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithAdvisoryLock_Acquired(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock`).WithArgs(int64(42)).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).WithArgs(int64(42)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	modelsDB := models.NewModels(db)
+	called := false
+	locked, err := modelsDB.DB.WithAdvisoryLock(context.Background(), 42, func() error {
+		called = true
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, locked)
+	assert.True(t, called)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithAdvisoryLock_HeldElsewhere(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock`).WithArgs(int64(42)).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(false))
+
+	modelsDB := models.NewModels(db)
+	locked, err := modelsDB.DB.WithAdvisoryLock(context.Background(), 42, func() error {
+		t.Error("fn must not run without the lock")
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.False(t, locked)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithAdvisoryLock_UnlocksOnError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock`).WithArgs(int64(42)).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).WithArgs(int64(42)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	modelsDB := models.NewModels(db)
+	locked, err := modelsDB.DB.WithAdvisoryLock(context.Background(), 42, func() error {
+		return errors.New("mocked error")
+	})
+
+	assert.EqualError(t, err, "mocked error")
+	assert.True(t, locked)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetAllCarIDs_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT id FROM users_cars`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(3))
+
+	modelsDB := models.NewModels(db)
+	ids, err := modelsDB.DB.GetAllCarIDs()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 3}, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpsertReminder_Inserted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	dueDate := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	reminder := models.Reminder{
+		CarID:     1,
+		Kind:      models.ReminderKindService,
+		SourceID:  2,
+		Title:     "Oil change is due soon",
+		Status:    models.DueStatusDueSoon,
+		DueDate:   &dueDate,
+		DedupeKey: "service:2:2022-07-01:30000",
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO reminders (.+) ON CONFLICT \(dedupe_key\) DO UPDATE`).
+		WithArgs(1, "service", 2, "Oil change is due soon", "due_soon", &dueDate, nil, "service:2:2022-07-01:30000").
+		WillReturnRows(sqlmock.NewRows([]string{"inserted"}).AddRow(true))
+	mock.ExpectExec(`INSERT INTO notification_outbox (.+) FROM users_cars c JOIN users u ON u.id IN \((.+)\) WHERE c.id=\$1 AND \(CASE (.+) END\) IN \('owner', 'editor'\)`).
+		WithArgs(1, "Reminder", "Body").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	modelsDB := models.NewModels(db)
+	inserted, err := modelsDB.DB.UpsertReminder(reminder, &models.NotificationContent{Subject: "Reminder", Body: "Body"})
+
+	assert.NoError(t, err)
+	assert.True(t, inserted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpsertReminder_ExistingIsNotNotified(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO reminders`).WillReturnRows(sqlmock.NewRows([]string{"inserted"}).AddRow(false))
+	mock.ExpectCommit()
+
+	modelsDB := models.NewModels(db)
+	inserted, err := modelsDB.DB.UpsertReminder(models.Reminder{CarID: 1}, &models.NotificationContent{Subject: "Reminder", Body: "Body"})
+
+	assert.NoError(t, err)
+	assert.False(t, inserted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResolveStaleReminders_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE reminders SET resolved_at=(.+) NOT \(dedupe_key = ANY`).WithArgs(1, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 2))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.ResolveStaleReminders(1, []string{"service:2:2022-07-01:30000"})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetPendingRemindersByUserID_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	dueDate := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"id", "car_id", "kind", "source_id", "title", "status", "due_date", "due_odometer", "dedupe_key", "created_at"}).
+		AddRow(1, 1, "service", 2, "Oil change is overdue", "overdue", dueDate, nil, "service:2:2022-07-01:30000", "2023-07-02 10:00:00")
+
+	mock.ExpectQuery(`SELECT (.+) FROM reminders r JOIN users_cars c (.+) WHERE \(\(c.user_id=\$1 (.+) \(CASE (.+) END\) IN \('owner', 'editor'\)`).WithArgs(5).WillReturnRows(rows)
+
+	modelsDB := models.NewModels(db)
+	reminders, err := modelsDB.DB.GetPendingRemindersByUserID(5)
+
+	assert.NoError(t, err)
+	assert.Len(t, reminders, 1)
+	assert.Equal(t, &dueDate, reminders[0].DueDate)
+	assert.Nil(t, reminders[0].DueOdometer)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDismissReminder_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE reminders r SET dismissed_at=CURRENT_TIMESTAMP FROM users_cars c (.+) \(CASE (.+) END\) IN \('owner', 'editor'\)`).WithArgs(1, 5).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.DismissReminder(5, 1)
+
+	assert.ErrorIs(t, err, models.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
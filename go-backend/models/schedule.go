@@ -0,0 +1,233 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// A service rule for a car, e.g. "oil change every 15000 km or 12 months".
+// StartDate and StartOdometer are the baseline used until a matching
+// maintenance record exists.
+type ServiceSchedule struct {
+	ID             int       `json:"id"`
+	CarID          int       `json:"car_id"`
+	ServiceType    string    `json:"service_type"`
+	IntervalKm     int       `json:"interval_km"`
+	IntervalMonths int       `json:"interval_months"`
+	Description    string    `json:"description,omitempty"`
+	StartDate      time.Time `json:"start_date"`
+	StartOdometer  int       `json:"start_odometer"`
+	CreatedAt      string    `json:"created_at"`
+}
+
+// A default service rule from the catalog. Templates without a CarModelID
+// apply to every model, templates without a FuelType to every engine.
+type ServiceScheduleTemplate struct {
+	ID             int     `json:"id"`
+	CarModelID     *int    `json:"car_model_id"`
+	FuelType       *string `json:"fuel_type"`
+	ServiceType    string  `json:"service_type"`
+	IntervalKm     int     `json:"interval_km"`
+	IntervalMonths int     `json:"interval_months"`
+	Description    string  `json:"description,omitempty"`
+}
+
+// Checks the fields a client has to provide for a service schedule
+func (s *ServiceSchedule) Validate() error {
+	s.ServiceType = strings.TrimSpace(s.ServiceType)
+
+	if s.ServiceType == "" {
+		return errors.New("service type is required")
+	}
+
+	if s.IntervalKm < 0 || s.IntervalMonths < 0 {
+		return errors.New("intervals must not be negative")
+	}
+
+	if s.IntervalKm == 0 && s.IntervalMonths == 0 {
+		return errors.New("either interval_km or interval_months is required")
+	}
+
+	if s.StartOdometer < 0 {
+		return errors.New("start odometer must not be negative")
+	}
+
+	return nil
+}
+
+func (m *DBModel) InsertServiceSchedule(s ServiceSchedule) (int, error) {
+	stmt := `INSERT INTO service_schedules (car_id, service_type, interval_km, interval_months, description, start_date, start_odometer) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+
+	var id int
+	err := m.DB.QueryRow(stmt, s.CarID, s.ServiceType, s.IntervalKm, s.IntervalMonths, s.Description, s.StartDate, s.StartOdometer).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (m *DBModel) GetServiceScheduleByID(carId, scheduleId int) (ServiceSchedule, error) {
+	var s ServiceSchedule
+	stmt := `SELECT id, car_id, service_type, interval_km, interval_months, description, start_date, start_odometer, created_at FROM service_schedules WHERE id=$1 AND car_id=$2`
+
+	row := m.DB.QueryRow(stmt, scheduleId, carId)
+	err := row.Scan(&s.ID, &s.CarID, &s.ServiceType, &s.IntervalKm, &s.IntervalMonths, &s.Description, &s.StartDate, &s.StartOdometer, &s.CreatedAt)
+	if err == sql.ErrNoRows {
+		return s, ErrRecordNotFound
+	} else if err != nil {
+		return s, err
+	}
+
+	return s, nil
+}
+
+func (m *DBModel) GetServiceSchedulesByCarID(carId int) ([]ServiceSchedule, error) {
+	stmt := `SELECT id, car_id, service_type, interval_km, interval_months, description, start_date, start_odometer, created_at FROM service_schedules WHERE car_id=$1 ORDER BY service_type ASC`
+
+	rows, err := m.DB.Query(stmt, carId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []ServiceSchedule
+
+	for rows.Next() {
+		var s ServiceSchedule
+
+		err := rows.Scan(&s.ID, &s.CarID, &s.ServiceType, &s.IntervalKm, &s.IntervalMonths, &s.Description, &s.StartDate, &s.StartOdometer, &s.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		schedules = append(schedules, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+func (m *DBModel) UpdateServiceSchedule(s ServiceSchedule) error {
+	stmt := `UPDATE service_schedules SET service_type=$1, interval_km=$2, interval_months=$3, description=$4, start_date=$5, start_odometer=$6 WHERE id=$7 AND car_id=$8`
+
+	res, err := m.DB.Exec(stmt, s.ServiceType, s.IntervalKm, s.IntervalMonths, s.Description, s.StartDate, s.StartOdometer, s.ID, s.CarID)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+func (m *DBModel) RemoveServiceSchedule(carId, scheduleId int) error {
+	stmt := `DELETE FROM service_schedules WHERE id=$1 AND car_id=$2`
+
+	res, err := m.DB.Exec(stmt, scheduleId, carId)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// Returns the default rules for a model. A model specific template replaces
+// the generic template of the same service type.
+// Returns the templates of a model and fuel type, an empty fuel type only
+// matches the templates for every engine
+func (m *DBModel) GetServiceScheduleTemplates(modelId int, fuelType string) ([]ServiceScheduleTemplate, error) {
+	stmt := `SELECT id, car_model_id, fuel_type, service_type, interval_km, interval_months, description FROM service_schedule_templates
+		WHERE (car_model_id=$1 OR car_model_id IS NULL) AND (fuel_type IS NULL OR fuel_type=$2)
+		ORDER BY car_model_id NULLS LAST, fuel_type NULLS LAST, service_type ASC, id ASC`
+
+	rows, err := m.DB.Query(stmt, modelId, fuelType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []ServiceScheduleTemplate
+	seen := make(map[string]bool)
+
+	for rows.Next() {
+		var t ServiceScheduleTemplate
+
+		err := rows.Scan(&t.ID, &t.CarModelID, &t.FuelType, &t.ServiceType, &t.IntervalKm, &t.IntervalMonths, &t.Description)
+		if err != nil {
+			return nil, err
+		}
+
+		// model and fuel specific rows come first, so a generic duplicate is skipped
+		key := strings.ToLower(t.ServiceType)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		// a template without intervals suppresses the generic rule for this model
+		if t.IntervalKm == 0 && t.IntervalMonths == 0 {
+			continue
+		}
+
+		templates = append(templates, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+// Creates schedules for the car from the catalog templates of its model and
+// the fuel type of its variant, skipping service types the car already has a
+// schedule for. Returns the number of schedules created.
+func (m *DBModel) ApplyServiceScheduleTemplates(car Car, startDate time.Time, startOdometer int) (int, error) {
+	fuelType := ""
+	if car.VariantID != nil {
+		variant, err := m.GetVariantByID(*car.VariantID)
+		if err != nil {
+			return 0, err
+		}
+		fuelType = variant.Engine.FuelType
+	}
+
+	templates, err := m.GetServiceScheduleTemplates(car.ModelID, fuelType)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt := `INSERT INTO service_schedules (car_id, service_type, interval_km, interval_months, description, start_date, start_odometer)
+		SELECT $1, $2, $3, $4, $5, $6, $7
+		WHERE NOT EXISTS (SELECT 1 FROM service_schedules WHERE car_id=$1 AND LOWER(service_type)=LOWER($2))`
+
+	created := 0
+	for _, t := range templates {
+		res, err := tx.Exec(stmt, car.ID, t.ServiceType, t.IntervalKm, t.IntervalMonths, t.Description, startDate, startOdometer)
+		if err != nil {
+			return 0, err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		created += int(n)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return created, nil
+}
@@ -0,0 +1,245 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var scheduleColumns = []string{"id", "car_id", "service_type", "interval_km", "interval_months", "description", "start_date", "start_odometer", "created_at"}
+
+func testServiceSchedule() models.ServiceSchedule {
+	return models.ServiceSchedule{
+		ID:             1,
+		CarID:          2,
+		ServiceType:    "Oil change",
+		IntervalKm:     15000,
+		IntervalMonths: 12,
+		Description:    "Engine oil and oil filter",
+		StartDate:      time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		StartOdometer:  30000,
+	}
+}
+
+func TestServiceScheduleValidate(t *testing.T) {
+	valid := testServiceSchedule()
+	assert.NoError(t, valid.Validate())
+
+	onlyMonths := testServiceSchedule()
+	onlyMonths.IntervalKm = 0
+	assert.NoError(t, onlyMonths.Validate())
+
+	noType := testServiceSchedule()
+	noType.ServiceType = ""
+	assert.EqualError(t, noType.Validate(), "service type is required")
+
+	noInterval := testServiceSchedule()
+	noInterval.IntervalKm = 0
+	noInterval.IntervalMonths = 0
+	assert.EqualError(t, noInterval.Validate(), "either interval_km or interval_months is required")
+
+	negative := testServiceSchedule()
+	negative.IntervalMonths = -1
+	assert.EqualError(t, negative.Validate(), "intervals must not be negative")
+}
+
+func TestInsertServiceSchedule_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	s := testServiceSchedule()
+	mock.ExpectQuery(`INSERT INTO service_schedules`).WithArgs(
+		s.CarID, s.ServiceType, s.IntervalKm, s.IntervalMonths, s.Description, s.StartDate, s.StartOdometer,
+	).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+	modelsDB := models.NewModels(db)
+	id, err := modelsDB.DB.InsertServiceSchedule(s)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetServiceSchedulesByCarID_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	s := testServiceSchedule()
+	rows := sqlmock.NewRows(scheduleColumns).
+		AddRow(1, 2, "Oil change", 15000, 12, "Engine oil and oil filter", s.StartDate, 30000, "2023-01-01 10:00:00")
+
+	mock.ExpectQuery(`SELECT (.+) FROM service_schedules WHERE car_id=`).WithArgs(2).WillReturnRows(rows)
+
+	modelsDB := models.NewModels(db)
+	schedules, err := modelsDB.DB.GetServiceSchedulesByCarID(2)
+
+	s.CreatedAt = "2023-01-01 10:00:00"
+	assert.NoError(t, err)
+	assert.Equal(t, []models.ServiceSchedule{s}, schedules)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetServiceScheduleByID_Error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT (.+) FROM service_schedules WHERE id=(.+) AND car_id=`).WithArgs(1, 2).WillReturnError(errors.New("mocked error"))
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.GetServiceScheduleByID(2, 1)
+
+	assert.EqualError(t, err, "mocked error")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateServiceSchedule_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE service_schedules SET`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.UpdateServiceSchedule(testServiceSchedule())
+
+	assert.ErrorIs(t, err, models.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRemoveServiceSchedule_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`DELETE FROM service_schedules WHERE id=(.+) AND car_id=`).WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.RemoveServiceSchedule(2, 1)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetServiceScheduleTemplates_ModelOverridesGeneric(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "car_model_id", "fuel_type", "service_type", "interval_km", "interval_months", "description"}).
+		AddRow(10, 210, nil, "Cabin filter", 0, 24, "").
+		AddRow(11, 210, nil, "Oil change", 0, 0, "Not applicable").
+		AddRow(1, nil, nil, "Brake fluid", 0, 24, "").
+		AddRow(2, nil, nil, "Cabin filter", 15000, 12, "").
+		AddRow(3, nil, nil, "Oil change", 15000, 12, "")
+
+	mock.ExpectQuery(`SELECT (.+) FROM service_schedule_templates WHERE \(car_model_id=(.+) OR car_model_id IS NULL\) AND \(fuel_type IS NULL OR fuel_type=`).WithArgs(210, "").WillReturnRows(rows)
+
+	modelsDB := models.NewModels(db)
+	templates, err := modelsDB.DB.GetServiceScheduleTemplates(210, "")
+
+	assert.NoError(t, err)
+	assert.Len(t, templates, 2)
+	assert.Equal(t, 10, templates[0].ID)
+	assert.Equal(t, 24, templates[0].IntervalMonths)
+	assert.Equal(t, "Brake fluid", templates[1].ServiceType)
+	assert.Nil(t, templates[1].CarModelID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApplyServiceScheduleTemplates_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	start := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"id", "car_model_id", "fuel_type", "service_type", "interval_km", "interval_months", "description"}).
+		AddRow(1, nil, nil, "Brake fluid", 0, 24, "").
+		AddRow(3, nil, nil, "Oil change", 15000, 12, "")
+
+	mock.ExpectQuery(`SELECT (.+) FROM service_schedule_templates`).WithArgs(5, "").WillReturnRows(rows)
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO service_schedules (.+) WHERE NOT EXISTS`).WithArgs(1, "Brake fluid", 0, 24, "", start, 40000).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO service_schedules (.+) WHERE NOT EXISTS`).WithArgs(1, "Oil change", 15000, 12, "", start, 40000).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	modelsDB := models.NewModels(db)
+	created, err := modelsDB.DB.ApplyServiceScheduleTemplates(models.Car{ID: 1, ModelID: 5}, start, 40000)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, created)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApplyServiceScheduleTemplates_RollbackOnError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "car_model_id", "fuel_type", "service_type", "interval_km", "interval_months", "description"}).
+		AddRow(3, nil, nil, "Oil change", 15000, 12, "")
+
+	mock.ExpectQuery(`SELECT (.+) FROM service_schedule_templates`).WithArgs(5, "").WillReturnRows(rows)
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO service_schedules`).WillReturnError(errors.New("mocked error"))
+	mock.ExpectRollback()
+
+	modelsDB := models.NewModels(db)
+	created, err := modelsDB.DB.ApplyServiceScheduleTemplates(models.Car{ID: 1, ModelID: 5}, time.Now(), 0)
+
+	assert.EqualError(t, err, "mocked error")
+	assert.Equal(t, 0, created)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApplyServiceScheduleTemplates_UsesVariantFuelType(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	start := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	variantId := 7
+
+	mock.ExpectQuery(`SELECT (.+) FROM car_variants v (.+) WHERE v.id=`).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "car_generation_id", "car_model_id", "name", "body_type", "engine_id", "engine_name", "fuel_type", "displacement_cc", "power_kw"}).
+			AddRow(7, 5, 211, "Long Range", "sedan", 5, "Long Range AWD", "electric", nil, 366))
+	rows := sqlmock.NewRows([]string{"id", "car_model_id", "fuel_type", "service_type", "interval_km", "interval_months", "description"}).
+		AddRow(20, nil, "electric", "Oil change", 0, 0, "Not applicable to electric vehicles").
+		AddRow(1, nil, nil, "Brake fluid", 0, 24, "").
+		AddRow(3, nil, nil, "Oil change", 15000, 12, "")
+	mock.ExpectQuery(`SELECT (.+) FROM service_schedule_templates`).WithArgs(211, "electric").WillReturnRows(rows)
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO service_schedules (.+) WHERE NOT EXISTS`).WithArgs(1, "Brake fluid", 0, 24, "", start, 0).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	modelsDB := models.NewModels(db)
+	created, err := modelsDB.DB.ApplyServiceScheduleTemplates(models.Car{ID: 1, ModelID: 211, VariantID: &variantId}, start, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, created)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
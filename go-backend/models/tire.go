@@ -0,0 +1,643 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// returned when a mount or unmount event does not fit the history of the tire set
+	ErrTireEvent = errors.New("invalid tire event")
+
+	// returned when removing an event would leave a gap in the history of the tire set
+	ErrTireEventNotLatest = errors.New("only the latest event of a tire set can be removed")
+)
+
+// Seasons a tire set is made for
+const (
+	TireSummer    = "summer"
+	TireWinter    = "winter"
+	TireAllSeason = "all_season"
+)
+
+var TireSeasons = []string{TireSummer, TireWinter, TireAllSeason}
+
+// Kinds of tire events
+const (
+	TireMount   = "mount"
+	TireUnmount = "unmount"
+)
+
+// Wheel positions a tread depth is measured at
+const (
+	WheelFrontLeft  = "front_left"
+	WheelFrontRight = "front_right"
+	WheelRearLeft   = "rear_left"
+	WheelRearRight  = "rear_right"
+	WheelSpare      = "spare"
+)
+
+var WheelPositions = []string{WheelFrontLeft, WheelFrontRight, WheelRearLeft, WheelRearRight, WheelSpare}
+
+// week and year of manufacture, the last four digits of the DOT code
+var dotCodePattern = regexp.MustCompile(`^\d{4}$`)
+
+// A set of tires of a car. Mounted and DistanceKm are derived from the mount
+// and unmount events.
+type TireSet struct {
+	ID     int    `json:"id"`
+	CarID  int    `json:"car_id"`
+	Name   string `json:"name"`
+	Season string `json:"season"`
+	Brand  string `json:"brand,omitempty"`
+	// size as printed on the sidewall, e.g. 205/55 R16 91V
+	Size string `json:"size,omitempty"`
+	// week and year of manufacture as WWYY
+	DOTCode      string  `json:"dot_code,omitempty"`
+	PurchaseCost float64 `json:"purchase_cost"`
+	Notes        string  `json:"notes,omitempty"`
+	CreatedAt    string  `json:"created_at"`
+	Mounted      bool    `json:"mounted"`
+	DistanceKm   int     `json:"distance_km"`
+}
+
+// A tire set put on or taken off the car
+type TireEvent struct {
+	ID        int       `json:"id"`
+	CarID     int       `json:"car_id"`
+	TireSetID int       `json:"tire_set_id"`
+	EventType string    `json:"event_type"`
+	EventDate time.Time `json:"event_date"`
+	Odometer  int       `json:"odometer"`
+	Notes     string    `json:"notes,omitempty"`
+	CreatedAt string    `json:"created_at"`
+}
+
+// A tread depth of one tire of a set
+type TreadMeasurement struct {
+	ID         int       `json:"id"`
+	TireSetID  int       `json:"tire_set_id"`
+	MeasuredOn time.Time `json:"measured_on"`
+	Position   string    `json:"position"`
+	DepthMm    float64   `json:"depth_mm"`
+	Odometer   *int      `json:"odometer,omitempty"`
+	CreatedAt  string    `json:"created_at"`
+}
+
+func IsTireSeason(season string) bool {
+	for _, s := range TireSeasons {
+		if s == season {
+			return true
+		}
+	}
+
+	return false
+}
+
+func IsWheelPosition(position string) bool {
+	for _, p := range WheelPositions {
+		if p == position {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Checks the fields a client has to provide for a tire set
+func (t *TireSet) Validate() error {
+	t.Name = strings.TrimSpace(t.Name)
+	t.Season = strings.ToLower(strings.TrimSpace(t.Season))
+	t.Brand = strings.TrimSpace(t.Brand)
+	t.Size = strings.TrimSpace(t.Size)
+	t.DOTCode = strings.TrimSpace(t.DOTCode)
+
+	if t.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if len(t.Name) > 100 || len(t.Brand) > 100 {
+		return errors.New("name and brand must not be longer than 100 characters")
+	}
+
+	if len(t.Size) > 40 {
+		return errors.New("size must not be longer than 40 characters")
+	}
+
+	if !IsTireSeason(t.Season) {
+		return fmt.Errorf("season must be one of %s", strings.Join(TireSeasons, ", "))
+	}
+
+	if t.DOTCode != "" {
+		if _, err := t.ManufacturedOn(); err != nil {
+			return err
+		}
+	}
+
+	if t.PurchaseCost < 0 {
+		return errors.New("purchase cost must not be negative")
+	}
+
+	return nil
+}
+
+// Returns the Monday of the week of manufacture encoded in the DOT code
+func (t TireSet) ManufacturedOn() (time.Time, error) {
+	if !dotCodePattern.MatchString(t.DOTCode) {
+		return time.Time{}, errors.New("DOT code must be the week and year of manufacture as WWYY")
+	}
+
+	week, _ := strconv.Atoi(t.DOTCode[:2])
+	year, _ := strconv.Atoi(t.DOTCode[2:])
+	if week < 1 || week > 53 {
+		return time.Time{}, errors.New("DOT code week must be between 01 and 53")
+	}
+
+	// the ISO week 1 is the week with the 4th of January
+	jan4 := time.Date(2000+year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	monday := jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+
+	return monday.AddDate(0, 0, (week-1)*7), nil
+}
+
+// Checks the fields a client has to provide for a tire event
+func (e *TireEvent) Validate() error {
+	e.EventType = strings.ToLower(strings.TrimSpace(e.EventType))
+
+	if e.EventType != TireMount && e.EventType != TireUnmount {
+		return errors.New("event type must be mount or unmount")
+	}
+
+	if e.EventDate.IsZero() {
+		return errors.New("event date is required")
+	}
+
+	if e.Odometer < 0 {
+		return errors.New("odometer must not be negative")
+	}
+
+	return nil
+}
+
+// Checks the fields a client has to provide for a tread depth measurement
+func (t *TreadMeasurement) Validate() error {
+	t.Position = strings.ToLower(strings.TrimSpace(t.Position))
+
+	if t.MeasuredOn.IsZero() {
+		return errors.New("measurement date is required")
+	}
+
+	if !IsWheelPosition(t.Position) {
+		return fmt.Errorf("position must be one of %s", strings.Join(WheelPositions, ", "))
+	}
+
+	if t.DepthMm < 0 || t.DepthMm > 30 {
+		return errors.New("depth must be between 0 and 30 mm")
+	}
+
+	if t.Odometer != nil && *t.Odometer < 0 {
+		return errors.New("odometer must not be negative")
+	}
+
+	return nil
+}
+
+// Checks that an event follows the last event of its tire set
+func CheckTireEvent(event TireEvent, last *TireEvent) error {
+	mounted := last != nil && last.EventType == TireMount
+
+	if event.EventType == TireMount && mounted {
+		return fmt.Errorf("%w: the tire set is already mounted", ErrTireEvent)
+	}
+
+	if event.EventType == TireUnmount && !mounted {
+		return fmt.Errorf("%w: the tire set is not mounted", ErrTireEvent)
+	}
+
+	if last != nil && event.EventDate.Before(last.EventDate) {
+		return fmt.Errorf("%w: the last event of the tire set was on %s", ErrTireEvent, last.EventDate.Format("2006-01-02"))
+	}
+
+	if last != nil && event.Odometer < last.Odometer {
+		return fmt.Errorf("%w: %d km is lower than %d km of the last event of the tire set", ErrTireEvent, event.Odometer, last.Odometer)
+	}
+
+	return nil
+}
+
+func (m *DBModel) InsertTireSet(t TireSet) (int, error) {
+	stmt := `INSERT INTO tire_sets (car_id, name, season, brand, size, dot_code, purchase_cost, notes) VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+
+	var id int
+	err := m.DB.QueryRow(stmt, t.CarID, t.Name, t.Season, t.Brand, t.Size, t.DOTCode, t.PurchaseCost, t.Notes).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (m *DBModel) GetTireSetByID(carId, setId int) (TireSet, error) {
+	var t TireSet
+	stmt := `SELECT id, car_id, name, season, brand, size, dot_code, purchase_cost, notes, created_at FROM tire_sets WHERE id=$1 AND car_id=$2`
+
+	row := m.DB.QueryRow(stmt, setId, carId)
+	err := row.Scan(&t.ID, &t.CarID, &t.Name, &t.Season, &t.Brand, &t.Size, &t.DOTCode, &t.PurchaseCost, &t.Notes, &t.CreatedAt)
+	if err == sql.ErrNoRows {
+		return t, ErrRecordNotFound
+	} else if err != nil {
+		return t, err
+	}
+
+	return t, nil
+}
+
+func (m *DBModel) GetTireSetsByCarID(carId int) ([]TireSet, error) {
+	stmt := `SELECT id, car_id, name, season, brand, size, dot_code, purchase_cost, notes, created_at FROM tire_sets WHERE car_id=$1 ORDER BY id ASC`
+
+	rows, err := m.DB.Query(stmt, carId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sets []TireSet
+
+	for rows.Next() {
+		var t TireSet
+
+		err := rows.Scan(&t.ID, &t.CarID, &t.Name, &t.Season, &t.Brand, &t.Size, &t.DOTCode, &t.PurchaseCost, &t.Notes, &t.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		sets = append(sets, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sets, nil
+}
+
+func (m *DBModel) UpdateTireSet(t TireSet) error {
+	stmt := `UPDATE tire_sets SET name=$1, season=$2, brand=$3, size=$4, dot_code=$5, purchase_cost=$6, notes=$7 WHERE id=$8 AND car_id=$9`
+
+	res, err := m.DB.Exec(stmt, t.Name, t.Season, t.Brand, t.Size, t.DOTCode, t.PurchaseCost, t.Notes, t.ID, t.CarID)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// Removes a tire set together with its events and measurements
+func (m *DBModel) RemoveTireSet(carId, setId int) error {
+	stmt := `DELETE FROM tire_sets WHERE id=$1 AND car_id=$2`
+
+	res, err := m.DB.Exec(stmt, setId, carId)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// Inserts a mount or unmount event after checking it against the history of
+// the tire set. Mounting a set takes the set mounted before off the car at the
+// same date and odometer, so a seasonal swap is a single event.
+func (m *DBModel) InsertTireEvent(event TireEvent) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// serialize concurrent events of the same car
+	_, err = tx.Exec(`SELECT id FROM users_cars WHERE id=$1 FOR UPDATE`, event.CarID)
+	if err != nil {
+		return 0, err
+	}
+
+	var setId int
+	err = tx.QueryRow(`SELECT id FROM tire_sets WHERE id=$1 AND car_id=$2`, event.TireSetID, event.CarID).Scan(&setId)
+	if err == sql.ErrNoRows {
+		return 0, ErrRecordNotFound
+	} else if err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.Query(`SELECT DISTINCT ON (tire_set_id) id, car_id, tire_set_id, event_type, event_date, odometer, notes, created_at FROM tire_events
+		WHERE car_id=$1 ORDER BY tire_set_id, event_date DESC, id DESC`, event.CarID)
+	if err != nil {
+		return 0, err
+	}
+
+	var last *TireEvent
+	var swapped []TireEvent
+	for rows.Next() {
+		var e TireEvent
+		err = rows.Scan(&e.ID, &e.CarID, &e.TireSetID, &e.EventType, &e.EventDate, &e.Odometer, &e.Notes, &e.CreatedAt)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		if e.TireSetID == event.TireSetID {
+			last = &e
+		} else if e.EventType == TireMount && event.EventType == TireMount {
+			swapped = append(swapped, e)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	err = CheckTireEvent(event, last)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt := `INSERT INTO tire_events (car_id, tire_set_id, event_type, event_date, odometer, notes) VALUES($1, $2, $3, $4, $5, $6) RETURNING id`
+
+	for _, mounted := range swapped {
+		unmount := TireEvent{CarID: event.CarID, TireSetID: mounted.TireSetID, EventType: TireUnmount, EventDate: event.EventDate, Odometer: event.Odometer}
+		err = CheckTireEvent(unmount, &mounted)
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = tx.Exec(stmt, unmount.CarID, unmount.TireSetID, unmount.EventType, unmount.EventDate, unmount.Odometer, "")
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var id int
+	err = tx.QueryRow(stmt, event.CarID, event.TireSetID, event.EventType, event.EventDate, event.Odometer, event.Notes).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// Returns the tire events of a car in the order they happened
+func (m *DBModel) GetTireEventsByCarID(carId int) ([]TireEvent, error) {
+	stmt := `SELECT id, car_id, tire_set_id, event_type, event_date, odometer, notes, created_at FROM tire_events WHERE car_id=$1 ORDER BY event_date ASC, id ASC`
+
+	rows, err := m.DB.Query(stmt, carId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []TireEvent
+
+	for rows.Next() {
+		var e TireEvent
+
+		err := rows.Scan(&e.ID, &e.CarID, &e.TireSetID, &e.EventType, &e.EventDate, &e.Odometer, &e.Notes, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// Removes the latest event of the tire set. Any earlier event is followed by
+// events relying on it, removing it fails with ErrTireEventNotLatest.
+func (m *DBModel) RemoveTireEvent(carId, setId, eventId int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// serialize with the events added to the car
+	_, err = tx.Exec(`SELECT id FROM users_cars WHERE id=$1 FOR UPDATE`, carId)
+	if err != nil {
+		return err
+	}
+
+	var latestId int
+	err = tx.QueryRow(`SELECT id FROM tire_events WHERE tire_set_id=$1 AND car_id=$2 ORDER BY event_date DESC, id DESC LIMIT 1`, setId, carId).Scan(&latestId)
+	if err == sql.ErrNoRows {
+		return ErrRecordNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if latestId != eventId {
+		var exists bool
+		err = tx.QueryRow(`SELECT exists (SELECT 1 FROM tire_events WHERE id=$1 AND tire_set_id=$2 AND car_id=$3)`, eventId, setId, carId).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrRecordNotFound
+		}
+
+		return ErrTireEventNotLatest
+	}
+
+	res, err := tx.Exec(`DELETE FROM tire_events WHERE id=$1`, eventId)
+	if err != nil {
+		return err
+	}
+
+	err = checkRowsAffected(res)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *DBModel) InsertTreadMeasurement(t TreadMeasurement) (int, error) {
+	stmt := `INSERT INTO tread_measurements (tire_set_id, measured_on, position, depth_mm, odometer) VALUES($1, $2, $3, $4, $5) RETURNING id`
+
+	var id int
+	err := m.DB.QueryRow(stmt, t.TireSetID, t.MeasuredOn, t.Position, t.DepthMm, t.Odometer).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// Returns the tread depths measured on the tire sets of a car, the oldest first
+func (m *DBModel) GetTreadMeasurementsByCarID(carId int) ([]TreadMeasurement, error) {
+	stmt := `SELECT t.id, t.tire_set_id, t.measured_on, t.position, t.depth_mm, t.odometer, t.created_at FROM tread_measurements t
+		JOIN tire_sets s ON s.id = t.tire_set_id WHERE s.car_id=$1 ORDER BY t.measured_on ASC, t.id ASC`
+
+	rows, err := m.DB.Query(stmt, carId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var measurements []TreadMeasurement
+
+	for rows.Next() {
+		var t TreadMeasurement
+
+		err := rows.Scan(&t.ID, &t.TireSetID, &t.MeasuredOn, &t.Position, &t.DepthMm, &t.Odometer, &t.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		measurements = append(measurements, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return measurements, nil
+}
+
+func (m *DBModel) RemoveTreadMeasurement(carId, setId, measurementId int) error {
+	stmt := `DELETE FROM tread_measurements t USING tire_sets s WHERE t.id=$1 AND t.tire_set_id=$2 AND s.id = t.tire_set_id AND s.car_id=$3`
+
+	res, err := m.DB.Exec(stmt, measurementId, setId, carId)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// Sets whether each tire set is on the car and the distance driven on it. The
+// set mounted at the moment counts up to the current odometer.
+func ApplyTireEvents(sets []TireSet, events []TireEvent, currentOdometer int) {
+	sorted := make([]TireEvent, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].EventDate.Before(sorted[j].EventDate)
+	})
+
+	for i := range sets {
+		var mountedAt *int
+		distance := 0
+
+		for _, e := range sorted {
+			if e.TireSetID != sets[i].ID {
+				continue
+			}
+
+			odometer := e.Odometer
+			switch {
+			case e.EventType == TireMount:
+				mountedAt = &odometer
+			case e.EventType == TireUnmount && mountedAt != nil:
+				if odometer > *mountedAt {
+					distance += odometer - *mountedAt
+				}
+				mountedAt = nil
+			}
+		}
+
+		sets[i].Mounted = mountedAt != nil
+		if mountedAt != nil && currentOdometer > *mountedAt {
+			distance += currentOdometer - *mountedAt
+		}
+		sets[i].DistanceKm = distance
+	}
+}
+
+// Limits a tire set is checked against
+type TireLimits struct {
+	MinTreadDepthMm float64
+	MaxAgeYears     int
+}
+
+// Kinds of tire warnings
+const (
+	TireWarningTreadDepth = "tread_depth"
+	TireWarningAge        = "age"
+)
+
+type TireWarning struct {
+	TireSetID int    `json:"tire_set_id"`
+	Kind      string `json:"kind"`
+	// set for tread depth warnings
+	Position string   `json:"position,omitempty"`
+	DepthMm  *float64 `json:"depth_mm,omitempty"`
+	// set for age warnings
+	ManufacturedOn *time.Time `json:"manufactured_on,omitempty"`
+	Message        string     `json:"message"`
+}
+
+// Returns the warnings of the tire sets: a latest tread depth of a wheel
+// position below the minimum, or tires older than the maximum age according
+// to their DOT code.
+func CheckTireSets(sets []TireSet, measurements []TreadMeasurement, limits TireLimits, now time.Time) []TireWarning {
+	warnings := []TireWarning{}
+
+	for _, set := range sets {
+		latest := make(map[string]TreadMeasurement)
+		for _, t := range measurements {
+			if t.TireSetID != set.ID {
+				continue
+			}
+			if prev, ok := latest[t.Position]; !ok || !t.MeasuredOn.Before(prev.MeasuredOn) {
+				latest[t.Position] = t
+			}
+		}
+
+		for _, position := range WheelPositions {
+			t, ok := latest[position]
+			if !ok || t.DepthMm >= limits.MinTreadDepthMm {
+				continue
+			}
+
+			depth := t.DepthMm
+			warnings = append(warnings, TireWarning{
+				TireSetID: set.ID,
+				Kind:      TireWarningTreadDepth,
+				Position:  position,
+				DepthMm:   &depth,
+				Message: fmt.Sprintf("%s: tread depth at %s is %.1f mm, below the minimum of %.1f mm",
+					set.Name, strings.ReplaceAll(position, "_", " "), depth, limits.MinTreadDepthMm),
+			})
+		}
+
+		if set.DOTCode == "" || limits.MaxAgeYears <= 0 {
+			continue
+		}
+
+		manufactured, err := set.ManufacturedOn()
+		if err != nil || now.Before(manufactured.AddDate(limits.MaxAgeYears, 0, 0)) {
+			continue
+		}
+
+		warnings = append(warnings, TireWarning{
+			TireSetID:      set.ID,
+			Kind:           TireWarningAge,
+			ManufacturedOn: &manufactured,
+			Message:        fmt.Sprintf("%s: tires made in week %s of 20%s are older than %d years", set.Name, set.DOTCode[:2], set.DOTCode[2:], limits.MaxAgeYears),
+		})
+	}
+
+	return warnings
+}
@@ -0,0 +1,194 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var tireEventColumns = []string{"id", "car_id", "tire_set_id", "event_type", "event_date", "odometer", "notes", "created_at"}
+
+func tireEvent(setId int, eventType string, month, odometer int) models.TireEvent {
+	return models.TireEvent{
+		CarID:     2,
+		TireSetID: setId,
+		EventType: eventType,
+		EventDate: time.Date(2023, time.Month(month), 1, 0, 0, 0, 0, time.UTC),
+		Odometer:  odometer,
+	}
+}
+
+func TestTireSetValidate(t *testing.T) {
+	valid := models.TireSet{Name: " Winter ", Season: "Winter", DOTCode: "4521"}
+	assert.NoError(t, valid.Validate())
+	assert.Equal(t, "Winter", valid.Name)
+	assert.Equal(t, models.TireWinter, valid.Season)
+
+	noName := models.TireSet{Season: "winter"}
+	assert.EqualError(t, noName.Validate(), "name is required")
+
+	badSeason := models.TireSet{Name: "Winter", Season: "spring"}
+	assert.EqualError(t, badSeason.Validate(), "season must be one of summer, winter, all_season")
+
+	badDOT := models.TireSet{Name: "Winter", Season: "winter", DOTCode: "21"}
+	assert.EqualError(t, badDOT.Validate(), "DOT code must be the week and year of manufacture as WWYY")
+
+	badWeek := models.TireSet{Name: "Winter", Season: "winter", DOTCode: "5921"}
+	assert.EqualError(t, badWeek.Validate(), "DOT code week must be between 01 and 53")
+}
+
+func TestTireSetManufacturedOn(t *testing.T) {
+	manufactured, err := models.TireSet{DOTCode: "2319"}.ManufacturedOn()
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2019, 6, 3, 0, 0, 0, 0, time.UTC), manufactured)
+}
+
+func TestCheckTireEvent(t *testing.T) {
+	mounted := tireEvent(1, models.TireMount, 4, 10000)
+
+	assert.NoError(t, models.CheckTireEvent(tireEvent(1, models.TireMount, 4, 10000), nil))
+	assert.NoError(t, models.CheckTireEvent(tireEvent(1, models.TireUnmount, 11, 15000), &mounted))
+
+	err := models.CheckTireEvent(tireEvent(1, models.TireMount, 11, 15000), &mounted)
+	assert.ErrorIs(t, err, models.ErrTireEvent)
+	assert.EqualError(t, err, "invalid tire event: the tire set is already mounted")
+
+	err = models.CheckTireEvent(tireEvent(1, models.TireUnmount, 11, 15000), nil)
+	assert.EqualError(t, err, "invalid tire event: the tire set is not mounted")
+
+	err = models.CheckTireEvent(tireEvent(1, models.TireUnmount, 3, 15000), &mounted)
+	assert.EqualError(t, err, "invalid tire event: the last event of the tire set was on 2023-04-01")
+
+	err = models.CheckTireEvent(tireEvent(1, models.TireUnmount, 11, 9000), &mounted)
+	assert.EqualError(t, err, "invalid tire event: 9000 km is lower than 10000 km of the last event of the tire set")
+}
+
+func TestInsertTireEvent_SwapsMountedSet(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	event := tireEvent(5, models.TireMount, 11, 15000)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT id FROM users_cars WHERE id=(.+) FOR UPDATE`).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT id FROM tire_sets WHERE id=(.+) AND car_id=`).WithArgs(5, 2).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectQuery(`SELECT DISTINCT ON \(tire_set_id\) (.+) FROM tire_events`).WithArgs(2).WillReturnRows(
+		sqlmock.NewRows(tireEventColumns).AddRow(3, 2, 4, "mount", time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC), 10000, "", "2023-04-01 10:00:00"))
+	mock.ExpectExec(`INSERT INTO tire_events`).WithArgs(2, 4, "unmount", event.EventDate, 15000, "").WillReturnResult(sqlmock.NewResult(8, 1))
+	mock.ExpectQuery(`INSERT INTO tire_events`).WithArgs(2, 5, "mount", event.EventDate, 15000, "").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectCommit()
+
+	modelsDB := models.NewModels(db)
+	id, err := modelsDB.DB.InsertTireEvent(event)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 9, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertTireEvent_UnknownSet(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT id FROM users_cars WHERE id=(.+) FOR UPDATE`).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT id FROM tire_sets WHERE id=(.+) AND car_id=`).WithArgs(5, 2).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	modelsDB := models.NewModels(db)
+	_, err = modelsDB.DB.InsertTireEvent(tireEvent(5, models.TireMount, 11, 15000))
+
+	assert.True(t, errors.Is(err, models.ErrRecordNotFound))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRemoveTireEvent_Latest(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT id FROM users_cars WHERE id=(.+) FOR UPDATE`).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT id FROM tire_events WHERE tire_set_id=\$1 AND car_id=\$2 ORDER BY event_date DESC, id DESC LIMIT 1`).WithArgs(5, 2).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectExec(`DELETE FROM tire_events WHERE id=\$1`).WithArgs(9).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.RemoveTireEvent(2, 5, 9)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRemoveTireEvent_NotLatest(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT id FROM users_cars WHERE id=(.+) FOR UPDATE`).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT id FROM tire_events WHERE tire_set_id=`).WithArgs(5, 2).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectQuery(`SELECT exists \(SELECT 1 FROM tire_events WHERE id=\$1`).WithArgs(7, 5, 2).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.RemoveTireEvent(2, 5, 7)
+
+	assert.True(t, errors.Is(err, models.ErrTireEventNotLatest))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApplyTireEvents(t *testing.T) {
+	sets := []models.TireSet{{ID: 1, Name: "Summer"}, {ID: 2, Name: "Winter"}, {ID: 3, Name: "Spare"}}
+	events := []models.TireEvent{
+		tireEvent(1, models.TireMount, 4, 10000),
+		tireEvent(1, models.TireUnmount, 11, 18000),
+		tireEvent(2, models.TireMount, 11, 18000),
+	}
+
+	models.ApplyTireEvents(sets, events, 21000)
+
+	assert.False(t, sets[0].Mounted)
+	assert.Equal(t, 8000, sets[0].DistanceKm)
+	assert.True(t, sets[1].Mounted)
+	assert.Equal(t, 3000, sets[1].DistanceKm)
+	assert.False(t, sets[2].Mounted)
+	assert.Equal(t, 0, sets[2].DistanceKm)
+}
+
+func TestCheckTireSets(t *testing.T) {
+	sets := []models.TireSet{{ID: 1, Name: "Summer", DOTCode: "2316"}, {ID: 2, Name: "Winter", DOTCode: "4521"}}
+	measurements := []models.TreadMeasurement{
+		{TireSetID: 1, MeasuredOn: time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC), Position: models.WheelFrontLeft, DepthMm: 1.4},
+		// a newer measurement replaces the older one
+		{TireSetID: 2, MeasuredOn: time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC), Position: models.WheelRearLeft, DepthMm: 1.5},
+		{TireSetID: 2, MeasuredOn: time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC), Position: models.WheelRearLeft, DepthMm: 8},
+		{TireSetID: 2, MeasuredOn: time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC), Position: models.WheelFrontRight, DepthMm: 1.6},
+	}
+	limits := models.TireLimits{MinTreadDepthMm: 1.6, MaxAgeYears: 6}
+
+	warnings := models.CheckTireSets(sets, measurements, limits, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC))
+
+	assert.Len(t, warnings, 2)
+	assert.Equal(t, models.TireWarningTreadDepth, warnings[0].Kind)
+	assert.Equal(t, models.WheelFrontLeft, warnings[0].Position)
+	assert.Equal(t, "Summer: tread depth at front left is 1.4 mm, below the minimum of 1.6 mm", warnings[0].Message)
+	assert.Equal(t, models.TireWarningAge, warnings[1].Kind)
+	assert.Equal(t, 1, warnings[1].TireSetID)
+	assert.Equal(t, "Summer: tires made in week 23 of 2016 are older than 6 years", warnings[1].Message)
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Kinds of records that can be restored from the trash
+const (
+	TrashKindCar         = "car"
+	TrashKindMaintenance = "maintenance"
+	TrashKindExpense     = "expense"
+)
+
+// Soft deleted records a user may restore: the cars the user could delete and
+// the records of the cars the user edits. Records of a deleted car are not
+// listed separately, they come back together with the car.
+type Trash struct {
+	Cars        []Car               `json:"cars"`
+	Maintenance []MaintenanceRecord `json:"maintenance"`
+	Expenses    []Expense           `json:"expenses"`
+}
+
+// Returns the soft deleted cars and records of the user, the most recently deleted first
+func (m *DBModel) GetTrashByUserID(userId int) (Trash, error) {
+	trash := Trash{
+		Cars:        []Car{},
+		Maintenance: []MaintenanceRecord{},
+		Expenses:    []Expense{},
+	}
+
+	rows, err := m.DB.Query(`SELECT c.id, c.user_id, c.brand_id, c.model_id, c.year, c.color, c.price, c.image, c.description, c.license_plate, c.vin, c.variant_id, c.created_at, c.archived_at, c.sold_at, c.sale_price, c.organization_id, c.deleted_at
+		FROM users_cars c WHERE c.deleted_at IS NOT NULL AND `+carRoleIn("$1", RoleOwner)+` ORDER BY c.deleted_at DESC, c.id DESC`, userId)
+	if err != nil {
+		return trash, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var car Car
+
+		err := rows.Scan(&car.ID, &car.UserId, &car.BrandID, &car.ModelID, &car.Year, &car.Color, &car.Price, &car.Image, &car.Description, &car.LicensePlate, &car.VIN, &car.VariantID, &car.CreatedAt, &car.ArchivedAt, &car.SoldAt, &car.SalePrice, &car.OrganizationID, &car.DeletedAt)
+		if err != nil {
+			return trash, err
+		}
+
+		trash.Cars = append(trash.Cars, car)
+	}
+
+	if err = rows.Err(); err != nil {
+		return trash, err
+	}
+
+	rows, err = m.DB.Query(`SELECT m.id, m.car_id, m.service_date, m.odometer, m.service_type, m.description, m.cost, m.performed_by, m.notes, m.created_at, m.deleted_at
+		FROM maintenance m JOIN users_cars c ON c.id = m.car_id
+		WHERE c.deleted_at IS NULL AND m.deleted_at IS NOT NULL AND `+carRoleIn("$1", RoleOwner, RoleEditor)+` ORDER BY m.deleted_at DESC, m.id DESC`, userId)
+	if err != nil {
+		return trash, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rec MaintenanceRecord
+
+		err := rows.Scan(&rec.ID, &rec.CarID, &rec.ServiceDate, &rec.Odometer, &rec.ServiceType, &rec.Description, &rec.Cost, &rec.PerformedBy, &rec.Notes, &rec.CreatedAt, &rec.DeletedAt)
+		if err != nil {
+			return trash, err
+		}
+
+		trash.Maintenance = append(trash.Maintenance, rec)
+	}
+
+	if err = rows.Err(); err != nil {
+		return trash, err
+	}
+
+	rows, err = m.DB.Query(`SELECT e.id, e.car_id, e.category, e.amount, e.currency, e.expense_date, e.odometer, e.maintenance_id, e.description, e.created_at, e.deleted_at
+		FROM expenses e JOIN users_cars c ON c.id = e.car_id
+		WHERE c.deleted_at IS NULL AND e.deleted_at IS NOT NULL AND `+carRoleIn("$1", RoleOwner, RoleEditor)+` ORDER BY e.deleted_at DESC, e.id DESC`, userId)
+	if err != nil {
+		return trash, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e Expense
+
+		err := rows.Scan(&e.ID, &e.CarID, &e.Category, &e.Amount, &e.Currency, &e.ExpenseDate, &e.Odometer, &e.MaintenanceID, &e.Description, &e.CreatedAt, &e.DeletedAt)
+		if err != nil {
+			return trash, err
+		}
+
+		trash.Expenses = append(trash.Expenses, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return trash, err
+	}
+
+	return trash, nil
+}
+
+// Restores a soft deleted record the user may restore, see Trash. A record of
+// a car that is itself in the trash can only come back together with the car.
+func (m *DBModel) RestoreFromTrash(userId int, kind string, id int) error {
+	var stmt string
+
+	switch kind {
+	case TrashKindCar:
+		stmt = `UPDATE users_cars c SET deleted_at=NULL WHERE c.id=$1 AND c.deleted_at IS NOT NULL AND ` + carRoleIn("$2", RoleOwner)
+	case TrashKindMaintenance:
+		stmt = `UPDATE maintenance m SET deleted_at=NULL FROM users_cars c
+			WHERE m.id=$1 AND c.id = m.car_id AND c.deleted_at IS NULL AND m.deleted_at IS NOT NULL AND ` + carRoleIn("$2", RoleOwner, RoleEditor)
+	case TrashKindExpense:
+		stmt = `UPDATE expenses e SET deleted_at=NULL FROM users_cars c
+			WHERE e.id=$1 AND c.id = e.car_id AND c.deleted_at IS NULL AND e.deleted_at IS NOT NULL AND ` + carRoleIn("$2", RoleOwner, RoleEditor)
+	default:
+		return errors.New("invalid trash kind")
+	}
+
+	res, err := m.DB.Exec(stmt, id, userId)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res)
+}
+
+// Permanently deletes the records that were moved to the trash before the
+// given time and returns how many were removed. The history of a purged car
+// goes with it.
+func (m *DBModel) PurgeTrash(ctx context.Context, before time.Time) (int64, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var purged int64
+	for _, stmt := range []string{
+		`DELETE FROM expenses WHERE deleted_at < $1`,
+		`DELETE FROM maintenance WHERE deleted_at < $1`,
+		`DELETE FROM users_cars WHERE deleted_at < $1`,
+	} {
+		res, err := tx.ExecContext(ctx, stmt, before)
+		if err != nil {
+			return 0, err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		purged += n
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, nil
+}
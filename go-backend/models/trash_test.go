@@ -0,0 +1,132 @@
+package models_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTrashByUserID_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	deletedAt := time.Date(2023, 7, 1, 10, 0, 0, 0, time.UTC)
+	serviceDate := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT (.+) FROM users_cars c WHERE c.deleted_at IS NOT NULL AND \(CASE (.+) END\) IN \('owner'\)`).WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "brand_id", "model_id", "year", "color", "price", "image", "description", "license_plate", "vin", "variant_id", "created_at", "archived_at", "sold_at", "sale_price", "organization_id", "deleted_at"}).
+			AddRow(1, 5, 1, 1, 2022, "red", 50000, "image.jpg", "This is car 1", "ABC123", "1HGCM82633A123456", nil, "2023-06-19 12:00:00", nil, nil, nil, nil, deletedAt))
+	mock.ExpectQuery(`SELECT (.+) FROM maintenance m JOIN users_cars c (.+) c.deleted_at IS NULL AND m.deleted_at IS NOT NULL AND \(CASE (.+) END\) IN \('owner', 'editor'\)`).WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "car_id", "service_date", "odometer", "service_type", "description", "cost", "performed_by", "notes", "created_at", "deleted_at"}).
+			AddRow(3, 2, serviceDate, 42000, "Oil change", "", 89.9, "", "", "2023-05-01 12:00:00", deletedAt))
+	mock.ExpectQuery(`SELECT (.+) FROM expenses e JOIN users_cars c (.+) c.deleted_at IS NULL AND e.deleted_at IS NOT NULL`).WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "car_id", "category", "amount", "currency", "expense_date", "odometer", "maintenance_id", "description", "created_at", "deleted_at"}))
+
+	modelsDB := models.NewModels(db)
+	trash, err := modelsDB.DB.GetTrashByUserID(5)
+
+	assert.NoError(t, err)
+	assert.Len(t, trash.Cars, 1)
+	assert.Equal(t, &deletedAt, trash.Cars[0].DeletedAt)
+	assert.Len(t, trash.Maintenance, 1)
+	assert.Equal(t, "Oil change", trash.Maintenance[0].ServiceType)
+	assert.Equal(t, []models.Expense{}, trash.Expenses)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreFromTrash_Car(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE users_cars c SET deleted_at=NULL WHERE c.id=\$1 AND c.deleted_at IS NOT NULL AND \(CASE (.+) END\) IN \('owner'\)`).WithArgs(1, 5).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.RestoreFromTrash(5, models.TrashKindCar, 1)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreFromTrash_ExpenseOfDeletedCar(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE expenses e SET deleted_at=NULL FROM users_cars c WHERE (.+) c.deleted_at IS NULL AND e.deleted_at IS NOT NULL`).WithArgs(4, 5).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.RestoreFromTrash(5, models.TrashKindExpense, 4)
+
+	assert.ErrorIs(t, err, models.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreFromTrash_InvalidKind(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	modelsDB := models.NewModels(db)
+	err = modelsDB.DB.RestoreFromTrash(5, "fuel", 4)
+
+	assert.EqualError(t, err, "invalid trash kind")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeTrash_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	before := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM expenses WHERE deleted_at < \$1`).WithArgs(before).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`DELETE FROM maintenance WHERE deleted_at < \$1`).WithArgs(before).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM users_cars WHERE deleted_at < \$1`).WithArgs(before).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	modelsDB := models.NewModels(db)
+	purged, err := modelsDB.DB.PurgeTrash(context.Background(), before)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), purged)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeTrash_RollsBackOnError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM expenses`).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`DELETE FROM maintenance`).WillReturnError(errors.New("mocked error"))
+	mock.ExpectRollback()
+
+	modelsDB := models.NewModels(db)
+	purged, err := modelsDB.DB.PurgeTrash(context.Background(), time.Now())
+
+	assert.EqualError(t, err, "mocked error")
+	assert.Zero(t, purged)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
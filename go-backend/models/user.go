@@ -101,3 +101,16 @@ func (m *DBModel) GetUserByID(id int) (*User, error) {
 
 	return user, nil
 }
+
+// Reports whether the user may administer the car catalog
+func (m *DBModel) IsAdmin(userId int) (bool, error) {
+	var isAdmin bool
+	err := m.DB.QueryRow(`SELECT is_admin FROM users WHERE id=$1`, userId).Scan(&isAdmin)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return isAdmin, nil
+}
@@ -0,0 +1,40 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Writes every message to its own file in a directory instead of delivering
+// it. Meant for development and tests.
+type FileNotifier struct {
+	dir string
+	mu  sync.Mutex
+	seq int
+}
+
+func NewFileNotifier(dir string) (*FileNotifier, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileNotifier{dir: dir}, nil
+}
+
+func (n *FileNotifier) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	n.seq++
+	name := fmt.Sprintf("%s-%04d.eml", time.Now().UTC().Format("20060102T150405.000000000"), n.seq)
+	n.mu.Unlock()
+
+	return os.WriteFile(filepath.Join(n.dir, name), formatMessage("", msg), 0o644)
+}
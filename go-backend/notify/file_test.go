@@ -0,0 +1,50 @@
+package notify
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileNotifier_WritesMessages(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+
+	n, err := NewFileNotifier(dir)
+	if err != nil {
+		t.Fatalf("failed to create notifier: %v", err)
+	}
+
+	assert.NoError(t, n.Send(context.Background(), Message{To: "john@example.com", Subject: "First", Body: "Hello"}))
+	assert.NoError(t, n.Send(context.Background(), Message{To: "jane@example.com", Subject: "Second", Body: "World"}))
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	assert.Len(t, entries, 2)
+
+	content, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	if err != nil {
+		t.Fatalf("failed to read message: %v", err)
+	}
+	assert.True(t, strings.HasSuffix(entries[0].Name(), ".eml"))
+	assert.Contains(t, string(content), "To: john@example.com\r\n")
+	assert.Contains(t, string(content), "Subject: First\r\n")
+	assert.Contains(t, string(content), "\r\n\r\nHello\r\n")
+}
+
+func TestFileNotifier_CancelledContext(t *testing.T) {
+	n, err := NewFileNotifier(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create notifier: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, n.Send(ctx, Message{To: "john@example.com"}), context.Canceled)
+}
@@ -0,0 +1,17 @@
+package notify
+
+import (
+	"context"
+)
+
+// An outbound message to a single recipient
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Delivers messages to users. Implementations must be safe for concurrent use.
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
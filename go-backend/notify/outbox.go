@@ -0,0 +1,123 @@
+package notify
+
+import (
+	"context"
+	"time"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// after this many failed attempts a notification is marked as failed
+	MaxAttempts = 8
+
+	batchSize   = 50
+	baseBackoff = time.Minute
+	maxBackoff  = 6 * time.Hour
+)
+
+// The outbox table, implemented by *models.DBModel
+type OutboxStore interface {
+	ClaimNotifications(limit int, now time.Time) ([]models.Notification, error)
+	MarkNotificationSent(id int) error
+	MarkNotificationFailed(id int, lastError string, nextAttempt time.Time, final bool) error
+}
+
+// Delivers the messages queued in the outbox and retries failed sends with an
+// exponential backoff
+type Dispatcher struct {
+	store    OutboxStore
+	notifier Notifier
+	interval time.Duration
+	logger   *zap.SugaredLogger
+	now      func() time.Time
+}
+
+func NewDispatcher(store OutboxStore, notifier Notifier, interval time.Duration, logger *zap.SugaredLogger) *Dispatcher {
+	return &Dispatcher{
+		store:    store,
+		notifier: notifier,
+		interval: interval,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Delivers pending messages on every tick until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		err := d.RunOnce(ctx)
+		if err != nil {
+			d.logger.Error("failed to dispatch notifications: ", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			d.logger.Info("notification dispatcher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Delivers the messages that are currently due, one batch at a time
+func (d *Dispatcher) RunOnce(ctx context.Context) error {
+	for ctx.Err() == nil {
+		notifications, err := d.store.ClaimNotifications(batchSize, d.now())
+		if err != nil {
+			return err
+		}
+
+		for _, n := range notifications {
+			d.deliver(ctx, n)
+		}
+
+		if len(notifications) < batchSize {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, n models.Notification) {
+	err := d.notifier.Send(ctx, Message{To: n.Recipient, Subject: n.Subject, Body: n.Body})
+	if err == nil {
+		err = d.store.MarkNotificationSent(n.ID)
+		if err != nil {
+			// the lease expires and the message is sent again, a duplicate beats a loss
+			d.logger.Error("failed to mark notification ", n.ID, " as sent: ", err)
+		}
+		return
+	}
+
+	attempts := n.Attempts + 1
+	final := attempts >= MaxAttempts
+	if final {
+		d.logger.Error("giving up on notification ", n.ID, " after ", attempts, " attempts: ", err)
+	} else {
+		d.logger.Warn("failed to send notification ", n.ID, ", retrying: ", err)
+	}
+
+	err = d.store.MarkNotificationFailed(n.ID, err.Error(), d.now().Add(Backoff(attempts)), final)
+	if err != nil {
+		d.logger.Error("failed to record the failed attempt of notification ", n.ID, ": ", err)
+	}
+}
+
+// Returns the delay before the next attempt after the given number of failed attempts
+func Backoff(attempts int) time.Duration {
+	delay := baseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+
+	return delay
+}
@@ -0,0 +1,102 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type failedAttempt struct {
+	id          int
+	lastError   string
+	nextAttempt time.Time
+	final       bool
+}
+
+type fakeOutbox struct {
+	pending []models.Notification
+	sent    []int
+	failed  []failedAttempt
+}
+
+func (f *fakeOutbox) ClaimNotifications(limit int, now time.Time) ([]models.Notification, error) {
+	if limit > len(f.pending) {
+		limit = len(f.pending)
+	}
+	claimed := f.pending[:limit]
+	f.pending = f.pending[limit:]
+
+	return claimed, nil
+}
+
+func (f *fakeOutbox) MarkNotificationSent(id int) error {
+	f.sent = append(f.sent, id)
+	return nil
+}
+
+func (f *fakeOutbox) MarkNotificationFailed(id int, lastError string, nextAttempt time.Time, final bool) error {
+	f.failed = append(f.failed, failedAttempt{id, lastError, nextAttempt, final})
+	return nil
+}
+
+type fakeNotifier struct {
+	failFor map[string]bool
+	sent    []Message
+}
+
+func (f *fakeNotifier) Send(ctx context.Context, msg Message) error {
+	if f.failFor[msg.To] {
+		return errors.New("connection refused")
+	}
+	f.sent = append(f.sent, msg)
+
+	return nil
+}
+
+func TestDispatcher_RunOnce(t *testing.T) {
+	now := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	store := &fakeOutbox{pending: []models.Notification{
+		{ID: 1, Recipient: "john@example.com", Subject: "First"},
+		{ID: 2, Recipient: "broken@example.com", Subject: "Second", Attempts: 2},
+		{ID: 3, Recipient: "broken@example.com", Subject: "Third", Attempts: MaxAttempts - 1},
+	}}
+	notifier := &fakeNotifier{failFor: map[string]bool{"broken@example.com": true}}
+
+	d := NewDispatcher(store, notifier, time.Minute, zap.NewNop().Sugar())
+	d.now = func() time.Time { return now }
+
+	err := d.RunOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, store.sent)
+	assert.Len(t, notifier.sent, 1)
+	assert.Equal(t, []failedAttempt{
+		{id: 2, lastError: "connection refused", nextAttempt: now.Add(4 * time.Minute), final: false},
+		{id: 3, lastError: "connection refused", nextAttempt: now.Add(Backoff(MaxAttempts)), final: true},
+	}, store.failed)
+}
+
+func TestDispatcher_RunOnceDrainsBatches(t *testing.T) {
+	store := &fakeOutbox{}
+	for i := 1; i <= batchSize+5; i++ {
+		store.pending = append(store.pending, models.Notification{ID: i, Recipient: "john@example.com"})
+	}
+
+	d := NewDispatcher(store, &fakeNotifier{}, time.Minute, zap.NewNop().Sugar())
+	err := d.RunOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, store.sent, batchSize+5)
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Minute, Backoff(1))
+	assert.Equal(t, 2*time.Minute, Backoff(2))
+	assert.Equal(t, 64*time.Minute, Backoff(7))
+	assert.Equal(t, 6*time.Hour, Backoff(20))
+}
@@ -0,0 +1,87 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Delivers messages through an SMTP server
+type SMTPNotifier struct {
+	addr string
+	host string
+	from string
+	auth smtp.Auth
+}
+
+// Creates an SMTP notifier. Authentication is only used when a username is set.
+func NewSMTPNotifier(host, port, username, password, from string) *SMTPNotifier {
+	n := &SMTPNotifier{
+		addr: net.JoinHostPort(host, port),
+		host: host,
+		from: from,
+	}
+
+	if username != "" {
+		n.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return n
+}
+
+func (n *SMTPNotifier) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	to, err := parseRecipient(msg.To)
+	if err != nil {
+		return err
+	}
+	msg.To = to
+
+	return smtp.SendMail(n.addr, n.auth, n.from, []string{to}, formatMessage(n.from, msg))
+}
+
+// Returns the bare address of the recipient. A line break in the address
+// would let it inject headers into the message, so it is rejected.
+func parseRecipient(to string) (string, error) {
+	if strings.ContainsAny(to, "\r\n") {
+		return "", fmt.Errorf("invalid recipient %q: contains a line break", to)
+	}
+
+	addr, err := mail.ParseAddress(to)
+	if err != nil {
+		return "", fmt.Errorf("invalid recipient %q: %w", to, err)
+	}
+
+	return addr.Address, nil
+}
+
+// Renders a message as a plain text RFC 5322 email
+func formatMessage(from string, msg Message) []byte {
+	var buf bytes.Buffer
+
+	if from != "" {
+		fmt.Fprintf(&buf, "From: %s\r\n", from)
+	}
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+
+	// SMTP requires CRLF line endings
+	body := strings.ReplaceAll(msg.Body, "\r\n", "\n")
+	buf.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	buf.WriteString("\r\n")
+
+	return buf.Bytes()
+}
@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Accepts a single SMTP session and returns the received DATA section
+func fakeSMTPServer(t *testing.T) (string, <-chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	received := make(chan string, 1)
+	go func() {
+		defer l.Close()
+
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		write := func(s string) { conn.Write([]byte(s + "\r\n")) }
+
+		write("220 localhost ESMTP")
+		var data strings.Builder
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			if inData {
+				if line == ".\r\n" {
+					inData = false
+					received <- data.String()
+					write("250 OK")
+					continue
+				}
+				data.WriteString(line)
+				continue
+			}
+
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				write("250 localhost")
+			case strings.HasPrefix(cmd, "DATA"):
+				inData = true
+				write("354 go ahead")
+			case strings.HasPrefix(cmd, "QUIT"):
+				write("221 bye")
+				return
+			default:
+				write("250 OK")
+			}
+		}
+	}()
+
+	return l.Addr().String(), received
+}
+
+func TestSMTPNotifier_Send(t *testing.T) {
+	addr, received := fakeSMTPServer(t)
+	host, port, _ := net.SplitHostPort(addr)
+
+	n := NewSMTPNotifier(host, port, "", "", "tracker@example.com")
+	err := n.Send(context.Background(), Message{To: "john@example.com", Subject: "Oil change is due", Body: "Line one\nLine two"})
+
+	assert.NoError(t, err)
+	data := <-received
+	assert.Contains(t, data, "From: tracker@example.com\r\n")
+	assert.Contains(t, data, "To: john@example.com\r\n")
+	assert.Contains(t, data, "Subject: Oil change is due\r\n")
+	assert.Contains(t, data, "Line one\r\nLine two\r\n")
+}
+
+func TestSMTPNotifier_ConnectionError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	l.Close()
+
+	n := NewSMTPNotifier(host, port, "", "", "tracker@example.com")
+	err = n.Send(context.Background(), Message{To: "john@example.com", Subject: "Subject", Body: "Body"})
+
+	assert.Error(t, err)
+}
+
+func TestSMTPNotifier_RejectsHeaderInjection(t *testing.T) {
+	// nothing listens on the address, the message must fail before dialing
+	n := NewSMTPNotifier("127.0.0.1", "1", "", "", "tracker@example.com")
+
+	for _, to := range []string{"john@example.com\r\nBcc: eve@example.com", "john@example.com\nSubject: spam", "not an address"} {
+		err := n.Send(context.Background(), Message{To: to, Subject: "Subject", Body: "Body"})
+
+		assert.ErrorContains(t, err, "invalid recipient")
+	}
+}
+
+func TestFormatMessage_EncodesSubject(t *testing.T) {
+	msg := formatMessage("", Message{To: "john@example.com", Subject: "Servis vozidla Citroën", Body: "Body"})
+
+	assert.Contains(t, string(msg), "Subject: =?utf-8?q?Servis_vozidla_Citro=C3=ABn?=\r\n")
+	assert.NotContains(t, string(msg), "From:")
+}
@@ -0,0 +1,256 @@
+package reminder
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+	"go.uber.org/zap"
+)
+
+// Advisory lock key shared by all API replicas, so only one of them evaluates
+// reminders at a time
+const LockKey int64 = 7_265_626_571
+
+// how many days before its end the owner is reminded of a document
+const DocumentNoticeDays = 30
+
+// The storage the scheduler needs, implemented by *models.DBModel
+type Store interface {
+	WithAdvisoryLock(ctx context.Context, key int64, fn func() error) (bool, error)
+	GetAllCarIDs() ([]int, error)
+	GetDueServicesByCarID(carId int, now time.Time) ([]models.DueService, error)
+	GetDocumentExpiriesByCarID(carId int, now time.Time, noticeDays int) ([]models.DocumentExpiry, error)
+	UpsertReminder(reminder models.Reminder, notice *models.NotificationContent) (bool, error)
+	ResolveStaleReminders(carId int, activeKeys []string) error
+}
+
+// Periodically evaluates the due and overdue services and the expiring
+// documents of every car and stores a reminder for each of them. The users who
+// may edit the car are notified through the outbox when a reminder is created.
+type Scheduler struct {
+	store    Store
+	interval time.Duration
+	logger   *zap.SugaredLogger
+	now      func() time.Time
+}
+
+func NewScheduler(store Store, interval time.Duration, logger *zap.SugaredLogger) *Scheduler {
+	return &Scheduler{
+		store:    store,
+		interval: interval,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Evaluates reminders right away and then on every tick until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		err := s.RunOnce(ctx)
+		if err != nil {
+			s.logger.Error("failed to evaluate reminders: ", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			s.logger.Info("reminder scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Evaluates the reminders of all cars once, unless another replica is already
+// doing so
+func (s *Scheduler) RunOnce(ctx context.Context) error {
+	locked, err := s.store.WithAdvisoryLock(ctx, LockKey, func() error {
+		return s.evaluate(ctx)
+	})
+	if err != nil {
+		return err
+	}
+
+	if !locked {
+		s.logger.Info("reminders are evaluated by another instance, skipping")
+	}
+
+	return nil
+}
+
+func (s *Scheduler) evaluate(ctx context.Context) error {
+	carIds, err := s.store.GetAllCarIDs()
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+	created := 0
+	for _, carId := range carIds {
+		// stop between cars on shutdown
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		n, err := s.evaluateCar(carId, now)
+		if err != nil {
+			// one broken car must not block the reminders of the others
+			s.logger.Error("failed to evaluate reminders of car ", carId, ": ", err)
+			continue
+		}
+		created += n
+	}
+
+	s.logger.Info("evaluated reminders of ", len(carIds), " cars, created ", created)
+
+	return nil
+}
+
+func (s *Scheduler) evaluateCar(carId int, now time.Time) (int, error) {
+	services, err := s.store.GetDueServicesByCarID(carId, now)
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	activeKeys := []string{}
+	for _, service := range services {
+		if service.Status == models.DueStatusUpcoming {
+			continue
+		}
+
+		reminder := ServiceReminder(service)
+		inserted, err := s.store.UpsertReminder(reminder, serviceNotice(service))
+		if err != nil {
+			return created, err
+		}
+
+		if inserted {
+			created++
+		}
+		activeKeys = append(activeKeys, reminder.DedupeKey)
+	}
+
+	documents, err := s.store.GetDocumentExpiriesByCarID(carId, now, DocumentNoticeDays)
+	if err != nil {
+		return created, err
+	}
+
+	for _, document := range documents {
+		if document.Status == models.DueStatusUpcoming {
+			continue
+		}
+
+		reminder := DocumentReminder(document)
+		inserted, err := s.store.UpsertReminder(reminder, documentNotice(document))
+		if err != nil {
+			return created, err
+		}
+		if inserted {
+			created++
+		}
+		activeKeys = append(activeKeys, reminder.DedupeKey)
+	}
+
+	return created, s.store.ResolveStaleReminders(carId, activeKeys)
+}
+
+// Builds the reminder of a due service. The dedupe key is derived from the
+// occurrence baseline, so the key stays the same until the service is performed.
+func ServiceReminder(service models.DueService) models.Reminder {
+	return models.Reminder{
+		CarID:       service.CarID,
+		Kind:        models.ReminderKindService,
+		SourceID:    service.ScheduleID,
+		Title:       serviceTitle(service),
+		Status:      service.Status,
+		DueDate:     service.DueDate,
+		DueOdometer: service.DueOdometer,
+		DedupeKey: fmt.Sprintf("%s:%d:%s:%d", models.ReminderKindService, service.ScheduleID,
+			service.LastServiceDate.Format("2006-01-02"), service.LastOdometer),
+	}
+}
+
+func serviceTitle(service models.DueService) string {
+	if service.Status == models.DueStatusOverdue {
+		return service.ServiceType + " is overdue"
+	}
+
+	return service.ServiceType + " is due soon"
+}
+
+func serviceNotice(service models.DueService) *models.NotificationContent {
+	var body strings.Builder
+
+	fmt.Fprintf(&body, "%s.\n\n", serviceTitle(service))
+	fmt.Fprintf(&body, "Last performed on %s at %d km.\n", service.LastServiceDate.Format("2006-01-02"), service.LastOdometer)
+	if service.DueDate != nil {
+		fmt.Fprintf(&body, "Due by %s.\n", service.DueDate.Format("2006-01-02"))
+	}
+	if service.DueOdometer != nil {
+		fmt.Fprintf(&body, "Due at %d km.\n", *service.DueOdometer)
+	}
+
+	return &models.NotificationContent{
+		Subject: "Maintenance reminder: " + serviceTitle(service),
+		Body:    body.String(),
+	}
+}
+
+// Builds the reminder of an expiring document. The dedupe key includes the end
+// of the validity, so renewing the document starts a new occurrence.
+func DocumentReminder(document models.DocumentExpiry) models.Reminder {
+	key := fmt.Sprintf("%s:%d:%s", models.ReminderKindDocument, document.ID, document.ValidUntil.Format("2006-01-02"))
+	if document.MileageLimit != nil {
+		key += fmt.Sprintf(":%d", *document.MileageLimit)
+	}
+
+	validUntil := document.ValidUntil
+
+	return models.Reminder{
+		CarID:       document.CarID,
+		Kind:        models.ReminderKindDocument,
+		SourceID:    document.ID,
+		Title:       documentTitle(document),
+		Status:      document.Status,
+		DueDate:     &validUntil,
+		DueOdometer: document.MileageLimit,
+		DedupeKey:   key,
+	}
+}
+
+func documentTitle(document models.DocumentExpiry) string {
+	if document.Status == models.DueStatusOverdue {
+		return models.DocumentTypeName(document.DocumentType) + " has expired"
+	}
+
+	return models.DocumentTypeName(document.DocumentType) + " expires soon"
+}
+
+func documentNotice(document models.DocumentExpiry) *models.NotificationContent {
+	var body strings.Builder
+	fmt.Fprintf(&body, "%s.\n\n", documentTitle(document))
+	if document.Issuer != "" {
+		fmt.Fprintf(&body, "Issued by %s", document.Issuer)
+		if document.PolicyNumber != "" {
+			fmt.Fprintf(&body, ", number %s", document.PolicyNumber)
+		}
+		body.WriteString(".\n")
+	} else if document.PolicyNumber != "" {
+		fmt.Fprintf(&body, "Number %s.\n", document.PolicyNumber)
+	}
+	fmt.Fprintf(&body, "Valid until %s.\n", document.ValidUntil.Format("2006-01-02"))
+	if document.MileageLimit != nil {
+		fmt.Fprintf(&body, "Valid up to %d km.\n", *document.MileageLimit)
+	}
+
+	return &models.NotificationContent{
+		Subject: "Document reminder: " + documentTitle(document),
+		Body:    body.String(),
+	}
+}
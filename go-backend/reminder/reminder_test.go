@@ -0,0 +1,249 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type fakeStore struct {
+	mu        sync.Mutex
+	locked    bool
+	carIds    []int
+	services  map[int][]models.DueService
+	documents map[int][]models.DocumentExpiry
+	failCar   int
+	reminders map[string]models.Reminder
+	active    map[int][]string
+	notices   []models.NotificationContent
+	runs      int
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		services:  make(map[int][]models.DueService),
+		documents: make(map[int][]models.DocumentExpiry),
+		reminders: make(map[string]models.Reminder),
+		active:    make(map[int][]string),
+	}
+}
+
+func (f *fakeStore) WithAdvisoryLock(ctx context.Context, key int64, fn func() error) (bool, error) {
+	f.mu.Lock()
+	f.runs++
+	f.mu.Unlock()
+
+	if f.locked {
+		return false, nil
+	}
+
+	return true, fn()
+}
+
+func (f *fakeStore) GetAllCarIDs() ([]int, error) {
+	return f.carIds, nil
+}
+
+func (f *fakeStore) GetDueServicesByCarID(carId int, now time.Time) ([]models.DueService, error) {
+	if carId == f.failCar {
+		return nil, errors.New("mocked error")
+	}
+
+	return f.services[carId], nil
+}
+
+func (f *fakeStore) GetDocumentExpiriesByCarID(carId int, now time.Time, noticeDays int) ([]models.DocumentExpiry, error) {
+	return f.documents[carId], nil
+}
+
+func (f *fakeStore) UpsertReminder(reminder models.Reminder, notice *models.NotificationContent) (bool, error) {
+	_, exists := f.reminders[reminder.DedupeKey]
+	f.reminders[reminder.DedupeKey] = reminder
+	if !exists && notice != nil {
+		f.notices = append(f.notices, *notice)
+	}
+
+	return !exists, nil
+}
+
+func (f *fakeStore) ResolveStaleReminders(carId int, activeKeys []string) error {
+	f.active[carId] = activeKeys
+
+	return nil
+}
+
+func dueService(scheduleId, carId int, status string) models.DueService {
+	dueDate := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	return models.DueService{
+		ScheduleID:      scheduleId,
+		CarID:           carId,
+		ServiceType:     "Oil change",
+		LastServiceDate: time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC),
+		LastOdometer:    30000,
+		DueDate:         &dueDate,
+		Status:          status,
+	}
+}
+
+func TestRunOnce_StoresDueAndOverdueServices(t *testing.T) {
+	store := newFakeStore()
+	store.carIds = []int{1, 2}
+	store.services[1] = []models.DueService{dueService(1, 1, models.DueStatusOverdue), dueService(2, 1, models.DueStatusUpcoming)}
+	store.services[2] = []models.DueService{dueService(3, 2, models.DueStatusDueSoon)}
+
+	s := NewScheduler(store, time.Hour, zap.NewNop().Sugar())
+	err := s.RunOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, store.reminders, 2)
+	assert.Equal(t, []string{"service:1:2022-07-01:30000"}, store.active[1])
+	assert.Equal(t, []string{"service:3:2022-07-01:30000"}, store.active[2])
+	assert.Equal(t, "Oil change is overdue", store.reminders["service:1:2022-07-01:30000"].Title)
+}
+
+func TestRunOnce_DoesNotDuplicate(t *testing.T) {
+	store := newFakeStore()
+	store.carIds = []int{1}
+	store.services[1] = []models.DueService{dueService(1, 1, models.DueStatusDueSoon)}
+
+	s := NewScheduler(store, time.Hour, zap.NewNop().Sugar())
+	assert.NoError(t, s.RunOnce(context.Background()))
+
+	// the same occurrence becomes overdue, the reminder is updated in place
+	store.services[1] = []models.DueService{dueService(1, 1, models.DueStatusOverdue)}
+	assert.NoError(t, s.RunOnce(context.Background()))
+
+	assert.Len(t, store.reminders, 1)
+	assert.Equal(t, models.DueStatusOverdue, store.reminders["service:1:2022-07-01:30000"].Status)
+	// the owner is only notified once per occurrence
+	assert.Len(t, store.notices, 1)
+	assert.Equal(t, "Maintenance reminder: Oil change is due soon", store.notices[0].Subject)
+	assert.Contains(t, store.notices[0].Body, "Due by 2023-07-01.")
+}
+
+func TestRunOnce_ResolvesWhenNothingIsDue(t *testing.T) {
+	store := newFakeStore()
+	store.carIds = []int{1}
+
+	s := NewScheduler(store, time.Hour, zap.NewNop().Sugar())
+	assert.NoError(t, s.RunOnce(context.Background()))
+
+	keys, ok := store.active[1]
+	assert.True(t, ok)
+	assert.Empty(t, keys)
+}
+
+func TestRunOnce_SkipsWhenLocked(t *testing.T) {
+	store := newFakeStore()
+	store.locked = true
+	store.carIds = []int{1}
+	store.services[1] = []models.DueService{dueService(1, 1, models.DueStatusOverdue)}
+
+	s := NewScheduler(store, time.Hour, zap.NewNop().Sugar())
+	err := s.RunOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Empty(t, store.reminders)
+}
+
+func TestRunOnce_ContinuesAfterCarError(t *testing.T) {
+	store := newFakeStore()
+	store.carIds = []int{1, 2}
+	store.failCar = 1
+	store.services[2] = []models.DueService{dueService(3, 2, models.DueStatusOverdue)}
+
+	s := NewScheduler(store, time.Hour, zap.NewNop().Sugar())
+	err := s.RunOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, store.reminders, 1)
+}
+
+func TestRunOnce_StoresExpiringDocuments(t *testing.T) {
+	store := newFakeStore()
+	store.carIds = []int{1}
+	store.services[1] = []models.DueService{dueService(1, 1, models.DueStatusOverdue)}
+
+	insurance := models.DocumentExpiry{
+		VehicleDocument: models.VehicleDocument{
+			ID:           4,
+			CarID:        1,
+			DocumentType: models.DocumentInsurance,
+			Issuer:       "Allianz",
+			PolicyNumber: "P-123",
+			ValidUntil:   time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC),
+		},
+		DaysLeft: 12,
+		Status:   models.DueStatusDueSoon,
+	}
+	vignette := models.DocumentExpiry{
+		VehicleDocument: models.VehicleDocument{ID: 5, CarID: 1, DocumentType: models.DocumentVignette, ValidUntil: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)},
+		DaysLeft:        226,
+		Status:          models.DueStatusUpcoming,
+	}
+	store.documents[1] = []models.DocumentExpiry{insurance, vignette}
+
+	s := NewScheduler(store, time.Hour, zap.NewNop().Sugar())
+	err := s.RunOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, store.reminders, 2)
+	// both kinds of reminders stay active
+	assert.Equal(t, []string{"service:1:2022-07-01:30000", "document:4:2023-07-01"}, store.active[1])
+
+	reminder := store.reminders["document:4:2023-07-01"]
+	assert.Equal(t, models.ReminderKindDocument, reminder.Kind)
+	assert.Equal(t, "Insurance expires soon", reminder.Title)
+	assert.Equal(t, insurance.ValidUntil, *reminder.DueDate)
+
+	assert.Len(t, store.notices, 2)
+	assert.Equal(t, "Document reminder: Insurance expires soon", store.notices[1].Subject)
+	assert.Contains(t, store.notices[1].Body, "Issued by Allianz, number P-123.")
+	assert.Contains(t, store.notices[1].Body, "Valid until 2023-07-01.")
+}
+
+func TestDocumentReminder_RenewalStartsNewOccurrence(t *testing.T) {
+	limit := 150000
+	document := models.DocumentExpiry{
+		VehicleDocument: models.VehicleDocument{ID: 4, CarID: 1, DocumentType: models.DocumentWarranty, ValidUntil: time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC), MileageLimit: &limit},
+		Status:          models.DueStatusOverdue,
+	}
+
+	reminder := DocumentReminder(document)
+	assert.Equal(t, "document:4:2023-07-01:150000", reminder.DedupeKey)
+	assert.Equal(t, "Extended warranty has expired", reminder.Title)
+
+	document.ValidUntil = time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	assert.NotEqual(t, reminder.DedupeKey, DocumentReminder(document).DedupeKey)
+}
+
+func TestRun_StopsOnCancel(t *testing.T) {
+	store := newFakeStore()
+	s := NewScheduler(store, time.Millisecond, zap.NewNop().Sugar())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler did not stop after cancel")
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	assert.Greater(t, store.runs, 1)
+}
@@ -0,0 +1,308 @@
+// Package report renders the printable documents of the tracker as PDF.
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/go-pdf/fpdf"
+)
+
+const (
+	pageMargin = 15.0
+	lineHeight = 5.0
+)
+
+// Everything printed in the history report of a car
+type CarReport struct {
+	Car         models.Car
+	Maker       string
+	Model       string
+	Maintenance []models.MaintenanceRecord
+	Odometer    []models.OdometerReading
+	Costs       []models.CategoryTotal
+	GeneratedAt time.Time
+}
+
+type column struct {
+	title string
+	width float64
+	align string
+}
+
+type document struct {
+	pdf *fpdf.Fpdf
+	// converts UTF-8 to the encoding of the core fonts
+	tr func(string) string
+}
+
+// Renders the vehicle history report of a car. The maintenance and odometer
+// history are printed in chronological order.
+func WriteCarReport(w io.Writer, data CarReport) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pageMargin, pageMargin, pageMargin)
+	pdf.SetAutoPageBreak(true, pageMargin)
+	pdf.SetCreationDate(data.GeneratedAt)
+	pdf.SetModificationDate(data.GeneratedAt)
+
+	doc := &document{pdf: pdf, tr: pdf.UnicodeTranslatorFromDescriptor("")}
+	title := fmt.Sprintf("%s %s", data.Maker, data.Model)
+	pdf.SetTitle(doc.tr("Vehicle history report - "+title), false)
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-pageMargin)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.CellFormat(0, lineHeight, fmt.Sprintf("Page %d", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(0, 10, doc.tr("Vehicle history report"), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(0, lineHeight, "Generated on "+data.GeneratedAt.Format("2006-01-02"), "", 1, "L", false, 0, "")
+
+	doc.vehicle(data, title)
+	doc.maintenance(data.Maintenance)
+	doc.odometer(data.Odometer)
+	doc.costs(data.Costs)
+
+	return pdf.Output(w)
+}
+
+func (d *document) heading(text string) {
+	d.pdf.Ln(lineHeight)
+	d.pdf.SetFont("Helvetica", "B", 13)
+	d.pdf.CellFormat(0, 8, d.tr(text), "B", 1, "L", false, 0, "")
+	d.pdf.Ln(2)
+}
+
+func (d *document) vehicle(data CarReport, title string) {
+	d.heading("Vehicle")
+
+	car := data.Car
+	fields := [][2]string{
+		{"Vehicle", title},
+		{"Year", optionalInt(car.Year)},
+		{"Color", car.Color},
+		{"License plate", car.LicensePlate},
+		{"VIN", car.VIN},
+	}
+	if car.Mileage != nil {
+		fields = append(fields, [2]string{"Odometer", formatKm(*car.Mileage)})
+	}
+	if car.Description != "" {
+		fields = append(fields, [2]string{"Description", car.Description})
+	}
+
+	for _, field := range fields {
+		if field[1] == "" {
+			continue
+		}
+		d.pdf.SetFont("Helvetica", "B", 10)
+		d.pdf.CellFormat(40, lineHeight+1, d.tr(field[0]), "", 0, "L", false, 0, "")
+		d.pdf.SetFont("Helvetica", "", 10)
+		d.pdf.MultiCell(0, lineHeight+1, d.tr(field[1]), "", "L", false)
+	}
+}
+
+func (d *document) maintenance(records []models.MaintenanceRecord) {
+	d.heading("Maintenance history")
+
+	if len(records) == 0 {
+		d.empty("No maintenance has been recorded.")
+		return
+	}
+
+	sorted := make([]models.MaintenanceRecord, len(records))
+	copy(sorted, records)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].ServiceDate.Equal(sorted[j].ServiceDate) {
+			return sorted[i].ServiceDate.Before(sorted[j].ServiceDate)
+		}
+		return sorted[i].Odometer < sorted[j].Odometer
+	})
+
+	columns := []column{
+		{"Date", 22, "L"},
+		{"Odometer", 24, "R"},
+		{"Service", 70, "L"},
+		{"Performed by", 40, "L"},
+		{"Cost", 24, "R"},
+	}
+	d.tableHeader(columns)
+
+	total := 0.0
+	for _, rec := range sorted {
+		service := rec.ServiceType
+		if rec.Description != "" {
+			service += "\n" + rec.Description
+		}
+
+		d.tableRow(columns, []string{
+			rec.ServiceDate.Format("2006-01-02"),
+			optionalKm(rec.Odometer),
+			service,
+			rec.PerformedBy,
+			formatMoney(rec.Cost),
+		})
+		total += rec.Cost
+	}
+
+	d.tableTotal(columns, formatMoney(total))
+}
+
+func (d *document) odometer(readings []models.OdometerReading) {
+	d.heading("Odometer history")
+
+	if len(readings) == 0 {
+		d.empty("No odometer readings have been recorded.")
+		return
+	}
+
+	sorted := make([]models.OdometerReading, len(readings))
+	copy(sorted, readings)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ReadingDate.Before(sorted[j].ReadingDate)
+	})
+
+	columns := []column{
+		{"Date", 22, "L"},
+		{"Odometer", 30, "R"},
+		{"Note", 128, "L"},
+	}
+	d.tableHeader(columns)
+
+	for _, reading := range sorted {
+		note := reading.Note
+		if reading.IsReplacement {
+			note = "Odometer replaced. " + note
+		}
+
+		d.tableRow(columns, []string{reading.ReadingDate.Format("2006-01-02"), formatKm(reading.Odometer), note})
+	}
+}
+
+func (d *document) costs(categories []models.CategoryTotal) {
+	d.heading("Cost totals")
+
+	if len(categories) == 0 {
+		d.empty("No costs have been recorded.")
+		return
+	}
+
+	columns := []column{
+		{"Category", 100, "L"},
+		{"Entries", 40, "R"},
+		{"Total", 40, "R"},
+	}
+	d.tableHeader(columns)
+
+	total := 0.0
+	for _, c := range categories {
+		d.tableRow(columns, []string{c.Category, strconv.Itoa(c.Count), formatMoney(c.Total)})
+		total += c.Total
+	}
+
+	d.tableTotal(columns, formatMoney(total))
+}
+
+func (d *document) empty(text string) {
+	d.pdf.SetFont("Helvetica", "I", 10)
+	d.pdf.CellFormat(0, lineHeight+1, d.tr(text), "", 1, "L", false, 0, "")
+}
+
+func (d *document) tableHeader(columns []column) {
+	d.pdf.SetFont("Helvetica", "B", 9)
+	d.pdf.SetFillColor(230, 230, 230)
+	for _, c := range columns {
+		d.pdf.CellFormat(c.width, lineHeight+2, d.tr(c.title), "1", 0, c.align, true, 0, "")
+	}
+	d.pdf.Ln(-1)
+}
+
+// Prints a row whose cells wrap, every cell gets the height of the tallest one
+func (d *document) tableRow(columns []column, values []string) {
+	d.pdf.SetFont("Helvetica", "", 9)
+
+	lines := make([][]string, len(columns))
+	height := lineHeight
+	for i, c := range columns {
+		for _, line := range d.pdf.SplitLines([]byte(d.tr(values[i])), c.width) {
+			lines[i] = append(lines[i], string(line))
+		}
+		if h := float64(len(lines[i])) * lineHeight; h > height {
+			height = h
+		}
+	}
+
+	_, pageHeight := d.pdf.GetPageSize()
+	if d.pdf.GetY()+height > pageHeight-pageMargin {
+		d.pdf.AddPage()
+		d.tableHeader(columns)
+		d.pdf.SetFont("Helvetica", "", 9)
+	}
+
+	x, y := d.pdf.GetXY()
+	for i, c := range columns {
+		d.pdf.Rect(x, y, c.width, height, "D")
+		for j, line := range lines[i] {
+			d.pdf.SetXY(x, y+float64(j)*lineHeight)
+			d.pdf.CellFormat(c.width, lineHeight, line, "", 0, c.align, false, 0, "")
+		}
+		x += c.width
+	}
+	d.pdf.SetXY(pageMargin, y+height)
+}
+
+// Prints the total under the last column
+func (d *document) tableTotal(columns []column, total string) {
+	width := 0.0
+	for _, c := range columns[:len(columns)-1] {
+		width += c.width
+	}
+
+	d.pdf.SetFont("Helvetica", "B", 9)
+	d.pdf.CellFormat(width, lineHeight+1, "Total", "", 0, "R", false, 0, "")
+	d.pdf.CellFormat(columns[len(columns)-1].width, lineHeight+1, total, "", 1, "R", false, 0, "")
+}
+
+func formatKm(km int) string {
+	return groupThousands(km) + " km"
+}
+
+func optionalKm(km int) string {
+	if km <= 0 {
+		return ""
+	}
+
+	return formatKm(km)
+}
+
+func optionalInt(value int) string {
+	if value == 0 {
+		return ""
+	}
+
+	return strconv.Itoa(value)
+}
+
+func formatMoney(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+func groupThousands(value int) string {
+	if value < 0 {
+		return "-" + groupThousands(-value)
+	}
+
+	digits := strconv.Itoa(value)
+	for i := len(digits) - 3; i > 0; i -= 3 {
+		digits = digits[:i] + " " + digits[i:]
+	}
+
+	return digits
+}
@@ -0,0 +1,83 @@
+package report
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func testReport(records int) CarReport {
+	mileage := 152300
+	data := CarReport{
+		Car: models.Car{
+			ID:           1,
+			Year:         2015,
+			Color:        "Grey",
+			LicensePlate: "BA123XY",
+			VIN:          "VF7SA9HZC12345678",
+			Mileage:      &mileage,
+		},
+		Maker: "Citroën",
+		Model: "C4 Picasso",
+		Odometer: []models.OdometerReading{
+			{ReadingDate: time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC), Odometer: 150000},
+			{ReadingDate: time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC), Odometer: 135000, Note: "Škoda dealer"},
+		},
+		Costs:       []models.CategoryTotal{{Category: "maintenance", Total: 540.5, Count: 3}},
+		GeneratedAt: time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	for i := 0; i < records; i++ {
+		data.Maintenance = append(data.Maintenance, models.MaintenanceRecord{
+			ServiceDate: time.Date(2015+i%8, 6, 1, 0, 0, 0, 0, time.UTC),
+			Odometer:    10000 * i,
+			ServiceType: "Oil change",
+			Description: "Engine oil, oil filter and a very long description that has to wrap onto the next line of the cell",
+			Cost:        120,
+			PerformedBy: "Local garage",
+		})
+	}
+
+	return data
+}
+
+func pageCount(pdf []byte) int {
+	return len(regexp.MustCompile(`/Type /Page\b[^s]`).FindAll(pdf, -1))
+}
+
+func TestWriteCarReport(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteCarReport(&buf, testReport(3))
+
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")))
+	assert.True(t, bytes.HasSuffix(bytes.TrimSpace(buf.Bytes()), []byte("%%EOF")))
+	assert.Equal(t, 1, pageCount(buf.Bytes()))
+}
+
+func TestWriteCarReport_BreaksPages(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteCarReport(&buf, testReport(60))
+
+	assert.NoError(t, err)
+	assert.Greater(t, pageCount(buf.Bytes()), 1)
+}
+
+func TestWriteCarReport_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteCarReport(&buf, CarReport{Maker: "Tesla", Model: "Model 3"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, pageCount(buf.Bytes()))
+}
+
+func TestGroupThousands(t *testing.T) {
+	assert.Equal(t, "0", groupThousands(0))
+	assert.Equal(t, "999", groupThousands(999))
+	assert.Equal(t, "152 300", groupThousands(152300))
+	assert.Equal(t, "-1 000 000", groupThousands(-1000000))
+}
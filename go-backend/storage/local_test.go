@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocal_PutGetDelete(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	err = store.Put(ctx, "attachments/1/receipt", strings.NewReader("invoice"), 7, "application/pdf")
+	assert.NoError(t, err)
+
+	rc, err := store.Get(ctx, "attachments/1/receipt")
+	assert.NoError(t, err)
+	content, _ := io.ReadAll(rc)
+	rc.Close()
+	assert.Equal(t, "invoice", string(content))
+
+	assert.NoError(t, store.Delete(ctx, "attachments/1/receipt"))
+	// deleting twice is fine
+	assert.NoError(t, store.Delete(ctx, "attachments/1/receipt"))
+
+	_, err = store.Get(ctx, "attachments/1/receipt")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLocal_RejectsEscapingKeys(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	assert.NoError(t, err)
+
+	for _, key := range []string{"", "/etc/passwd", "../secret", "a/../../b", "a//b", `a\b`} {
+		err = store.Put(context.Background(), key, strings.NewReader("x"), 1, "")
+		assert.ErrorIs(t, err, ErrInvalidKey, key)
+	}
+}
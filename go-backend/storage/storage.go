@@ -0,0 +1,39 @@
+// Package storage keeps the uploaded files in a blob store.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// returned when a key does not exist in the store
+var ErrNotFound = errors.New("object not found")
+
+// returned for keys that are empty or could escape the store
+var ErrInvalidKey = errors.New("invalid object key")
+
+// A blob store. Keys are slash separated paths such as attachments/1/2/abc.
+type Storage interface {
+	// Stores the content under key, replacing an existing object
+	Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error
+	// Opens the object, ErrNotFound when it does not exist
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Deletes the object, deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return ErrInvalidKey
+	}
+
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return ErrInvalidKey
+		}
+	}
+
+	return nil
+}
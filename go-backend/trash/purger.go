@@ -0,0 +1,158 @@
+package trash
+
+import (
+	"context"
+	"time"
+
+	"github.com/acornak/car-maintenance-tracker/imaging"
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/acornak/car-maintenance-tracker/storage"
+	"go.uber.org/zap"
+)
+
+// how many orphaned attachments or car images are deleted per batch
+const attachmentBatchSize = 100
+
+// The storage the purger needs, implemented by *models.DBModel
+type Store interface {
+	PurgeTrash(ctx context.Context, before time.Time) (int64, error)
+	GetOrphanedAttachments(ctx context.Context, limit int) ([]models.Attachment, error)
+	RemoveOrphanedAttachment(ctx context.Context, attachmentId int) error
+	GetOrphanedCarImages(ctx context.Context, limit int) ([]models.CarImage, error)
+	RemoveOrphanedCarImage(ctx context.Context, imageId int) error
+}
+
+// Periodically deletes the records that have been in the trash for longer
+// than the retention period, and the files attached to records or cars that
+// are gone
+type Purger struct {
+	store     Store
+	blobs     storage.Storage
+	retention time.Duration
+	interval  time.Duration
+	logger    *zap.SugaredLogger
+	now       func() time.Time
+}
+
+func NewPurger(store Store, blobs storage.Storage, retention, interval time.Duration, logger *zap.SugaredLogger) *Purger {
+	return &Purger{
+		store:     store,
+		blobs:     blobs,
+		retention: retention,
+		interval:  interval,
+		logger:    logger,
+		now:       time.Now,
+	}
+}
+
+// Purges right away and then on every tick until ctx is cancelled
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		err := p.RunOnce(ctx)
+		if err != nil {
+			p.logger.Error("failed to purge the trash: ", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			p.logger.Info("trash purger stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Deletes everything that was moved to the trash before the retention period
+// and then the attachments and car images left without their car or record
+func (p *Purger) RunOnce(ctx context.Context) error {
+	purged, err := p.store.PurgeTrash(ctx, p.now().Add(-p.retention))
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		p.logger.Info("purged ", purged, " records from the trash")
+	}
+
+	err = p.purgeAttachments(ctx)
+	if err != nil {
+		return err
+	}
+
+	return p.purgeCarImages(ctx)
+}
+
+// The file is deleted before the row, so a failed delete is retried on the
+// next run instead of leaking the file
+func (p *Purger) purgeAttachments(ctx context.Context) error {
+	var purged int
+
+	for {
+		attachments, err := p.store.GetOrphanedAttachments(ctx, attachmentBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, a := range attachments {
+			err = p.blobs.Delete(ctx, a.StorageKey)
+			if err != nil {
+				return err
+			}
+
+			err = p.store.RemoveOrphanedAttachment(ctx, a.ID)
+			if err != nil {
+				return err
+			}
+			purged++
+		}
+
+		if len(attachments) < attachmentBatchSize {
+			break
+		}
+	}
+
+	if purged > 0 {
+		p.logger.Info("purged ", purged, " orphaned attachments")
+	}
+
+	return nil
+}
+
+func (p *Purger) purgeCarImages(ctx context.Context) error {
+	var purged int
+
+	for {
+		images, err := p.store.GetOrphanedCarImages(ctx, attachmentBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, img := range images {
+			for _, size := range imaging.Sizes {
+				err = p.blobs.Delete(ctx, img.StorageKey(size.Name))
+				if err != nil {
+					return err
+				}
+			}
+
+			err = p.store.RemoveOrphanedCarImage(ctx, img.ID)
+			if err != nil {
+				return err
+			}
+			purged++
+		}
+
+		if len(images) < attachmentBatchSize {
+			break
+		}
+	}
+
+	if purged > 0 {
+		p.logger.Info("purged ", purged, " images of purged cars")
+	}
+
+	return nil
+}
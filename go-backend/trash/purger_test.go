@@ -0,0 +1,181 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/acornak/car-maintenance-tracker/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type fakeStore struct {
+	mu          sync.Mutex
+	before      []time.Time
+	err         error
+	attachments []models.Attachment
+	removed     []int
+	images      []models.CarImage
+}
+
+func (f *fakeStore) PurgeTrash(ctx context.Context, before time.Time) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.before = append(f.before, before)
+
+	return 3, f.err
+}
+
+func (f *fakeStore) GetOrphanedAttachments(ctx context.Context, limit int) ([]models.Attachment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n := len(f.attachments)
+	if n > limit {
+		n = limit
+	}
+
+	return append([]models.Attachment{}, f.attachments[:n]...), nil
+}
+
+func (f *fakeStore) RemoveOrphanedAttachment(ctx context.Context, attachmentId int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.removed = append(f.removed, attachmentId)
+	for i, a := range f.attachments {
+		if a.ID == attachmentId {
+			f.attachments = append(f.attachments[:i], f.attachments[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeStore) GetOrphanedCarImages(ctx context.Context, limit int) ([]models.CarImage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	images := f.images
+	f.images = nil
+
+	return images, nil
+}
+
+func (f *fakeStore) RemoveOrphanedCarImage(ctx context.Context, imageId int) error {
+	return nil
+}
+
+type fakeBlobs struct {
+	deleted []string
+	err     error
+}
+
+func (f *fakeBlobs) Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error {
+	return nil
+}
+
+func (f *fakeBlobs) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return nil, nil
+}
+
+func (f *fakeBlobs) Delete(ctx context.Context, key string) error {
+	if f.err != nil {
+		return f.err
+	}
+
+	f.deleted = append(f.deleted, key)
+
+	return nil
+}
+
+func TestRunOnce_PurgesOlderThanRetention(t *testing.T) {
+	store := &fakeStore{}
+	p := NewPurger(store, &fakeBlobs{}, 30*24*time.Hour, time.Hour, zap.NewNop().Sugar())
+	p.now = func() time.Time { return time.Date(2023, 7, 31, 12, 0, 0, 0, time.UTC) }
+
+	err := p.RunOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)}, store.before)
+}
+
+func TestRunOnce_ReturnsStoreError(t *testing.T) {
+	store := &fakeStore{err: errors.New("mocked error")}
+	p := NewPurger(store, &fakeBlobs{}, time.Hour, time.Hour, zap.NewNop().Sugar())
+
+	err := p.RunOnce(context.Background())
+
+	assert.EqualError(t, err, "mocked error")
+}
+
+func TestRun_StopsOnCancel(t *testing.T) {
+	store := &fakeStore{}
+	p := NewPurger(store, &fakeBlobs{}, time.Hour, time.Millisecond, zap.NewNop().Sugar())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("purger did not stop after cancel")
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	assert.Greater(t, len(store.before), 1)
+}
+
+func TestRunOnce_DeletesOrphanedAttachments(t *testing.T) {
+	store := &fakeStore{}
+	for i := 1; i <= attachmentBatchSize+1; i++ {
+		store.attachments = append(store.attachments, models.Attachment{ID: i, StorageKey: fmt.Sprintf("attachments/1/2/%d", i)})
+	}
+	blobs := &fakeBlobs{}
+	p := NewPurger(store, blobs, time.Hour, time.Hour, zap.NewNop().Sugar())
+
+	err := p.RunOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, blobs.deleted, attachmentBatchSize+1)
+	assert.Equal(t, "attachments/1/2/1", blobs.deleted[0])
+	assert.Len(t, store.removed, attachmentBatchSize+1)
+	assert.Empty(t, store.attachments)
+}
+
+func TestRunOnce_KeepsAttachmentWhenFileDeleteFails(t *testing.T) {
+	store := &fakeStore{attachments: []models.Attachment{{ID: 1, StorageKey: "attachments/1/2/abc"}}}
+	p := NewPurger(store, &fakeBlobs{err: errors.New("mocked error")}, time.Hour, time.Hour, zap.NewNop().Sugar())
+
+	err := p.RunOnce(context.Background())
+
+	assert.EqualError(t, err, "mocked error")
+	assert.Empty(t, store.removed)
+	assert.Len(t, store.attachments, 1)
+}
+
+func TestRunOnce_DeletesImagesOfPurgedCars(t *testing.T) {
+	store := &fakeStore{images: []models.CarImage{{ID: 1, StoragePrefix: "cars/5/2/abc"}}}
+	blobs := &fakeBlobs{}
+	p := NewPurger(store, blobs, time.Hour, time.Hour, zap.NewNop().Sugar())
+
+	err := p.RunOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cars/5/2/abc/large.jpg", "cars/5/2/abc/medium.jpg", "cars/5/2/abc/small.jpg", "cars/5/2/abc/thumb.jpg"}, blobs.deleted)
+}
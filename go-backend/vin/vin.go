@@ -0,0 +1,250 @@
+// Package vin validates vehicle identification numbers and decodes the
+// manufacturer, model year and plant without any network access.
+package vin
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Length of a VIN as defined by ISO 3779
+const Length = 17
+
+var (
+	ErrLength     = errors.New("VIN must be 17 characters long")
+	ErrCharset    = errors.New("VIN may only contain digits and the letters A to Z except I, O and Q")
+	ErrCheckDigit = errors.New("VIN check digit does not match")
+)
+
+// Model year characters in the order of their years, the cycle repeats every 30 years
+const yearCodes = "ABCDEFGHJKLMNPRSTVWXY123456789"
+
+// Weights of the positions in the check digit sum, position 9 is the check digit itself
+var weights = [Length]int{8, 7, 6, 5, 4, 3, 2, 10, 0, 9, 8, 7, 6, 5, 4, 3, 2}
+
+// clock of Decode, replaced in tests
+var now = time.Now
+
+//go:embed wmi.csv
+var wmiData string
+
+// manufacturers by world manufacturer identifier
+var wmiTable = loadWMI(wmiData)
+
+// The information encoded in a VIN
+type Decoded struct {
+	VIN string `json:"vin"`
+	// world manufacturer identifier, the first three characters
+	WMI string `json:"wmi"`
+	// empty when the WMI is not in the embedded table
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Region       string `json:"region,omitempty"`
+	ModelYear    int    `json:"model_year,omitempty"`
+	// position 11, its meaning is up to the manufacturer
+	PlantCode    string `json:"plant_code"`
+	SerialNumber string `json:"serial_number"`
+	// position 9 matches the computed check digit
+	CheckDigitValid bool `json:"check_digit_valid"`
+}
+
+// Upper cases the VIN and removes surrounding whitespace
+func Normalize(vin string) string {
+	return strings.ToUpper(strings.TrimSpace(vin))
+}
+
+// Checks the length, the character set and, where the region requires it, the
+// check digit of a normalized VIN. North American and Chinese VINs must carry a
+// valid check digit, elsewhere manufacturers are free to use position 9 for
+// other data, so a mismatch there is only reported by Decode.
+func Validate(vin string) error {
+	if len(vin) != Length {
+		return ErrLength
+	}
+
+	for i := 0; i < Length; i++ {
+		if transliterate(vin[i]) < 0 {
+			return ErrCharset
+		}
+	}
+
+	if checkDigitRequired(vin) && vin[8] != CheckDigit(vin) {
+		return ErrCheckDigit
+	}
+
+	return nil
+}
+
+// Validates and decodes a VIN
+func Decode(vin string) (Decoded, error) {
+	vin = Normalize(vin)
+
+	err := Validate(vin)
+	if err != nil {
+		return Decoded{}, err
+	}
+
+	decoded := Decoded{
+		VIN:             vin,
+		WMI:             vin[:3],
+		Manufacturer:    wmiTable[vin[:3]],
+		Region:          region(vin[0]),
+		PlantCode:       vin[10:11],
+		SerialNumber:    vin[11:],
+		CheckDigitValid: vin[8] == CheckDigit(vin),
+	}
+	decoded.ModelYear = modelYear(vin, now().Year())
+
+	return decoded, nil
+}
+
+// Computes the check digit of a VIN with a valid character set, 'X' stands for 10
+func CheckDigit(vin string) byte {
+	sum := 0
+	for i := 0; i < Length; i++ {
+		sum += transliterate(vin[i]) * weights[i]
+	}
+
+	digit := sum % 11
+	if digit == 10 {
+		return 'X'
+	}
+
+	return byte('0' + digit)
+}
+
+// Reports whether the decoded manufacturer is the given car maker, ignoring
+// case, spaces, hyphens and diacritics
+func SameMaker(manufacturer, maker string) bool {
+	return fold(manufacturer) == fold(maker)
+}
+
+// Numeric value of a VIN character, -1 for characters a VIN must not contain
+func transliterate(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0')
+	case c == 'I' || c == 'O' || c == 'Q':
+		return -1
+	case c >= 'A' && c <= 'H':
+		return int(c-'A') + 1
+	case c >= 'J' && c <= 'N':
+		return int(c-'J') + 1
+	case c == 'P':
+		return 7
+	case c == 'R':
+		return 9
+	case c >= 'S' && c <= 'Z':
+		return int(c-'S') + 2
+	}
+
+	return -1
+}
+
+func checkDigitRequired(vin string) bool {
+	return (vin[0] >= '1' && vin[0] <= '5') || vin[0] == 'L'
+}
+
+func region(c byte) string {
+	switch {
+	case c >= 'A' && c <= 'H':
+		return "Africa"
+	case c >= 'J' && c <= 'R':
+		return "Asia"
+	case c >= 'S' && c <= 'Z':
+		return "Europe"
+	case c >= '1' && c <= '5':
+		return "North America"
+	case c == '6' || c == '7':
+		return "Oceania"
+	case c == '8' || c == '9':
+		return "South America"
+	}
+
+	return ""
+}
+
+// Resolves the 30 year cycle of the model year character at position 10. North
+// American VINs tell the cycle by position 7, a letter there means 2010 or
+// later. Otherwise the latest year not after next year is taken.
+func modelYear(vin string, currentYear int) int {
+	i := strings.IndexByte(yearCodes, vin[9])
+	if i < 0 {
+		return 0
+	}
+	year := 1980 + i
+
+	if vin[0] >= '1' && vin[0] <= '5' {
+		if vin[6] >= 'A' && vin[6] <= 'Z' {
+			year += 30
+		}
+		return year
+	}
+
+	for year+30 <= currentYear+1 {
+		year += 30
+	}
+
+	return year
+}
+
+func fold(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r == ' ' || r == '-':
+			continue
+		case r > unicode.MaxASCII:
+			r = unaccent(r)
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// Base letter of the accented letters used in maker names
+func unaccent(r rune) rune {
+	switch r {
+	case 'à', 'á', 'â', 'ä', 'ã', 'å':
+		return 'a'
+	case 'ç', 'č':
+		return 'c'
+	case 'è', 'é', 'ê', 'ë', 'ě':
+		return 'e'
+	case 'ì', 'í', 'î', 'ï':
+		return 'i'
+	case 'ò', 'ó', 'ô', 'ö', 'õ':
+		return 'o'
+	case 'ù', 'ú', 'û', 'ü', 'ů':
+		return 'u'
+	case 'š':
+		return 's'
+	case 'ž':
+		return 'z'
+	case 'ř':
+		return 'r'
+	case 'ý':
+		return 'y'
+	}
+
+	return r
+}
+
+func loadWMI(data string) map[string]string {
+	records, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+	if err != nil {
+		// the table is embedded at build time, a broken one is a programming error
+		panic("vin: invalid WMI table: " + err.Error())
+	}
+
+	table := make(map[string]string, len(records))
+	for _, record := range records[1:] {
+		table[record[0]] = record[1]
+	}
+
+	return table
+}
@@ -0,0 +1,98 @@
+package vin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckDigit(t *testing.T) {
+	assert.Equal(t, byte('X'), CheckDigit("1M8GDM9AXKP042788"))
+	assert.Equal(t, byte('3'), CheckDigit("1HGCM82633A004352"))
+	assert.Equal(t, byte('2'), CheckDigit("5YJ3E1EA2KF317000"))
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate("1M8GDM9AXKP042788"))
+	// Europe does not require the check digit
+	assert.NoError(t, Validate("WVWZZZ1JZ3W386752"))
+
+	assert.ErrorIs(t, Validate("1HGCM82633A00435"), ErrLength)
+	assert.ErrorIs(t, Validate("1HGCM82633A0043521"), ErrLength)
+	assert.ErrorIs(t, Validate("1HGCM82633A0O4352"), ErrCharset)
+	assert.ErrorIs(t, Validate("1HGCM82633a004352"), ErrCharset)
+	assert.ErrorIs(t, Validate("1HGCM82643A004352"), ErrCheckDigit)
+	assert.ErrorIs(t, Validate("LRW3E7EKXMC000001"), ErrCheckDigit)
+}
+
+func TestDecode_NorthAmerica(t *testing.T) {
+	decoded, err := Decode(" 1hgcm82633a004352 ")
+
+	assert.NoError(t, err)
+	assert.Equal(t, Decoded{
+		VIN:             "1HGCM82633A004352",
+		WMI:             "1HG",
+		Manufacturer:    "Honda",
+		Region:          "North America",
+		ModelYear:       2003,
+		PlantCode:       "A",
+		SerialNumber:    "004352",
+		CheckDigitValid: true,
+	}, decoded)
+
+	// a letter at position 7 moves the year to the 2010 cycle
+	decoded, err = Decode("5YJ3E1EA2KF317000")
+	assert.NoError(t, err)
+	assert.Equal(t, "Tesla", decoded.Manufacturer)
+	assert.Equal(t, 2019, decoded.ModelYear)
+	assert.Equal(t, "F", decoded.PlantCode)
+}
+
+func TestDecode_Europe(t *testing.T) {
+	defer func() { now = time.Now }()
+	now = func() time.Time { return time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC) }
+
+	decoded, err := Decode("WVWZZZ1JZ3W386752")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Volkswagen", decoded.Manufacturer)
+	assert.Equal(t, "Europe", decoded.Region)
+	assert.Equal(t, 2003, decoded.ModelYear)
+	assert.False(t, decoded.CheckDigitValid)
+
+	// the latest year not after next year wins
+	decoded, err = Decode("WBA8E9G50GNU12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "BMW", decoded.Manufacturer)
+	assert.Equal(t, 2016, decoded.ModelYear)
+}
+
+func TestDecode_UnknownWMI(t *testing.T) {
+	decoded, err := Decode("ZZZZZZZZZZZZZZZZZ")
+
+	assert.NoError(t, err)
+	assert.Empty(t, decoded.Manufacturer)
+	assert.Equal(t, "Europe", decoded.Region)
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	_, err := Decode("not a vin")
+	assert.ErrorIs(t, err, ErrLength)
+}
+
+func TestSameMaker(t *testing.T) {
+	assert.True(t, SameMaker("Citroën", "Citroen"))
+	assert.True(t, SameMaker("Mercedes-Benz", "mercedes benz"))
+	assert.True(t, SameMaker("Land Rover", "LandRover"))
+	assert.False(t, SameMaker("Lexus", "Toyota"))
+}
+
+func TestWMITable(t *testing.T) {
+	assert.Equal(t, "Škoda", wmiTable["TMB"])
+	assert.Equal(t, "Mini", wmiTable["WMW"])
+	for wmi, manufacturer := range wmiTable {
+		assert.Len(t, wmi, 3, manufacturer)
+		assert.NotEmpty(t, manufacturer, wmi)
+	}
+}
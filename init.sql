@@ -5,20 +5,77 @@ CREATE TABLE IF NOT EXISTS users (
     nickname VARCHAR(100) UNIQUE NOT NULL,
     email VARCHAR(100) UNIQUE NOT NULL,
     password VARCHAR(100) NOT NULL,
+    -- administrators maintain the car catalog
+    is_admin BOOLEAN NOT NULL DEFAULT FALSE,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+-- retired makers and models stay for the cars using them but are hidden from the pick lists
 CREATE TABLE IF NOT EXISTS car_makers (
     id SERIAL PRIMARY KEY,
-    name VARCHAR(100) NOT NULL
+    name VARCHAR(100) NOT NULL,
+    retired_at TIMESTAMP WITH TIME ZONE
 );
 
+CREATE UNIQUE INDEX IF NOT EXISTS car_makers_name_idx ON car_makers (LOWER(name));
+
 CREATE TABLE IF NOT EXISTS car_models (
     id SERIAL PRIMARY KEY,
     car_maker_id INTEGER REFERENCES car_makers(id),
-    name VARCHAR(100) NOT NULL
+    name VARCHAR(100) NOT NULL,
+    retired_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS car_models_maker_name_idx ON car_models (car_maker_id, LOWER(name));
+
+-- a model generation, year_to is NULL while it is still produced
+CREATE TABLE IF NOT EXISTS car_generations (
+    id SERIAL PRIMARY KEY,
+    car_model_id INTEGER REFERENCES car_models(id),
+    name VARCHAR(100) NOT NULL,
+    year_from INTEGER NOT NULL,
+    year_to INTEGER
 );
 
+CREATE INDEX IF NOT EXISTS car_generations_model_id_idx ON car_generations (car_model_id);
+
+-- displacement is NULL for electric motors
+CREATE TABLE IF NOT EXISTS engines (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    fuel_type VARCHAR(20) NOT NULL,
+    displacement_cc INTEGER,
+    power_kw INTEGER NOT NULL
+);
+
+-- a trim of a generation with its engine and body
+CREATE TABLE IF NOT EXISTS car_variants (
+    id SERIAL PRIMARY KEY,
+    car_generation_id INTEGER REFERENCES car_generations(id),
+    engine_id INTEGER REFERENCES engines(id),
+    name VARCHAR(100) NOT NULL,
+    body_type VARCHAR(20) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS car_variants_generation_id_idx ON car_variants (car_generation_id);
+
+-- companies and teams owning a fleet of cars, admins manage the members and the fleet
+CREATE TABLE IF NOT EXISTS organizations (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS organization_members (
+    organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organization_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS organization_members_user_id_idx ON organization_members (user_id);
+
 CREATE TABLE IF NOT EXISTS users_cars (
     id SERIAL PRIMARY KEY,
     user_id INTEGER REFERENCES users(id),
@@ -31,12 +88,298 @@ CREATE TABLE IF NOT EXISTS users_cars (
     description VARCHAR(100) NOT NULL,
     license_plate VARCHAR(100) NOT NULL,
     vin VARCHAR(100) NOT NULL,
-    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+    variant_id INTEGER REFERENCES car_variants(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    archived_at TIMESTAMP WITH TIME ZONE,
+    sold_at DATE,
+    sale_price INTEGER,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    -- a fleet car, user_id is then the user who added it
+    organization_id INTEGER REFERENCES organizations(id)
 );
 
+CREATE INDEX IF NOT EXISTS users_cars_organization_id_idx ON users_cars (organization_id) WHERE organization_id IS NOT NULL;
+
 CREATE TABLE IF NOT EXISTS maintenance (
     id SERIAL PRIMARY KEY,
-    car_id INTEGER REFERENCES users_cars(id)
+    car_id INTEGER REFERENCES users_cars(id) ON DELETE CASCADE,
+    service_date DATE NOT NULL,
+    odometer INTEGER NOT NULL DEFAULT 0,
+    service_type VARCHAR(100) NOT NULL,
+    description VARCHAR(400) NOT NULL DEFAULT '',
+    cost NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    performed_by VARCHAR(100) NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS maintenance_car_id_idx ON maintenance (car_id, service_date);
+
+CREATE TABLE IF NOT EXISTS expenses (
+    id SERIAL PRIMARY KEY,
+    car_id INTEGER REFERENCES users_cars(id) ON DELETE CASCADE,
+    category VARCHAR(20) NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    currency CHAR(3) NOT NULL DEFAULT 'EUR',
+    expense_date DATE NOT NULL,
+    odometer INTEGER,
+    maintenance_id INTEGER REFERENCES maintenance(id) ON DELETE SET NULL,
+    description VARCHAR(400) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS expenses_car_id_idx ON expenses (car_id, expense_date);
+
+-- the trash listing and the purge only look at soft deleted rows
+CREATE INDEX IF NOT EXISTS users_cars_deleted_at_idx ON users_cars (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS maintenance_deleted_at_idx ON maintenance (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS expenses_deleted_at_idx ON expenses (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS fuel_logs (
+    id SERIAL PRIMARY KEY,
+    car_id INTEGER REFERENCES users_cars(id) ON DELETE CASCADE,
+    fill_date DATE NOT NULL,
+    odometer INTEGER NOT NULL,
+    volume NUMERIC(8, 2) NOT NULL,
+    price_per_unit NUMERIC(8, 3) NOT NULL DEFAULT 0,
+    full_tank BOOLEAN NOT NULL DEFAULT TRUE,
+    missed_previous BOOLEAN NOT NULL DEFAULT FALSE,
+    station VARCHAR(100) NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS fuel_logs_car_id_idx ON fuel_logs (car_id, fill_date);
+
+CREATE TABLE IF NOT EXISTS charging_sessions (
+    id SERIAL PRIMARY KEY,
+    car_id INTEGER REFERENCES users_cars(id) ON DELETE CASCADE,
+    charge_date DATE NOT NULL,
+    odometer INTEGER NOT NULL,
+    energy_kwh NUMERIC(8, 2) NOT NULL,
+    cost NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    charger_type VARCHAR(2) NOT NULL,
+    location_type VARCHAR(20) NOT NULL DEFAULT 'other',
+    start_soc INTEGER CHECK (start_soc BETWEEN 0 AND 100),
+    end_soc INTEGER CHECK (end_soc BETWEEN 0 AND 100),
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS charging_sessions_car_id_idx ON charging_sessions (car_id, charge_date);
+
+CREATE TABLE IF NOT EXISTS battery_health_logs (
+    id SERIAL PRIMARY KEY,
+    car_id INTEGER REFERENCES users_cars(id) ON DELETE CASCADE,
+    log_date DATE NOT NULL,
+    odometer INTEGER,
+    capacity_kwh NUMERIC(6, 2),
+    range_km INTEGER,
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS battery_health_logs_car_id_idx ON battery_health_logs (car_id, log_date);
+
+CREATE TABLE IF NOT EXISTS tire_sets (
+    id SERIAL PRIMARY KEY,
+    car_id INTEGER REFERENCES users_cars(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    season VARCHAR(20) NOT NULL,
+    brand VARCHAR(100) NOT NULL DEFAULT '',
+    size VARCHAR(40) NOT NULL DEFAULT '',
+    dot_code VARCHAR(4) NOT NULL DEFAULT '',
+    purchase_cost NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS tire_sets_car_id_idx ON tire_sets (car_id);
+
+CREATE TABLE IF NOT EXISTS tire_events (
+    id SERIAL PRIMARY KEY,
+    car_id INTEGER REFERENCES users_cars(id) ON DELETE CASCADE,
+    tire_set_id INTEGER REFERENCES tire_sets(id) ON DELETE CASCADE,
+    event_type VARCHAR(10) NOT NULL,
+    event_date DATE NOT NULL,
+    odometer INTEGER NOT NULL,
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS tire_events_car_id_idx ON tire_events (car_id, event_date);
+
+CREATE TABLE IF NOT EXISTS tread_measurements (
+    id SERIAL PRIMARY KEY,
+    tire_set_id INTEGER REFERENCES tire_sets(id) ON DELETE CASCADE,
+    measured_on DATE NOT NULL,
+    position VARCHAR(20) NOT NULL,
+    depth_mm NUMERIC(4, 1) NOT NULL,
+    odometer INTEGER,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS tread_measurements_tire_set_id_idx ON tread_measurements (tire_set_id, measured_on);
+
+CREATE TABLE IF NOT EXISTS vehicle_documents (
+    id SERIAL PRIMARY KEY,
+    car_id INTEGER REFERENCES users_cars(id) ON DELETE CASCADE,
+    document_type VARCHAR(20) NOT NULL,
+    issuer VARCHAR(100) NOT NULL DEFAULT '',
+    policy_number VARCHAR(100) NOT NULL DEFAULT '',
+    valid_from DATE NOT NULL,
+    valid_until DATE NOT NULL,
+    mileage_limit INTEGER,
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS vehicle_documents_car_id_idx ON vehicle_documents (car_id, document_type, valid_until);
+
+-- files attached to maintenance records, expenses and vehicle documents, the
+-- content is in the blob storage. Rows outlive their car so the purger can
+-- still delete the files.
+CREATE TABLE IF NOT EXISTS attachments (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    car_id INTEGER REFERENCES users_cars(id) ON DELETE SET NULL,
+    record_type VARCHAR(20) NOT NULL,
+    record_id INTEGER NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    storage_key VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS attachments_car_id_idx ON attachments (car_id, record_type, record_id);
+CREATE INDEX IF NOT EXISTS attachments_user_id_idx ON attachments (user_id);
+
+-- the uploaded photo of a car, users_cars.image holds its URL. Cars that still
+-- have a pasted URL in users_cars.image have no row here.
+CREATE TABLE IF NOT EXISTS car_images (
+    id SERIAL PRIMARY KEY,
+    car_id INTEGER UNIQUE REFERENCES users_cars(id) ON DELETE SET NULL,
+    user_id INTEGER NOT NULL,
+    token VARCHAR(64) NOT NULL,
+    storage_prefix VARCHAR(255) NOT NULL UNIQUE,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- users a car is shared with besides its owner in users_cars.user_id, an
+-- editor may change the car and its history, a viewer only reads them
+CREATE TABLE IF NOT EXISTS car_members (
+    car_id INTEGER NOT NULL REFERENCES users_cars(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (car_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS car_members_user_id_idx ON car_members (user_id);
+
+-- invitations to share a car, answered ones are kept with their response
+CREATE TABLE IF NOT EXISTS car_invitations (
+    id SERIAL PRIMARY KEY,
+    car_id INTEGER NOT NULL REFERENCES users_cars(id) ON DELETE CASCADE,
+    inviter_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    invitee_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    responded_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS car_invitations_pending_idx ON car_invitations (car_id, invitee_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS car_invitations_invitee_id_idx ON car_invitations (invitee_id, status);
+
+-- drivers of fleet cars, an assignment without an end is the current one.
+-- Costs dated from started_at up to the day before ended_at count for the driver.
+CREATE TABLE IF NOT EXISTS driver_assignments (
+    id SERIAL PRIMARY KEY,
+    car_id INTEGER NOT NULL REFERENCES users_cars(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    started_at DATE NOT NULL,
+    ended_at DATE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS driver_assignments_current_idx ON driver_assignments (car_id) WHERE ended_at IS NULL;
+CREATE INDEX IF NOT EXISTS driver_assignments_user_id_idx ON driver_assignments (user_id) WHERE ended_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS service_schedules (
+    id SERIAL PRIMARY KEY,
+    car_id INTEGER REFERENCES users_cars(id) ON DELETE CASCADE,
+    service_type VARCHAR(100) NOT NULL,
+    interval_km INTEGER NOT NULL DEFAULT 0,
+    interval_months INTEGER NOT NULL DEFAULT 0,
+    description VARCHAR(400) NOT NULL DEFAULT '',
+    start_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    start_odometer INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS odometer_readings (
+    id SERIAL PRIMARY KEY,
+    car_id INTEGER REFERENCES users_cars(id) ON DELETE CASCADE,
+    reading_date DATE NOT NULL,
+    odometer INTEGER NOT NULL,
+    is_replacement BOOLEAN NOT NULL DEFAULT FALSE,
+    note VARCHAR(400) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS odometer_readings_car_id_idx ON odometer_readings (car_id, reading_date);
+
+CREATE TABLE IF NOT EXISTS reminders (
+    id SERIAL PRIMARY KEY,
+    car_id INTEGER REFERENCES users_cars(id) ON DELETE CASCADE,
+    kind VARCHAR(50) NOT NULL,
+    source_id INTEGER NOT NULL,
+    title VARCHAR(200) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    due_date DATE,
+    due_odometer INTEGER,
+    dedupe_key VARCHAR(200) UNIQUE NOT NULL,
+    dismissed_at TIMESTAMP WITH TIME ZONE,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS notification_outbox (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    recipient VARCHAR(100) NOT NULL,
+    subject VARCHAR(200) NOT NULL,
+    body TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS notification_outbox_pending_idx ON notification_outbox (next_attempt_at) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS service_schedule_templates (
+    id SERIAL PRIMARY KEY,
+    car_model_id INTEGER REFERENCES car_models(id),
+    -- NULL applies to every fuel type
+    fuel_type VARCHAR(20),
+    service_type VARCHAR(100) NOT NULL,
+    interval_km INTEGER NOT NULL DEFAULT 0,
+    interval_months INTEGER NOT NULL DEFAULT 0,
+    description VARCHAR(400) NOT NULL DEFAULT ''
 );
 
 INSERT INTO car_makers (id, name) VALUES
@@ -310,4 +653,75 @@ INSERT INTO car_models (id, car_maker_id, name) VALUES
     (237, 29, 'XC60'),
     (238, 29, 'XC90');
 
+INSERT INTO car_generations (id, car_model_id, name, year_from, year_to) VALUES
+    (1, 226, 'Golf V', 2003, 2008),
+    (2, 226, 'Golf VI', 2008, 2012),
+    (3, 226, 'Golf VII', 2012, 2020),
+    (4, 226, 'Golf VIII', 2019, NULL),
+    (5, 211, 'Model 3', 2017, 2023),
+    (6, 211, 'Model 3 Highland', 2023, NULL),
+    (7, 214, 'Corolla E210', 2018, NULL);
+
+INSERT INTO engines (id, name, fuel_type, displacement_cc, power_kw) VALUES
+    (1, '1.6 TDI', 'diesel', 1598, 77),
+    (2, '2.0 TDI', 'diesel', 1968, 110),
+    (3, '1.4 TSI', 'petrol', 1395, 90),
+    (4, '1.4 eHybrid', 'plug_in_hybrid', 1395, 180),
+    (5, 'Long Range AWD', 'electric', NULL, 366),
+    (6, 'RWD', 'electric', NULL, 208),
+    (7, '1.8 Hybrid', 'hybrid', 1798, 90);
+
+INSERT INTO car_variants (id, car_generation_id, engine_id, name, body_type) VALUES
+    (1, 1, 1, '1.6 TDI Comfortline', 'hatchback'),
+    (2, 2, 1, '1.6 TDI Comfortline', 'hatchback'),
+    (3, 2, 2, '2.0 TDI Variant', 'wagon'),
+    (4, 3, 3, '1.4 TSI Highline', 'hatchback'),
+    (5, 3, 2, '2.0 TDI GTD', 'hatchback'),
+    (6, 4, 4, 'GTE', 'hatchback'),
+    (7, 5, 5, 'Long Range', 'sedan'),
+    (8, 6, 6, 'RWD', 'sedan'),
+    (9, 7, 7, '1.8 Hybrid', 'hatchback'),
+    (10, 7, 7, '1.8 Hybrid Touring Sports', 'wagon');
+
+-- templates without a model apply to every car, model specific rows replace them
+-- and a model specific row without intervals removes the rule for that model
+INSERT INTO service_schedule_templates (car_model_id, service_type, interval_km, interval_months, description) VALUES
+    (NULL, 'Oil change', 15000, 12, 'Engine oil and oil filter'),
+    (NULL, 'Air filter', 30000, 24, 'Engine air filter'),
+    (NULL, 'Cabin filter', 15000, 12, 'Cabin air filter'),
+    (NULL, 'Brake fluid', 0, 24, 'Brake fluid replacement'),
+    (NULL, 'Spark plugs', 60000, 48, 'Spark plugs replacement'),
+    (NULL, 'Coolant', 0, 60, 'Coolant replacement'),
+    (NULL, 'Tire rotation', 10000, 0, 'Rotate tires'),
+    (210, 'Oil change', 0, 0, 'Not applicable to electric vehicles'),
+    (211, 'Oil change', 0, 0, 'Not applicable to electric vehicles'),
+    (212, 'Oil change', 0, 0, 'Not applicable to electric vehicles'),
+    (213, 'Oil change', 0, 0, 'Not applicable to electric vehicles'),
+    (210, 'Spark plugs', 0, 0, 'Not applicable to electric vehicles'),
+    (211, 'Spark plugs', 0, 0, 'Not applicable to electric vehicles'),
+    (212, 'Spark plugs', 0, 0, 'Not applicable to electric vehicles'),
+    (213, 'Spark plugs', 0, 0, 'Not applicable to electric vehicles'),
+    (210, 'Air filter', 0, 0, 'Not applicable to electric vehicles'),
+    (211, 'Air filter', 0, 0, 'Not applicable to electric vehicles'),
+    (212, 'Air filter', 0, 0, 'Not applicable to electric vehicles'),
+    (213, 'Air filter', 0, 0, 'Not applicable to electric vehicles'),
+    (210, 'Cabin filter', 0, 24, 'Cabin air filter'),
+    (211, 'Cabin filter', 0, 24, 'Cabin air filter'),
+    (212, 'Cabin filter', 0, 24, 'Cabin air filter'),
+    (213, 'Cabin filter', 0, 24, 'Cabin air filter');
+
+-- fuel specific templates apply to cars whose variant has that fuel type
+INSERT INTO service_schedule_templates (car_model_id, fuel_type, service_type, interval_km, interval_months, description) VALUES
+    (NULL, 'diesel', 'Fuel filter', 30000, 24, 'Diesel fuel filter'),
+    (NULL, 'electric', 'Oil change', 0, 0, 'Not applicable to electric vehicles'),
+    (NULL, 'electric', 'Air filter', 0, 0, 'Not applicable to electric vehicles'),
+    (NULL, 'electric', 'Spark plugs', 0, 0, 'Not applicable to electric vehicles');
+
+-- the seed uses explicit ids, move the sequences past them so new rows get free ids
+SELECT setval('car_makers_id_seq', (SELECT MAX(id) FROM car_makers));
+SELECT setval('car_models_id_seq', (SELECT MAX(id) FROM car_models));
+SELECT setval('car_generations_id_seq', (SELECT MAX(id) FROM car_generations));
+SELECT setval('engines_id_seq', (SELECT MAX(id) FROM engines));
+SELECT setval('car_variants_id_seq', (SELECT MAX(id) FROM car_variants));
+
     COMMIT;